@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/config"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+	"github.com/vasu74/Call_Session_Management/internal/store/postgres"
+)
+
+const importUsage = `usage: main import -format FORMAT [-source NAME] [-tz ZONE] [-columns LIST] [-org ID] FILE...
+
+Imports call detail records as ended sessions. Records whose unique ID was already
+imported from the same source into the same organization are skipped, so a file can be
+imported again safely. Sessions belong to the default organization unless -org is given.
+Gzip compressed files are read as is.
+
+formats:
+  asterisk_csv     Asterisk Master.csv
+  freeswitch_csv   FreeSWITCH mod_cdr_csv; -columns lists the template's variables
+                   when the file has no header row and does not use the default template
+  freeswitch_xml   FreeSWITCH mod_xml_cdr, one or more cdr documents per file`
+
+// runImport implements the import subcommand
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(flags.Output(), importUsage) }
+	format := flags.String("format", "", "CDR format")
+	source := flags.String("source", "", "external source of the imported sessions (default: asterisk or freeswitch)")
+	tz := flags.String("tz", "", "time zone of timestamps without one (default: UTC)")
+	columns := flags.String("columns", "", "comma separated variables of a FreeSWITCH CSV template")
+	org := flags.String("org", model.DefaultOrganizationID.String(), "ID of the organization the sessions belong to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("missing file to import\n\n%s", importUsage)
+	}
+	orgID, err := uuid.Parse(*org)
+	if err != nil {
+		return fmt.Errorf("invalid -org: %w", err)
+	}
+
+	opts, err := model.ParseImportOptions(url.Values{
+		"format":  {*format},
+		"source":  {*source},
+		"tz":      {*tz},
+		"columns": {*columns},
+	})
+	if err != nil {
+		return err
+	}
+
+	numbers, err := numberNormalizer()
+	if err != nil {
+		return fmt.Errorf("invalid PHONE_DEFAULT_REGION: %w", err)
+	}
+
+	db := config.ConnectDB()
+	defer db.Close()
+	svc := service.New(postgres.New(db),
+		service.WithMetricRules(metricRules()),
+		service.WithNumberNormalizer(numbers),
+	)
+
+	ctx := context.Background()
+	if _, err := svc.GetOrganization(ctx, orgID.String()); err != nil {
+		return fmt.Errorf("organization %s: %w", orgID, err)
+	}
+
+	failed := false
+	for _, path := range flags.Args() {
+		result, err := importFile(ctx, svc, path, orgID, opts)
+		if result != nil {
+			printImportResult(path, result)
+			failed = failed || result.Failed > 0
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if failed {
+		return fmt.Errorf("some records were rejected")
+	}
+	return nil
+}
+
+func importFile(ctx context.Context, svc *service.Service, path string, orgID uuid.UUID, opts model.ImportOptions) (*model.ImportResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Sessions imported from the command line have no owner, so only callers who see
+	// all sessions of the organization can read them
+	access := model.SessionAccess{Scope: model.SessionScopeAll, OrgID: orgID, HomeOrgID: orgID}
+	return svc.ImportSessions(ctx, access, file, opts)
+}
+
+func printImportResult(path string, result *model.ImportResult) {
+	fmt.Printf("%s: %d rows, %d imported, %d duplicates, %d failed\n",
+		path, result.Rows, result.Imported, result.Duplicates, result.Failed)
+	for _, rowErr := range result.Errors {
+		fmt.Printf("  row %d: %s (%s)\n", rowErr.Row, rowErr.Message, rowErr.Code)
+	}
+	if result.ErrorsTruncated {
+		fmt.Printf("  ... further errors omitted\n")
+	}
+}
@@ -7,14 +7,32 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
+	// Analytics time zones must resolve even on hosts without a zoneinfo database
+	_ "time/tzdata"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/vasu74/Call_Session_Management/internal"
+	"github.com/vasu74/Call_Session_Management/internal/cdr"
+	"github.com/vasu74/Call_Session_Management/internal/cdrwriter"
 	"github.com/vasu74/Call_Session_Management/internal/config"
+	"github.com/vasu74/Call_Session_Management/internal/exporter"
+	"github.com/vasu74/Call_Session_Management/internal/handler"
+	"github.com/vasu74/Call_Session_Management/internal/leader"
+	"github.com/vasu74/Call_Session_Management/internal/middleware"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/phone"
+	"github.com/vasu74/Call_Session_Management/internal/reaper"
+	"github.com/vasu74/Call_Session_Management/internal/relay"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+	"github.com/vasu74/Call_Session_Management/internal/store/postgres"
+	"github.com/vasu74/Call_Session_Management/internal/stream"
+	"github.com/vasu74/Call_Session_Management/internal/webhook"
 )
 
 func init() {
@@ -30,6 +48,26 @@ func init() {
 }
 
 func main() {
+	// Run the migrate subcommand instead of the server when requested
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := runImport(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "normalize" {
+		if err := runNormalize(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Initialize logger
 	logger := log.New(os.Stdout, "[Call Session Management] ", log.LstdFlags|log.Lshortfile)
 
@@ -41,11 +79,23 @@ func main() {
 		}
 	}()
 
+	// Refuse to serve against an outdated schema
+	ensureSchemaCurrent(db, logger)
+
 	// Set up Gin router with custom logger
 	router := gin.New()
+	// Route on the escaped path so external call IDs may contain an encoded "/"
+	router.UseRawPath = true
+	// Client addresses, which API key allowlists are checked against, are only taken
+	// from forwarding headers set by a trusted proxy
+	if err := router.SetTrustedProxies(getListEnv("TRUSTED_PROXIES")); err != nil {
+		logger.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	router.Use(middleware.RequestID())
 	router.Use(gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		return fmt.Sprintf("[%s] | %s | %d | %s | %s | %s | %s | %s\n",
+		return fmt.Sprintf("[%s] | %s | %s | %d | %s | %s | %s | %s | %s\n",
 			param.TimeStamp.Format(time.RFC3339),
+			param.Keys["requestID"],
 			param.ClientIP,
 			param.StatusCode,
 			param.Method,
@@ -56,19 +106,178 @@ func main() {
 		)
 	}))
 	router.Use(gin.Recovery())
+	router.Use(middleware.ErrorHandler())
 
 	// Configure CORS
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{getEnv("CORS_ALLOW_ORIGINS", "*")},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", middleware.APIKeyHeader, "Last-Event-ID", middleware.RequestIDHeader, middleware.IdempotencyKeyHeader},
+		ExposeHeaders:    []string{"Content-Length", middleware.RequestIDHeader, middleware.IdempotentReplayedHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// Configure the consumers of the outbox, which are only purged once every enabled
+	// one of them has processed a message
+	webhookConfig := webhook.Config{
+		Interval:             getDurationEnv("WEBHOOK_POLL_INTERVAL", time.Second),
+		Timeout:              getDurationEnv("WEBHOOK_TIMEOUT", 10*time.Second),
+		MaxAttempts:          getIntEnv("WEBHOOK_MAX_ATTEMPTS", 10),
+		Retention:            getDurationEnv("WEBHOOK_RETENTION", 7*24*time.Hour),
+		AllowPrivateNetworks: getEnv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "false") == "true",
+	}
+	publisher, err := newOutboxPublisher()
+	if err != nil {
+		logger.Fatalf("Failed to set up outbox publisher: %v", err)
+	}
+	relayConfig := relay.Config{
+		Interval: getDurationEnv("OUTBOX_RELAY_INTERVAL", time.Second),
+	}
+	relayEnabled := publisher != nil && relayConfig.Enabled()
+
+	// Export jobs write their files to a directory shared by all replicas
+	exportDir := getEnv("EXPORT_DIR", "exports")
+	if err := os.MkdirAll(exportDir, 0o750); err != nil {
+		logger.Fatalf("Failed to create export directory: %v", err)
+	}
+
+	// CDR files are written by one replica at a time, so with several replicas CDR_DIR
+	// should be shared storage too
+	cdrTemplate, err := cdr.ParseTemplate(getEnv("CDR_TEMPLATE", cdr.DefaultTemplate))
+	if err != nil {
+		logger.Fatalf("Invalid CDR_TEMPLATE: %v", err)
+	}
+	cdrConfig := cdrwriter.Config{
+		Dir:            getEnv("CDR_DIR", "cdr"),
+		Interval:       getDurationEnv("CDR_WRITE_INTERVAL", 5*time.Second),
+		RotateInterval: getDurationEnv("CDR_ROTATE_INTERVAL", time.Hour),
+		MaxFileSize:    int64(getIntEnv("CDR_MAX_FILE_SIZE", 64<<20)),
+	}
+	if cdrConfig.Enabled() {
+		if err := os.MkdirAll(cdrConfig.Dir, 0o750); err != nil {
+			logger.Fatalf("Failed to create CDR directory: %v", err)
+		}
+	}
+
+	// National caller and callee numbers are taken to belong to the default region
+	numbers, err := numberNormalizer()
+	if err != nil {
+		logger.Fatalf("Invalid PHONE_DEFAULT_REGION: %v", err)
+	}
+
+	// Row-level security policies back the organization filter of session reads and writes
+	var storeOpts []postgres.Option
+	rowLevelSecurity := getEnv("DB_ROW_LEVEL_SECURITY", "false") == "true"
+	if rowLevelSecurity {
+		storeOpts = append(storeOpts, postgres.WithRowLevelSecurity())
+	}
+	store := postgres.New(db, storeOpts...)
+	if rowLevelSecurity {
+		if err := store.CheckRowLevelSecurity(context.Background()); err != nil {
+			logger.Fatalf("Invalid DB_ROW_LEVEL_SECURITY: %v", err)
+		}
+	}
+
 	// Set up routes
-	internal.Routes(router)
+	svc := service.New(store,
+		service.WithTokenTTLs(getDurationEnv("ACCESS_TOKEN_TTL", 15*time.Minute), getDurationEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour)),
+		service.WithIdempotencyTTL(getDurationEnv("IDEMPOTENCY_KEY_TTL", 24*time.Hour)),
+		service.WithIdempotencyLockTimeout(getDurationEnv("IDEMPOTENCY_LOCK_TIMEOUT", time.Minute)),
+		service.WithEventBatchLimit(getIntEnv("EVENT_BATCH_MAX_SIZE", 500)),
+		service.WithOutboxRetention(getDurationEnv("OUTBOX_RETENTION", 24*time.Hour)),
+		service.WithOutboxConsumers(model.OutboxConsumers{
+			Webhooks:  webhookConfig.Enabled(),
+			Publisher: relayEnabled,
+		}),
+		service.WithMetricRules(metricRules()),
+		service.WithExportJobs(exportDir, getDurationEnv("EXPORT_RETENTION", 24*time.Hour)),
+		service.WithCDRTemplate(cdrTemplate),
+		service.WithNumberNormalizer(numbers),
+		service.WithOrganizationSignup(getEnv("ALLOW_ORGANIZATION_SIGNUP", "false") == "true"),
+	)
+	hub := stream.NewHub(svc, stream.Config{
+		ReplayBuffer: getIntEnv("SSE_REPLAY_BUFFER", 1000),
+		Heartbeat:    getDurationEnv("SSE_HEARTBEAT_INTERVAL", 15*time.Second),
+	})
+	internal.Routes(router, svc, hub,
+		handler.WithWebSocketOrigins(getListEnv("WS_ALLOWED_ORIGINS")...),
+		handler.WithWebSocketAuthGrace(getDurationEnv("WS_AUTH_GRACE_PERIOD", 30*time.Second)),
+	)
+
+	// Fan session activity from every replica out to the streams served by this one
+	hubCtx, stopHub := context.WithCancel(context.Background())
+	go func() {
+		if err := hub.Run(hubCtx, postgres.NewActivityListener(config.DSN())); err != nil {
+			logger.Printf("Session activity stream stopped: %v", err)
+		}
+	}()
+
+	// Start the reaper for stale sessions and expired idempotency keys; the advisory
+	// lock keeps it to one replica
+	reaperCtx, stopReaper := context.WithCancel(context.Background())
+	reaperDone := make(chan struct{})
+	reaperConfig := reaper.Config{
+		Interval:    getDurationEnv("REAPER_INTERVAL", time.Minute),
+		Inactivity:  getDurationEnv("SESSION_INACTIVITY_TIMEOUT", time.Hour),
+		MaxDuration: getDurationEnv("SESSION_MAX_DURATION", 24*time.Hour),
+	}
+	if reaperConfig.Enabled() {
+		go func() {
+			defer close(reaperDone)
+			reaper.New(svc, leader.NewLock(db, reaper.LockKey), reaperConfig, logger).Run(reaperCtx)
+		}()
+	} else {
+		close(reaperDone)
+	}
+
+	// Start the webhook dispatcher; like the reaper it runs on one replica at a time
+	webhookDone := make(chan struct{})
+	if webhookConfig.Enabled() {
+		go func() {
+			defer close(webhookDone)
+			webhook.New(svc, leader.NewLock(db, webhook.LockKey), webhookConfig, logger).Run(reaperCtx)
+		}()
+	} else {
+		close(webhookDone)
+	}
+
+	// Start the relay publishing the outbox to the message bus, also on one replica
+	relayDone := make(chan struct{})
+	if relayEnabled {
+		go func() {
+			defer close(relayDone)
+			relay.New(svc, publisher, leader.NewLock(db, relay.LockKey), relayConfig, logger).Run(reaperCtx)
+		}()
+	} else {
+		close(relayDone)
+	}
+
+	// Start the exporter running background export jobs, also on one replica
+	exporterDone := make(chan struct{})
+	exporterConfig := exporter.Config{
+		Interval: getDurationEnv("EXPORT_JOB_INTERVAL", 5*time.Second),
+		Timeout:  getDurationEnv("EXPORT_JOB_TIMEOUT", time.Hour),
+	}
+	if exporterConfig.Enabled() {
+		go func() {
+			defer close(exporterDone)
+			exporter.New(svc, leader.NewLock(db, exporter.LockKey), exporterConfig, logger).Run(reaperCtx)
+		}()
+	} else {
+		close(exporterDone)
+	}
+
+	// Start the writer appending call detail records to CDR files, also on one replica
+	cdrWriterDone := make(chan struct{})
+	if cdrConfig.Enabled() {
+		go func() {
+			defer close(cdrWriterDone)
+			cdrwriter.New(svc, leader.NewLock(db, cdrwriter.LockKey), cdrConfig, logger).Run(reaperCtx)
+		}()
+	} else {
+		close(cdrWriterDone)
+	}
 
 	// Create HTTP server
 	port := getEnv("PORT", "8080")
@@ -79,6 +288,8 @@ func main() {
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	// Open streams never go idle, so end them when shutdown begins
+	srv.RegisterOnShutdown(hub.Close)
 
 	// Start server in a goroutine
 	go func() {
@@ -98,10 +309,22 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Attempt graceful shutdown
+	// Stop background workers and attempt graceful shutdown
+	stopReaper()
+	stopHub()
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Fatalf("Server forced to shutdown: %v", err)
 	}
+	<-reaperDone
+	<-webhookDone
+	<-relayDone
+	<-exporterDone
+	<-cdrWriterDone
+	if publisher != nil {
+		if err := publisher.Close(); err != nil {
+			logger.Printf("Error closing outbox publisher: %v", err)
+		}
+	}
 
 	logger.Println("Server exiting")
 }
@@ -114,3 +337,73 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// getDurationEnv parses a duration such as "30m" from an environment variable,
+// returning the default when it is unset or invalid
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: invalid duration %q for %s, using %s", value, key, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
+// getIntEnv parses an integer from an environment variable, returning the default when
+// it is unset or invalid
+func getIntEnv(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: invalid integer %q for %s, using %d", value, key, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// getListEnv splits a comma separated environment variable, returning nil when it is
+// unset
+func getListEnv(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEventRulesEnv parses a list of event rules such as "hold,state_transition:on_hold"
+// from an environment variable, returning the default when it is unset
+func getEventRulesEnv(key string, defaultValue []model.EventRule) []model.EventRule {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return model.ParseEventRules(value)
+}
+
+// metricRules reads the event rules used to derive session metrics
+func metricRules() model.MetricRules {
+	return model.MetricRules{
+		Answer:    getEventRulesEnv("METRICS_ANSWER_EVENTS", model.DefaultMetricRules.Answer),
+		HoldStart: getEventRulesEnv("METRICS_HOLD_START_EVENTS", model.DefaultMetricRules.HoldStart),
+		HoldEnd:   getEventRulesEnv("METRICS_HOLD_END_EVENTS", model.DefaultMetricRules.HoldEnd),
+		Transfer:  getEventRulesEnv("METRICS_TRANSFER_EVENTS", model.DefaultMetricRules.Transfer),
+	}
+}
+
+// numberNormalizer reads how caller and callee IDs are normalized
+func numberNormalizer() (*phone.Normalizer, error) {
+	return phone.NewNormalizer(
+		getEnv("PHONE_DEFAULT_REGION", phone.DefaultRegion),
+		getIntEnv("PHONE_EXTENSION_MAX_DIGITS", phone.DefaultExtensionMaxDigits),
+	)
+}
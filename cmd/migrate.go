@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/config"
+	"github.com/vasu74/Call_Session_Management/internal/migrate"
+)
+
+const migrateUsage = `usage: main migrate <command>
+
+commands:
+  up               apply all pending migrations
+  down [N]         roll back the last N migrations (default 1)
+  status           list migrations and whether they are applied
+  goto VERSION     migrate up or down to exactly VERSION
+  rls              create the session_workers role and enable the row-level
+                   security policies that DB_ROW_LEVEL_SECURITY relies on`
+
+// runMigrate implements the migrate subcommand
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate command\n\n%s", migrateUsage)
+	}
+
+	db := config.ConnectDB()
+	defer db.Close()
+
+	migrator, err := migrate.New(db)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		if err := migrator.Up(ctx); err != nil {
+			return err
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps: %s", args[1])
+			}
+		}
+		if err := migrator.Down(ctx, steps); err != nil {
+			return err
+		}
+	case "goto":
+		if len(args) < 2 {
+			return fmt.Errorf("missing target version\n\n%s", migrateUsage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version: %s", args[1])
+		}
+		if err := migrator.Goto(ctx, version); err != nil {
+			return err
+		}
+	case "rls":
+		if err := migrator.EnableRowLevelSecurity(ctx); err != nil {
+			return err
+		}
+	case "status":
+	default:
+		return fmt.Errorf("unknown migrate command %q\n\n%s", args[0], migrateUsage)
+	}
+
+	return printMigrationStatus(ctx, migrator)
+}
+
+func printMigrationStatus(ctx context.Context, migrator *migrate.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", "-"
+		if status.Applied {
+			state = "applied"
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		if status.Missing {
+			state = "applied (unknown to this binary)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
+
+// ensureSchemaCurrent refuses to start the server against a database with pending
+// migrations, unless DB_AUTO_MIGRATE is enabled in which case they are applied
+func ensureSchemaCurrent(db *sql.DB, logger *log.Logger) {
+	migrator, err := migrate.New(db)
+	if err != nil {
+		logger.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		logger.Fatalf("Failed to read schema version: %v", err)
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	if getEnv("DB_AUTO_MIGRATE", "false") != "true" {
+		logger.Fatalf("Database schema is behind: %d pending migration(s), latest is %d. Run `migrate up` before starting the server",
+			len(pending), migrator.Latest())
+	}
+
+	logger.Printf("Applying %d pending migration(s)", len(pending))
+	if err := migrator.Up(ctx); err != nil {
+		logger.Fatalf("Failed to apply migrations: %v", err)
+	}
+}
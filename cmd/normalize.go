@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/vasu74/Call_Session_Management/internal/config"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+	"github.com/vasu74/Call_Session_Management/internal/store/postgres"
+)
+
+const normalizeUsage = `usage: main normalize [-batch N]
+
+Normalizes the caller and callee IDs of all sessions again from the IDs as they were
+given, using PHONE_DEFAULT_REGION and PHONE_EXTENSION_MAX_DIGITS. Run it once after
+upgrading to store sessions created before IDs were normalized in normalized form, and
+again whenever PHONE_DEFAULT_REGION changes. Call detail records keep the IDs they were
+generated with.`
+
+// runNormalize implements the normalize subcommand
+func runNormalize(args []string) error {
+	flags := flag.NewFlagSet("normalize", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(flags.Output(), normalizeUsage) }
+	batch := flags.Int("batch", 500, "number of sessions updated per statement")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments\n\n%s", normalizeUsage)
+	}
+
+	numbers, err := numberNormalizer()
+	if err != nil {
+		return fmt.Errorf("invalid PHONE_DEFAULT_REGION: %w", err)
+	}
+
+	db := config.ConnectDB()
+	defer db.Close()
+	svc := service.New(postgres.New(db), service.WithNumberNormalizer(numbers))
+
+	updated, err := svc.NormalizeSessionIdentities(context.Background(), *batch)
+	fmt.Printf("%d sessions updated\n", updated)
+	return err
+}
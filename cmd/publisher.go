@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/relay"
+)
+
+// newOutboxPublisher creates the message bus publisher selected by OUTBOX_PUBLISHER,
+// or returns nil when none is configured
+func newOutboxPublisher() (relay.Publisher, error) {
+	switch kind := getEnv("OUTBOX_PUBLISHER", ""); kind {
+	case "":
+		return nil, nil
+	case "nats":
+		return relay.NewNATSPublisher(getEnv("NATS_URL", "nats://localhost:4222"), getEnv("NATS_SUBJECT_PREFIX", "csm"))
+	case "kafka_rest":
+		restURL := getEnv("KAFKA_REST_URL", "")
+		if restURL == "" {
+			return nil, fmt.Errorf("KAFKA_REST_URL is required for the kafka_rest publisher")
+		}
+		return relay.NewKafkaRESTPublisher(restURL, getEnv("KAFKA_REST_TOPIC", "call-sessions"), getDurationEnv("KAFKA_REST_TIMEOUT", 10*time.Second)), nil
+	case "file":
+		return relay.NewFilePublisher(getEnv("OUTBOX_FILE", "outbox.ndjson"))
+	case "stdout":
+		return relay.NewFilePublisher("-")
+	default:
+		return nil, fmt.Errorf("unknown OUTBOX_PUBLISHER %q (want nats, kafka_rest, file or stdout)", kind)
+	}
+}
@@ -4,38 +4,136 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/vasu74/Call_Session_Management/internal/handler"
 	"github.com/vasu74/Call_Session_Management/internal/middleware"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+	"github.com/vasu74/Call_Session_Management/internal/stream"
 )
 
-func Routes(server *gin.Engine) {
+func Routes(server *gin.Engine, svc *service.Service, hub *stream.Hub, opts ...handler.Option) {
+	h := handler.New(svc, hub, opts...)
+
 	// Public routes
 	auth := server.Group("/auth")
 	{
-		auth.POST("/register", handler.RegisterHandler)
-		auth.POST("/login", handler.LoginHandler)
+		auth.POST("/register", h.RegisterHandler)
+		auth.POST("/login", h.LoginHandler)
+		auth.POST("/refresh", h.RefreshHandler)
+
+		// Revocation of the caller's own tokens
+		auth.POST("/logout", middleware.AuthMiddleware(svc), h.LogoutHandler)
+		auth.POST("/logout-all", middleware.AuthMiddleware(svc), h.LogoutAllHandler)
 	}
 
+	// Permissions required of the caller's token or API key
+	readSessions := middleware.RequirePermission(model.PermissionSessionsRead)
+	writeSessions := middleware.RequirePermission(model.PermissionSessionsWrite)
+	writeEvents := middleware.RequirePermission(model.PermissionEventsWrite)
+	exportSessions := middleware.RequirePermission(model.PermissionSessionsExport)
+	importSessions := middleware.RequirePermission(model.PermissionSessionsImport)
+	manageUsers := middleware.RequirePermission(model.PermissionUsersManage)
+	manageWebhooks := middleware.RequirePermission(model.PermissionWebhooksManage)
+	manageSettings := middleware.RequirePermission(model.PermissionSettingsManage)
+	manageOrgs := middleware.RequirePermission(model.PermissionOrgsManage)
+
 	// Protected routes
 	api := server.Group("/api")
-	api.Use(middleware.AuthMiddleware())
+	api.Use(middleware.AuthMiddleware(svc))
 	{
 		// User profile
-		api.GET("/profile", handler.GetProfileHandler)
+		api.GET("/profile", h.GetProfileHandler)
+		api.GET("/organization", h.GetOwnOrganizationHandler)
+		api.PATCH("/organization", manageSettings, middleware.Idempotency(svc), h.UpdateOwnOrganizationHandler)
+
+		// Bidirectional session control and live notifications
+		api.GET("/ws", readSessions, h.WebSocketHandler)
+
+		// Cross-session custom methods
+		api.POST("/:method", writeEvents, middleware.Idempotency(svc), handler.CustomMethods(map[string]gin.HandlerFunc{
+			"events:batch": h.LogEventBatchHandler,
+		}))
 
 		// Session routes
+		sessionMethods := handler.CustomMethods(map[string]gin.HandlerFunc{
+			"events:batch": h.LogSessionEventBatchHandler,
+		})
 		sessions := api.Group("/sessions")
+		sessions.Use(middleware.Idempotency(svc))
 		{
-			sessions.GET("", handler.ListSessionsHandler)
-			sessions.POST("/start", handler.StartSessionHandler)
-			sessions.POST("/:sessionId/events", handler.LogSessionEventHandler)
-			sessions.POST("/:sessionId/end", handler.EndSessionHandler)
-			sessions.GET("/:sessionId", handler.GetSessionDetailsHandler)
+			sessions.GET("", readSessions, h.ListSessionsHandler)
+			sessions.GET("/stream", readSessions, h.StreamSessionsHandler)
+			sessions.GET("/export", exportSessions, h.ExportSessionsHandler)
+			sessions.POST("/export", exportSessions, h.CreateExportJobHandler)
+			sessions.POST("/start", writeSessions, h.StartSessionHandler)
+			sessions.POST("/:sessionId/events", writeEvents, h.LogSessionEventHandler)
+			sessions.POST("/:sessionId/end", writeSessions, h.EndSessionHandler)
+			sessions.POST("/:sessionId/transition", writeSessions, h.TransitionSessionHandler)
+			sessions.POST("/:sessionId/:method", writeEvents, sessionMethods)
+			sessions.GET("/:sessionId", readSessions, h.GetSessionDetailsHandler)
+			sessions.GET("/:sessionId/stream", readSessions, h.StreamSessionHandler)
+
+			// The same session routes, addressing the session by its external call ID
+			external := sessions.Group("/by-external/:source/:externalId")
+			{
+				external.GET("", readSessions, h.GetSessionDetailsHandler)
+				external.GET("/stream", readSessions, h.StreamSessionHandler)
+				external.POST("/events", writeEvents, h.LogSessionEventHandler)
+				external.POST("/end", writeSessions, h.EndSessionHandler)
+				external.POST("/transition", writeSessions, h.TransitionSessionHandler)
+				external.POST("/:method", writeEvents, sessionMethods)
+			}
 		}
 
+		// Aggregates over sessions for reporting
+		api.GET("/analytics/sessions", readSessions, h.SessionAnalyticsHandler)
+
+		// Background exports and their files
+		api.GET("/exports/:jobId", exportSessions, h.GetExportJobHandler)
+		api.GET("/exports/:jobId/download", exportSessions, h.DownloadExportJobHandler)
+
+		// Call detail records of ended sessions
+		api.GET("/cdr", readSessions, h.GetCDRsHandler)
+
 		// Admin routes
 		admin := api.Group("/admin")
-		admin.Use(middleware.RequireRole("admin"))
+		admin.Use(middleware.Idempotency(svc))
 		{
-			// Add admin-specific routes here
+			// Outbound webhooks and their delivery log
+			admin.POST("/webhooks", manageWebhooks, h.CreateWebhookHandler)
+			admin.GET("/webhooks", manageWebhooks, h.ListWebhooksHandler)
+			admin.GET("/webhooks/:webhookId", manageWebhooks, h.GetWebhookHandler)
+			admin.PATCH("/webhooks/:webhookId", manageWebhooks, h.UpdateWebhookHandler)
+			admin.DELETE("/webhooks/:webhookId", manageWebhooks, h.DeleteWebhookHandler)
+			admin.GET("/webhooks/:webhookId/deliveries", manageWebhooks, h.ListWebhookDeliveriesHandler)
+			admin.GET("/webhooks/:webhookId/deliveries/:deliveryId", manageWebhooks, h.GetWebhookDeliveryHandler)
+			admin.POST("/webhooks/:webhookId/deliveries/:deliveryId/retry", manageWebhooks, h.RetryWebhookDeliveryHandler)
+
+			// API keys of machine-to-machine clients
+			admin.POST("/api-keys", manageUsers, h.CreateAPIKeyHandler)
+			admin.GET("/api-keys", manageUsers, h.ListAPIKeysHandler)
+			admin.GET("/api-keys/:keyId", manageUsers, h.GetAPIKeyHandler)
+			admin.PATCH("/api-keys/:keyId", manageUsers, h.UpdateAPIKeyHandler)
+			admin.DELETE("/api-keys/:keyId", manageUsers, h.DeleteAPIKeyHandler)
+
+			// Users and the roles granting them permissions; roles are shared by every
+			// organization, so only platform admins change them
+			admin.POST("/users", manageUsers, h.CreateUserHandler)
+			admin.GET("/users", manageUsers, h.ListUsersHandler)
+			admin.GET("/users/:userId", manageUsers, h.GetUserHandler)
+			admin.PATCH("/users/:userId", manageUsers, h.UpdateUserHandler)
+			admin.POST("/roles", manageOrgs, h.CreateRoleHandler)
+			admin.GET("/roles", manageUsers, h.ListRolesHandler)
+			admin.GET("/roles/:role", manageUsers, h.GetRoleHandler)
+			admin.PATCH("/roles/:role", manageOrgs, h.UpdateRoleHandler)
+			admin.DELETE("/roles/:role", manageOrgs, h.DeleteRoleHandler)
+
+			// Organizations and their settings
+			admin.POST("/orgs", manageOrgs, h.CreateOrganizationHandler)
+			admin.GET("/orgs", manageOrgs, h.ListOrganizationsHandler)
+			admin.GET("/orgs/:orgId", manageOrgs, h.GetOrganizationHandler)
+			admin.PATCH("/orgs/:orgId", manageOrgs, h.UpdateOrganizationHandler)
+
+			// Bulk import of historical call detail records
+			admin.POST("/import", importSessions, h.ImportSessionsHandler)
 		}
 	}
 }
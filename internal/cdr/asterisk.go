@@ -0,0 +1,141 @@
+package cdr
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// asteriskColumns are the columns of Master.csv in the order cdr_csv writes them.
+// uniqueid and userfield are only written with loguniqueid and loguserfield, and some
+// versions append peeraccount, linkedid and sequence.
+var asteriskColumns = []string{
+	"accountcode", "src", "dst", "dcontext", "clid", "channel", "dstchannel", "lastapp", "lastdata",
+	"start", "answer", "end", "duration", "billsec", "disposition", "amaflags", "uniqueid", "userfield",
+	"peeraccount", "linkedid", "sequence",
+}
+
+// asteriskMinColumns is the number of columns written without the optional ones
+const asteriskMinColumns = 16
+
+// asteriskStatus maps the disposition of a CDR to the final session state
+var asteriskStatus = map[string]model.SessionStatus{
+	"ANSWERED":   model.SessionStatusCompleted,
+	"NO ANSWER":  model.SessionStatusNoAnswer,
+	"BUSY":       model.SessionStatusBusy,
+	"FAILED":     model.SessionStatusFailed,
+	"CONGESTION": model.SessionStatusFailed,
+}
+
+type asteriskReader struct {
+	csv  *csv.Reader
+	opts model.ImportOptions
+	now  time.Time
+}
+
+func newAsteriskReader(r io.Reader, opts model.ImportOptions) *asteriskReader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+	return &asteriskReader{csv: reader, opts: opts, now: time.Now()}
+}
+
+func (r *asteriskReader) Next() (*Record, error) {
+	for {
+		fields, err := r.csv.Read()
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, &RowError{Row: parseErr.StartLine, Err: model.ErrInvalidRequest.WithMessage(parseErr.Err.Error())}
+			}
+			return nil, err
+		}
+		row, _ := r.csv.FieldPos(0)
+
+		// Master.csv has no header row, but files assembled by hand often do
+		if row == 1 && fields[0] == "accountcode" && len(fields) > 1 && fields[1] == "src" {
+			continue
+		}
+
+		imported, err := r.convert(fields)
+		if err != nil {
+			return nil, &RowError{Row: row, Err: err}
+		}
+		return &Record{Row: row, ImportedSession: *imported}, nil
+	}
+}
+
+func (r *asteriskReader) convert(fields []string) (*model.ImportedSession, error) {
+	if len(fields) < asteriskMinColumns {
+		return nil, model.ErrInvalidRequest.WithMessage(fmt.Sprintf("expected at least %d columns, got %d", asteriskMinColumns, len(fields)))
+	}
+	value := make(map[string]string, len(fields))
+	for i, field := range fields {
+		if i < len(asteriskColumns) {
+			value[asteriskColumns[i]] = field
+		}
+	}
+
+	c := call{
+		caller:      value["src"],
+		callee:      value["dst"],
+		disposition: value["disposition"],
+		uniqueID:    value["uniqueid"],
+		metadata:    model.SessionMetadata{},
+	}
+	if c.caller == "" {
+		c.caller = callerIDNumber(value["clid"])
+	}
+	// Without loguniqueid the channel name and start time identify the record
+	if c.uniqueID == "" {
+		c.uniqueID = value["channel"] + "@" + value["start"]
+	}
+
+	var err error
+	if c.start, err = parseStamp(value["start"], r.opts.Location); err != nil {
+		return nil, err
+	}
+	if c.answer, err = parseStamp(value["answer"], r.opts.Location); err != nil {
+		return nil, err
+	}
+	if c.end, err = parseStamp(value["end"], r.opts.Location); err != nil {
+		return nil, err
+	}
+
+	status, ok := asteriskStatus[strings.ToUpper(c.disposition)]
+	if !ok {
+		return nil, model.ErrInvalidRequest.WithMessage(fmt.Sprintf("unknown disposition %q", c.disposition))
+	}
+	c.status = status
+	// Unanswered calls carry an answer time equal to their start in some versions
+	if status != model.SessionStatusCompleted {
+		c.answer = time.Time{}
+	}
+
+	for _, key := range []string{
+		"accountcode", "dcontext", "clid", "channel", "dstchannel", "lastapp", "lastdata",
+		"duration", "billsec", "amaflags", "userfield", "peeraccount", "linkedid", "sequence",
+	} {
+		if v := value[key]; v != "" {
+			c.metadata[key] = metadataValue(v)
+		}
+	}
+
+	return c.session(r.opts.Source, r.now)
+}
+
+// callerIDNumber extracts the number from a caller ID such as `"Alice" <1001>`
+func callerIDNumber(clid string) string {
+	start := strings.LastIndexByte(clid, '<')
+	end := strings.LastIndexByte(clid, '>')
+	if start >= 0 && end > start {
+		return clid[start+1 : end]
+	}
+	return ""
+}
@@ -0,0 +1,191 @@
+// Package cdr reads call detail records written by Asterisk and FreeSWITCH and turns
+// them into sessions with the events the service would have recorded for the call.
+// Records are read one at a time, so files of any size can be imported. Templates lay
+// out the records the service generates itself when sessions end.
+package cdr
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// anonymousCaller is the caller ID of records that carry no caller number
+const anonymousCaller = "anonymous"
+
+// Record is a call detail record converted to a session. Row is the line of a CSV file
+// or the position of the record in an XML file, counting from 1.
+type Record struct {
+	Row int
+	model.ImportedSession
+}
+
+// RowError reports a record that could not be converted. Reading continues with the
+// next record.
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Reader reads the records of a CDR file
+type Reader interface {
+	// Next returns the next record, a *RowError for a record that was rejected, or
+	// io.EOF at the end of the file. Any other error ends the file.
+	Next() (*Record, error)
+}
+
+// NewReader creates a reader for a file in the given format. Gzip compressed files are
+// decompressed transparently.
+func NewReader(r io.Reader, opts model.ImportOptions) (Reader, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, model.ErrInvalidRequest.WithMessage("invalid gzip file")
+		}
+		r = zr
+	} else {
+		r = br
+	}
+
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+
+	switch opts.Format {
+	case model.ImportAsteriskCSV:
+		return newAsteriskReader(r, opts), nil
+	case model.ImportFreeSWITCHCSV:
+		return newFreeSWITCHCSVReader(r, opts), nil
+	case model.ImportFreeSWITCHXML:
+		return newFreeSWITCHXMLReader(r, opts), nil
+	}
+	return nil, fmt.Errorf("unsupported import format %q", opts.Format)
+}
+
+// call holds the fields of a record common to all formats. answer is zero for calls
+// that were not answered.
+type call struct {
+	uniqueID    string
+	caller      string
+	callee      string
+	start       time.Time
+	answer      time.Time
+	end         time.Time
+	status      model.SessionStatus
+	disposition string
+	metadata    model.SessionMetadata
+}
+
+// session builds the ended session of the call together with the state transitions
+// the service records for a live call: into answered when it was answered, and into
+// its final state when it ended
+func (c *call) session(source string, now time.Time) (*model.ImportedSession, error) {
+	switch {
+	case c.uniqueID == "":
+		return nil, model.ErrInvalidRequest.WithMessage("unique ID is missing")
+	case len(c.uniqueID) > 255:
+		return nil, model.ErrInvalidRequest.WithMessage("unique ID is longer than 255 characters")
+	case c.callee == "":
+		return nil, model.ErrInvalidRequest.WithMessage("destination is missing")
+	case c.start.IsZero():
+		return nil, model.ErrInvalidRequest.WithMessage("start time is missing")
+	case c.end.IsZero():
+		return nil, model.ErrInvalidRequest.WithMessage("end time is missing")
+	case c.end.Before(c.start):
+		return nil, model.ErrInvalidTimeRange.WithMessage("end time is before start time")
+	case !c.answer.IsZero() && (c.answer.Before(c.start) || c.answer.After(c.end)):
+		return nil, model.ErrInvalidTimeRange.WithMessage("answer time is outside the call")
+	}
+	if c.caller == "" {
+		c.caller = anonymousCaller
+	}
+
+	session := model.Session{
+		ID:              uuid.New(),
+		StartedAt:       c.start,
+		CallerID:        c.caller,
+		CalleeID:        c.callee,
+		Status:          model.SessionStatusInitiated,
+		InitialMetadata: c.metadata,
+		ExternalSource:  &source,
+		ExternalID:      &c.uniqueID,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	imported := &model.ImportedSession{}
+	if !c.answer.IsZero() {
+		t, err := model.NewSessionTransition(&session, model.SessionStatusAnswered, c.answer, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		imported.Events = append(imported.Events, t.Event)
+		session.Status = t.To
+	}
+
+	t, err := model.NewSessionTransition(&session, c.status, c.end, &c.disposition, nil)
+	if err != nil {
+		return nil, err
+	}
+	imported.Events = append(imported.Events, t.Event)
+	session.Status = t.To
+	session.Disposition = t.Disposition
+	session.EndedAt = &c.end
+
+	imported.Session = session
+	return imported, nil
+}
+
+// parseStamp parses a "2006-01-02 15:04:05" timestamp in the given time zone and returns
+// it in UTC; an empty value is the zero time
+func parseStamp(value string, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", value, loc)
+	if err != nil {
+		return time.Time{}, model.ErrInvalidRequest.WithMessage(fmt.Sprintf("invalid timestamp %q", value))
+	}
+	return t.UTC(), nil
+}
+
+// parseEpoch parses seconds or, with micro set, microseconds since the Unix epoch. An
+// empty value or 0, which FreeSWITCH writes for calls that were never answered, is the
+// zero time.
+func parseEpoch(value string, micro bool) (time.Time, error) {
+	if value == "" || value == "0" {
+		return time.Time{}, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return time.Time{}, model.ErrInvalidRequest.WithMessage(fmt.Sprintf("invalid epoch %q", value))
+	}
+	if micro {
+		return time.UnixMicro(n).UTC(), nil
+	}
+	return time.Unix(n, 0).UTC(), nil
+}
+
+// metadataValue stores whole numbers such as durations as numbers and everything else,
+// including numbers with leading zeros like account codes, as text
+func metadataValue(value string) interface{} {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil && strconv.FormatInt(n, 10) == value {
+		return n
+	}
+	return value
+}
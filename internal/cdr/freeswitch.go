@@ -0,0 +1,271 @@
+package cdr
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// freeswitchColumns are the variables of the default "example" template of mod_cdr_csv,
+// used for files without a header row when no columns are given
+var freeswitchColumns = []string{
+	"caller_id_name", "caller_id_number", "destination_number", "context", "start_stamp", "answer_stamp",
+	"end_stamp", "duration", "billsec", "hangup_cause", "uuid", "bleg_uuid", "accountcode", "read_codec", "write_codec",
+}
+
+// freeswitchMapped are the variables that make up the session itself rather than its
+// metadata
+var freeswitchMapped = map[string]bool{
+	"uuid": true, "caller_id_number": true, "destination_number": true,
+	"start_stamp": true, "start_epoch": true, "start_uepoch": true,
+	"answer_stamp": true, "answer_epoch": true, "answer_uepoch": true,
+	"end_stamp": true, "end_epoch": true, "end_uepoch": true,
+}
+
+// freeswitchMetadata are the variables of an XML CDR kept as session metadata; XML CDRs
+// carry every channel variable, most of which are of no interest afterwards
+var freeswitchMetadata = []string{
+	"caller_id_name", "context", "direction", "duration", "billsec", "hangup_cause", "hangup_cause_q850",
+	"bleg_uuid", "accountcode", "sip_call_id", "read_codec", "write_codec", "last_app", "last_arg",
+}
+
+// freeswitchUnansweredStatus maps the hangup cause of a call that was never answered to
+// its final session state; other causes end it as failed. Answered calls complete.
+var freeswitchUnansweredStatus = map[string]model.SessionStatus{
+	"USER_BUSY":           model.SessionStatusBusy,
+	"NO_ANSWER":           model.SessionStatusNoAnswer,
+	"NO_USER_RESPONSE":    model.SessionStatusNoAnswer,
+	"ALLOTTED_TIMEOUT":    model.SessionStatusNoAnswer,
+	"ORIGINATOR_CANCEL":   model.SessionStatusMissed,
+	"NORMAL_CLEARING":     model.SessionStatusMissed,
+	"LOSE_RACE":           model.SessionStatusMissed,
+	"PICKED_OFF":          model.SessionStatusMissed,
+	"CALL_REJECTED":       model.SessionStatusBusy,
+	"USER_NOT_REGISTERED": model.SessionStatusFailed,
+}
+
+// freeswitchCall converts the variables of a FreeSWITCH CDR. Epochs are preferred over
+// stamps, which are written in the switch's local time.
+func freeswitchCall(vars map[string]string, loc *time.Location) (*call, error) {
+	c := call{
+		uniqueID:    vars["uuid"],
+		caller:      vars["caller_id_number"],
+		callee:      vars["destination_number"],
+		disposition: vars["hangup_cause"],
+	}
+
+	var err error
+	if c.start, err = freeswitchTime(vars, "start", loc); err != nil {
+		return nil, err
+	}
+	if c.answer, err = freeswitchTime(vars, "answer", loc); err != nil {
+		return nil, err
+	}
+	if c.end, err = freeswitchTime(vars, "end", loc); err != nil {
+		return nil, err
+	}
+
+	c.status = model.SessionStatusCompleted
+	if c.answer.IsZero() {
+		status, ok := freeswitchUnansweredStatus[c.disposition]
+		if !ok {
+			status = model.SessionStatusFailed
+		}
+		c.status = status
+	}
+	return &c, nil
+}
+
+func freeswitchTime(vars map[string]string, prefix string, loc *time.Location) (time.Time, error) {
+	if v := vars[prefix+"_uepoch"]; v != "" {
+		return parseEpoch(v, true)
+	}
+	if v := vars[prefix+"_epoch"]; v != "" {
+		return parseEpoch(v, false)
+	}
+	return parseStamp(vars[prefix+"_stamp"], loc)
+}
+
+type freeswitchCSVReader struct {
+	csv     *csv.Reader
+	opts    model.ImportOptions
+	columns []string
+	now     time.Time
+}
+
+func newFreeSWITCHCSVReader(r io.Reader, opts model.ImportOptions) *freeswitchCSVReader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+	return &freeswitchCSVReader{csv: reader, opts: opts, columns: opts.Columns, now: time.Now()}
+}
+
+func (r *freeswitchCSVReader) Next() (*Record, error) {
+	for {
+		fields, err := r.csv.Read()
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, &RowError{Row: parseErr.StartLine, Err: model.ErrInvalidRequest.WithMessage(parseErr.Err.Error())}
+			}
+			return nil, err
+		}
+		row, _ := r.csv.FieldPos(0)
+
+		// A first row naming the uuid variable is a header describing the template
+		if row == 1 && len(r.columns) == 0 {
+			if isFreeSWITCHHeader(fields) {
+				r.columns = append([]string{}, fields...)
+				continue
+			}
+			r.columns = freeswitchColumns
+		}
+
+		imported, err := r.convert(fields)
+		if err != nil {
+			return nil, &RowError{Row: row, Err: err}
+		}
+		return &Record{Row: row, ImportedSession: *imported}, nil
+	}
+}
+
+func isFreeSWITCHHeader(fields []string) bool {
+	for _, field := range fields {
+		if field == "uuid" {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *freeswitchCSVReader) convert(fields []string) (*model.ImportedSession, error) {
+	if len(fields) != len(r.columns) {
+		return nil, model.ErrInvalidRequest.WithMessage(fmt.Sprintf("expected %d columns, got %d", len(r.columns), len(fields)))
+	}
+	vars := make(map[string]string, len(fields))
+	for i, field := range fields {
+		vars[r.columns[i]] = field
+	}
+
+	c, err := freeswitchCall(vars, r.opts.Location)
+	if err != nil {
+		return nil, err
+	}
+	// The columns of a CSV template were chosen deliberately, so all of them are kept
+	c.metadata = model.SessionMetadata{}
+	for key, value := range vars {
+		if value != "" && !freeswitchMapped[key] {
+			c.metadata[key] = metadataValue(value)
+		}
+	}
+	return c.session(r.opts.Source, r.now)
+}
+
+// xmlCDR is the part of a mod_xml_cdr document the import reads. Variable values are
+// URL encoded. The last callflow is the profile the call was created with.
+type xmlCDR struct {
+	Variables struct {
+		Items []struct {
+			XMLName xml.Name
+			Value   string `xml:",chardata"`
+		} `xml:",any"`
+	} `xml:"variables"`
+	Callflows []struct {
+		CallerProfile struct {
+			CallerIDNumber    string `xml:"caller_id_number"`
+			DestinationNumber string `xml:"destination_number"`
+		} `xml:"caller_profile"`
+		Times struct {
+			Created  string `xml:"created_time"`
+			Answered string `xml:"answered_time"`
+			Hangup   string `xml:"hangup_time"`
+		} `xml:"times"`
+	} `xml:"callflow"`
+}
+
+// freeswitchXMLReader reads the cdr elements of a stream, which may hold one document,
+// several concatenated documents or cdr elements wrapped in a common root
+type freeswitchXMLReader struct {
+	xml  *xml.Decoder
+	opts model.ImportOptions
+	row  int
+	now  time.Time
+}
+
+func newFreeSWITCHXMLReader(r io.Reader, opts model.ImportOptions) *freeswitchXMLReader {
+	return &freeswitchXMLReader{xml: xml.NewDecoder(r), opts: opts, now: time.Now()}
+}
+
+func (r *freeswitchXMLReader) Next() (*Record, error) {
+	for {
+		token, err := r.xml.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.EOF
+			}
+			return nil, model.ErrInvalidRequest.WithMessage(fmt.Sprintf("after cdr %d: %v", r.row, err))
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "cdr" {
+			continue
+		}
+
+		r.row++
+		var doc xmlCDR
+		if err := r.xml.DecodeElement(&doc, &start); err != nil {
+			return nil, model.ErrInvalidRequest.WithMessage(fmt.Sprintf("cdr %d: %v", r.row, err))
+		}
+		imported, err := r.convert(&doc)
+		if err != nil {
+			return nil, &RowError{Row: r.row, Err: err}
+		}
+		return &Record{Row: r.row, ImportedSession: *imported}, nil
+	}
+}
+
+func (r *freeswitchXMLReader) convert(doc *xmlCDR) (*model.ImportedSession, error) {
+	vars := make(map[string]string, len(doc.Variables.Items))
+	for _, item := range doc.Variables.Items {
+		value, err := url.PathUnescape(item.Value)
+		if err != nil {
+			value = item.Value
+		}
+		vars[item.XMLName.Local] = value
+	}
+
+	// Numbers and times missing from the variables come from the original profile
+	if n := len(doc.Callflows); n > 0 {
+		origin := doc.Callflows[n-1]
+		fallback := map[string]string{
+			"caller_id_number":   origin.CallerProfile.CallerIDNumber,
+			"destination_number": origin.CallerProfile.DestinationNumber,
+			"start_uepoch":       origin.Times.Created,
+			"answer_uepoch":      origin.Times.Answered,
+			"end_uepoch":         origin.Times.Hangup,
+		}
+		for key, value := range fallback {
+			if vars[key] == "" {
+				vars[key] = value
+			}
+		}
+	}
+
+	c, err := freeswitchCall(vars, r.opts.Location)
+	if err != nil {
+		return nil, err
+	}
+	c.metadata = model.SessionMetadata{}
+	for _, key := range freeswitchMetadata {
+		if value := vars[key]; value != "" {
+			c.metadata[key] = metadataValue(value)
+		}
+	}
+	return c.session(r.opts.Source, r.now)
+}
@@ -0,0 +1,223 @@
+package cdr
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// readAll reads every record of a file, collecting the rows of rejected records
+func readAll(t *testing.T, r io.Reader, opts model.ImportOptions) ([]*Record, []int) {
+	t.Helper()
+	reader, err := NewReader(r, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []*Record
+	var rejected []int
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return records, rejected
+		}
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			rejected = append(rejected, rowErr.Row)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+}
+
+// wantRecord describes the session expected from a record
+type wantRecord struct {
+	row         int
+	externalID  string
+	caller      string
+	callee      string
+	status      model.SessionStatus
+	disposition string
+	start       time.Time
+	answered    bool
+	duration    time.Duration
+}
+
+func assertRecords(t *testing.T, records []*Record, want []wantRecord, source string) {
+	t.Helper()
+	if len(records) != len(want) {
+		t.Fatalf("read %d records, want %d", len(records), len(want))
+	}
+	for i, w := range want {
+		r := records[i]
+		s := r.Session
+		if r.Row != w.row || *s.ExternalID != w.externalID || *s.ExternalSource != source || s.CallerID != w.caller || s.CalleeID != w.callee {
+			t.Errorf("record %d: row %d, %s/%s from %s to %s", i, r.Row, *s.ExternalSource, *s.ExternalID, s.CallerID, s.CalleeID)
+		}
+		if s.Status != w.status || *s.Disposition != w.disposition || !s.StartedAt.Equal(w.start) || s.EndedAt.Sub(s.StartedAt) != w.duration {
+			t.Errorf("record %d: %s (%s) from %s for %s", i, s.Status, *s.Disposition, s.StartedAt, s.EndedAt.Sub(s.StartedAt))
+		}
+		// An answered call transitions into answered and then into its final state
+		wantEvents := 1
+		if w.answered {
+			wantEvents = 2
+		}
+		if len(r.Events) != wantEvents || r.Events[len(r.Events)-1].Metadata["to"] != w.status {
+			t.Errorf("record %d has events %+v", i, r.Events)
+		}
+	}
+}
+
+func TestAsteriskReader(t *testing.T) {
+	file := strings.Join([]string{
+		`accountcode,src,dst,dcontext,clid,channel,dstchannel,lastapp,lastdata,start,answer,end,duration,billsec,disposition,amaflags,uniqueid`,
+		`"0042","1001","2002","default","""Alice"" <1001>","SIP/1001-0001","SIP/2002-0002","Dial","SIP/2002","2024-03-20 10:00:00","2024-03-20 10:00:05","2024-03-20 10:01:05",65,60,"ANSWERED","DOCUMENTATION","1710928800.1"`,
+		`"","","2002","default","""Bob"" <1003>","SIP/1003-0003","","Dial","SIP/2002","2024-03-20 11:00:00","2024-03-20 11:00:00","2024-03-20 11:00:20",20,0,"NO ANSWER","DOCUMENTATION",""`,
+		`"","1004","2002","default","","SIP/1004-0004","","Dial","SIP/2002","2024-03-20 12:00:00","","2024-03-20 12:00:03",3,0,"HUNG UP","DOCUMENTATION","1710936000.4"`,
+		`"","1005","2002","default","","SIP/1005-0005","","Dial","SIP/2002","2024-03-20 12:00:00","","2024-03-20 11:59:00",0,0,"BUSY","DOCUMENTATION","1710936000.5"`,
+		`"","1006","2002"`,
+		`"","1007","2002","default","","SIP/1007-0007","","Dial","SIP/2002","2024-03-20 13:00:00","","2024-03-20 13:00:04",4,0,"BUSY","DOCUMENTATION"`,
+	}, "\n")
+
+	berlin := time.FixedZone("CET", 3600)
+	records, rejected := readAll(t, strings.NewReader(file), model.ImportOptions{Format: model.ImportAsteriskCSV, Source: "pbx-1", Location: berlin})
+	assertRecords(t, records, []wantRecord{
+		{row: 2, externalID: "1710928800.1", caller: "1001", callee: "2002", status: model.SessionStatusCompleted, disposition: "ANSWERED", start: time.Date(2024, 3, 20, 9, 0, 0, 0, time.UTC), answered: true, duration: 65 * time.Second},
+		{row: 3, externalID: "SIP/1003-0003@2024-03-20 11:00:00", caller: "1003", callee: "2002", status: model.SessionStatusNoAnswer, disposition: "NO ANSWER", start: time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC), duration: 20 * time.Second},
+		{row: 7, externalID: "SIP/1007-0007@2024-03-20 13:00:00", caller: "1007", callee: "2002", status: model.SessionStatusBusy, disposition: "BUSY", start: time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC), duration: 4 * time.Second},
+	}, "pbx-1")
+
+	// An unknown disposition, an end before the start and too few columns
+	if want := []int{4, 5, 6}; len(rejected) != len(want) || rejected[0] != 4 || rejected[1] != 5 || rejected[2] != 6 {
+		t.Errorf("rejected rows %v, want %v", rejected, want)
+	}
+	metadata := records[0].Session.InitialMetadata
+	if metadata["accountcode"] != "0042" || metadata["billsec"] != int64(60) || metadata["lastapp"] != "Dial" {
+		t.Errorf("metadata %v", metadata)
+	}
+}
+
+func TestFreeSWITCHCSVReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		columns []string
+		// firstRow is the line of the first record
+		firstRow int
+	}{
+		{
+			name: "default template", firstRow: 1,
+			file: `"Alice","1001","2002","default","2024-03-20 10:00:00","2024-03-20 10:00:05","2024-03-20 10:01:05","65","60","NORMAL_CLEARING","a-1","","","PCMU","PCMU"` + "\n" +
+				`"Bob","1003","2002","default","2024-03-20 11:00:00","","2024-03-20 11:00:20","20","0","USER_BUSY","a-2","","","PCMU","PCMU"`,
+		},
+		{
+			name: "header row", firstRow: 2,
+			file: "uuid,caller_id_number,destination_number,start_epoch,answer_epoch,end_epoch,hangup_cause\n" +
+				"a-1,1001,2002,1710928800,1710928805,1710928865,NORMAL_CLEARING\n" +
+				"a-2,1003,2002,1710932400,0,1710932420,USER_BUSY",
+		},
+		{
+			name: "named columns", firstRow: 1,
+			columns: []string{"uuid", "caller_id_number", "destination_number", "start_uepoch", "answer_uepoch", "end_uepoch", "hangup_cause"},
+			file: "a-1,1001,2002,1710928800000000,1710928805000000,1710928865000000,NORMAL_CLEARING\n" +
+				"a-2,1003,2002,1710932400000000,0,1710932420000000,USER_BUSY",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, rejected := readAll(t, strings.NewReader(tt.file), model.ImportOptions{Format: model.ImportFreeSWITCHCSV, Source: "freeswitch", Columns: tt.columns})
+			if len(rejected) != 0 {
+				t.Fatalf("rejected rows %v", rejected)
+			}
+			assertRecords(t, records, []wantRecord{
+				{row: tt.firstRow, externalID: "a-1", caller: "1001", callee: "2002", status: model.SessionStatusCompleted, disposition: "NORMAL_CLEARING", start: time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC), answered: true, duration: 65 * time.Second},
+				{row: tt.firstRow + 1, externalID: "a-2", caller: "1003", callee: "2002", status: model.SessionStatusBusy, disposition: "USER_BUSY", start: time.Date(2024, 3, 20, 11, 0, 0, 0, time.UTC), duration: 20 * time.Second},
+			}, "freeswitch")
+		})
+	}
+
+	_, rejected := readAll(t, strings.NewReader("a-1,1001\n"), model.ImportOptions{Format: model.ImportFreeSWITCHCSV, Source: "freeswitch"})
+	if len(rejected) != 1 {
+		t.Errorf("rejected rows %v, want the row with missing columns", rejected)
+	}
+}
+
+func TestFreeSWITCHXMLReader(t *testing.T) {
+	file := `<?xml version="1.0"?>
+<cdrs>
+<cdr core-uuid="c-1">
+  <variables>
+    <uuid>x-1</uuid>
+    <sip_call_id>abc%40pbx</sip_call_id>
+    <hangup_cause>NORMAL_CLEARING</hangup_cause>
+    <billsec>60</billsec>
+    <ignored_variable>1</ignored_variable>
+  </variables>
+  <callflow>
+    <caller_profile><caller_id_number>1001</caller_id_number><destination_number>2002</destination_number></caller_profile>
+    <times><created_time>1710928800000000</created_time><answered_time>1710928805000000</answered_time><hangup_time>1710928865000000</hangup_time></times>
+  </callflow>
+</cdr>
+<cdr core-uuid="c-1">
+  <variables><uuid>x-2</uuid><hangup_cause>ORIGINATOR_CANCEL</hangup_cause><caller_id_number>1003</caller_id_number><destination_number>2002</destination_number></variables>
+  <callflow><times><created_time>1710932400000000</created_time><answered_time>0</answered_time><hangup_time>1710932410000000</hangup_time></times></callflow>
+</cdr>
+<cdr core-uuid="c-1">
+  <variables><uuid>x-3</uuid></variables>
+</cdr>
+</cdrs>`
+
+	records, rejected := readAll(t, strings.NewReader(file), model.ImportOptions{Format: model.ImportFreeSWITCHXML, Source: "freeswitch"})
+	assertRecords(t, records, []wantRecord{
+		{row: 1, externalID: "x-1", caller: "1001", callee: "2002", status: model.SessionStatusCompleted, disposition: "NORMAL_CLEARING", start: time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC), answered: true, duration: 65 * time.Second},
+		{row: 2, externalID: "x-2", caller: "1003", callee: "2002", status: model.SessionStatusMissed, disposition: "ORIGINATOR_CANCEL", start: time.Date(2024, 3, 20, 11, 0, 0, 0, time.UTC), duration: 10 * time.Second},
+	}, "freeswitch")
+	if len(rejected) != 1 || rejected[0] != 3 {
+		t.Errorf("rejected records %v, want the third without destination and times", rejected)
+	}
+
+	metadata := records[0].Session.InitialMetadata
+	if metadata["sip_call_id"] != "abc@pbx" || metadata["billsec"] != int64(60) || metadata["ignored_variable"] != nil {
+		t.Errorf("metadata %v", metadata)
+	}
+}
+
+func TestNewReaderDecompressesGzip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	io.WriteString(zw, `"","1001","2002","default","","SIP/1001-0001","","Dial","","2024-03-20 10:00:00","","2024-03-20 10:00:04",4,0,"BUSY","DOCUMENTATION","u-1"`)
+	zw.Close()
+
+	records, rejected := readAll(t, &buf, model.ImportOptions{Format: model.ImportAsteriskCSV, Source: "asterisk"})
+	if len(records) != 1 || len(rejected) != 0 || *records[0].Session.ExternalID != "u-1" {
+		t.Errorf("read %d records and rejected %v from a gzip file", len(records), rejected)
+	}
+
+	if _, err := NewReader(strings.NewReader(""), model.ImportOptions{Format: "cisco_csv"}); err == nil {
+		t.Error("NewReader accepted an unknown format")
+	}
+}
+
+func TestCallerIDNumber(t *testing.T) {
+	tests := []struct {
+		clid, want string
+	}{
+		{clid: `"Alice" <1001>`, want: "1001"},
+		{clid: `<+14155550100>`, want: "+14155550100"},
+		{clid: `Alice`, want: ""},
+		{clid: `Alice >1001<`, want: ""},
+	}
+	for _, tt := range tests {
+		if got := callerIDNumber(tt.clid); got != tt.want {
+			t.Errorf("callerIDNumber(%q) = %q, want %q", tt.clid, got, tt.want)
+		}
+	}
+}
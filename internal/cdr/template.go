@@ -0,0 +1,154 @@
+package cdr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vasu74/Call_Session_Management/internal/export"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// metadataFieldPrefix selects an initial_metadata key as a field, such as metadata.queue
+const metadataFieldPrefix = "metadata."
+
+// fieldKinds are the fields a template can hold besides metadata keys
+var fieldKinds = map[string]export.Kind{
+	"id":              export.Int,
+	"session_id":      export.String,
+	"caller":          export.String,
+	"callee":          export.String,
+	"start":           export.Time,
+	"answer":          export.Time,
+	"end":             export.Time,
+	"duration":        export.Int,
+	"billsec":         export.Int,
+	"status":          export.String,
+	"disposition":     export.String,
+	"external_source": export.String,
+	"external_id":     export.String,
+}
+
+// DefaultTemplate is the layout used when none is configured
+const DefaultTemplate = "session_id,caller,callee,start,answer,end,duration,billsec,status,disposition"
+
+// Field is a column of a template: the record field it holds and its column name
+type Field struct {
+	Name   string
+	Source string
+}
+
+// Template lists the columns of the CDRs in a file, in order
+type Template []Field
+
+// ParseTemplate parses a comma separated list of fields. A field may be given a column
+// name of its own as name=field, such as calldate=start; metadata.<key> selects a key
+// of the session's initial metadata.
+func ParseTemplate(s string) (Template, error) {
+	var t Template
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, source, renamed := strings.Cut(item, "=")
+		name, source = strings.TrimSpace(name), strings.TrimSpace(source)
+		if !renamed {
+			source = name
+		}
+
+		_, known := fieldKinds[source]
+		if !known && (!strings.HasPrefix(source, metadataFieldPrefix) || source == metadataFieldPrefix) {
+			return nil, fmt.Errorf("unknown CDR field %q", source)
+		}
+		if name == "" {
+			return nil, fmt.Errorf("CDR field %q has an empty column name", source)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate CDR column %q", name)
+		}
+		seen[name] = true
+		t = append(t, Field{Name: name, Source: source})
+	}
+	if len(t) == 0 {
+		return nil, fmt.Errorf("CDR template has no fields")
+	}
+	return t, nil
+}
+
+// MustParseTemplate is like ParseTemplate but panics if the template is invalid
+func MustParseTemplate(s string) Template {
+	t, err := ParseTemplate(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Columns returns the export columns of the template
+func (t Template) Columns() []export.Column {
+	columns := make([]export.Column, len(t))
+	for i, field := range t {
+		kind, known := fieldKinds[field.Source]
+		if !known {
+			kind = export.String
+		}
+		columns[i] = export.Column{Name: field.Name, Kind: kind}
+	}
+	return columns
+}
+
+// Row returns the values of a record for the template's columns. Metadata values are
+// rendered as text like in session exports.
+func (t Template) Row(c *model.CDR) export.Row {
+	row := make(export.Row, len(t))
+	for i, field := range t {
+		row[i] = fieldValue(c, field.Source)
+	}
+	return row
+}
+
+func fieldValue(c *model.CDR, source string) interface{} {
+	switch source {
+	case "id":
+		return c.ID
+	case "session_id":
+		return c.SessionID.String()
+	case "caller":
+		return c.CallerID
+	case "callee":
+		return c.CalleeID
+	case "start":
+		return c.StartedAt
+	case "answer":
+		if c.AnsweredAt == nil {
+			return nil
+		}
+		return *c.AnsweredAt
+	case "end":
+		return c.EndedAt
+	case "duration":
+		return c.Duration
+	case "billsec":
+		return c.Billsec
+	case "status":
+		return string(c.Status)
+	case "disposition":
+		return optional(c.Disposition)
+	case "external_source":
+		return optional(c.ExternalSource)
+	case "external_id":
+		return optional(c.ExternalID)
+	}
+	if value, ok := c.Metadata.Text(strings.TrimPrefix(source, metadataFieldPrefix)); ok {
+		return value
+	}
+	return nil
+}
+
+func optional(s *string) interface{} {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
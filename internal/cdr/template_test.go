@@ -0,0 +1,80 @@
+package cdr
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/export"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    Template
+		wantErr bool
+	}{
+		{name: "fields", in: "caller, callee,billsec", want: Template{{"caller", "caller"}, {"callee", "callee"}, {"billsec", "billsec"}}},
+		{name: "renamed", in: "calldate=start,src = caller", want: Template{{"calldate", "start"}, {"src", "caller"}}},
+		{name: "metadata", in: "queue=metadata.queue,metadata.agent", want: Template{{"queue", "metadata.queue"}, {"metadata.agent", "metadata.agent"}}},
+		{name: "empty items", in: ",caller,,", want: Template{{"caller", "caller"}}},
+		{name: "unknown field", in: "caller,password", wantErr: true},
+		{name: "metadata without a key", in: "metadata.", wantErr: true},
+		{name: "empty column name", in: "=caller", wantErr: true},
+		{name: "duplicate column", in: "caller,caller=callee", wantErr: true},
+		{name: "no fields", in: " , ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTemplate(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTemplate(%q) error = %v, want error: %v", tt.in, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTemplate(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseTemplate(DefaultTemplate); err != nil {
+		t.Errorf("default template: %v", err)
+	}
+}
+
+func TestTemplateRow(t *testing.T) {
+	template := MustParseTemplate("id,calldate=start,answer,billsec,disposition,external_id,queue=metadata.queue,metadata.priority,metadata.missing")
+
+	wantColumns := []export.Column{
+		{Name: "id", Kind: export.Int},
+		{Name: "calldate", Kind: export.Time},
+		{Name: "answer", Kind: export.Time},
+		{Name: "billsec", Kind: export.Int},
+		{Name: "disposition", Kind: export.String},
+		{Name: "external_id", Kind: export.String},
+		{Name: "queue", Kind: export.String},
+		{Name: "metadata.priority", Kind: export.String},
+		{Name: "metadata.missing", Kind: export.String},
+	}
+	if columns := template.Columns(); !reflect.DeepEqual(columns, wantColumns) {
+		t.Errorf("Columns = %v, want %v", columns, wantColumns)
+	}
+
+	start := time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)
+	disposition := "Answered"
+	record := &model.CDR{
+		ID:          7,
+		SessionID:   uuid.New(),
+		StartedAt:   start,
+		EndedAt:     start.Add(time.Minute),
+		Billsec:     50,
+		Disposition: &disposition,
+		Metadata:    model.SessionMetadata{"queue": "sales", "priority": 2},
+	}
+	want := export.Row{int64(7), start, nil, int64(50), "Answered", nil, "sales", "2", nil}
+	if row := template.Row(record); !reflect.DeepEqual(row, want) {
+		t.Errorf("Row = %v, want %v", row, want)
+	}
+}
@@ -0,0 +1,161 @@
+package cdrwriter
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/service"
+)
+
+// LockKey identifies the advisory lock that elects the replica writing CDR files
+const LockKey int64 = 0x63736d5f636472 // "csm_cdr"
+
+// Leader decides whether this replica should write CDR files
+type Leader interface {
+	Acquire(ctx context.Context) (bool, error)
+	Release()
+}
+
+// Config controls where CDR files are written, how often new records are picked up and
+// when a new file is started
+type Config struct {
+	Dir       string
+	Interval  time.Duration
+	BatchSize int
+	// RotateInterval is how long a file is written before the next one is started
+	RotateInterval time.Duration
+	// MaxFileSize starts the next file once a file reaches this many bytes; zero
+	// disables the limit
+	MaxFileSize int64
+}
+
+// Enabled reports whether CDR files should be written at all
+func (c Config) Enabled() bool {
+	return c.Dir != "" && c.Interval > 0
+}
+
+// Writer appends the call detail records of ended sessions to rotating CSV files laid
+// out by the service's CDR template. Records are marked written once their file has
+// been synced, so every record reaches a file at least once; one whose write was
+// interrupted is written again to the next file.
+type Writer struct {
+	svc    *service.Service
+	leader Leader
+	cfg    Config
+	logger *log.Logger
+
+	files   *files
+	leading bool
+}
+
+// New creates a writer; leader may be nil when only a single replica runs
+func New(svc *service.Service, leader Leader, cfg Config, logger *log.Logger) *Writer {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.RotateInterval <= 0 {
+		cfg.RotateInterval = time.Hour
+	}
+	return &Writer{
+		svc:    svc,
+		leader: leader,
+		cfg:    cfg,
+		logger: logger,
+		files: &files{
+			dir:      cfg.Dir,
+			template: svc.CDRTemplate(),
+			interval: cfg.RotateInterval,
+			maxSize:  cfg.MaxFileSize,
+		},
+	}
+}
+
+// Run writes new records every interval until ctx is cancelled, then completes the
+// current file
+func (w *Writer) Run(ctx context.Context) {
+	if w.leader != nil {
+		defer w.leader.Release()
+	}
+	defer w.stop()
+
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.tick(ctx)
+		}
+	}
+}
+
+func (w *Writer) tick(ctx context.Context) {
+	if w.leader != nil {
+		leading, err := w.leader.Acquire(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				w.logger.Printf("CDR writer leader election failed: %v", err)
+			}
+			return
+		}
+		if !leading {
+			// Another replica took over; hand it a complete file
+			w.stop()
+			return
+		}
+	}
+	if !w.leading {
+		if err := w.files.recover(); err != nil {
+			w.logger.Printf("CDR writer failed to complete unfinished files: %v", err)
+			return
+		}
+		w.leading = true
+	}
+
+	if err := w.files.rotate(time.Now()); err != nil {
+		w.logger.Printf("CDR writer failed to complete %s: %v", w.files.name, err)
+	}
+
+	for ctx.Err() == nil {
+		cdrs, err := w.svc.ListUnwrittenCDRs(ctx, w.cfg.BatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				w.logger.Printf("CDR writer failed to read records: %v", err)
+			}
+			return
+		}
+		if len(cdrs) == 0 {
+			return
+		}
+
+		if err := w.files.write(cdrs, time.Now()); err != nil {
+			w.logger.Printf("CDR writer failed to write %d records: %v", len(cdrs), err)
+			// The file may end in a partial batch; start afresh with the next one
+			if err := w.files.close(); err != nil {
+				w.logger.Printf("CDR writer failed to complete %s: %v", w.files.name, err)
+			}
+			return
+		}
+
+		// The records are on disk; record that even if shutdown has begun so they are
+		// not written again
+		if err := w.svc.MarkCDRsWritten(context.WithoutCancel(ctx), cdrs); err != nil {
+			w.logger.Printf("CDR writer failed to mark %d records written: %v", len(cdrs), err)
+			return
+		}
+		if len(cdrs) < w.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+// stop completes the current file when this replica stops writing
+func (w *Writer) stop() {
+	w.leading = false
+	if err := w.files.close(); err != nil {
+		w.logger.Printf("CDR writer failed to complete %s: %v", w.files.name, err)
+	}
+}
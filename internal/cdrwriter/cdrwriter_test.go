@@ -0,0 +1,169 @@
+package cdrwriter
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/cdr"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+	"github.com/vasu74/Call_Session_Management/internal/store/memory"
+)
+
+type fakeLeader struct {
+	leading bool
+}
+
+func (l *fakeLeader) Acquire(ctx context.Context) (bool, error) {
+	return l.leading, nil
+}
+
+func (l *fakeLeader) Release() {}
+
+// dirFiles returns the names of the files in dir
+func dirFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	return names
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestFilesRotate(t *testing.T) {
+	record := model.CDR{SessionID: uuid.MustParse("6f1c2c3e-0000-4000-8000-000000000001"), CallerID: "+14155550100", Billsec: 42}
+	opened := time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		maxSize   int64
+		next      time.Duration
+		wantFiles []string
+	}{
+		{name: "within the interval", next: 30 * time.Minute, wantFiles: []string{"cdr-20240320T100000Z.csv.part"}},
+		{name: "interval elapsed", next: time.Hour, wantFiles: []string{"cdr-20240320T100000Z.csv", "cdr-20240320T110000Z.csv.part"}},
+		{name: "size limit reached", maxSize: 10, next: time.Minute, wantFiles: []string{"cdr-20240320T100000Z.csv", "cdr-20240320T100100Z.csv.part"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			f := &files{dir: dir, template: cdr.MustParseTemplate("session_id,caller,billsec"), interval: time.Hour, maxSize: tt.maxSize}
+			if err := f.write([]model.CDR{record}, opened); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.write([]model.CDR{record}, opened.Add(tt.next)); err != nil {
+				t.Fatal(err)
+			}
+			if got := dirFiles(t, dir); strings.Join(got, " ") != strings.Join(tt.wantFiles, " ") {
+				t.Fatalf("files %v, want %v", got, tt.wantFiles)
+			}
+			if err := f.close(); err != nil {
+				t.Fatal(err)
+			}
+
+			row := record.SessionID.String() + ",+14155550100,42\n"
+			want := "session_id,caller,billsec\n" + row
+			if len(tt.wantFiles) == 1 {
+				want += row
+			}
+			if got := readFile(t, filepath.Join(dir, "cdr-20240320T100000Z.csv")); got != want {
+				t.Errorf("first file %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestFilesRecover(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"cdr-20240320T100000Z.csv.part", "cdr-20240320T110000Z.csv", "notes.part"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("caller\n"), 0o640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := &files{dir: dir, template: cdr.MustParseTemplate("caller"), interval: time.Hour}
+	if err := f.recover(); err != nil {
+		t.Fatal(err)
+	}
+	// A file opened in the same second as a completed one gets a sequence number
+	if err := f.open(time.Date(2024, 3, 20, 11, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"cdr-20240320T100000Z.csv", "cdr-20240320T110000Z-1.csv.part", "cdr-20240320T110000Z.csv", "notes.part"}
+	if got := dirFiles(t, dir); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("files %v, want %v", got, want)
+	}
+	if err := f.close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriterWritesEndedSessions(t *testing.T) {
+	ctx := context.Background()
+	access := model.SessionAccess{Scope: model.SessionScopeAll, OrgID: model.DefaultOrganizationID, HomeOrgID: model.DefaultOrganizationID}
+	st := memory.New()
+	svc := service.New(st, service.WithCDRTemplate(cdr.MustParseTemplate("caller,status")))
+
+	for _, status := range []model.SessionStatus{model.SessionStatusCompleted, model.SessionStatusMissed, model.SessionStatusBusy} {
+		session, _, err := svc.StartSession(ctx, access, model.StartSessionRequest{CallerID: "+14155550100", CalleeID: "+14155550199"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.EndSession(ctx, access, session.ID.String(), model.EndSessionRequest{Status: status, Disposition: "done", EndTime: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Left active, so it has no record yet
+	if _, _, err := svc.StartSession(ctx, access, model.StartSessionRequest{CallerID: "+14155550101", CalleeID: "+14155550199"}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	leader := &fakeLeader{}
+	// A batch size of two makes a single tick work through several batches
+	w := New(svc, leader, Config{Dir: dir, Interval: time.Minute, BatchSize: 2}, log.New(io.Discard, "", 0))
+
+	w.tick(ctx)
+	if got := dirFiles(t, dir); len(got) != 0 {
+		t.Fatalf("a follower wrote %v", got)
+	}
+
+	leader.leading = true
+	w.tick(ctx)
+	if unwritten, _ := svc.ListUnwrittenCDRs(ctx, 10); len(unwritten) != 0 {
+		t.Errorf("%d records left unwritten", len(unwritten))
+	}
+	got := dirFiles(t, dir)
+	if len(got) != 1 || !strings.HasSuffix(got[0], ".csv"+partSuffix) {
+		t.Fatalf("files %v, want one file being written", got)
+	}
+
+	// Losing leadership completes the file for the next leader
+	leader.leading = false
+	w.tick(ctx)
+	name := strings.TrimSuffix(got[0], partSuffix)
+	want := "caller,status\n+14155550100,completed\n+14155550100,missed\n+14155550100,busy\n"
+	if content := readFile(t, filepath.Join(dir, name)); content != want {
+		t.Errorf("file %s holds %q, want %q", name, content, want)
+	}
+}
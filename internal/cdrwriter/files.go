@@ -0,0 +1,145 @@
+package cdrwriter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/cdr"
+	"github.com/vasu74/Call_Session_Management/internal/export"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// partSuffix marks a CDR file that is still being written
+const partSuffix = ".part"
+
+// files appends records to CSV files in a directory. A new file is started once the
+// current one has been open for the rotation interval or has reached its size limit.
+// Files are written under a .part name and renamed once complete, so whoever collects
+// them only ever sees whole files.
+type files struct {
+	dir      string
+	template cdr.Template
+	interval time.Duration
+	maxSize  int64
+
+	file   *os.File
+	enc    export.Encoder
+	name   string
+	opened time.Time
+	size   int64
+}
+
+// write appends the records to the current file, starting a new one when it is due,
+// and syncs the file to disk
+func (f *files) write(cdrs []model.CDR, now time.Time) error {
+	if err := f.rotate(now); err != nil {
+		return err
+	}
+	if f.file == nil {
+		if err := f.open(now); err != nil {
+			return err
+		}
+	}
+
+	for i := range cdrs {
+		if err := f.enc.WriteRow(f.template.Row(&cdrs[i])); err != nil {
+			return err
+		}
+	}
+	if err := f.enc.Flush(); err != nil {
+		return err
+	}
+	if err := f.file.Sync(); err != nil {
+		return err
+	}
+	info, err := f.file.Stat()
+	if err != nil {
+		return err
+	}
+	f.size = info.Size()
+	return nil
+}
+
+// rotate completes the current file once it has been open for the rotation interval or
+// has reached its size limit
+func (f *files) rotate(now time.Time) error {
+	if f.file == nil {
+		return nil
+	}
+	if now.Sub(f.opened) < f.interval && (f.maxSize <= 0 || f.size < f.maxSize) {
+		return nil
+	}
+	return f.close()
+}
+
+// open starts a file named after the time it was opened
+func (f *files) open(now time.Time) error {
+	base := "cdr-" + now.UTC().Format("20060102T150405Z")
+	for seq := 0; ; seq++ {
+		name := base + ".csv"
+		if seq > 0 {
+			name = fmt.Sprintf("%s-%d.csv", base, seq)
+		}
+		if _, err := os.Stat(filepath.Join(f.dir, name)); err == nil {
+			continue
+		}
+
+		file, err := os.OpenFile(filepath.Join(f.dir, name+partSuffix), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		enc, err := export.NewEncoder(model.ExportCSV, file, f.template.Columns())
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return err
+		}
+		f.file, f.enc, f.name, f.opened, f.size = file, enc, name, now, 0
+		return nil
+	}
+}
+
+// close completes the current file, if any
+func (f *files) close() error {
+	if f.file == nil {
+		return nil
+	}
+	file, name := f.file, f.name
+	f.file, f.enc = nil, nil
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), filepath.Join(f.dir, name))
+}
+
+// recover completes files left behind by a writer that stopped without closing them.
+// The records of a batch whose write was interrupted were not marked written, so they
+// are written again to the next file.
+func (f *files) recover() error {
+	parts, err := filepath.Glob(filepath.Join(f.dir, "cdr-*.csv"+partSuffix))
+	if err != nil {
+		return err
+	}
+	for _, part := range parts {
+		if f.file != nil && part == f.file.Name() {
+			continue
+		}
+		if err := os.Rename(part, strings.TrimSuffix(part, partSuffix)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvEncoder writes a header line followed by one line per row. Null values are
+// written as empty fields and JSON values as their encoded text.
+type csvEncoder struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVEncoder(w io.Writer, columns []Column) (*csvEncoder, error) {
+	e := &csvEncoder{w: csv.NewWriter(w), record: make([]string, len(columns))}
+	for i, column := range columns {
+		e.record[i] = column.Name
+	}
+	if err := e.w.Write(e.record); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *csvEncoder) WriteRow(row Row) error {
+	for i, value := range row {
+		switch v := value.(type) {
+		case nil:
+			e.record[i] = ""
+		case string:
+			e.record[i] = v
+		case time.Time:
+			e.record[i] = formatTime(v)
+		case json.RawMessage:
+			e.record[i] = string(v)
+		case int64:
+			e.record[i] = strconv.FormatInt(v, 10)
+		default:
+			return fmt.Errorf("unsupported CSV value of type %T", value)
+		}
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *csvEncoder) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) Close() error {
+	return e.Flush()
+}
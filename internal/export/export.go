@@ -0,0 +1,173 @@
+// Package export encodes sessions and events as CSV, NDJSON or Parquet rows for bulk
+// exports. Encoders write rows as they are given, so memory use does not grow with the
+// size of the export.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// Kind is the type of the values of a column
+type Kind int
+
+const (
+	// String values are Go strings
+	String Kind = iota
+	// Time values are time.Time and are written in UTC
+	Time
+	// JSON values are json.RawMessage holding an encoded JSON value
+	JSON
+	// Int values are int64
+	Int
+)
+
+// Column describes a column of an export
+type Column struct {
+	Name string
+	Kind Kind
+}
+
+// Row holds one value per column; nil is written as an empty or null value
+type Row []interface{}
+
+// Encoder writes the rows of an export in one format
+type Encoder interface {
+	// WriteRow writes a row matching the encoder's columns
+	WriteRow(row Row) error
+	// Flush writes the rows buffered so far to the underlying writer. Parquet rows are
+	// buffered in row groups and only written once a group is full or on Close.
+	Flush() error
+	// Close writes any buffered rows and the end of the file; it does not close the
+	// underlying writer
+	Close() error
+}
+
+// NewEncoder creates an encoder writing rows with the given columns to w
+func NewEncoder(format model.ExportFormat, w io.Writer, columns []Column) (Encoder, error) {
+	switch format {
+	case model.ExportCSV:
+		return newCSVEncoder(w, columns)
+	case model.ExportNDJSON:
+		return newNDJSONEncoder(w, columns), nil
+	case model.ExportParquet:
+		return newParquetEncoder(w, columns), nil
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+var sessionColumns = []Column{
+	{Name: "id", Kind: String},
+	{Name: "started_at", Kind: Time},
+	{Name: "ended_at", Kind: Time},
+	{Name: "caller_id", Kind: String},
+	{Name: "callee_id", Kind: String},
+	{Name: "status", Kind: String},
+	{Name: "disposition", Kind: String},
+	{Name: "external_source", Kind: String},
+	{Name: "external_id", Kind: String},
+	{Name: "initial_metadata", Kind: JSON},
+	{Name: "created_at", Kind: Time},
+	{Name: "updated_at", Kind: Time},
+	{Name: "caller_id_raw", Kind: String},
+	{Name: "caller_id_type", Kind: String},
+	{Name: "caller_number_type", Kind: String},
+	{Name: "callee_id_raw", Kind: String},
+	{Name: "callee_id_type", Kind: String},
+	{Name: "callee_number_type", Kind: String},
+}
+
+// metadataColumnPrefix names the columns that initial_metadata keys are flattened into
+const metadataColumnPrefix = "initial_metadata."
+
+// SessionColumns returns the columns of a sessions export, followed by one column for
+// each flattened initial_metadata key
+func SessionColumns(metadataKeys []string) []Column {
+	columns := make([]Column, len(sessionColumns), len(sessionColumns)+len(metadataKeys))
+	copy(columns, sessionColumns)
+	for _, key := range metadataKeys {
+		columns = append(columns, Column{Name: metadataColumnPrefix + key, Kind: String})
+	}
+	return columns
+}
+
+// SessionRow returns the values of a session for the columns returned by SessionColumns.
+// Flattened metadata values are rendered as text like the Postgres ->> operator does.
+func SessionRow(s *model.Session, metadataKeys []string) (Row, error) {
+	metadata, err := jsonValue(map[string]interface{}(s.InitialMetadata))
+	if err != nil {
+		return nil, err
+	}
+
+	row := make(Row, 0, len(sessionColumns)+len(metadataKeys))
+	row = append(row,
+		s.ID.String(), s.StartedAt, timeValue(s.EndedAt),
+		s.CallerID, s.CalleeID, string(s.Status),
+		stringValue(s.Disposition), stringValue(s.ExternalSource), stringValue(s.ExternalID),
+		metadata, s.CreatedAt, s.UpdatedAt,
+		s.CallerIDRaw, string(s.CallerIDType), string(s.CallerNumberType),
+		s.CalleeIDRaw, string(s.CalleeIDType), string(s.CalleeNumberType),
+	)
+	for _, key := range metadataKeys {
+		if value, ok := s.InitialMetadata.Text(key); ok {
+			row = append(row, value)
+		} else {
+			row = append(row, nil)
+		}
+	}
+	return row, nil
+}
+
+// EventColumns are the columns of an events export
+var EventColumns = []Column{
+	{Name: "id", Kind: String},
+	{Name: "session_id", Kind: String},
+	{Name: "event_type", Kind: String},
+	{Name: "event_time", Kind: Time},
+	{Name: "metadata", Kind: JSON},
+	{Name: "created_at", Kind: Time},
+}
+
+// EventRow returns the values of an event for EventColumns
+func EventRow(e *model.SessionEvent) (Row, error) {
+	metadata, err := jsonValue(map[string]interface{}(e.Metadata))
+	if err != nil {
+		return nil, err
+	}
+	return Row{e.ID.String(), e.SessionID.String(), e.EventType, e.EventTime, metadata, e.CreatedAt}, nil
+}
+
+func stringValue(s *string) interface{} {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+func timeValue(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+// jsonValue encodes a metadata map, leaving missing metadata null
+func jsonValue(m map[string]interface{}) (interface{}, error) {
+	if m == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(encoded), nil
+}
+
+// formatTime renders times in the text formats
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
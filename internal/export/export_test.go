@@ -0,0 +1,168 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+var testColumns = []Column{
+	{Name: "id", Kind: String},
+	{Name: "at", Kind: Time},
+	{Name: "metadata", Kind: JSON},
+	{Name: "count", Kind: Int},
+}
+
+var testRows = []Row{
+	{"a,1", time.Date(2024, 3, 20, 11, 0, 0, 500, time.FixedZone("CET", 3600)), json.RawMessage(`{"queue":"sales"}`), int64(42)},
+	{"b\"2", nil, nil, nil},
+}
+
+func TestEncoders(t *testing.T) {
+	tests := []struct {
+		format model.ExportFormat
+		want   string
+	}{
+		{
+			format: model.ExportCSV,
+			want: "id,at,metadata,count\n" +
+				`"a,1",2024-03-20T10:00:00.0000005Z,"{""queue"":""sales""}",42` + "\n" +
+				`"b""2",,,` + "\n",
+		},
+		{
+			format: model.ExportNDJSON,
+			want: `{"id":"a,1","at":"2024-03-20T10:00:00.0000005Z","metadata":{"queue":"sales"},"count":42}` + "\n" +
+				`{"id":"b\"2","at":null,"metadata":null,"count":null}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			enc, err := NewEncoder(tt.format, &buf, testColumns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, row := range testRows {
+				if err := enc.WriteRow(row); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("encoded\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+
+			if err := enc.WriteRow(Row{1.5, nil, nil, nil}); err == nil {
+				t.Error("WriteRow accepted a float")
+			}
+		})
+	}
+
+	if _, err := NewEncoder("xml", &bytes.Buffer{}, testColumns); err == nil {
+		t.Error("NewEncoder accepted an unknown format")
+	}
+}
+
+func TestParquetEncoder(t *testing.T) {
+	for _, rows := range []int{0, 3} {
+		var buf bytes.Buffer
+		enc, err := NewEncoder(model.ExportParquet, &buf, testColumns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < rows; i++ {
+			if err := enc.WriteRow(testRows[i%len(testRows)]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		file := buf.Bytes()
+		if !bytes.HasPrefix(file, parquetMagic) || !bytes.HasSuffix(file, parquetMagic) {
+			t.Fatalf("file with %d rows is not framed by %s", rows, parquetMagic)
+		}
+		footerLength := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+		if footerLength == 0 || footerLength > len(file)-12 {
+			t.Errorf("file with %d rows of %d bytes has a %d byte footer", rows, len(file), footerLength)
+		}
+		if rows > 0 && len(file)-12-footerLength <= 0 {
+			t.Errorf("file with %d rows has no row group", rows)
+		}
+	}
+}
+
+func TestEncodeLevels(t *testing.T) {
+	tests := []struct {
+		defined []bool
+		want    []byte
+	}{
+		{defined: nil, want: nil},
+		{defined: []bool{true, true, false}, want: []byte{4, 1, 2, 0}},
+		{defined: []bool{false, true, false}, want: []byte{2, 0, 2, 1, 2, 0}},
+		// A run of 100 needs a two byte varint header: 200 = 0xc8 0x01
+		{defined: make([]bool, 100), want: []byte{0xc8, 0x01, 0}},
+	}
+	for _, tt := range tests {
+		if got := encodeLevels(tt.defined); !bytes.Equal(got, tt.want) {
+			t.Errorf("encodeLevels(%v) = %v, want %v", tt.defined, got, tt.want)
+		}
+	}
+}
+
+func TestSessionRow(t *testing.T) {
+	disposition := "resolved"
+	session := &model.Session{
+		ID:              uuid.New(),
+		StartedAt:       time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC),
+		CallerID:        "+14155550100",
+		CalleeID:        "+14155550199",
+		Status:          model.SessionStatusCompleted,
+		Disposition:     &disposition,
+		InitialMetadata: model.SessionMetadata{"queue": "sales", "priority": 2, "vip": true},
+	}
+	keys := []string{"queue", "priority", "vip", "missing"}
+	columns := SessionColumns(keys)
+	row, err := SessionRow(session, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(row) != len(columns) {
+		t.Fatalf("row has %d values for %d columns", len(row), len(columns))
+	}
+
+	values := make(map[string]interface{})
+	for i, column := range columns {
+		values[column.Name] = row[i]
+	}
+	tests := []struct {
+		column string
+		want   interface{}
+	}{
+		{column: "id", want: session.ID.String()},
+		{column: "ended_at", want: nil},
+		{column: "disposition", want: "resolved"},
+		{column: "external_id", want: nil},
+		{column: "initial_metadata.queue", want: "sales"},
+		{column: "initial_metadata.priority", want: "2"},
+		{column: "initial_metadata.vip", want: "true"},
+		{column: "initial_metadata.missing", want: nil},
+	}
+	for _, tt := range tests {
+		if values[tt.column] != tt.want {
+			t.Errorf("%s = %v, want %v", tt.column, values[tt.column], tt.want)
+		}
+	}
+	if metadata, ok := values["initial_metadata"].(json.RawMessage); !ok || !strings.Contains(string(metadata), `"queue":"sales"`) {
+		t.Errorf("initial_metadata = %v", values["initial_metadata"])
+	}
+}
@@ -0,0 +1,69 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ndjsonEncoder writes one JSON object per line with the columns as keys, in column
+// order. JSON values are embedded as they are rather than as strings.
+type ndjsonEncoder struct {
+	w    io.Writer
+	keys [][]byte
+	buf  bytes.Buffer
+}
+
+func newNDJSONEncoder(w io.Writer, columns []Column) *ndjsonEncoder {
+	e := &ndjsonEncoder{w: w, keys: make([][]byte, len(columns))}
+	for i, column := range columns {
+		key, _ := json.Marshal(column.Name)
+		e.keys[i] = append(key, ':')
+	}
+	return e
+}
+
+func (e *ndjsonEncoder) WriteRow(row Row) error {
+	e.buf.Reset()
+	e.buf.WriteByte('{')
+	for i, value := range row {
+		if i > 0 {
+			e.buf.WriteByte(',')
+		}
+		e.buf.Write(e.keys[i])
+
+		switch v := value.(type) {
+		case nil:
+			e.buf.WriteString("null")
+		case json.RawMessage:
+			e.buf.Write(v)
+		case time.Time:
+			e.buf.WriteString(`"` + formatTime(v) + `"`)
+		case int64:
+			e.buf.WriteString(strconv.FormatInt(v, 10))
+		case string:
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			e.buf.Write(encoded)
+		default:
+			return fmt.Errorf("unsupported NDJSON value of type %T", value)
+		}
+	}
+	e.buf.WriteString("}\n")
+
+	_, err := e.w.Write(e.buf.Bytes())
+	return err
+}
+
+func (e *ndjsonEncoder) Flush() error {
+	return nil
+}
+
+func (e *ndjsonEncoder) Close() error {
+	return nil
+}
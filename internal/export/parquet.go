@@ -0,0 +1,297 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+)
+
+// parquetRowGroupSize is the number of rows buffered before they are written as a row
+// group, which bounds the memory used by a Parquet export
+const parquetRowGroupSize = 10000
+
+// Values of the Parquet format enums
+const (
+	parquetInt64           = 2
+	parquetByteArray       = 6
+	parquetOptional        = 1
+	parquetUTF8            = 0
+	parquetTimestampMicros = 10
+	parquetSigned64        = 18
+	parquetPlain           = 0
+	parquetRLE             = 3
+	parquetSnappy          = 1
+	parquetDataPage        = 0
+)
+
+var parquetMagic = []byte("PAR1")
+
+// parquetEncoder writes a Parquet file with a flat schema of optional columns. Strings
+// and JSON are stored as UTF-8 byte arrays, integers as 64-bit integers and times as
+// microsecond timestamps. Every
+// column chunk is a single Snappy compressed data page of plain encoded values.
+type parquetEncoder struct {
+	w       *countingWriter
+	columns []Column
+	buffers []columnBuffer
+	rows    int
+
+	numRows   int64
+	rowGroups []rowGroupMeta
+}
+
+// columnBuffer collects the values of a column for the current row group
+type columnBuffer struct {
+	defined []bool
+	values  bytes.Buffer
+}
+
+type rowGroupMeta struct {
+	numRows   int64
+	totalSize int64
+	chunks    []columnChunkMeta
+}
+
+type columnChunkMeta struct {
+	offset           int64
+	numValues        int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+func newParquetEncoder(w io.Writer, columns []Column) *parquetEncoder {
+	return &parquetEncoder{
+		w:       &countingWriter{w: w},
+		columns: columns,
+		buffers: make([]columnBuffer, len(columns)),
+	}
+}
+
+func (e *parquetEncoder) WriteRow(row Row) error {
+	if e.w.n == 0 {
+		if _, err := e.w.Write(parquetMagic); err != nil {
+			return err
+		}
+	}
+
+	var scratch [8]byte
+	for i, value := range row {
+		buf := &e.buffers[i]
+		if value == nil {
+			buf.defined = append(buf.defined, false)
+			continue
+		}
+
+		switch v := value.(type) {
+		case string:
+			writeByteArray(&buf.values, []byte(v))
+		case json.RawMessage:
+			writeByteArray(&buf.values, v)
+		case time.Time:
+			binary.LittleEndian.PutUint64(scratch[:], uint64(v.UnixMicro()))
+			buf.values.Write(scratch[:])
+		case int64:
+			binary.LittleEndian.PutUint64(scratch[:], uint64(v))
+			buf.values.Write(scratch[:])
+		default:
+			return fmt.Errorf("unsupported Parquet value of type %T", value)
+		}
+		buf.defined = append(buf.defined, true)
+	}
+
+	e.rows++
+	if e.rows >= parquetRowGroupSize {
+		return e.flushRowGroup()
+	}
+	return nil
+}
+
+func (e *parquetEncoder) Flush() error {
+	return nil
+}
+
+func (e *parquetEncoder) Close() error {
+	if e.w.n == 0 {
+		if _, err := e.w.Write(parquetMagic); err != nil {
+			return err
+		}
+	}
+	if e.rows > 0 {
+		if err := e.flushRowGroup(); err != nil {
+			return err
+		}
+	}
+
+	footer := e.footer()
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(footer)))
+	for _, part := range [][]byte{footer, length[:], parquetMagic} {
+		if _, err := e.w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flushRowGroup writes the buffered rows as a row group with one page per column
+func (e *parquetEncoder) flushRowGroup() error {
+	group := rowGroupMeta{numRows: int64(e.rows), chunks: make([]columnChunkMeta, len(e.columns))}
+
+	for i := range e.buffers {
+		buf := &e.buffers[i]
+
+		// Definition levels are RLE encoded and prefixed with their length, followed by
+		// the plain encoded values of the rows that are not null
+		levels := encodeLevels(buf.defined)
+		page := make([]byte, 4, 4+len(levels)+buf.values.Len())
+		binary.LittleEndian.PutUint32(page, uint32(len(levels)))
+		page = append(page, levels...)
+		page = append(page, buf.values.Bytes()...)
+		compressed := snappy.Encode(nil, page)
+
+		var header thriftWriter
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(compressed)))
+		header.beginStruct(5)
+		header.i32(1, int32(len(buf.defined)))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.end()
+		header.end()
+
+		chunk := columnChunkMeta{
+			offset:           e.w.n,
+			numValues:        int64(len(buf.defined)),
+			uncompressedSize: int64(header.buf.Len() + len(page)),
+			compressedSize:   int64(header.buf.Len() + len(compressed)),
+		}
+		if _, err := e.w.Write(header.buf.Bytes()); err != nil {
+			return err
+		}
+		if _, err := e.w.Write(compressed); err != nil {
+			return err
+		}
+		group.chunks[i] = chunk
+		group.totalSize += chunk.uncompressedSize
+
+		buf.defined = buf.defined[:0]
+		buf.values.Reset()
+	}
+
+	e.rowGroups = append(e.rowGroups, group)
+	e.numRows += group.numRows
+	e.rows = 0
+	return nil
+}
+
+// footer encodes the FileMetaData of the file
+func (e *parquetEncoder) footer() []byte {
+	var w thriftWriter
+	w.i32(1, 1)
+
+	w.list(2, thriftStruct, len(e.columns)+1)
+	w.beginElement()
+	w.string(4, "schema")
+	w.i32(5, int32(len(e.columns)))
+	w.end()
+	for _, column := range e.columns {
+		physical, converted := parquetTypes(column.Kind)
+		w.beginElement()
+		w.i32(1, physical)
+		w.i32(3, parquetOptional)
+		w.string(4, column.Name)
+		w.i32(6, converted)
+		w.end()
+	}
+
+	w.i64(3, e.numRows)
+
+	w.list(4, thriftStruct, len(e.rowGroups))
+	for _, group := range e.rowGroups {
+		w.beginElement()
+		w.list(1, thriftStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			physical, _ := parquetTypes(e.columns[i].Kind)
+			w.beginElement()
+			w.i64(2, chunk.offset)
+			w.beginStruct(3)
+			w.i32(1, physical)
+			w.list(2, thriftI32, 2)
+			w.varint(parquetPlain)
+			w.varint(parquetRLE)
+			w.list(3, thriftBinary, 1)
+			w.stringValue(e.columns[i].Name)
+			w.i32(4, parquetSnappy)
+			w.i64(5, chunk.numValues)
+			w.i64(6, chunk.uncompressedSize)
+			w.i64(7, chunk.compressedSize)
+			w.i64(9, chunk.offset)
+			w.end()
+			w.end()
+		}
+		w.i64(2, group.totalSize)
+		w.i64(3, group.numRows)
+		w.end()
+	}
+
+	w.string(6, "call-session-management")
+	w.end()
+	return w.buf.Bytes()
+}
+
+// parquetTypes returns the physical and converted type storing values of a kind
+func parquetTypes(kind Kind) (physical, converted int32) {
+	switch kind {
+	case Time:
+		return parquetInt64, parquetTimestampMicros
+	case Int:
+		return parquetInt64, parquetSigned64
+	}
+	return parquetByteArray, parquetUTF8
+}
+
+func writeByteArray(buf *bytes.Buffer, b []byte) {
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(b)))
+	buf.Write(length[:])
+	buf.Write(b)
+}
+
+// encodeLevels encodes definition levels of bit width 1 as runs of the RLE/bit-packing
+// hybrid encoding
+func encodeLevels(defined []bool) []byte {
+	var out []byte
+	var header [binary.MaxVarintLen64]byte
+	for start := 0; start < len(defined); {
+		end := start + 1
+		for end < len(defined) && defined[end] == defined[start] {
+			end++
+		}
+		out = append(out, header[:binary.PutUvarint(header[:], uint64(end-start)<<1)]...)
+		if defined[start] {
+			out = append(out, 1)
+		} else {
+			out = append(out, 0)
+		}
+		start = end
+	}
+	return out
+}
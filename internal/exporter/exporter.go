@@ -0,0 +1,106 @@
+package exporter
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/service"
+)
+
+// LockKey identifies the advisory lock that elects the replica running export jobs
+const LockKey int64 = 0x63736d5f657870 // "csm_exp"
+
+// Leader decides whether this replica should run export jobs
+type Leader interface {
+	Acquire(ctx context.Context) (bool, error)
+	Release()
+}
+
+// Config controls how often queued export jobs are picked up and how long one may run
+type Config struct {
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+// Enabled reports whether the exporter should run at all
+func (c Config) Enabled() bool {
+	return c.Interval > 0
+}
+
+// Exporter runs queued export jobs one at a time and removes expired export files
+type Exporter struct {
+	svc    *service.Service
+	leader Leader
+	cfg    Config
+	logger *log.Logger
+}
+
+// New creates an exporter; leader may be nil when only a single replica runs
+func New(svc *service.Service, leader Leader, cfg Config, logger *log.Logger) *Exporter {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Hour
+	}
+	return &Exporter{svc: svc, leader: leader, cfg: cfg, logger: logger}
+}
+
+// Run processes export jobs every interval until ctx is cancelled
+func (e *Exporter) Run(ctx context.Context) {
+	if e.leader != nil {
+		defer e.leader.Release()
+	}
+
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.tick(ctx)
+		}
+	}
+}
+
+func (e *Exporter) tick(ctx context.Context) {
+	if e.leader != nil {
+		leading, err := e.leader.Acquire(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				e.logger.Printf("Exporter leader election failed: %v", err)
+			}
+			return
+		}
+		if !leading {
+			return
+		}
+	}
+
+	if _, err := e.svc.PurgeExportJobs(ctx); err != nil && ctx.Err() == nil {
+		e.logger.Printf("Exporter failed to purge expired exports: %v", err)
+	}
+
+	// Drain the queue one job at a time. The lease outlives the timeout, so a job is
+	// only claimed again when the replica running it went away.
+	for ctx.Err() == nil {
+		jobs, err := e.svc.ClaimExportJobs(ctx, 1, e.cfg.Timeout+time.Minute)
+		if err != nil {
+			if ctx.Err() == nil {
+				e.logger.Printf("Exporter failed to claim jobs: %v", err)
+			}
+			return
+		}
+		if len(jobs) == 0 {
+			return
+		}
+
+		job := jobs[0]
+		jobCtx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+		err = e.svc.RunExportJob(jobCtx, &job)
+		cancel()
+		if err != nil && ctx.Err() == nil {
+			e.logger.Printf("Export job %s failed: %v", job.ID, err)
+		}
+	}
+}
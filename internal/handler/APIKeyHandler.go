@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vasu74/Call_Session_Management/internal/middleware"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+func (h *Handler) CreateAPIKeyHandler(c *gin.Context) {
+	var req model.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	granted, err := middleware.Permissions(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	key, err := h.svc.CreateAPIKey(c.Request.Context(), access, granted, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "API key created successfully",
+		"api_key": key,
+	})
+}
+
+func (h *Handler) ListAPIKeysHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	keys, err := h.svc.ListAPIKeys(c.Request.Context(), access)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+func (h *Handler) GetAPIKeyHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	key, err := h.svc.GetAPIKey(c.Request.Context(), access, c.Param("keyId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+func (h *Handler) UpdateAPIKeyHandler(c *gin.Context) {
+	var req model.UpdateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	granted, err := middleware.Permissions(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	key, err := h.svc.UpdateAPIKey(c.Request.Context(), access, granted, c.Param("keyId"), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API key updated successfully",
+		"api_key": key,
+	})
+}
+
+func (h *Handler) DeleteAPIKeyHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.svc.DeleteAPIKey(c.Request.Context(), access, c.Param("keyId")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
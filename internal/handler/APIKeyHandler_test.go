@@ -0,0 +1,153 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// createAPIKey creates an API key as the admin with the given token
+func (s *testServer) createAPIKey(token string, body gin.H) model.APIKey {
+	s.t.Helper()
+	var out struct {
+		APIKey model.APIKey `json:"api_key"`
+	}
+	expect(s.t, http.StatusCreated, s.do(token, http.MethodPost, "/api/admin/api-keys", body), &out)
+	return out.APIKey
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("admin@example.com", model.UserRoleAdmin, "")
+	admin := s.login("admin@example.com")
+	key := s.createAPIKey(admin, gin.H{"name": "pbx-1", "scopes": []string{"sessions:read", "sessions:write"}})
+	if !strings.HasPrefix(key.Key, key.Prefix+"_") || key.ServiceAccountID == uuid.Nil {
+		t.Fatalf("created key %+v", key)
+	}
+	start := gin.H{"caller_id": "+14155550100", "callee_id": "+14155550199"}
+
+	// Writes are attributed to the key's service account
+	var started struct {
+		Session model.Session `json:"session"`
+	}
+	expect(t, http.StatusCreated, s.do("", http.MethodPost, "/api/sessions/start", start, "X-API-Key", key.Key), &started)
+	if started.Session.CreatedBy == nil || *started.Session.CreatedBy != key.ServiceAccountID {
+		t.Errorf("session created by %v, want the service account %s", started.Session.CreatedBy, key.ServiceAccountID)
+	}
+	expect(t, http.StatusOK, s.do("", http.MethodGet, "/api/sessions/"+started.Session.ID.String(), nil, "Authorization", "ApiKey "+key.Key), nil)
+
+	events := "/api/sessions/" + started.Session.ID.String() + "/events"
+	tests := []struct {
+		name   string
+		header []string
+		method string
+		path   string
+		status int
+		code   string
+	}{
+		{name: "outside the scopes", header: []string{"X-API-Key", key.Key}, method: http.MethodPost, path: events, status: http.StatusForbidden, code: model.ErrForbidden.Code},
+		{name: "wrong secret", header: []string{"X-API-Key", key.Prefix + "_" + strings.Repeat("0", 64)}, method: http.MethodGet, path: "/api/sessions", status: http.StatusUnauthorized, code: model.ErrUnauthorized.Code},
+		{name: "unknown prefix", header: []string{"X-API-Key", model.NewAPIKey("0123456789abcdef", "secret")}, method: http.MethodGet, path: "/api/sessions", status: http.StatusUnauthorized, code: model.ErrUnauthorized.Code},
+		{name: "malformed key", header: []string{"Authorization", "ApiKey nope"}, method: http.MethodGet, path: "/api/sessions", status: http.StatusUnauthorized, code: model.ErrUnauthorized.Code},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectError(t, tt.status, tt.code, s.do("", tt.method, tt.path, gin.H{"event_type": "dtmf", "event_time": time.Now()}, tt.header...))
+		})
+	}
+
+	// Keys cannot sign out, as they have no login to revoke
+	expectError(t, http.StatusBadRequest, model.ErrInvalidRequest.Code, s.do("", http.MethodPost, "/auth/logout", nil, "X-API-Key", key.Key))
+
+	var stored model.APIKey
+	expect(t, http.StatusOK, s.do(admin, http.MethodGet, "/api/admin/api-keys/"+key.ID.String(), nil), &stored)
+	if stored.Key != "" || stored.LastUsedAt == nil {
+		t.Errorf("stored key %+v, want no key and a last use", stored)
+	}
+}
+
+func TestAPIKeyRestrictions(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("admin@example.com", model.UserRoleAdmin, "")
+	admin := s.login("admin@example.com")
+	key := s.createAPIKey(admin, gin.H{"name": "pbx-1", "scopes": []string{"sessions:read"}, "allowed_cidrs": []string{"10.0.0.0/8"}})
+	path := "/api/admin/api-keys/" + key.ID.String()
+
+	// httptest requests come from 192.0.2.1
+	expectError(t, http.StatusForbidden, model.ErrForbidden.Code, s.do("", http.MethodGet, "/api/sessions", nil, "X-API-Key", key.Key))
+	expect(t, http.StatusOK, s.do(admin, http.MethodPatch, path, gin.H{"allowed_cidrs": []string{"10.0.0.0/8", "192.0.2.0/24"}}), nil)
+	expect(t, http.StatusOK, s.do("", http.MethodGet, "/api/sessions", nil, "X-API-Key", key.Key), nil)
+
+	expectError(t, http.StatusBadRequest, model.ErrInvalidRequest.Code, s.do(admin, http.MethodPatch, path, gin.H{"expires_at": time.Now().Add(-time.Hour)}))
+	stored, err := s.store.GetAPIKey(context.Background(), key.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := time.Now().Add(-time.Minute)
+	stored.ExpiresAt = &expired
+	if err := s.store.UpdateAPIKey(context.Background(), stored); err != nil {
+		t.Fatal(err)
+	}
+	expectError(t, http.StatusUnauthorized, model.ErrUnauthorized.Code, s.do("", http.MethodGet, "/api/sessions", nil, "X-API-Key", key.Key))
+	expect(t, http.StatusOK, s.do(admin, http.MethodPatch, path, gin.H{"no_expiry": true}), nil)
+	expect(t, http.StatusOK, s.do("", http.MethodGet, "/api/sessions", nil, "X-API-Key", key.Key), nil)
+
+	expect(t, http.StatusNoContent, s.do(admin, http.MethodDelete, path, nil), nil)
+	expectError(t, http.StatusUnauthorized, model.ErrUnauthorized.Code, s.do("", http.MethodGet, "/api/sessions", nil, "X-API-Key", key.Key))
+	expectError(t, http.StatusNotFound, model.ErrAPIKeyNotFound.Code, s.do(admin, http.MethodGet, path, nil))
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("admin@example.com", model.UserRoleAdmin, "")
+	s.createUser("orgadmin@example.com", model.UserRoleOrgAdmin, "")
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	admin := s.login("admin@example.com")
+	first := s.createAPIKey(admin, gin.H{"name": "pbx-1", "scopes": []string{"sessions:write"}})
+
+	// A replacement key acts as the same service account
+	replacement := s.createAPIKey(admin, gin.H{"name": "pbx-1 rotated", "scopes": []string{"sessions:write"}, "service_account_id": first.ServiceAccountID})
+	if replacement.ServiceAccountID != first.ServiceAccountID || replacement.Prefix == first.Prefix {
+		t.Errorf("replacement key %+v of %+v", replacement, first)
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		body   gin.H
+		status int
+		code   string
+	}{
+		{name: "without scopes", token: admin, body: gin.H{"name": "k"}, status: http.StatusBadRequest, code: model.ErrInvalidRequest.Code},
+		{name: "unknown scope", token: admin, body: gin.H{"name": "k", "scopes": []string{"sessions:delete"}}, status: http.StatusBadRequest, code: model.ErrInvalidRequest.Code},
+		{name: "invalid CIDR", token: admin, body: gin.H{"name": "k", "scopes": []string{"sessions:read"}, "allowed_cidrs": []string{"10.0.0.1"}}, status: http.StatusBadRequest, code: model.ErrInvalidRequest.Code},
+		{name: "expired", token: admin, body: gin.H{"name": "k", "scopes": []string{"sessions:read"}, "expires_at": time.Now().Add(-time.Hour)}, status: http.StatusBadRequest, code: model.ErrInvalidRequest.Code},
+		{name: "human account", token: admin, body: gin.H{"name": "k", "scopes": []string{"sessions:read"}, "service_account_id": s.createUser("human@example.com", model.UserRoleUser, "").ID}, status: http.StatusBadRequest, code: model.ErrInvalidRequest.Code},
+		{name: "scope the caller lacks", token: s.login("orgadmin@example.com"), body: gin.H{"name": "k", "scopes": []string{"orgs:manage"}}, status: http.StatusForbidden, code: model.ErrForbidden.Code},
+		{name: "without users:manage", token: s.login("agent@example.com"), body: gin.H{"name": "k", "scopes": []string{"sessions:read"}}, status: http.StatusForbidden, code: model.ErrForbidden.Code},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectError(t, tt.status, tt.code, s.do(tt.token, http.MethodPost, "/api/admin/api-keys", tt.body))
+		})
+	}
+
+	var list struct {
+		APIKeys []model.APIKey `json:"api_keys"`
+	}
+	expect(t, http.StatusOK, s.do(admin, http.MethodGet, "/api/admin/api-keys", nil), &list)
+	if len(list.APIKeys) != 2 {
+		t.Fatalf("listed %d keys, want 2", len(list.APIKeys))
+	}
+	for _, key := range list.APIKeys {
+		if key.Key != "" {
+			t.Errorf("listed key %s with its secret", key.Name)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vasu74/Call_Session_Management/internal/middleware"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// maxMetadataFilters bounds the number of metadata[key]=value parameters
+const maxMetadataFilters = 20
+
+func (h *Handler) SessionAnalyticsHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	query := model.SessionAnalyticsQuery{
+		Interval: model.AnalyticsInterval(c.DefaultQuery("interval", string(model.IntervalDay))),
+		Location: time.UTC,
+	}
+	if err := bindSessionFilter(c, &query.Filter); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if tz := c.Query("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			c.Error(model.ErrInvalidQuery.WithMessage(fmt.Sprintf("unknown time zone %q", tz)))
+			return
+		}
+		query.Location = loc
+	}
+
+	query.Metadata = c.QueryMap("metadata")
+	if len(query.Metadata) > maxMetadataFilters {
+		c.Error(model.ErrInvalidQuery.WithMessage(fmt.Sprintf("at most %d metadata filters are allowed", maxMetadataFilters)))
+		return
+	}
+	for key := range query.Metadata {
+		if key == "" {
+			c.Error(model.ErrInvalidQuery.WithMessage("metadata filter keys must not be empty"))
+			return
+		}
+	}
+
+	analytics, err := h.svc.SessionAnalytics(c.Request.Context(), access, query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, analytics)
+}
@@ -0,0 +1,121 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+func TestSessionAnalyticsHandler(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("admin@example.com", model.UserRoleAdmin, "")
+	token := s.login("admin@example.com")
+
+	// Sessions by status, start time, disposition and queue. In New York the first one
+	// started on March 19 and the last three on March 21.
+	day := time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC)
+	sessions := []struct {
+		status      model.SessionStatus
+		startedAt   time.Time
+		disposition string
+		queue       string
+	}{
+		{status: model.SessionStatusCompleted, startedAt: day.Add(30 * time.Minute), disposition: "resolved", queue: "sales"},
+		{status: model.SessionStatusCompleted, startedAt: day.Add(14 * time.Hour), disposition: "resolved", queue: "sales"},
+		{status: model.SessionStatusFailed, startedAt: day.Add(15 * time.Hour), disposition: "error", queue: "support"},
+		{status: model.SessionStatusBusy, startedAt: day.Add(16 * time.Hour), disposition: "busy", queue: "sales"},
+		{status: model.SessionStatusNoAnswer, startedAt: day.Add(49 * time.Hour), disposition: "no_answer", queue: "sales"},
+		{status: model.SessionStatusTransferred, startedAt: day.Add(50 * time.Hour), disposition: "transferred", queue: "sales"},
+		{status: model.SessionStatusAnswered, startedAt: day.Add(51 * time.Hour), queue: "sales"},
+	}
+	for _, tt := range sessions {
+		session := &model.Session{ID: uuid.New(), OrgID: model.DefaultOrganizationID, StartedAt: tt.startedAt, CallerID: "+14155550100", CalleeID: "+14155550199", Status: tt.status, InitialMetadata: model.SessionMetadata{"queue": tt.queue}, CreatedAt: tt.startedAt, UpdatedAt: tt.startedAt}
+		if tt.status.IsTerminal() {
+			endedAt, disposition := tt.startedAt.Add(time.Minute), tt.disposition
+			session.EndedAt, session.Disposition = &endedAt, &disposition
+		}
+		if err := s.store.CreateSession(context.Background(), session); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		total         int64
+		completed     int64
+		failed        int64
+		bucketStarts  []string
+		bucketTotals  []int64
+		dispositions  map[string]int64
+		durationCount int64
+	}{
+		{
+			name:  "by day",
+			query: "interval=day", total: 7, completed: 2, failed: 3,
+			bucketStarts:  []string{"2024-03-20T00:00:00Z", "2024-03-21T00:00:00Z", "2024-03-22T00:00:00Z"},
+			bucketTotals:  []int64{4, 0, 3},
+			dispositions:  map[string]int64{"resolved": 2, "error": 1, "busy": 1, "no_answer": 1, "transferred": 1},
+			durationCount: 6,
+		},
+		{
+			name:  "in a time zone",
+			query: "interval=day&tz=America/New_York", total: 7, completed: 2, failed: 3,
+			bucketStarts:  []string{"2024-03-19T00:00:00-04:00", "2024-03-20T00:00:00-04:00", "2024-03-21T00:00:00-04:00"},
+			bucketTotals:  []int64{1, 3, 3},
+			durationCount: 6,
+		},
+		{
+			name:  "by metadata",
+			query: "interval=week&metadata[queue]=support", total: 1, failed: 1,
+			bucketStarts:  []string{"2024-03-18T00:00:00Z"},
+			bucketTotals:  []int64{1},
+			dispositions:  map[string]int64{"error": 1},
+			durationCount: 1,
+		},
+		{
+			name:  "within dates",
+			query: "interval=day&start_date=2024-03-19T00:00:00Z&end_date=2024-03-20T23:59:59Z&status=completed", total: 2, completed: 2,
+			bucketStarts:  []string{"2024-03-19T00:00:00Z", "2024-03-20T00:00:00Z"},
+			bucketTotals:  []int64{0, 2},
+			durationCount: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out model.SessionAnalytics
+			expect(t, http.StatusOK, s.do(token, http.MethodGet, "/api/analytics/sessions?"+tt.query, nil), &out)
+
+			totals := out.Totals
+			if totals.Total != tt.total || totals.Completed != tt.completed || totals.Failed != tt.failed || totals.Duration.Count != tt.durationCount {
+				t.Errorf("totals %+v, want %d sessions, %d completed, %d failed, %d durations", totals, tt.total, tt.completed, tt.failed, tt.durationCount)
+			}
+			if ended := tt.completed + tt.failed; ended > 0 && (totals.SuccessRate == nil || *totals.SuccessRate != float64(tt.completed)/float64(ended)) {
+				t.Errorf("success rate %v, want %d of %d", totals.SuccessRate, tt.completed, ended)
+			}
+			for disposition, count := range tt.dispositions {
+				if totals.Dispositions[disposition] != count {
+					t.Errorf("%d sessions with disposition %s, want %d", totals.Dispositions[disposition], disposition, count)
+				}
+			}
+
+			if len(out.Buckets) != len(tt.bucketStarts) {
+				t.Fatalf("%d buckets, want %v", len(out.Buckets), tt.bucketStarts)
+			}
+			for i, bucket := range out.Buckets {
+				if got := bucket.Start.Format(time.RFC3339); got != tt.bucketStarts[i] || bucket.Total != tt.bucketTotals[i] {
+					t.Errorf("bucket %d starts %s with %d sessions, want %s with %d", i, got, bucket.Total, tt.bucketStarts[i], tt.bucketTotals[i])
+				}
+			}
+		})
+	}
+
+	for _, query := range []string{"interval=month", "tz=Mars/Olympus_Mons", "interval=minute&start_date=2024-01-01T00:00:00Z&end_date=2024-03-01T00:00:00Z", "filter=password:eq:secret"} {
+		expectError(t, http.StatusBadRequest, model.ErrInvalidQuery.Code, s.do(token, http.MethodGet, "/api/analytics/sessions?"+query, nil))
+	}
+}
@@ -7,20 +7,16 @@ import (
 	"github.com/vasu74/Call_Session_Management/internal/model"
 )
 
-func RegisterHandler(c *gin.Context) {
+func (h *Handler) RegisterHandler(c *gin.Context) {
 	var req model.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(invalidRequest(err))
 		return
 	}
 
-	var user model.User
-	if err := user.Register(req); err != nil {
-		if err.Error() == "user already exists" {
-			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	user, err := h.svc.Register(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -30,37 +26,76 @@ func RegisterHandler(c *gin.Context) {
 			"id":         user.ID,
 			"email":      user.Email,
 			"role":       user.Role,
+			"org_id":     user.OrgID,
 			"created_at": user.CreatedAt,
 		},
 	})
 }
 
-func LoginHandler(c *gin.Context) {
+func (h *Handler) LoginHandler(c *gin.Context) {
 	var req model.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(invalidRequest(err))
 		return
 	}
 
-	var user model.User
-	response, err := user.Login(req)
+	response, err := h.svc.Login(c.Request.Context(), req)
 	if err != nil {
-		if err.Error() == "invalid credentials" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-func GetProfileHandler(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	user, err := model.GetUserByID(userID.(string))
+func (h *Handler) RefreshHandler(c *gin.Context) {
+	var req model.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	response, err := h.svc.RefreshTokens(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) LogoutHandler(c *gin.Context) {
+	value, _ := c.Get("claims")
+	claims, ok := value.(*model.JWTClaims)
+	if !ok {
+		c.Error(model.ErrInvalidRequest.WithMessage("logout requires an access token"))
+		return
+	}
+	if err := h.svc.Logout(c.Request.Context(), claims); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) LogoutAllHandler(c *gin.Context) {
+	if _, exists := c.Get("claims"); !exists {
+		c.Error(model.ErrInvalidRequest.WithMessage("logout requires an access token"))
+		return
+	}
+	if err := h.svc.LogoutAll(c.Request.Context(), c.GetString("userID")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) GetProfileHandler(c *gin.Context) {
+	user, err := h.svc.GetUser(c.Request.Context(), c.GetString("userID"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -0,0 +1,159 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+)
+
+// loginTokens logs a user in and returns the access and refresh tokens
+func (s *testServer) loginTokens(email string, scopes ...model.Permission) model.LoginResponse {
+	s.t.Helper()
+	body := gin.H{"email": email, "password": testPassword}
+	if scopes != nil {
+		body["scopes"] = scopes
+	}
+	var out model.LoginResponse
+	expect(s.t, http.StatusOK, s.do("", http.MethodPost, "/auth/login", body), &out)
+	return out
+}
+
+func (s *testServer) refresh(refreshToken string) *model.LoginResponse {
+	s.t.Helper()
+	var out model.LoginResponse
+	expect(s.t, http.StatusOK, s.do("", http.MethodPost, "/auth/refresh", gin.H{"refresh_token": refreshToken}), &out)
+	return &out
+}
+
+func TestRefreshTokens(t *testing.T) {
+	s := newTestServer(t, service.WithTokenTTLs(time.Minute, time.Hour))
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	login := s.loginTokens("agent@example.com")
+	if login.RefreshToken == "" || !login.ExpiresAt.Before(login.RefreshTokenExpiresAt) || time.Until(login.ExpiresAt) > time.Minute {
+		t.Fatalf("login response %+v, want a short-lived access token and a refresh token", login)
+	}
+
+	next := s.refresh(login.RefreshToken)
+	if next.RefreshToken == login.RefreshToken || next.Token == login.Token {
+		t.Fatal("refresh did not rotate the tokens")
+	}
+	expect(t, http.StatusOK, s.do(next.Token, http.MethodGet, "/api/profile", nil), nil)
+	expect(t, http.StatusOK, s.do(login.Token, http.MethodGet, "/api/profile", nil), nil)
+
+	// Presenting the used token again revokes the whole family
+	expectError(t, http.StatusUnauthorized, model.ErrRefreshTokenReused.Code, s.do("", http.MethodPost, "/auth/refresh", gin.H{"refresh_token": login.RefreshToken}))
+	expectError(t, http.StatusUnauthorized, model.ErrInvalidRefreshToken.Code, s.do("", http.MethodPost, "/auth/refresh", gin.H{"refresh_token": next.RefreshToken}))
+	for _, token := range []string{login.Token, next.Token} {
+		expectError(t, http.StatusUnauthorized, model.ErrUnauthorized.Code, s.do(token, http.MethodGet, "/api/profile", nil))
+	}
+
+	expectError(t, http.StatusUnauthorized, model.ErrInvalidRefreshToken.Code, s.do("", http.MethodPost, "/auth/refresh", gin.H{"refresh_token": "rt_unknown"}))
+	expectError(t, http.StatusBadRequest, model.ErrInvalidRequest.Code, s.do("", http.MethodPost, "/auth/refresh", gin.H{}))
+}
+
+func TestRefreshTokensReadsCurrentRole(t *testing.T) {
+	s := newTestServer(t)
+	user := s.createUser("lead@example.com", model.UserRoleAdmin, "")
+	scoped := s.loginTokens("lead@example.com", model.PermissionSessionsRead, model.PermissionUsersManage)
+	full := s.loginTokens("lead@example.com")
+	if !slices.Contains(full.Permissions, model.PermissionUsersManage) {
+		t.Fatalf("admin permissions %v", full.Permissions)
+	}
+
+	user.Role = model.UserRoleUser
+	if err := s.store.UpdateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	// The demoted user keeps the permissions of the user role, within the scopes of the login
+	if got := s.refresh(full.RefreshToken).Permissions; slices.Contains(got, model.PermissionUsersManage) || !slices.Contains(got, model.PermissionSessionsWrite) {
+		t.Errorf("permissions after demotion %v", got)
+	}
+	if got := s.refresh(scoped.RefreshToken); len(got.Permissions) != 1 || got.Permissions[0] != model.PermissionSessionsRead {
+		t.Errorf("scoped permissions after demotion %v, want only sessions:read", got.Permissions)
+	} else {
+		expectError(t, http.StatusForbidden, model.ErrForbidden.Code, s.do(got.Token, http.MethodPost, "/api/sessions/start", gin.H{"caller_id": "+14155550100", "callee_id": "+14155550199"}))
+	}
+}
+
+func TestLogout(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	laptop := s.loginTokens("agent@example.com")
+	phone := s.loginTokens("agent@example.com")
+	tablet := s.loginTokens("agent@example.com")
+
+	expect(t, http.StatusNoContent, s.do(laptop.Token, http.MethodPost, "/auth/logout", nil), nil)
+	expectError(t, http.StatusUnauthorized, model.ErrUnauthorized.Code, s.do(laptop.Token, http.MethodGet, "/api/profile", nil))
+	expectError(t, http.StatusUnauthorized, model.ErrInvalidRefreshToken.Code, s.do("", http.MethodPost, "/auth/refresh", gin.H{"refresh_token": laptop.RefreshToken}))
+	// Other logins are unaffected
+	expect(t, http.StatusOK, s.do(phone.Token, http.MethodGet, "/api/profile", nil), nil)
+	phone = *s.refresh(phone.RefreshToken)
+
+	expect(t, http.StatusNoContent, s.do(phone.Token, http.MethodPost, "/auth/logout-all", nil), nil)
+	for _, login := range []model.LoginResponse{phone, tablet} {
+		expectError(t, http.StatusUnauthorized, model.ErrUnauthorized.Code, s.do(login.Token, http.MethodGet, "/api/profile", nil))
+		expectError(t, http.StatusUnauthorized, model.ErrInvalidRefreshToken.Code, s.do("", http.MethodPost, "/auth/refresh", gin.H{"refresh_token": login.RefreshToken}))
+	}
+
+	expectError(t, http.StatusUnauthorized, model.ErrUnauthorized.Code, s.do("", http.MethodPost, "/auth/logout", nil))
+}
+
+func TestAccessTokenChecks(t *testing.T) {
+	s := newTestServer(t)
+	user := s.createUser("agent@example.com", model.UserRoleUser, "")
+	sign := func(claims *model.JWTClaims, method jwt.SigningMethod, key interface{}) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	claims := func(id string, permissions model.Permissions, expiresAt time.Time) *model.JWTClaims {
+		return &model.JWTClaims{
+			UserID:           user.ID.String(),
+			Email:            user.Email,
+			Role:             user.Role,
+			Permissions:      permissions,
+			RegisteredClaims: jwt.RegisteredClaims{ID: id, ExpiresAt: jwt.NewNumericDate(expiresAt)},
+		}
+	}
+	later := time.Now().Add(time.Hour)
+	read := model.Permissions{model.PermissionSessionsRead}
+	unknown := *claims(uuid.NewString(), read, later)
+	unknown.UserID = uuid.NewString()
+
+	tests := []struct {
+		name   string
+		header string
+		code   string
+	}{
+		{name: "no header", code: model.ErrUnauthorized.Code},
+		{name: "not a bearer token", header: "Token abc", code: model.ErrUnauthorized.Code},
+		{name: "malformed token", header: "Bearer abc", code: model.ErrUnauthorized.Code},
+		{name: "wrong key", header: "Bearer " + sign(claims(uuid.NewString(), read, later), jwt.SigningMethodHS256, []byte("other-secret")), code: model.ErrUnauthorized.Code},
+		{name: "unsigned", header: "Bearer " + sign(claims(uuid.NewString(), read, later), jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType), code: model.ErrUnauthorized.Code},
+		{name: "expired", header: "Bearer " + sign(claims(uuid.NewString(), read, time.Now().Add(-time.Minute)), jwt.SigningMethodHS256, []byte("jwt-secret")), code: model.ErrUnauthorized.Code},
+		{name: "without an ID", header: "Bearer " + sign(claims("", read, later), jwt.SigningMethodHS256, []byte("jwt-secret")), code: model.ErrUnauthorized.Code},
+		{name: "without permissions", header: "Bearer " + sign(claims(uuid.NewString(), nil, later), jwt.SigningMethodHS256, []byte("jwt-secret")), code: model.ErrUnauthorized.Code},
+		{name: "unknown user", header: "Bearer " + sign(&unknown, jwt.SigningMethodHS256, []byte("jwt-secret")), code: model.ErrUnauthorized.Code},
+		{name: "valid", header: "Bearer " + sign(claims(uuid.NewString(), read, later), jwt.SigningMethodHS256, []byte("jwt-secret"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do("", http.MethodGet, "/api/sessions", nil, "Authorization", tt.header)
+			if tt.code == "" {
+				expect(t, http.StatusOK, w, nil)
+				return
+			}
+			expectError(t, http.StatusUnauthorized, tt.code, w)
+		})
+	}
+}
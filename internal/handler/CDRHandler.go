@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"bufio"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vasu74/Call_Session_Management/internal/middleware"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+func (h *Handler) GetCDRsHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	req, err := model.ParseCDRRequest(c.Request.URL.Query())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// A long date range outlives the server's write timeout
+	if err := clearDeadlines(c, false); err != nil {
+		c.Error(err)
+		return
+	}
+
+	out := &exportResponse{c: c, contentType: req.Format.ContentType(), fileName: req.FileName(time.Now())}
+	w := bufio.NewWriterSize(out, 64<<10)
+	rows, err := h.svc.ExportCDRs(c.Request.Context(), access, w, req)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		if !out.started {
+			c.Error(err)
+			return
+		}
+		if c.Request.Context().Err() == nil {
+			log.Printf("request %s: CDR download failed after %d records: %v", c.GetString("requestID"), rows, err)
+		}
+		return
+	}
+
+	out.start()
+}
@@ -0,0 +1,76 @@
+package handler_test
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+func TestGetCDRsHandler(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	s.createUser("other@example.com", model.UserRoleUser, "")
+	s.createUser("admin@example.com", model.UserRoleAdmin, "")
+	token := s.login("agent@example.com")
+	otherToken := s.login("other@example.com")
+
+	ended := time.Now().Add(time.Second).UTC().Truncate(time.Second)
+	end := func(token, callerID, status string) string {
+		session := s.startSession(token, gin.H{"caller_id": callerID, "callee_id": "+14155550199"})
+		expect(t, http.StatusOK, s.do(token, http.MethodPost, "/api/sessions/"+session.ID.String()+"/end", gin.H{"status": status, "disposition": "done", "end_time": ended}), nil)
+		return session.ID.String()
+	}
+	completed := end(token, "+14155550100", "completed")
+	missed := end(token, "+14155550101", "missed")
+	other := end(otherToken, "+14155550102", "completed")
+	// Active sessions have no record
+	s.startSession(token, gin.H{"caller_id": "+14155550103", "callee_id": "+14155550199"})
+
+	tests := []struct {
+		name  string
+		token string
+		query url.Values
+		want  []string
+	}{
+		{name: "own records", token: token, want: []string{completed, missed}},
+		{name: "all records", token: s.login("admin@example.com"), want: []string{completed, missed, other}},
+		{name: "ended in range", token: token, query: url.Values{"start_date": {ended.Add(-time.Minute).Format(time.RFC3339)}, "end_date": {ended.Add(time.Minute).Format(time.RFC3339)}}, want: []string{completed, missed}},
+		{name: "ended before range", token: token, query: url.Values{"start_date": {ended.Add(time.Minute).Format(time.RFC3339)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(tt.token, http.MethodGet, "/api/cdr?"+tt.query.Encode(), nil)
+			if w.Code != http.StatusOK || w.Header().Get("Content-Type") != model.ExportCSV.ContentType() {
+				t.Fatalf("CDR download %d with headers %v", w.Code, w.Header())
+			}
+			records, err := csv.NewReader(w.Body).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) == 0 || records[0][0] != "session_id" || records[0][7] != "billsec" {
+				t.Fatalf("CDR header %v", records)
+			}
+			got := make(map[string]bool)
+			for _, record := range records[1:] {
+				got[record[0]] = true
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("records %v, want sessions %v", records[1:], tt.want)
+			}
+			for _, id := range tt.want {
+				if !got[id] {
+					t.Errorf("no record for session %s", id)
+				}
+			}
+		})
+	}
+
+	expectError(t, http.StatusBadRequest, model.ErrInvalidQuery.Code, s.do(token, http.MethodGet, "/api/cdr?format=xml", nil))
+	expectError(t, http.StatusBadRequest, model.ErrInvalidQuery.Code, s.do(token, http.MethodGet, "/api/cdr?start_date=yesterday", nil))
+	expectError(t, http.StatusBadRequest, model.ErrInvalidQuery.Code, s.do(token, http.MethodGet, "/api/cdr?start_date=2024-03-20T10:00:00Z&end_date=2024-03-20T10:00:00Z", nil))
+}
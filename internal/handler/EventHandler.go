@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vasu74/Call_Session_Management/internal/middleware"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+func (h *Handler) LogSessionEventBatchHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	sessionID, err := h.sessionID(c, access)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req model.BatchLogEventsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	items := make([]model.BatchEventItem, len(req.Events))
+	for i, event := range req.Events {
+		items[i] = model.BatchEventItem{SessionID: sessionID, LogEventRequest: event}
+	}
+
+	h.logEventBatch(c, access, items)
+}
+
+func (h *Handler) LogEventBatchHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req model.CrossSessionBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	h.logEventBatch(c, access, req.Events)
+}
+
+// logEventBatch responds with 200 when every event was stored and 207 when some failed
+func (h *Handler) logEventBatch(c *gin.Context, access model.SessionAccess, items []model.BatchEventItem) {
+	response, err := h.svc.LogEventBatch(c.Request.Context(), access, items)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	status := http.StatusOK
+	if response.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, response)
+}
@@ -0,0 +1,82 @@
+package handler_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+)
+
+func TestLogEventBatch(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	s.createUser("other@example.com", model.UserRoleUser, "")
+	token := s.login("agent@example.com")
+	active := s.startSession(token, gin.H{"caller_id": "+14155550100", "callee_id": "+14155550199"}).ID.String()
+	ended := s.startSession(token, gin.H{"caller_id": "+14155550101", "callee_id": "+14155550199"}).ID.String()
+	expect(t, http.StatusOK, s.do(token, http.MethodPost, "/api/sessions/"+ended+"/end", gin.H{"status": "completed", "disposition": "Answered", "end_time": time.Now()}), nil)
+	hidden := s.startSession(s.login("other@example.com"), gin.H{"caller_id": "+14155550102", "callee_id": "+14155550199"}).ID.String()
+
+	now := time.Now()
+	event := func(sessionID, eventType string, at time.Time) gin.H {
+		return gin.H{"session_id": sessionID, "event_type": eventType, "event_time": at}
+	}
+	var out model.BatchLogEventsResponse
+	expect(t, http.StatusMultiStatus, s.do(token, http.MethodPost, "/api/events:batch", gin.H{"events": []gin.H{
+		event(active, "hold", now),
+		event(ended, "hold", now),
+		event(hidden, "hold", now),
+		event("not-a-uuid", "hold", now),
+		event(active, "", now),
+		event(active, "resume", now.Add(-2*model.MaxEventAge)),
+		event(active, "resume", now.Add(time.Second)),
+	}}), &out)
+
+	want := []string{"", model.ErrSessionAlreadyEnded.Code, model.ErrSessionNotFound.Code, model.ErrInvalidID.Code, model.ErrInvalidRequest.Code, model.ErrEventTimeOutOfRange.Code, ""}
+	if out.Succeeded != 2 || out.Failed != 5 || len(out.Results) != len(want) {
+		t.Fatalf("batch response %+v", out)
+	}
+	for i, code := range want {
+		result := out.Results[i]
+		if result.Index != i {
+			t.Errorf("result %d has index %d", i, result.Index)
+		}
+		if code == "" {
+			if result.Status != model.BatchItemCreated || result.Event == nil {
+				t.Errorf("result %d = %+v, want created", i, result)
+			}
+			continue
+		}
+		if result.Status != model.BatchItemFailed || result.Error == nil || result.Error.Code != code {
+			t.Errorf("result %d = %+v, want failed with %s", i, result, code)
+		}
+	}
+
+	var details model.SessionDetails
+	expect(t, http.StatusOK, s.do(token, http.MethodGet, "/api/sessions/"+active, nil), &details)
+	if len(details.Events) != 2 {
+		t.Errorf("session has %d events, want the 2 accepted ones", len(details.Events))
+	}
+}
+
+func TestLogSessionEventBatch(t *testing.T) {
+	s := newTestServer(t, service.WithEventBatchLimit(2))
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	token := s.login("agent@example.com")
+	session := s.startSession(token, gin.H{"caller_id": "+14155550100", "callee_id": "+14155550199"})
+	path := "/api/sessions/" + session.ID.String() + "/events:batch"
+	event := gin.H{"event_type": "dtmf", "event_time": time.Now()}
+
+	var out model.BatchLogEventsResponse
+	expect(t, http.StatusOK, s.do(token, http.MethodPost, path, gin.H{"events": []gin.H{event, event}}), &out)
+	if out.Succeeded != 2 || out.Failed != 0 {
+		t.Fatalf("batch response %+v, want both events created", out)
+	}
+
+	expectError(t, http.StatusBadRequest, model.ErrInvalidRequest.Code, s.do(token, http.MethodPost, path, gin.H{"events": []gin.H{event, event, event}}))
+	expectError(t, http.StatusBadRequest, model.ErrInvalidRequest.Code, s.do(token, http.MethodPost, path, gin.H{"events": []gin.H{}}))
+	expectError(t, http.StatusNotFound, model.ErrNotFound.Code, s.do(token, http.MethodPost, "/api/sessions/"+session.ID.String()+"/events:purge", gin.H{}))
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"bufio"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vasu74/Call_Session_Management/internal/middleware"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+func (h *Handler) ExportSessionsHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	req, err := model.ParseExportRequest(c.Request.URL.Query())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Large exports outlive the server's write timeout
+	if err := clearDeadlines(c, false); err != nil {
+		c.Error(err)
+		return
+	}
+
+	// The response only starts once the first buffer is flushed, so errors that occur
+	// before that are still reported as a JSON error
+	out := &exportResponse{c: c, contentType: req.Format.ContentType(), fileName: req.FileName(time.Now())}
+	w := bufio.NewWriterSize(out, 64<<10)
+	rows, err := h.svc.Export(c.Request.Context(), access, w, req)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		if !out.started {
+			c.Error(err)
+			return
+		}
+		if c.Request.Context().Err() == nil {
+			log.Printf("request %s: export failed after %d rows: %v", c.GetString("requestID"), rows, err)
+		}
+		return
+	}
+
+	// An NDJSON export without rows writes no bytes, so send the headers regardless
+	out.start()
+}
+
+// exportResponse writes the headers of an export download before its first bytes
+type exportResponse struct {
+	c           *gin.Context
+	contentType string
+	fileName    string
+	started     bool
+}
+
+func (r *exportResponse) start() {
+	if r.started {
+		return
+	}
+	r.started = true
+	r.c.Header("Content-Type", r.contentType)
+	r.c.Header("Content-Disposition", `attachment; filename="`+r.fileName+`"`)
+	r.c.Status(http.StatusOK)
+	r.c.Writer.WriteHeaderNow()
+}
+
+func (r *exportResponse) Write(p []byte) (int, error) {
+	r.start()
+	return r.c.Writer.Write(p)
+}
+
+func (h *Handler) CreateExportJobHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	job, err := h.svc.CreateExportJob(c.Request.Context(), access, c.Request.URL.Query())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Location", "/api/exports/"+job.ID.String())
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Export job queued",
+		"job":     job,
+	})
+}
+
+func (h *Handler) GetExportJobHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	job, err := h.svc.GetExportJob(c.Request.Context(), access, c.Param("jobId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if job.Status == model.ExportJobSucceeded {
+		job.DownloadURL = "/api/exports/" + job.ID.String() + "/download"
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+func (h *Handler) DownloadExportJobHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	job, path, err := h.svc.ExportJobFile(c.Request.Context(), access, c.Param("jobId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := clearDeadlines(c, false); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Header("Content-Type", job.Format.ContentType())
+	c.FileAttachment(path, string(job.Dataset)+"-"+job.CreatedAt.UTC().Format("20060102T150405Z")+"."+string(job.Format))
+}
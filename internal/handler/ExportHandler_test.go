@@ -0,0 +1,161 @@
+package handler_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+)
+
+func TestExportSessionsHandler(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	s.createUser("other@example.com", model.UserRoleUser, "")
+	token := s.login("agent@example.com")
+	sales := s.startSession(token, gin.H{"caller_id": "+14155550100", "callee_id": "+14155550199", "initial_metadata": gin.H{"queue": "sales"}})
+	s.startSession(token, gin.H{"caller_id": "+14155550101", "callee_id": "+14155550199"})
+	s.startSession(s.login("other@example.com"), gin.H{"caller_id": "+14155550102", "callee_id": "+14155550199"})
+	expect(t, http.StatusCreated, s.do(token, http.MethodPost, "/api/sessions/"+sales.ID.String()+"/events", gin.H{"event_type": "dtmf", "event_time": time.Now()}), nil)
+
+	w := s.do(token, http.MethodGet, "/api/sessions/export?metadata_columns=queue", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != model.ExportCSV.ContentType() || !strings.HasPrefix(w.Header().Get("Content-Disposition"), "attachment") {
+		t.Fatalf("CSV export %d with headers %v", w.Code, w.Header())
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The header and the two sessions the agent started, oldest first
+	if len(records) != 3 || records[0][len(records[0])-1] != "initial_metadata.queue" {
+		t.Fatalf("CSV export %v", records)
+	}
+	if records[1][0] != sales.ID.String() || records[1][len(records[1])-1] != "sales" || records[2][len(records[2])-1] != "" {
+		t.Errorf("CSV rows %v", records[1:])
+	}
+
+	w = s.do(token, http.MethodGet, "/api/sessions/export?format=ndjson&dataset=events&caller_id=%2B14155550100", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("NDJSON export %d: %s", w.Code, w.Body)
+	}
+	var types []string
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var event struct {
+			SessionID string `json:"session_id"`
+			EventType string `json:"event_type"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.SessionID != sales.ID.String() {
+			t.Errorf("exported an event of session %s", event.SessionID)
+		}
+		types = append(types, event.EventType)
+	}
+	if len(types) != 1 || types[0] != "dtmf" {
+		t.Errorf("exported events %v, want the logged dtmf event", types)
+	}
+
+	for _, query := range []string{"format=xml", "dataset=users", "dataset=events&metadata_columns=queue", "sort=password"} {
+		expectError(t, http.StatusBadRequest, model.ErrInvalidQuery.Code, s.do(token, http.MethodGet, "/api/sessions/export?"+query, nil))
+	}
+}
+
+func TestExportJobHandlers(t *testing.T) {
+	s := newTestServer(t, service.WithExportJobs(t.TempDir(), time.Hour))
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	s.createUser("other@example.com", model.UserRoleUser, "")
+	token := s.login("agent@example.com")
+	session := s.startSession(token, gin.H{"caller_id": "+14155550100", "callee_id": "+14155550199"})
+
+	var created struct {
+		Job model.ExportJob `json:"job"`
+	}
+	w := s.do(token, http.MethodPost, "/api/sessions/export?format=ndjson", nil)
+	expect(t, http.StatusAccepted, w, &created)
+	path := "/api/exports/" + created.Job.ID.String()
+	if created.Job.Status != model.ExportJobPending || created.Job.Format != model.ExportNDJSON || w.Header().Get("Location") != path {
+		t.Fatalf("created job %+v at %s", created.Job, w.Header().Get("Location"))
+	}
+
+	var job model.ExportJob
+	expect(t, http.StatusOK, s.do(token, http.MethodGet, path, nil), &job)
+	if job.DownloadURL != "" {
+		t.Errorf("pending job has download URL %s", job.DownloadURL)
+	}
+	expectError(t, http.StatusConflict, model.ErrExportNotReady.Code, s.do(token, http.MethodGet, path+"/download", nil))
+	expectError(t, http.StatusNotFound, model.ErrExportJobNotFound.Code, s.do(s.login("other@example.com"), http.MethodGet, path, nil))
+	expectError(t, http.StatusBadRequest, model.ErrInvalidQuery.Code, s.do(token, http.MethodPost, "/api/sessions/export?format=xml", nil))
+
+	// Run the job as the exporter does
+	ctx := context.Background()
+	jobs, err := s.svc.ClaimExportJobs(ctx, 1, time.Minute)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("claimed %d jobs, %v", len(jobs), err)
+	}
+	if err := s.svc.RunExportJob(ctx, &jobs[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	expect(t, http.StatusOK, s.do(token, http.MethodGet, path, nil), &job)
+	if job.Status != model.ExportJobSucceeded || job.DownloadURL != path+"/download" {
+		t.Fatalf("finished job %+v", job)
+	}
+	w = s.do(token, http.MethodGet, job.DownloadURL, nil)
+	var row struct {
+		ID string `json:"id"`
+	}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &row) != nil || row.ID != session.ID.String() {
+		t.Errorf("download %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestExportJobKeepsOrganizationAccess(t *testing.T) {
+	s := newTestServer(t, service.WithExportJobs(t.TempDir(), time.Hour), service.WithOrganizationSignup(true))
+	s.createUser("admin@example.com", model.UserRoleAdmin, "")
+	expect(t, http.StatusCreated, s.do("", http.MethodPost, "/auth/register", gin.H{"email": "owner@acme.example", "password": testPassword, "organization": "acme"}), nil)
+	admin := s.login("admin@example.com")
+	s.startSession(admin, gin.H{"caller_id": "+14155550100", "callee_id": "+14155550199"})
+	s.startSession(s.login("owner@acme.example"), gin.H{"caller_id": "+14155550101", "callee_id": "+14155550199"})
+
+	// A token scoped without orgs:manage queues a job limited to the admin's organization
+	scoped := s.loginTokens("admin@example.com", model.PermissionSessionsRead, model.PermissionSessionsExport).Token
+	tests := []struct {
+		name     string
+		token    string
+		wantRows int
+	}{
+		{name: "every organization", token: admin, wantRows: 2},
+		{name: "scoped token", token: scoped, wantRows: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created struct {
+				Job model.ExportJob `json:"job"`
+			}
+			expect(t, http.StatusAccepted, s.do(tt.token, http.MethodPost, "/api/sessions/export?format=ndjson", nil), &created)
+
+			ctx := context.Background()
+			jobs, err := s.svc.ClaimExportJobs(ctx, 1, time.Minute)
+			if err != nil || len(jobs) != 1 {
+				t.Fatalf("claimed %d jobs, %v", len(jobs), err)
+			}
+			if err := s.svc.RunExportJob(ctx, &jobs[0]); err != nil {
+				t.Fatal(err)
+			}
+			var job model.ExportJob
+			expect(t, http.StatusOK, s.do(tt.token, http.MethodGet, "/api/exports/"+created.Job.ID.String(), nil), &job)
+			if job.RowCount == nil || *job.RowCount != int64(tt.wantRows) {
+				t.Errorf("exported %v rows, want %d", job.RowCount, tt.wantRows)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+	"github.com/vasu74/Call_Session_Management/internal/stream"
+)
+
+// Handler serves the HTTP API on top of the service layer
+type Handler struct {
+	svc         *service.Service
+	hub         *stream.Hub
+	upgrader    websocket.Upgrader
+	wsAuthGrace time.Duration
+}
+
+// Option configures optional Handler behaviour
+type Option func(*Handler)
+
+// WithWebSocketOrigins sets the browser origins, such as "https://app.example.com",
+// that may open the WebSocket in addition to the API's own origin; "*" allows any
+func WithWebSocketOrigins(origins ...string) Option {
+	return func(h *Handler) {
+		h.upgrader.CheckOrigin = originChecker(origins)
+	}
+}
+
+// WithWebSocketAuthGrace sets how long a WebSocket whose access token has expired stays
+// open for the client to send a fresh one in an auth message
+func WithWebSocketAuthGrace(grace time.Duration) Option {
+	return func(h *Handler) {
+		h.wsAuthGrace = grace
+	}
+}
+
+// New creates a Handler for the given service, streaming session activity from hub
+func New(svc *service.Service, hub *stream.Hub, opts ...Option) *Handler {
+	h := &Handler{svc: svc, hub: hub, upgrader: newUpgrader(), wsAuthGrace: wsAuthGrace}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// clearDeadlines lifts the server's write timeout, and its read timeout if read is set,
+// for requests that legitimately outlive them
+func clearDeadlines(c *gin.Context, read bool) error {
+	rc := http.NewResponseController(c.Writer)
+	if read {
+		if err := rc.SetReadDeadline(time.Time{}); err != nil {
+			return fmt.Errorf("clear read deadline: %w", err)
+		}
+	}
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		return fmt.Errorf("clear write deadline: %w", err)
+	}
+	return nil
+}
+
+// invalidRequest classifies a request binding failure, keeping the validator's message
+func invalidRequest(err error) error {
+	return model.ErrInvalidRequest.WithMessage(err.Error())
+}
+
+// sessionID returns the ID of the session addressed by the request path, either
+// directly through sessionId or through the source and externalId of the call
+func (h *Handler) sessionID(c *gin.Context, access model.SessionAccess) (string, error) {
+	if source := c.Param("source"); source != "" {
+		session, err := h.svc.GetSessionByExternalID(c.Request.Context(), access, source, c.Param("externalId"))
+		if err != nil {
+			return "", err
+		}
+		return session.ID.String(), nil
+	}
+
+	sessionID := c.Param("sessionId")
+	if sessionID == "" {
+		return "", model.ErrInvalidRequest.WithMessage("session ID is required")
+	}
+	return sessionID, nil
+}
+
+// CustomMethods serves custom methods such as "events:batch" that gin cannot route
+// directly because of the colon. It is registered on a "/:method" path segment and
+// dispatches on the segment's value.
+func CustomMethods(methods map[string]gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handler, ok := methods[c.Param("method")]
+		if !ok {
+			c.Error(model.ErrNotFound)
+			return
+		}
+		handler(c)
+	}
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vasu74/Call_Session_Management/internal/middleware"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+func (h *Handler) ImportSessionsHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	opts, err := model.ParseImportOptions(c.Request.URL.Query())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Uploads of historical files outlive the server's read and write timeouts
+	if err := clearDeadlines(c, true); err != nil {
+		c.Error(err)
+		return
+	}
+
+	body, err := importBody(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	result, err := h.svc.ImportSessions(c.Request.Context(), access, body, opts)
+	if err != nil {
+		if result != nil {
+			err = &model.ImportError{Result: result, Err: err}
+		}
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// importBody returns the uploaded file: the first file of a multipart form, or the
+// request body itself. The file is streamed rather than buffered.
+func importBody(c *gin.Context) (io.Reader, error) {
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		return c.Request.Body, nil
+	}
+
+	form, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, invalidRequest(err)
+	}
+	for {
+		part, err := form.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, model.ErrInvalidRequest.WithMessage("the form contains no file")
+		}
+		if err != nil {
+			return nil, invalidRequest(err)
+		}
+		if part.FileName() != "" {
+			return part, nil
+		}
+	}
+}
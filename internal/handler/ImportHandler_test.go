@@ -0,0 +1,86 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+const masterCSV = `"","+14155550100","+14155550199","default","","SIP/1001-0001","SIP/2002-0002","Dial","","2024-03-20 10:00:00","2024-03-20 10:00:05","2024-03-20 10:01:05",65,60,"ANSWERED","DOCUMENTATION","u-1"
+"","+14155550101","+14155550199","default","","SIP/1003-0003","","Dial","","2024-03-20 11:00:00","","2024-03-20 11:00:20",20,0,"BUSY","DOCUMENTATION","u-2"
+"","+14155550102","+14155550199","default","","SIP/1004-0004","","Dial","","2024-03-20 12:00:00","","2024-03-20 12:00:03",3,0,"HUNG UP","DOCUMENTATION","u-3"
+`
+
+func TestImportSessionsHandler(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("admin@example.com", model.UserRoleAdmin, "")
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	token := s.login("admin@example.com")
+
+	var result model.ImportResult
+	expect(t, http.StatusOK, s.do(token, http.MethodPost, "/api/admin/import?format=asterisk_csv&source=pbx-1", strings.NewReader(masterCSV), "Content-Type", "text/csv"), &result)
+	if result.Source != "pbx-1" || result.Rows != 3 || result.Imported != 2 || result.Failed != 1 || len(result.Errors) != 1 || result.Errors[0].Row != 3 {
+		t.Fatalf("import result %+v", result)
+	}
+
+	var details model.SessionDetails
+	expect(t, http.StatusOK, s.do(token, http.MethodGet, "/api/sessions/by-external/pbx-1/u-1", nil), &details)
+	if details.Session.Status != model.SessionStatusCompleted || details.Session.EndedAt == nil || len(details.Events) != 2 {
+		t.Errorf("imported session %+v with %d events", details.Session, len(details.Events))
+	}
+
+	// The same file uploaded as a form is recognised as already imported
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	mw.WriteField("note", "re-upload")
+	file, _ := mw.CreateFormFile("file", "Master.csv")
+	file.Write([]byte(masterCSV))
+	mw.Close()
+	expect(t, http.StatusOK, s.do(token, http.MethodPost, "/api/admin/import?format=asterisk_csv&source=pbx-1", &form, "Content-Type", mw.FormDataContentType()), &result)
+	if result.Imported != 0 || result.Duplicates != 2 || result.Failed != 1 {
+		t.Errorf("re-import result %+v, want the two sessions reported as duplicates", result)
+	}
+
+	expectError(t, http.StatusBadRequest, model.ErrInvalidQuery.Code, s.do(token, http.MethodPost, "/api/admin/import?format=cisco", strings.NewReader(masterCSV)))
+	expectError(t, http.StatusBadRequest, model.ErrInvalidQuery.Code, s.do(token, http.MethodPost, "/api/admin/import?format=asterisk_csv&columns=uuid", strings.NewReader(masterCSV)))
+	expectError(t, http.StatusForbidden, model.ErrForbidden.Code, s.do(s.login("agent@example.com"), http.MethodPost, "/api/admin/import?format=asterisk_csv", strings.NewReader(masterCSV)))
+}
+
+func TestImportSessionsHandlerIdempotent(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("admin@example.com", model.UserRoleAdmin, "")
+	token := s.login("admin@example.com")
+
+	// The idempotency recorder must still let the handler lift the connection deadlines
+	var result model.ImportResult
+	expect(t, http.StatusOK, s.do(token, http.MethodPost, "/api/admin/import?format=asterisk_csv", strings.NewReader(masterCSV), "Content-Type", "text/csv", "Idempotency-Key", "import-1"), &result)
+	if result.Rows != 3 || result.Imported != 2 {
+		t.Fatalf("import result %+v", result)
+	}
+}
+
+func TestImportSessionsHandlerReturnsPartialResult(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("admin@example.com", model.UserRoleAdmin, "")
+	token := s.login("admin@example.com")
+
+	body := io.MultiReader(strings.NewReader(masterCSV), iotest.ErrReader(errors.New("connection reset")))
+	out := expectError(t, http.StatusInternalServerError, model.ErrInternal.Code, s.do(token, http.MethodPost, "/api/admin/import?format=asterisk_csv", body, "Content-Type", "text/csv"))
+
+	raw, _ := json.Marshal(out.Error.Details)
+	var result model.ImportResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Rows != 3 || result.Failed != 1 || len(result.Errors) != 1 || result.Errors[0].Row != 3 {
+		t.Errorf("partial result %+v, want the rows read before the failure", result)
+	}
+}
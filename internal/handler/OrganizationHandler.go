@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vasu74/Call_Session_Management/internal/middleware"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+func (h *Handler) CreateOrganizationHandler(c *gin.Context) {
+	var req model.CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	org, err := h.svc.CreateOrganization(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Organization created successfully",
+		"organization": org,
+	})
+}
+
+func (h *Handler) ListOrganizationsHandler(c *gin.Context) {
+	orgs, err := h.svc.ListOrganizations(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"organizations": orgs})
+}
+
+func (h *Handler) GetOrganizationHandler(c *gin.Context) {
+	org, err := h.svc.GetOrganization(c.Request.Context(), c.Param("orgId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, org)
+}
+
+func (h *Handler) GetOwnOrganizationHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	org, err := h.svc.GetOrganization(c.Request.Context(), access.HomeOrgID.String())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, org)
+}
+
+func (h *Handler) UpdateOrganizationHandler(c *gin.Context) {
+	var req model.UpdateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	org, err := h.svc.UpdateOrganization(c.Request.Context(), c.Param("orgId"), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Organization updated successfully",
+		"organization": org,
+	})
+}
+
+func (h *Handler) UpdateOwnOrganizationHandler(c *gin.Context) {
+	var req model.UpdateOrganizationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	org, err := h.svc.UpdateOwnOrganization(c.Request.Context(), access, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Organization updated successfully",
+		"organization": org,
+	})
+}
@@ -0,0 +1,215 @@
+package handler_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+)
+
+// registered is the user returned by /auth/register
+type registered struct {
+	User struct {
+		ID    uuid.UUID      `json:"id"`
+		Role  model.UserRole `json:"role"`
+		OrgID uuid.UUID      `json:"org_id"`
+	} `json:"user"`
+}
+
+func TestRegister(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+
+	// Registrants join the default organization as users
+	var out registered
+	expect(t, http.StatusCreated, s.do("", http.MethodPost, "/auth/register", gin.H{"email": "new@example.com", "password": testPassword}), &out)
+	if out.User.Role != model.UserRoleUser || out.User.OrgID != model.DefaultOrganizationID {
+		t.Fatalf("registered %+v, want a user of the default organization", out.User)
+	}
+
+	expectError(t, http.StatusForbidden, model.ErrForbidden.Code, s.do("", http.MethodPost, "/auth/register", gin.H{"email": "owner@acme.example", "password": testPassword, "organization": "acme"}))
+	expectError(t, http.StatusConflict, model.ErrUserAlreadyExists.Code, s.do("", http.MethodPost, "/auth/register", gin.H{"email": "agent@example.com", "password": testPassword}))
+}
+
+func TestRegisterOrganization(t *testing.T) {
+	s := newTestServer(t, service.WithOrganizationSignup(true))
+	s.createUser("admin@example.com", model.UserRoleAdmin, "")
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	admin := s.login("admin@example.com")
+
+	// Registrants naming an organization administer a new one of their own
+	var out registered
+	expect(t, http.StatusCreated, s.do("", http.MethodPost, "/auth/register", gin.H{"email": "owner@acme.example", "password": testPassword, "organization": "acme"}), &out)
+	if out.User.Role != model.UserRoleOrgAdmin || out.User.OrgID == uuid.Nil || out.User.OrgID == model.DefaultOrganizationID {
+		t.Fatalf("registered %+v", out.User)
+	}
+	var org model.Organization
+	expect(t, http.StatusOK, s.do(s.login("owner@acme.example"), http.MethodGet, "/api/organization", nil), &org)
+	if org.ID != out.User.OrgID || org.Name != "acme" {
+		t.Errorf("own organization %+v", org)
+	}
+
+	// Names are not unique, so registering one reveals nothing about existing ones
+	var other registered
+	expect(t, http.StatusCreated, s.do("", http.MethodPost, "/auth/register", gin.H{"email": "owner@other.example", "password": testPassword, "organization": "acme"}), &other)
+	if other.User.OrgID == out.User.OrgID {
+		t.Error("second registrant joined the first one's organization")
+	}
+
+	// Without an organization registrants still join the default one
+	var solo registered
+	expect(t, http.StatusCreated, s.do("", http.MethodPost, "/auth/register", gin.H{"email": "solo@example.com", "password": testPassword}), &solo)
+	if solo.User.Role != model.UserRoleUser || solo.User.OrgID != model.DefaultOrganizationID {
+		t.Errorf("registered %+v without an organization", solo.User)
+	}
+
+	// Rejected registrations leave no organization behind
+	expectError(t, http.StatusConflict, model.ErrUserAlreadyExists.Code, s.do("", http.MethodPost, "/auth/register", gin.H{"email": "agent@example.com", "password": testPassword, "organization": "agents"}))
+	var orgs struct {
+		Organizations []model.Organization `json:"organizations"`
+	}
+	expect(t, http.StatusOK, s.do(admin, http.MethodGet, "/api/admin/orgs", nil), &orgs)
+	if len(orgs.Organizations) != 3 {
+		t.Errorf("%d organizations, want the default one and one per organization registrant", len(orgs.Organizations))
+	}
+}
+
+func TestOrganizationIsolation(t *testing.T) {
+	s := newTestServer(t, service.WithOrganizationSignup(true))
+	s.createUser("admin@example.com", model.UserRoleAdmin, "")
+	agentUser := s.createUser("agent@example.com", model.UserRoleUser, "")
+	admin := s.login("admin@example.com")
+	agent := s.login("agent@example.com")
+	var acme registered
+	expect(t, http.StatusCreated, s.do("", http.MethodPost, "/auth/register", gin.H{"email": "owner@acme.example", "password": testPassword, "organization": "acme"}), &acme)
+	owner := s.login("owner@acme.example")
+
+	// External call IDs only need to be unique within an organization
+	start := gin.H{"caller_id": "+14155550100", "callee_id": "+14155550199", "external_source": "pbx-1", "external_id": "call-1"}
+	ours := s.startSession(agent, start)
+	theirs := s.startSession(owner, start)
+	if ours.OrgID != model.DefaultOrganizationID || theirs.OrgID != acme.User.OrgID {
+		t.Fatalf("sessions stamped with %s and %s", ours.OrgID, theirs.OrgID)
+	}
+	// Callers seeing every organization still create sessions in their own
+	if session := s.startSession(admin, gin.H{"caller_id": "+14155550101", "callee_id": "+14155550199"}); session.OrgID != model.DefaultOrganizationID {
+		t.Errorf("admin's session stamped with %s", session.OrgID)
+	}
+
+	counts := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{name: "organization admin", token: owner, want: 1},
+		{name: "member of the default organization", token: agent, want: 1},
+		{name: "holder of orgs:manage", token: admin, want: 3},
+		{name: "holder of orgs:manage with a token scoped without it", token: s.loginTokens("admin@example.com", model.PermissionSessionsRead).Token, want: 2},
+	}
+	for _, tt := range counts {
+		t.Run("sessions of "+tt.name, func(t *testing.T) {
+			var list model.SessionListResponse
+			expect(t, http.StatusOK, s.do(tt.token, http.MethodGet, "/api/sessions", nil), &list)
+			if list.Total == nil || *list.Total != int64(tt.want) {
+				t.Errorf("total %v, want %d", list.Total, tt.want)
+			}
+			var analytics model.SessionAnalytics
+			expect(t, http.StatusOK, s.do(tt.token, http.MethodGet, "/api/analytics/sessions", nil), &analytics)
+			if analytics.Totals.Total != int64(tt.want) {
+				t.Errorf("analytics total %d, want %d", analytics.Totals.Total, tt.want)
+			}
+		})
+	}
+
+	var webhook struct {
+		Webhook model.Webhook `json:"webhook"`
+	}
+	expect(t, http.StatusCreated, s.do(admin, http.MethodPost, "/api/admin/webhooks", gin.H{"url": "https://hooks.example.com/calls", "events": []string{"session.started"}}), &webhook)
+	if webhook.Webhook.OrgID != model.DefaultOrganizationID {
+		t.Errorf("admin's webhook created in %s", webhook.Webhook.OrgID)
+	}
+	key := s.createAPIKey(admin, gin.H{"name": "pbx-1", "scopes": []string{"sessions:read"}})
+
+	tests := []struct {
+		name   string
+		token  string
+		path   string
+		status int
+		code   string
+	}{
+		{name: "other organization's session", token: agent, path: "/api/sessions/" + theirs.ID.String(), status: http.StatusNotFound, code: model.ErrSessionNotFound.Code},
+		{name: "default organization's session", token: owner, path: "/api/sessions/" + ours.ID.String(), status: http.StatusNotFound, code: model.ErrSessionNotFound.Code},
+		{name: "any session with orgs:manage", token: admin, path: "/api/sessions/" + theirs.ID.String(), status: http.StatusOK},
+		{name: "other organization's webhook", token: owner, path: "/api/admin/webhooks/" + webhook.Webhook.ID.String(), status: http.StatusNotFound, code: model.ErrWebhookNotFound.Code},
+		{name: "other organization's API key", token: owner, path: "/api/admin/api-keys/" + key.ID.String(), status: http.StatusNotFound, code: model.ErrAPIKeyNotFound.Code},
+		{name: "other organization's user", token: owner, path: "/api/admin/users/" + agentUser.ID.String(), status: http.StatusNotFound, code: model.ErrUserNotFound.Code},
+		{name: "other organization's users", token: owner, path: "/api/admin/users?org_id=" + model.DefaultOrganizationID.String(), status: http.StatusForbidden, code: model.ErrForbidden.Code},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(tt.token, http.MethodGet, tt.path, nil)
+			if tt.code != "" {
+				expectError(t, tt.status, tt.code, w)
+				return
+			}
+			expect(t, tt.status, w, nil)
+		})
+	}
+
+	// Lookups by external ID resolve within the caller's own organization
+	var details model.SessionDetails
+	expect(t, http.StatusOK, s.do(owner, http.MethodGet, "/api/sessions/by-external/pbx-1/call-1", nil), &details)
+	if details.Session.ID != theirs.ID {
+		t.Errorf("resolved session %s, want %s", details.Session.ID, theirs.ID)
+	}
+	expect(t, http.StatusOK, s.do(admin, http.MethodGet, "/api/sessions/by-external/pbx-1/call-1", nil), &details)
+	if details.Session.ID != ours.ID {
+		t.Errorf("resolved session %s for the admin, want %s", details.Session.ID, ours.ID)
+	}
+
+	var webhooks struct {
+		Webhooks []model.Webhook `json:"webhooks"`
+	}
+	expect(t, http.StatusOK, s.do(owner, http.MethodGet, "/api/admin/webhooks", nil), &webhooks)
+	var keys struct {
+		APIKeys []model.APIKey `json:"api_keys"`
+	}
+	expect(t, http.StatusOK, s.do(owner, http.MethodGet, "/api/admin/api-keys", nil), &keys)
+	var users struct {
+		Users []model.User `json:"users"`
+	}
+	expect(t, http.StatusOK, s.do(owner, http.MethodGet, "/api/admin/users", nil), &users)
+	if len(webhooks.Webhooks) != 0 || len(keys.APIKeys) != 0 || len(users.Users) != 1 {
+		t.Errorf("organization admin lists %d webhooks, %d keys and %d users", len(webhooks.Webhooks), len(keys.APIKeys), len(users.Users))
+	}
+}
+
+func TestUpdateOwnOrganization(t *testing.T) {
+	s := newTestServer(t, service.WithOrganizationSignup(true))
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	var acme registered
+	expect(t, http.StatusCreated, s.do("", http.MethodPost, "/auth/register", gin.H{"email": "owner@acme.example", "password": testPassword, "organization": "acme"}), &acme)
+	owner := s.login("owner@acme.example")
+
+	var out struct {
+		Organization model.Organization `json:"organization"`
+	}
+	expect(t, http.StatusOK, s.do(owner, http.MethodPatch, "/api/organization", gin.H{"retention_days": 30, "allowed_event_types": []string{"dtmf"}}), &out)
+	if out.Organization.ID != acme.User.OrgID || out.Organization.Name != "acme" || out.Organization.RetentionDays != 30 || len(out.Organization.AllowedEventTypes) != 1 {
+		t.Errorf("updated organization %+v", out.Organization)
+	}
+
+	// The default organization is untouched and its users cannot change it
+	var org model.Organization
+	agent := s.login("agent@example.com")
+	expect(t, http.StatusOK, s.do(agent, http.MethodGet, "/api/organization", nil), &org)
+	if org.RetentionDays != 0 {
+		t.Errorf("default organization %+v changed", org)
+	}
+	expectError(t, http.StatusForbidden, model.ErrForbidden.Code, s.do(agent, http.MethodPatch, "/api/organization", gin.H{"retention_days": 1}))
+	expectError(t, http.StatusForbidden, model.ErrForbidden.Code, s.do(owner, http.MethodPatch, "/api/admin/orgs/"+acme.User.OrgID.String(), gin.H{"name": "acme inc"}))
+	expectError(t, http.StatusBadRequest, model.ErrInvalidRequest.Code, s.do(owner, http.MethodPatch, "/api/organization", gin.H{"retention_days": -1}))
+}
@@ -0,0 +1,192 @@
+package handler_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+)
+
+func TestPermissionsByRole(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("admin@example.com", model.UserRoleAdmin, "")
+	s.createUser("orgadmin@example.com", model.UserRoleOrgAdmin, "")
+	s.createUser("supervisor@example.com", model.UserRoleSupervisor, "sales")
+	s.createUser("agent@example.com", model.UserRoleUser, "sales")
+	tokens := map[model.UserRole]string{
+		model.UserRoleAdmin:      s.login("admin@example.com"),
+		model.UserRoleOrgAdmin:   s.login("orgadmin@example.com"),
+		model.UserRoleSupervisor: s.login("supervisor@example.com"),
+		model.UserRoleUser:       s.login("agent@example.com"),
+	}
+	session := s.startSession(tokens[model.UserRoleUser], gin.H{"caller_id": "+14155550100", "callee_id": "+14155550199"})
+
+	tests := []struct {
+		method, path string
+		body         gin.H
+		// allowed lists the roles whose requests pass the permission check
+		allowed []model.UserRole
+		status  int
+	}{
+		{method: http.MethodGet, path: "/api/sessions/" + session.ID.String(), allowed: []model.UserRole{model.UserRoleAdmin, model.UserRoleOrgAdmin, model.UserRoleSupervisor, model.UserRoleUser}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/api/sessions/export", allowed: []model.UserRole{model.UserRoleAdmin, model.UserRoleOrgAdmin, model.UserRoleSupervisor, model.UserRoleUser}, status: http.StatusOK},
+		{method: http.MethodPost, path: "/api/sessions/start", body: gin.H{"caller_id": "+14155550101", "callee_id": "+14155550199"}, allowed: []model.UserRole{model.UserRoleAdmin, model.UserRoleOrgAdmin, model.UserRoleUser}, status: http.StatusCreated},
+		{method: http.MethodGet, path: "/api/admin/users", allowed: []model.UserRole{model.UserRoleAdmin, model.UserRoleOrgAdmin}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/api/admin/roles", allowed: []model.UserRole{model.UserRoleAdmin, model.UserRoleOrgAdmin}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/api/admin/webhooks", allowed: []model.UserRole{model.UserRoleAdmin, model.UserRoleOrgAdmin}, status: http.StatusOK},
+		{method: http.MethodGet, path: "/api/admin/orgs", allowed: []model.UserRole{model.UserRoleAdmin}, status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		for role, token := range tokens {
+			t.Run(string(role)+" "+tt.method+" "+tt.path, func(t *testing.T) {
+				w := s.do(token, tt.method, tt.path, tt.body)
+				for _, allowed := range tt.allowed {
+					if role == allowed {
+						expect(t, tt.status, w, nil)
+						return
+					}
+				}
+				expectError(t, http.StatusForbidden, model.ErrForbidden.Code, w)
+			})
+		}
+	}
+}
+
+func TestScopedLogin(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("supervisor@example.com", model.UserRoleSupervisor, "sales")
+
+	login := s.loginTokens("supervisor@example.com", model.PermissionSessionsRead)
+	if len(login.Permissions) != 1 || login.Permissions[0] != model.PermissionSessionsRead {
+		t.Fatalf("scoped permissions %v", login.Permissions)
+	}
+	expect(t, http.StatusOK, s.do(login.Token, http.MethodGet, "/api/sessions", nil), nil)
+	expectError(t, http.StatusForbidden, model.ErrForbidden.Code, s.do(login.Token, http.MethodGet, "/api/sessions/export", nil))
+
+	// Scopes cannot exceed the role
+	expectError(t, http.StatusForbidden, model.ErrForbidden.Code, s.do("", http.MethodPost, "/auth/login", gin.H{"email": "supervisor@example.com", "password": testPassword, "scopes": []string{"sessions:write"}}))
+	expectError(t, http.StatusBadRequest, model.ErrInvalidRequest.Code, s.do("", http.MethodPost, "/auth/login", gin.H{"email": "supervisor@example.com", "password": testPassword, "scopes": []string{"sessions:delete"}}))
+}
+
+func TestRoleManagement(t *testing.T) {
+	s := newTestServer(t)
+	adminUser := s.createUser("admin@example.com", model.UserRoleAdmin, "")
+	s.createUser("orgadmin@example.com", model.UserRoleOrgAdmin, "")
+	agent := s.createUser("agent@example.com", model.UserRoleUser, "")
+	admin := s.login("admin@example.com")
+	orgAdmin := s.login("orgadmin@example.com")
+
+	var created struct {
+		Role model.Role `json:"role"`
+	}
+	expect(t, http.StatusCreated, s.do(admin, http.MethodPost, "/api/admin/roles", gin.H{"name": "qa", "permissions": []string{"sessions:read"}}), &created)
+	if created.Role.SessionScope != model.SessionScopeOwn || created.Role.Builtin {
+		t.Errorf("created role %+v, want the own scope", created.Role)
+	}
+
+	// Moving the agent to the new role revokes their logins and grants its permissions
+	agentLogin := s.loginTokens("agent@example.com")
+	expect(t, http.StatusOK, s.do(orgAdmin, http.MethodPatch, "/api/admin/users/"+agent.ID.String(), gin.H{"role": "qa"}), nil)
+	expectError(t, http.StatusUnauthorized, model.ErrUnauthorized.Code, s.do(agentLogin.Token, http.MethodGet, "/api/profile", nil))
+	qa := s.loginTokens("agent@example.com")
+	if len(qa.Permissions) != 1 || qa.Permissions[0] != model.PermissionSessionsRead {
+		t.Errorf("qa permissions %v", qa.Permissions)
+	}
+
+	tests := []struct {
+		name         string
+		token        string
+		method, path string
+		body         gin.H
+		status       int
+		code         string
+	}{
+		{name: "org admin creating a role", token: orgAdmin, method: http.MethodPost, path: "/api/admin/roles", body: gin.H{"name": "auditor", "permissions": []string{"sessions:read"}}, status: http.StatusForbidden, code: model.ErrForbidden.Code},
+		{name: "invalid name", token: admin, method: http.MethodPost, path: "/api/admin/roles", body: gin.H{"name": "Auditors!", "permissions": []string{"sessions:read"}}, status: http.StatusBadRequest, code: model.ErrInvalidRequest.Code},
+		{name: "existing name", token: admin, method: http.MethodPost, path: "/api/admin/roles", body: gin.H{"name": "qa", "permissions": []string{"sessions:read"}}, status: http.StatusConflict, code: model.ErrRoleAlreadyExists.Code},
+		{name: "admin permissions", token: admin, method: http.MethodPatch, path: "/api/admin/roles/admin", body: gin.H{"permissions": []string{"sessions:read"}}, status: http.StatusForbidden, code: model.ErrForbidden.Code},
+		{name: "admin session scope", token: admin, method: http.MethodPatch, path: "/api/admin/roles/admin", body: gin.H{"session_scope": "own"}, status: http.StatusForbidden, code: model.ErrForbidden.Code},
+		{name: "deleting a built-in role", token: admin, method: http.MethodDelete, path: "/api/admin/roles/supervisor", status: http.StatusForbidden, code: model.ErrForbidden.Code},
+		{name: "deleting a role in use", token: admin, method: http.MethodDelete, path: "/api/admin/roles/qa", status: http.StatusConflict, code: model.ErrRoleInUse.Code},
+		{name: "unknown role", token: admin, method: http.MethodGet, path: "/api/admin/roles/auditor", status: http.StatusNotFound, code: model.ErrRoleNotFound.Code},
+		{name: "assigning a role beyond the caller", token: orgAdmin, method: http.MethodPatch, path: "/api/admin/users/" + agent.ID.String(), body: gin.H{"role": "admin"}, status: http.StatusForbidden, code: model.ErrForbidden.Code},
+		{name: "changing one's own role", token: admin, method: http.MethodPatch, path: "/api/admin/users/" + adminUser.ID.String(), body: gin.H{"role": "user"}, status: http.StatusForbidden, code: model.ErrForbidden.Code},
+		{name: "making a user a service account", token: admin, method: http.MethodPatch, path: "/api/admin/users/" + agent.ID.String(), body: gin.H{"role": "service"}, status: http.StatusBadRequest, code: model.ErrInvalidRequest.Code},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectError(t, tt.status, tt.code, s.do(tt.token, tt.method, tt.path, tt.body))
+		})
+	}
+
+	// A new description leaves the role's logins alone
+	expect(t, http.StatusOK, s.do(admin, http.MethodPatch, "/api/admin/roles/qa", gin.H{"description": "Quality assurance"}), nil)
+	expect(t, http.StatusOK, s.do(qa.Token, http.MethodGet, "/api/profile", nil), nil)
+
+	// Permission and scope changes revoke the logins of the role's users, so they apply at once
+	expect(t, http.StatusOK, s.do(admin, http.MethodPatch, "/api/admin/roles/qa", gin.H{"permissions": []string{"sessions:read", "sessions:export"}}), nil)
+	expectError(t, http.StatusUnauthorized, model.ErrUnauthorized.Code, s.do(qa.Token, http.MethodGet, "/api/profile", nil))
+	expectError(t, http.StatusUnauthorized, model.ErrInvalidRefreshToken.Code, s.do("", http.MethodPost, "/auth/refresh", gin.H{"refresh_token": qa.RefreshToken}))
+	qa = s.loginTokens("agent@example.com")
+	if len(qa.Permissions) != 2 {
+		t.Errorf("permissions %v after logging in again, want the role's new permissions", qa.Permissions)
+	}
+	expect(t, http.StatusOK, s.do(admin, http.MethodPatch, "/api/admin/roles/qa", gin.H{"session_scope": "team"}), nil)
+	expectError(t, http.StatusUnauthorized, model.ErrUnauthorized.Code, s.do(qa.Token, http.MethodGet, "/api/profile", nil))
+
+	expect(t, http.StatusOK, s.do(admin, http.MethodPatch, "/api/admin/users/"+agent.ID.String(), gin.H{"role": "user"}), nil)
+	expect(t, http.StatusNoContent, s.do(admin, http.MethodDelete, "/api/admin/roles/qa", nil), nil)
+}
+
+func TestCreateUser(t *testing.T) {
+	s := newTestServer(t, service.WithOrganizationSignup(true))
+	s.createUser("admin@example.com", model.UserRoleAdmin, "")
+	s.createUser("supervisor@example.com", model.UserRoleSupervisor, "sales")
+	admin := s.login("admin@example.com")
+	var acme registered
+	expect(t, http.StatusCreated, s.do("", http.MethodPost, "/auth/register", gin.H{"email": "owner@acme.example", "password": testPassword, "organization": "acme"}), &acme)
+	owner := s.login("owner@acme.example")
+
+	// Organization admins add users to their own organization
+	var created struct {
+		User model.User `json:"user"`
+	}
+	expect(t, http.StatusCreated, s.do(owner, http.MethodPost, "/api/admin/users", gin.H{"email": "agent@acme.example", "password": testPassword, "role": "supervisor", "team": "support"}), &created)
+	if created.User.OrgID != acme.User.OrgID || created.User.Role != model.UserRoleSupervisor || created.User.Team != "support" {
+		t.Fatalf("created user %+v, want a supervisor of the owner's organization", created.User)
+	}
+	if token := s.login("agent@acme.example"); token == "" {
+		t.Error("created user cannot log in")
+	}
+
+	// Holders of orgs:manage add users to any organization
+	expect(t, http.StatusCreated, s.do(admin, http.MethodPost, "/api/admin/users", gin.H{"email": "second@acme.example", "password": testPassword, "org_id": acme.User.OrgID}), &created)
+	if created.User.OrgID != acme.User.OrgID || created.User.Role != model.UserRoleUser {
+		t.Errorf("created user %+v, want a user of the given organization", created.User)
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		body   gin.H
+		status int
+		code   string
+	}{
+		{name: "other organization", token: owner, body: gin.H{"email": "x@example.com", "password": testPassword, "org_id": model.DefaultOrganizationID}, status: http.StatusForbidden, code: model.ErrForbidden.Code},
+		{name: "role beyond the caller", token: owner, body: gin.H{"email": "x@acme.example", "password": testPassword, "role": "admin"}, status: http.StatusForbidden, code: model.ErrForbidden.Code},
+		{name: "service account", token: owner, body: gin.H{"email": "x@acme.example", "password": testPassword, "role": "service"}, status: http.StatusBadRequest, code: model.ErrInvalidRequest.Code},
+		{name: "unknown role", token: owner, body: gin.H{"email": "x@acme.example", "password": testPassword, "role": "auditor"}, status: http.StatusNotFound, code: model.ErrRoleNotFound.Code},
+		{name: "invalid team", token: owner, body: gin.H{"email": "x@acme.example", "password": testPassword, "team": "Support!"}, status: http.StatusBadRequest, code: model.ErrInvalidRequest.Code},
+		{name: "taken email", token: owner, body: gin.H{"email": "admin@example.com", "password": testPassword}, status: http.StatusConflict, code: model.ErrUserAlreadyExists.Code},
+		{name: "unknown organization", token: admin, body: gin.H{"email": "x@example.com", "password": testPassword, "org_id": uuid.New()}, status: http.StatusBadRequest, code: model.ErrInvalidRequest.Code},
+		{name: "without users:manage", token: s.login("supervisor@example.com"), body: gin.H{"email": "x@example.com", "password": testPassword}, status: http.StatusForbidden, code: model.ErrForbidden.Code},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectError(t, tt.status, tt.code, s.do(tt.token, http.MethodPost, "/api/admin/users", tt.body))
+		})
+	}
+}
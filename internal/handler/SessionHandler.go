@@ -2,24 +2,36 @@ package handler
 
 import (
 	"net/http"
-	"strconv"
-	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/vasu74/Call_Session_Management/internal/middleware"
 	"github.com/vasu74/Call_Session_Management/internal/model"
 )
 
-func StartSessionHandler(c *gin.Context) {
+func (h *Handler) StartSessionHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
 	var req model.StartSessionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(invalidRequest(err))
 		return
 	}
 
-	var session model.Session
-	if err := session.StartSession(req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	session, created, err := h.svc.StartSession(c.Request.Context(), access, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !created {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Session already exists",
+			"session": session,
+		})
 		return
 	}
 
@@ -29,34 +41,27 @@ func StartSessionHandler(c *gin.Context) {
 	})
 }
 
-func LogSessionEventHandler(c *gin.Context) {
-	sessionID := c.Param("sessionId")
-	if sessionID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "session ID is required"})
+func (h *Handler) LogSessionEventHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	sessionID, err := h.sessionID(c, access)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
 	var req model.LogEventRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(invalidRequest(err))
 		return
 	}
 
-	var event model.SessionEvent
-	if err := event.LogEvent(sessionID, req); err != nil {
-		if err.Error() == "session not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
-		// Check for event_time constraint violation
-		if strings.Contains(err.Error(), "valid_event_time") {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "event_time must be within the last year",
-			})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	event, err := h.svc.LogEvent(c.Request.Context(), access, sessionID, req)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -66,38 +71,27 @@ func LogSessionEventHandler(c *gin.Context) {
 	})
 }
 
-func EndSessionHandler(c *gin.Context) {
-	sessionID := c.Param("sessionId")
-	if sessionID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "session ID is required"})
+func (h *Handler) EndSessionHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	sessionID, err := h.sessionID(c, access)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
 	var req model.EndSessionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	var session model.Session
-	if err := session.EndSession(sessionID, req); err != nil {
-		switch err.Error() {
-		case "session not found":
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		case "session is already ended with status: completed",
-			"session is already ended with status: failed",
-			"session could not be ended - it may have been ended by another request":
-			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
-		default:
-			// Add this block:
-			if strings.Contains(err.Error(), "valid_session_times") {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": "end_time must be after or equal to started_at",
-				})
-				return
-			}
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	session, err := h.svc.EndSession(c.Request.Context(), access, sessionID, req)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -107,78 +101,113 @@ func EndSessionHandler(c *gin.Context) {
 	})
 }
 
-func GetSessionDetailsHandler(c *gin.Context) {
-	sessionID := c.Param("sessionId")
-	if sessionID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "session ID is required"})
+func (h *Handler) TransitionSessionHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	sessionID, err := h.sessionID(c, access)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	details, err := model.GetSessionDetails(sessionID)
+	var req model.TransitionSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	session, err := h.svc.TransitionSession(c.Request.Context(), access, sessionID, req)
 	if err != nil {
-		if err.Error() == "session not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, details)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session state updated successfully",
+		"session": session,
+	})
 }
 
-func ListSessionsHandler(c *gin.Context) {
-	var filter model.SessionFilter
-
-	// Parse query parameters
-	if startDate := c.Query("start_date"); startDate != "" {
-		if t, err := time.Parse(time.RFC3339, startDate); err == nil {
-			filter.StartDate = &t
-		}
-	}
-	if endDate := c.Query("end_date"); endDate != "" {
-		if t, err := time.Parse(time.RFC3339, endDate); err == nil {
-			filter.EndDate = &t
-		}
+func (h *Handler) GetSessionDetailsHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
 	}
-	if status := c.Query("status"); status != "" {
-		filter.Status = model.SessionStatus(status)
+	sessionID, err := h.sessionID(c, access)
+	if err != nil {
+		c.Error(err)
+		return
 	}
-	if callerID := c.Query("caller_id"); callerID != "" {
-		filter.CallerID = callerID
+
+	details, err := h.svc.GetSessionDetails(c.Request.Context(), access, sessionID)
+	if err != nil {
+		c.Error(err)
+		return
 	}
-	if calleeID := c.Query("callee_id"); calleeID != "" {
-		filter.CalleeID = calleeID
+
+	c.JSON(http.StatusOK, details)
+}
+
+func (h *Handler) ListSessionsHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
 	}
-	if limit := c.Query("limit"); limit != "" {
-		if l, err := strconv.Atoi(limit); err == nil && l > 0 {
-			filter.Limit = l
-		}
+
+	filter := model.SessionFilter{Limit: 50}
+	if err := bindSessionFilter(c, &filter); err != nil {
+		c.Error(err)
+		return
 	}
-	if offset := c.Query("offset"); offset != "" {
-		if o, err := strconv.Atoi(offset); err == nil && o >= 0 {
-			filter.Offset = o
-		}
+
+	// Parse pagination parameters
+	if filter.Limit, err = model.ParseSessionLimit(c.Query("limit"), filter.Limit); err != nil {
+		c.Error(err)
+		return
 	}
-	if sortBy := c.Query("sort_by"); sortBy != "" {
-		filter.SortBy = sortBy
+	if filter.Offset, err = model.ParseSessionOffset(c.Query("offset")); err != nil {
+		c.Error(err)
+		return
 	}
-	if sortOrder := c.Query("sort_order"); sortOrder != "" {
-		filter.SortOrder = sortOrder
+
+	filter.IncludeTotal = c.Query("include_total") != "false"
+
+	// Cursor pagination is requested explicitly or by continuing from a cursor
+	if cursor := c.Query("cursor"); cursor != "" {
+		decoded, err := model.DecodeSessionCursor(cursor)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		filter.UseCursor = true
+		filter.Cursor = decoded
+	} else if c.Query("pagination") == "cursor" {
+		filter.UseCursor = true
 	}
 
-	// Validate status if provided
-	if filter.Status != "" && filter.Status != model.SessionStatusOngoing && filter.Status != model.SessionStatusCompleted && filter.Status != model.SessionStatusFailed {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status value"})
+	// Parse the sort expression against the sessions schema
+	if err := filter.BindSortQuery(c.Request.URL.Query()); err != nil {
+		c.Error(err)
 		return
 	}
 
 	// Get sessions
-	sessions, err := model.ListSessions(filter)
+	sessions, err := h.svc.ListSessions(c.Request.Context(), access, filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, sessions)
 }
+
+// bindSessionFilter parses the query parameters that select sessions, shared by the
+// endpoints that list or aggregate them
+func bindSessionFilter(c *gin.Context, filter *model.SessionFilter) error {
+	return filter.BindQuery(c.Request.URL.Query())
+}
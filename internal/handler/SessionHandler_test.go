@@ -0,0 +1,378 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+func TestSessionLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	token := s.login("agent@example.com")
+
+	session := s.startSession(token, gin.H{"caller_id": "+14155550100", "callee_id": "+14155550199"})
+	if session.Status != model.SessionStatusInitiated || session.OrgID != model.DefaultOrganizationID {
+		t.Fatalf("started session %+v", session)
+	}
+	path := "/api/sessions/" + session.ID.String()
+
+	expect(t, http.StatusCreated, s.do(token, http.MethodPost, path+"/events", gin.H{"event_type": "ringing", "event_time": time.Now()}), nil)
+	expect(t, http.StatusOK, s.do(token, http.MethodPost, path+"/end", gin.H{"status": "completed", "disposition": "Answered", "end_time": time.Now()}), nil)
+
+	var details model.SessionDetails
+	expect(t, http.StatusOK, s.do(token, http.MethodGet, path, nil), &details)
+	if details.Session.Status != model.SessionStatusCompleted || details.Session.EndedAt == nil {
+		t.Errorf("ended session %+v", details.Session)
+	}
+	if len(details.Events) != 2 {
+		t.Errorf("session has %d events, want the logged event and the end event", len(details.Events))
+	}
+
+	expectError(t, http.StatusConflict, model.ErrSessionAlreadyEnded.Code, s.do(token, http.MethodPost, path+"/end", gin.H{"status": "completed", "disposition": "Answered", "end_time": time.Now()}))
+}
+
+func TestSessionErrors(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	token := s.login("agent@example.com")
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		body   interface{}
+		status int
+		code   string
+	}{
+		{name: "no token", method: http.MethodGet, path: "/api/sessions", status: http.StatusUnauthorized, code: model.ErrUnauthorized.Code},
+		{name: "invalid token", token: "nope", method: http.MethodGet, path: "/api/sessions", status: http.StatusUnauthorized, code: model.ErrUnauthorized.Code},
+		{name: "invalid session id", token: token, method: http.MethodGet, path: "/api/sessions/42", status: http.StatusNotFound, code: model.ErrSessionNotFound.Code},
+		{name: "unknown session", token: token, method: http.MethodGet, path: "/api/sessions/6f1f7a4e-0c55-4bb4-9a55-5a8a5d1f0e0b", status: http.StatusNotFound, code: model.ErrSessionNotFound.Code},
+		{name: "missing callee", token: token, method: http.MethodPost, path: "/api/sessions/start", body: gin.H{"caller_id": "+14155550100"}, status: http.StatusBadRequest, code: model.ErrInvalidRequest.Code},
+		{name: "admin route", token: token, method: http.MethodGet, path: "/api/admin/users", status: http.StatusForbidden, code: model.ErrForbidden.Code},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := expectError(t, tt.status, tt.code, s.do(tt.token, tt.method, tt.path, tt.body))
+			if out.Error.RequestID == "" {
+				t.Error("error envelope has no request ID")
+			}
+		})
+	}
+}
+
+func TestListSessionsHandler(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	token := s.login("agent@example.com")
+	for _, caller := range []string{"+14155550100", "+14155550101", "+14155550102"} {
+		s.startSession(token, gin.H{"caller_id": caller, "callee_id": "+14155550199"})
+	}
+
+	tests := []struct {
+		name  string
+		query url.Values
+		want  int
+		code  string
+	}{
+		{name: "default", query: url.Values{}, want: 3},
+		{name: "limit", query: url.Values{"limit": {"2"}}, want: 2},
+		{name: "filter", query: url.Values{"filter": {"caller_id:eq:+14155550101"}}, want: 1},
+		{name: "sort", query: url.Values{"sort": {"caller_id,-started_at"}}, want: 3},
+		{name: "limit above the maximum", query: url.Values{"limit": {"1001"}}, code: model.ErrInvalidQuery.Code},
+		{name: "non-numeric limit", query: url.Values{"limit": {"ten"}}, code: model.ErrInvalidQuery.Code},
+		{name: "negative offset", query: url.Values{"offset": {"-1"}}, code: model.ErrInvalidQuery.Code},
+		{name: "malformed start date", query: url.Values{"start_date": {"yesterday"}}, code: model.ErrInvalidQuery.Code},
+		{name: "unknown sort field", query: url.Values{"sort": {"password"}}, code: model.ErrInvalidQuery.Code},
+		{name: "malformed filter", query: url.Values{"filter": {"status"}}, code: model.ErrInvalidQuery.Code},
+		{name: "tampered cursor", query: url.Values{"cursor": {"abc.def"}}, code: model.ErrInvalidQuery.Code},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(token, http.MethodGet, "/api/sessions?"+tt.query.Encode(), nil)
+			if tt.code != "" {
+				expectError(t, http.StatusBadRequest, tt.code, w)
+				return
+			}
+			var out model.SessionListResponse
+			expect(t, http.StatusOK, w, &out)
+			if len(out.Sessions) != tt.want {
+				t.Errorf("listed %d sessions, want %d", len(out.Sessions), tt.want)
+			}
+		})
+	}
+}
+
+func TestListSessionsByCursorHandler(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	token := s.login("agent@example.com")
+	for _, caller := range []string{"+14155550100", "+14155550101", "+14155550102"} {
+		s.startSession(token, gin.H{"caller_id": caller, "callee_id": "+14155550199"})
+	}
+
+	seen := map[string]bool{}
+	query := url.Values{"pagination": {"cursor"}, "limit": {"2"}}
+	for pages := 0; ; pages++ {
+		if pages == 3 {
+			t.Fatal("cursor pagination did not end")
+		}
+		var out model.SessionListResponse
+		expect(t, http.StatusOK, s.do(token, http.MethodGet, "/api/sessions?"+query.Encode(), nil), &out)
+		for _, session := range out.Sessions {
+			if seen[session.ID.String()] {
+				t.Fatalf("session %s listed twice", session.ID)
+			}
+			seen[session.ID.String()] = true
+		}
+		if out.NextCursor == "" {
+			break
+		}
+		query = url.Values{"cursor": {out.NextCursor}, "limit": {"2"}}
+	}
+	if len(seen) != 3 {
+		t.Errorf("cursor pages listed %d sessions, want 3", len(seen))
+	}
+}
+
+func TestTransitionSessionHandler(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	token := s.login("agent@example.com")
+	session := s.startSession(token, gin.H{"caller_id": "+14155550100", "callee_id": "+14155550199"})
+	path := "/api/sessions/" + session.ID.String() + "/transition"
+
+	steps := []struct {
+		status string
+		want   int
+		code   string
+	}{
+		{status: "ringing", want: http.StatusOK},
+		{status: "on_hold", want: http.StatusConflict, code: model.ErrInvalidTransition.Code},
+		{status: "answered", want: http.StatusOK},
+		{status: "on_hold", want: http.StatusOK},
+		{status: "hung_up", want: http.StatusBadRequest, code: model.ErrInvalidRequest.Code},
+		{status: "completed", want: http.StatusOK},
+		{status: "answered", want: http.StatusConflict, code: model.ErrSessionAlreadyEnded.Code},
+	}
+	for _, step := range steps {
+		w := s.do(token, http.MethodPost, path, gin.H{"status": step.status, "disposition": "Answered"})
+		if step.code != "" {
+			expectError(t, step.want, step.code, w)
+			continue
+		}
+		var out struct {
+			Session model.Session `json:"session"`
+		}
+		expect(t, step.want, w, &out)
+		if string(out.Session.Status) != step.status {
+			t.Fatalf("session status %s, want %s", out.Session.Status, step.status)
+		}
+	}
+
+	var details model.SessionDetails
+	expect(t, http.StatusOK, s.do(token, http.MethodGet, "/api/sessions/"+session.ID.String(), nil), &details)
+	if details.Session.EndedAt == nil || len(details.Events) != 4 {
+		t.Errorf("ended session has ended_at %v and %d events, want 4 transition events", details.Session.EndedAt, len(details.Events))
+	}
+	for _, event := range details.Events {
+		if event.EventType != model.EventTypeStateTransition {
+			t.Errorf("event type %s, want %s", event.EventType, model.EventTypeStateTransition)
+		}
+	}
+}
+
+func TestSessionsByExternalID(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	s.createUser("other@example.com", model.UserRoleUser, "")
+	token := s.login("agent@example.com")
+	body := gin.H{"caller_id": "+14155550100", "callee_id": "+14155550199", "external_source": "asterisk", "external_id": "1710930000.42"}
+
+	session := s.startSession(token, body)
+
+	// Starting the same call again returns the existing session
+	var again struct {
+		Message string        `json:"message"`
+		Session model.Session `json:"session"`
+	}
+	expect(t, http.StatusOK, s.do(token, http.MethodPost, "/api/sessions/start", body), &again)
+	if again.Session.ID != session.ID {
+		t.Fatalf("second start returned session %s, want %s", again.Session.ID, session.ID)
+	}
+
+	// The call of a session the caller cannot see is only reported as taken
+	expectError(t, http.StatusConflict, model.ErrExternalIDConflict.Code, s.do(s.login("other@example.com"), http.MethodPost, "/api/sessions/start", body))
+
+	path := "/api/sessions/by-external/asterisk/1710930000.42"
+	var details model.SessionDetails
+	expect(t, http.StatusOK, s.do(token, http.MethodGet, path, nil), &details)
+	if details.Session.ID != session.ID {
+		t.Fatalf("lookup by external ID returned session %s, want %s", details.Session.ID, session.ID)
+	}
+	expect(t, http.StatusCreated, s.do(token, http.MethodPost, path+"/events", gin.H{"event_type": "dtmf", "event_time": time.Now()}), nil)
+	expect(t, http.StatusOK, s.do(token, http.MethodPost, path+"/end", gin.H{"status": "completed", "disposition": "Answered", "end_time": time.Now()}), nil)
+
+	expectError(t, http.StatusNotFound, model.ErrSessionNotFound.Code, s.do(token, http.MethodGet, "/api/sessions/by-external/freeswitch/1710930000.42", nil))
+	expectError(t, http.StatusBadRequest, model.ErrInvalidRequest.Code, s.do(token, http.MethodPost, "/api/sessions/start", gin.H{"caller_id": "+14155550100", "callee_id": "+14155550199", "external_id": "no-source"}))
+}
+
+func TestEndedSessionMetrics(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	token := s.login("agent@example.com")
+	session := s.startSession(token, gin.H{"caller_id": "+14155550100", "callee_id": "+14155550199"})
+	path := "/api/sessions/" + session.ID.String()
+
+	var details model.SessionDetails
+	expect(t, http.StatusOK, s.do(token, http.MethodGet, path, nil), &details)
+	if details.Metrics != nil {
+		t.Fatalf("active session has metrics %+v", details.Metrics)
+	}
+
+	for _, eventType := range []string{"answered", "hold", "unhold"} {
+		expect(t, http.StatusCreated, s.do(token, http.MethodPost, path+"/events", gin.H{"event_type": eventType, "event_time": time.Now()}), nil)
+	}
+	expect(t, http.StatusOK, s.do(token, http.MethodPost, path+"/end", gin.H{"status": "completed", "disposition": "Answered", "end_time": time.Now()}), nil)
+
+	expect(t, http.StatusOK, s.do(token, http.MethodGet, path, nil), &details)
+	metrics := details.Metrics
+	if metrics == nil || metrics.SessionID != session.ID {
+		t.Fatalf("ended session metrics %+v", metrics)
+	}
+	if metrics.TimeToAnswer == nil || metrics.TalkTime == nil || metrics.HoldCount != 1 || metrics.EventCount != 4 {
+		t.Errorf("metrics %+v, want an answered call with one hold and four events", metrics)
+	}
+}
+
+func TestSessionIdentities(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	token := s.login("agent@example.com")
+
+	session := s.startSession(token, gin.H{"caller_id": "(415) 555-0100", "callee_id": "1-800-555-0100"})
+	if session.CallerID != "+14155550100" || session.CallerIDRaw != "(415) 555-0100" || session.CallerIDType != model.IdentityE164 || session.CallerNumberType != model.NumberTypeFixedLineOrMobile {
+		t.Errorf("caller %q (%q, %s, %s)", session.CallerID, session.CallerIDRaw, session.CallerIDType, session.CallerNumberType)
+	}
+	if session.CalleeID != "+18005550100" || session.CalleeNumberType != model.NumberTypeTollFree {
+		t.Errorf("callee %q (%s)", session.CalleeID, session.CalleeNumberType)
+	}
+	s.startSession(token, gin.H{"caller_id": "+1 415 555 0100", "callee_id": "<sip:Queue@PBX.example.com;transport=tls>"})
+	s.startSession(token, gin.H{"caller_id": "1001", "callee_id": "anonymous"})
+
+	tests := []struct {
+		name  string
+		query url.Values
+		want  int
+	}{
+		{name: "caller written nationally", query: url.Values{"caller_id": {"415-555-0100"}}, want: 2},
+		{name: "caller filter in another form", query: url.Values{"filter": {"caller_id:eq:14155550100"}}, want: 2},
+		{name: "caller as given", query: url.Values{"filter": {"caller_id_raw:eq:(415) 555-0100"}}, want: 1},
+		{name: "SIP callee", query: url.Values{"filter": {"callee_id:eq:sip:Queue@pbx.example.com"}}, want: 1},
+		{name: "identity type", query: url.Values{"filter": {"caller_id_type:eq:extension"}}, want: 1},
+		{name: "number type", query: url.Values{"filter": {"callee_number_type:eq:toll_free"}}, want: 1},
+		{name: "unknown callee", query: url.Values{"filter": {"callee_id_type:eq:unknown"}}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out model.SessionListResponse
+			expect(t, http.StatusOK, s.do(token, http.MethodGet, "/api/sessions?"+tt.query.Encode(), nil), &out)
+			if len(out.Sessions) != tt.want {
+				t.Errorf("listed %d sessions, want %d", len(out.Sessions), tt.want)
+			}
+		})
+	}
+	expectError(t, http.StatusBadRequest, model.ErrInvalidQuery.Code, s.do(token, http.MethodGet, "/api/sessions?filter=caller_id_type:eq:pstn", nil))
+}
+
+func TestSessionOwnership(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("alice@example.com", model.UserRoleUser, "sales")
+	s.createUser("bob@example.com", model.UserRoleUser, "sales")
+	s.createUser("carol@example.com", model.UserRoleUser, "support")
+	s.createUser("lead@example.com", model.UserRoleSupervisor, "sales")
+	s.createUser("admin@example.com", model.UserRoleAdmin, "")
+	tokens := map[string]string{}
+	for _, name := range []string{"alice", "bob", "carol", "lead", "admin"} {
+		tokens[name] = s.login(name + "@example.com")
+	}
+
+	alice := s.startSession(tokens["alice"], gin.H{"caller_id": "+14155550100", "callee_id": "+14155550199", "external_source": "pbx-1", "external_id": "call-1"})
+	s.startSession(tokens["alice"], gin.H{"caller_id": "+14155550101", "callee_id": "+14155550199"})
+	bob := s.startSession(tokens["bob"], gin.H{"caller_id": "+14155550102", "callee_id": "+14155550199"})
+	carol := s.startSession(tokens["carol"], gin.H{"caller_id": "+14155550103", "callee_id": "+14155550199"})
+	if alice.CreatedBy == nil || alice.Team != "sales" {
+		t.Fatalf("session created by %v in team %q", alice.CreatedBy, alice.Team)
+	}
+
+	// Lists, counts, exports and analytics only cover the sessions in scope
+	visible := map[string]int{"alice": 2, "bob": 1, "carol": 1, "lead": 3, "admin": 4}
+	for name, want := range visible {
+		t.Run("visible to "+name, func(t *testing.T) {
+			var list model.SessionListResponse
+			expect(t, http.StatusOK, s.do(tokens[name], http.MethodGet, "/api/sessions", nil), &list)
+			if len(list.Sessions) != want || list.Total == nil || *list.Total != int64(want) {
+				t.Errorf("listed %d sessions of %v, want %d", len(list.Sessions), list.Total, want)
+			}
+
+			w := s.do(tokens[name], http.MethodGet, "/api/sessions/export?format=ndjson", nil)
+			if w.Code != http.StatusOK || strings.Count(w.Body.String(), "\n") != want {
+				t.Errorf("export %d: %s, want %d sessions", w.Code, w.Body, want)
+			}
+
+			var analytics model.SessionAnalytics
+			expect(t, http.StatusOK, s.do(tokens[name], http.MethodGet, "/api/analytics/sessions", nil), &analytics)
+			if analytics.Totals.Total != int64(want) {
+				t.Errorf("analytics total %d, want %d", analytics.Totals.Total, want)
+			}
+		})
+	}
+
+	// Sessions out of scope are not found, for reads and writes alike
+	tests := []struct {
+		name         string
+		token        string
+		method, path string
+		body         gin.H
+		status       int
+	}{
+		{name: "own session", token: tokens["alice"], method: http.MethodGet, path: "/api/sessions/" + alice.ID.String(), status: http.StatusOK},
+		{name: "teammate's session", token: tokens["alice"], method: http.MethodGet, path: "/api/sessions/" + bob.ID.String(), status: http.StatusNotFound},
+		{name: "team session as supervisor", token: tokens["lead"], method: http.MethodGet, path: "/api/sessions/" + bob.ID.String(), status: http.StatusOK},
+		{name: "other team's session as supervisor", token: tokens["lead"], method: http.MethodGet, path: "/api/sessions/" + carol.ID.String(), status: http.StatusNotFound},
+		{name: "any session as admin", token: tokens["admin"], method: http.MethodGet, path: "/api/sessions/" + carol.ID.String(), status: http.StatusOK},
+		{name: "by external ID", token: tokens["carol"], method: http.MethodGet, path: "/api/sessions/by-external/pbx-1/call-1", status: http.StatusNotFound},
+		{name: "logging an event", token: tokens["alice"], method: http.MethodPost, path: "/api/sessions/" + bob.ID.String() + "/events", body: gin.H{"event_type": "dtmf", "event_time": time.Now()}, status: http.StatusNotFound},
+		{name: "transitioning", token: tokens["carol"], method: http.MethodPost, path: "/api/sessions/by-external/pbx-1/call-1/transition", body: gin.H{"status": "ringing"}, status: http.StatusNotFound},
+		{name: "ending", token: tokens["bob"], method: http.MethodPost, path: "/api/sessions/" + alice.ID.String() + "/end", body: gin.H{"status": "completed", "disposition": "done", "end_time": time.Now()}, status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(tt.token, tt.method, tt.path, tt.body)
+			if tt.status == http.StatusNotFound {
+				expectError(t, http.StatusNotFound, model.ErrSessionNotFound.Code, w)
+				return
+			}
+			expect(t, tt.status, w, nil)
+		})
+	}
+
+	// Batches report the session as not found per event
+	w := s.do(tokens["alice"], http.MethodPost, "/api/sessions/"+bob.ID.String()+"/events:batch", gin.H{"events": []gin.H{{"event_type": "dtmf", "event_time": time.Now()}}})
+	if w.Code != http.StatusMultiStatus || !strings.Contains(w.Body.String(), model.ErrSessionNotFound.Code) {
+		t.Errorf("batch on a session out of scope %d: %s", w.Code, w.Body)
+	}
+
+	var details model.SessionDetails
+	expect(t, http.StatusOK, s.do(tokens["admin"], http.MethodGet, "/api/sessions/"+alice.ID.String(), nil), &details)
+	if details.Session.Status != model.SessionStatusInitiated || len(details.Events) != 0 {
+		t.Errorf("session changed by callers out of scope: %s with %d events", details.Session.Status, len(details.Events))
+	}
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/vasu74/Call_Session_Management/internal/middleware"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/stream"
+)
+
+func (h *Handler) StreamSessionsHandler(c *gin.Context) {
+	filter, err := h.streamFilter(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	h.streamNotifications(c, filter)
+}
+
+func (h *Handler) StreamSessionHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	sessionID, err := h.sessionID(c, access)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	session, err := h.svc.GetSession(c.Request.Context(), access, sessionID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	h.streamNotifications(c, stream.Filter{SessionID: session.ID.String(), Access: access})
+}
+
+// streamFilter parses the status, caller_id and callee_id query parameters. status
+// accepts a comma separated list of session states; the IDs are normalized like those
+// of the sessions they are compared with. Only sessions visible to the caller match.
+func (h *Handler) streamFilter(c *gin.Context) (stream.Filter, error) {
+	var filter stream.Filter
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		return filter, err
+	}
+	filter.Access = access
+	if callerID := c.Query("caller_id"); callerID != "" {
+		filter.CallerID = h.svc.NormalizeIdentity(callerID)
+	}
+	if calleeID := c.Query("callee_id"); calleeID != "" {
+		filter.CalleeID = h.svc.NormalizeIdentity(calleeID)
+	}
+	if status := c.Query("status"); status != "" {
+		for _, raw := range strings.Split(status, ",") {
+			s := model.SessionStatus(strings.TrimSpace(raw))
+			if !s.IsValid() {
+				return filter, model.ErrInvalidQuery.WithMessage("invalid status value")
+			}
+			filter.Statuses = append(filter.Statuses, s)
+		}
+	}
+	return filter, nil
+}
+
+// streamNotifications serves the notifications matching filter as Server-Sent Events
+// until the client disconnects or the server shuts down. Clients resume with the
+// Last-Event-ID header, or the last_event_id query parameter for clients that cannot
+// set headers.
+func (h *Handler) streamNotifications(c *gin.Context, filter stream.Filter) {
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	sub, replay, err := h.hub.Subscribe(filter, lastEventID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer sub.Close()
+
+	// Streams outlive the server's write timeout
+	if err := clearDeadlines(c, false); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	for _, n := range replay {
+		if err := writeNotification(c, n); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(h.hub.Heartbeat())
+	defer heartbeat.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n, ok := <-sub.Notifications():
+			if !ok {
+				return
+			}
+			if err := writeNotification(c, n); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := c.Writer.WriteString(": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+func writeNotification(c *gin.Context, n stream.Notification) error {
+	err := sse.Encode(c.Writer, sse.Event{
+		Id:    strconv.FormatInt(n.ID, 10),
+		Event: string(n.Type),
+		Data:  n,
+	})
+	if err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
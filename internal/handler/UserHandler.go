@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vasu74/Call_Session_Management/internal/middleware"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+func (h *Handler) CreateUserHandler(c *gin.Context) {
+	var req model.CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	granted, err := middleware.Permissions(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, err := h.svc.CreateUser(c.Request.Context(), access, granted, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "User created successfully",
+		"user":    user,
+	})
+}
+
+func (h *Handler) ListUsersHandler(c *gin.Context) {
+	granted, err := middleware.Permissions(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	users, err := h.svc.ListUsers(c.Request.Context(), access, granted, c.Query("org_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users})
+}
+
+func (h *Handler) GetUserHandler(c *gin.Context) {
+	granted, err := middleware.Permissions(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, err := h.svc.GetManagedUser(c.Request.Context(), access, granted, c.Param("userId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *Handler) UpdateUserHandler(c *gin.Context) {
+	var req model.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	granted, err := middleware.Permissions(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, err := h.svc.UpdateUser(c.Request.Context(), access, granted, c.Param("userId"), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User updated successfully",
+		"user":    user,
+	})
+}
@@ -0,0 +1,603 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/vasu74/Call_Session_Management/internal/middleware"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/stream"
+)
+
+const (
+	wsWriteWait        = 10 * time.Second
+	wsPongWait         = 60 * time.Second
+	wsPingInterval     = wsPongWait * 9 / 10
+	wsMaxMessageSize   = 1 << 20
+	wsMaxSubscriptions = 32
+	// wsAuthGrace is how long a connection whose access token has expired stays open
+	// for the client to send a fresh one
+	wsAuthGrace = 30 * time.Second
+)
+
+// Client message types
+const (
+	wsAuth              = "auth"
+	wsSubscribe         = "subscribe"
+	wsUnsubscribe       = "unsubscribe"
+	wsStartSession      = "start_session"
+	wsLogEvent          = "log_event"
+	wsEndSession        = "end_session"
+	wsTransitionSession = "transition_session"
+)
+
+// wsPermissions are the permissions required by the client messages that change
+// sessions; connecting requires sessions:read
+var wsPermissions = map[string]model.Permission{
+	wsStartSession:      model.PermissionSessionsWrite,
+	wsLogEvent:          model.PermissionEventsWrite,
+	wsEndSession:        model.PermissionSessionsWrite,
+	wsTransitionSession: model.PermissionSessionsWrite,
+}
+
+// Server message types
+const (
+	wsAck          = "ack"
+	wsError        = "error"
+	wsNotification = "notification"
+	wsAuthRequired = "auth_required"
+)
+
+// newUpgrader creates the WebSocket upgrader, which only accepts browser connections
+// from the API's own origin until WithWebSocketOrigins allows others
+func newUpgrader() websocket.Upgrader {
+	return websocket.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+		CheckOrigin:     originChecker(nil),
+	}
+}
+
+// originChecker accepts upgrade requests without an Origin header, which do not come
+// from a browser, requests from the host serving the API, and requests from the allowed
+// origins. Any other page could otherwise open a socket with the credentials of a user
+// visiting it.
+func originChecker(allowed []string) func(r *http.Request) bool {
+	origins := make(map[string]bool, len(allowed))
+	for _, origin := range allowed {
+		origins[strings.ToLower(strings.TrimRight(strings.TrimSpace(origin), "/"))] = true
+	}
+
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || origins["*"] || origins[strings.ToLower(origin)] {
+			return true
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+}
+
+// wsRequest is a message sent by the client. ID is echoed in the reply so the client
+// can correlate it with the request.
+type wsRequest struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	SessionID string          `json:"session_id,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// wsResponse is a message sent by the server: the ack or error for a client message,
+// or a notification for one of the client's subscriptions
+type wsResponse struct {
+	ID             string                `json:"id,omitempty"`
+	Type           string                `json:"type"`
+	SubscriptionID string                `json:"subscription_id,omitempty"`
+	Data           interface{}           `json:"data,omitempty"`
+	Error          *middleware.ErrorBody `json:"error,omitempty"`
+}
+
+// wsSubscribeRequest is the data of a subscribe message
+type wsSubscribeRequest struct {
+	SessionID   string                `json:"session_id"`
+	Status      []model.SessionStatus `json:"status"`
+	CallerID    string                `json:"caller_id"`
+	CalleeID    string                `json:"callee_id"`
+	LastEventID string                `json:"last_event_id"`
+}
+
+// wsUnsubscribeRequest is the data of an unsubscribe message
+type wsUnsubscribeRequest struct {
+	SubscriptionID string `json:"subscription_id" binding:"required"`
+}
+
+// wsAuthRequest is the data of an auth message
+type wsAuthRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// wsConn serves one WebSocket connection. Client messages are handled one at a time in
+// the order they arrive; notifications are written concurrently by one goroutine per
+// subscription.
+type wsConn struct {
+	h      *Handler
+	c      *gin.Context
+	conn   *websocket.Conn
+	access model.SessionAccess
+
+	// The token or API key the connection was opened with, which must stay valid for
+	// as long as the connection is open; expiresAt is zero if it does not expire. An
+	// auth message replaces the token with a fresh one and signals renewed.
+	authMu    sync.Mutex
+	claims    *model.JWTClaims
+	apiKey    *model.APIKey
+	expiresAt time.Time
+	renewed   chan struct{}
+
+	writeMu sync.Mutex
+
+	mu   sync.Mutex
+	subs map[string]*stream.Subscription
+}
+
+func (h *Handler) WebSocketHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	ws := &wsConn{h: h, c: c, access: access, subs: make(map[string]*stream.Subscription), renewed: make(chan struct{}, 1)}
+	if value, ok := c.Get("claims"); ok {
+		ws.claims = value.(*model.JWTClaims)
+		if ws.claims.ExpiresAt != nil {
+			ws.expiresAt = ws.claims.ExpiresAt.Time
+		}
+	}
+	if value, ok := c.Get("apiKey"); ok {
+		ws.apiKey = value.(*model.APIKey)
+		if ws.apiKey.ExpiresAt != nil {
+			ws.expiresAt = *ws.apiKey.ExpiresAt
+		}
+	}
+
+	ws.conn, err = h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written the error response
+		return
+	}
+	ws.serve()
+}
+
+func (ws *wsConn) serve() {
+	defer ws.conn.Close()
+	defer ws.unsubscribeAll()
+
+	done := make(chan struct{})
+	defer close(done)
+	go ws.keepAlive(done)
+
+	ws.conn.SetReadLimit(wsMaxMessageSize)
+	ws.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	ws.conn.SetPongHandler(func(string) error {
+		return ws.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, message, err := ws.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("request %s: websocket read failed: %v", ws.c.GetString("requestID"), err)
+			}
+			return
+		}
+
+		var req wsRequest
+		if err := json.Unmarshal(message, &req); err != nil {
+			ws.reply("", nil, model.ErrInvalidRequest.WithMessage("message must be a JSON object"))
+			continue
+		}
+
+		if req.Type == wsAuth {
+			data, err := ws.authenticate(req)
+			ws.reply(req.ID, data, err)
+			continue
+		}
+
+		// Commands are only run while the token or API key is still valid. Expired
+		// connections are closed by keepAlive once the grace period for an auth
+		// message has passed.
+		if ws.expired() {
+			ws.reply(req.ID, nil, model.ErrUnauthorized.WithMessage("credentials have expired"))
+			continue
+		}
+		if err := ws.checkCredentials(); err != nil {
+			ws.reply(req.ID, nil, err)
+			ws.closeUnauthorized(err)
+			return
+		}
+
+		if req.Type == wsSubscribe {
+			ws.subscribe(req)
+			continue
+		}
+		data, err := ws.handle(req)
+		ws.reply(req.ID, data, err)
+	}
+}
+
+// reply acknowledges a client message, or reports why it failed
+func (ws *wsConn) reply(id string, data interface{}, err error) {
+	if err != nil {
+		ws.send(wsResponse{ID: id, Type: wsError, Error: ws.errorBody(err)})
+		return
+	}
+	ws.send(wsResponse{ID: id, Type: wsAck, Data: data})
+}
+
+// keepAlive pings the client so dead connections are noticed, and closes the
+// connection when the server shuts down, when its API key expires, when its access
+// token expires and no fresh one arrives within the grace period, or when a ping finds
+// that its credentials have been revoked
+func (ws *wsConn) keepAlive(done <-chan struct{}) {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	var expired <-chan time.Time
+	expiry := time.NewTimer(time.Until(ws.expiry()))
+	defer expiry.Stop()
+	if !ws.expiry().IsZero() {
+		expired = expiry.C
+	}
+	grace := false
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ws.h.hub.Done():
+			ws.close(websocket.CloseGoingAway, "server shutting down")
+			return
+		case <-ws.renewed:
+			expiry.Reset(time.Until(ws.expiry()))
+			expired, grace = expiry.C, false
+		case <-expired:
+			if grace || ws.apiKey != nil {
+				ws.closeUnauthorized(model.ErrUnauthorized.WithMessage("credentials have expired"))
+				return
+			}
+			// Give the client time to send a fresh access token
+			grace = true
+			expiry.Reset(ws.h.wsAuthGrace)
+			if err := ws.send(wsResponse{Type: wsAuthRequired, Data: gin.H{"grace_period": ws.h.wsAuthGrace.Seconds()}}); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := ws.checkCredentials(); err != nil {
+				ws.closeUnauthorized(err)
+				return
+			}
+			if err := ws.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// expiry returns when the connection's credentials expire, or zero if they do not
+func (ws *wsConn) expiry() time.Time {
+	ws.authMu.Lock()
+	defer ws.authMu.Unlock()
+	return ws.expiresAt
+}
+
+// expired reports whether the connection's credentials have expired
+func (ws *wsConn) expired() bool {
+	expiresAt := ws.expiry()
+	return !expiresAt.IsZero() && !time.Now().Before(expiresAt)
+}
+
+// checkCredentials reports whether the token or API key of the connection has been
+// revoked
+func (ws *wsConn) checkCredentials() error {
+	ws.authMu.Lock()
+	claims := ws.claims
+	ws.authMu.Unlock()
+
+	ctx := ws.c.Request.Context()
+	switch {
+	case claims != nil:
+		return ws.h.svc.CheckAccessToken(ctx, claims)
+	case ws.apiKey != nil:
+		return ws.h.svc.CheckAPIKey(ctx, ws.apiKey)
+	}
+	return nil
+}
+
+// authenticate replaces the access token of the connection with a fresh one, which
+// must belong to the same user and role, and extends the connection to its expiry.
+// Its permissions apply to the commands that follow.
+func (ws *wsConn) authenticate(req wsRequest) (interface{}, error) {
+	var data wsAuthRequest
+	if err := bindData(req, &data); err != nil {
+		return nil, err
+	}
+
+	ws.authMu.Lock()
+	current := ws.claims
+	ws.authMu.Unlock()
+	if current == nil {
+		return nil, model.ErrInvalidRequest.WithMessage("only connections opened with an access token can re-authenticate")
+	}
+
+	claims, err := model.ValidateToken(data.Token)
+	if err != nil || claims.ExpiresAt == nil {
+		return nil, model.ErrUnauthorized.WithMessage("invalid token")
+	}
+	if err := ws.h.svc.CheckAccessToken(ws.c.Request.Context(), claims); err != nil {
+		return nil, err
+	}
+	if claims.UserID != current.UserID || claims.Role != current.Role {
+		return nil, model.ErrForbidden.WithMessage("token must belong to the user and role the connection was opened with")
+	}
+	// Subscriptions were filtered with the connection's access, so it cannot change
+	user, err := middleware.CurrentUser(ws.c)
+	if err != nil {
+		return nil, err
+	}
+	access, err := ws.h.svc.SessionAccess(ws.c.Request.Context(), user, claims.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if access != ws.access {
+		return nil, model.ErrForbidden.WithMessage("token must grant access to the sessions the connection was opened with")
+	}
+
+	ws.authMu.Lock()
+	ws.claims = claims
+	ws.expiresAt = claims.ExpiresAt.Time
+	ws.authMu.Unlock()
+	ws.c.Set("claims", claims)
+	ws.c.Set("permissions", claims.Permissions)
+
+	select {
+	case ws.renewed <- struct{}{}:
+	default:
+	}
+	return gin.H{"expires_at": claims.ExpiresAt.Time}, nil
+}
+
+// closeUnauthorized closes a connection whose credentials are no longer valid. Errors
+// checking them, such as a database outage, close it too, since the client can
+// reconnect once they pass again.
+func (ws *wsConn) closeUnauthorized(err error) {
+	reason := err.Error()
+	if model.ErrorCode(err) != model.ErrUnauthorized.Code {
+		log.Printf("request %s: websocket credential check failed: %v", ws.c.GetString("requestID"), err)
+		reason = "credentials could not be checked"
+	}
+	ws.close(websocket.ClosePolicyViolation, reason)
+}
+
+// handle runs a client message and returns the data of its ack
+func (ws *wsConn) handle(req wsRequest) (interface{}, error) {
+	ctx := ws.c.Request.Context()
+
+	if permission, ok := wsPermissions[req.Type]; ok {
+		if err := middleware.CheckPermission(ws.c, permission); err != nil {
+			return nil, err
+		}
+	}
+
+	switch req.Type {
+	case wsUnsubscribe:
+		var data wsUnsubscribeRequest
+		if err := bindData(req, &data); err != nil {
+			return nil, err
+		}
+		if !ws.unsubscribe(data.SubscriptionID) {
+			return nil, model.ErrNotFound.WithMessage("subscription not found")
+		}
+		return gin.H{"subscription_id": data.SubscriptionID}, nil
+
+	case wsStartSession:
+		var data model.StartSessionRequest
+		if err := bindData(req, &data); err != nil {
+			return nil, err
+		}
+		session, created, err := ws.h.svc.StartSession(ctx, ws.access, data)
+		if err != nil {
+			return nil, err
+		}
+		return gin.H{"session": session, "created": created}, nil
+
+	case wsLogEvent:
+		var data model.LogEventRequest
+		if err := bindSessionData(req, &data); err != nil {
+			return nil, err
+		}
+		event, err := ws.h.svc.LogEvent(ctx, ws.access, req.SessionID, data)
+		if err != nil {
+			return nil, err
+		}
+		return gin.H{"event": event}, nil
+
+	case wsEndSession:
+		var data model.EndSessionRequest
+		if err := bindSessionData(req, &data); err != nil {
+			return nil, err
+		}
+		session, err := ws.h.svc.EndSession(ctx, ws.access, req.SessionID, data)
+		if err != nil {
+			return nil, err
+		}
+		return gin.H{"session": session}, nil
+
+	case wsTransitionSession:
+		var data model.TransitionSessionRequest
+		if err := bindSessionData(req, &data); err != nil {
+			return nil, err
+		}
+		session, err := ws.h.svc.TransitionSession(ctx, ws.access, req.SessionID, data)
+		if err != nil {
+			return nil, err
+		}
+		return gin.H{"session": session}, nil
+	}
+
+	return nil, model.ErrInvalidRequest.WithMessage(fmt.Sprintf("unknown message type %q", req.Type))
+}
+
+// bindData decodes and validates the data of a message like ShouldBindJSON does for a
+// request body
+func bindData(req wsRequest, obj interface{}) error {
+	data := req.Data
+	if len(data) == 0 {
+		data = []byte("{}")
+	}
+	if err := binding.JSON.BindBody(data, obj); err != nil {
+		return invalidRequest(err)
+	}
+	return nil
+}
+
+// bindSessionData binds the data of a message that addresses a session
+func bindSessionData(req wsRequest, obj interface{}) error {
+	if req.SessionID == "" {
+		return model.ErrInvalidRequest.WithMessage("session_id is required")
+	}
+	return bindData(req, obj)
+}
+
+// subscribe registers a subscription and acknowledges it with its ID before any of
+// its notifications are sent
+func (ws *wsConn) subscribe(req wsRequest) {
+	id, sub, replay, err := ws.register(req)
+	if err != nil {
+		ws.reply(req.ID, nil, err)
+		return
+	}
+	ws.reply(req.ID, gin.H{"subscription_id": id}, nil)
+	go ws.forward(id, sub, replay)
+}
+
+func (ws *wsConn) register(req wsRequest) (string, *stream.Subscription, []stream.Notification, error) {
+	var data wsSubscribeRequest
+	if err := bindData(req, &data); err != nil {
+		return "", nil, nil, err
+	}
+
+	filter := stream.Filter{Statuses: data.Status, Access: ws.access}
+	if data.CallerID != "" {
+		filter.CallerID = ws.h.svc.NormalizeIdentity(data.CallerID)
+	}
+	if data.CalleeID != "" {
+		filter.CalleeID = ws.h.svc.NormalizeIdentity(data.CalleeID)
+	}
+	for _, status := range filter.Statuses {
+		if !status.IsValid() {
+			return "", nil, nil, model.ErrInvalidRequest.WithMessage("invalid status value")
+		}
+	}
+	if data.SessionID != "" {
+		session, err := ws.h.svc.GetSession(ws.c.Request.Context(), ws.access, data.SessionID)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		filter.SessionID = session.ID.String()
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if len(ws.subs) >= wsMaxSubscriptions {
+		return "", nil, nil, model.ErrInvalidRequest.WithMessage(fmt.Sprintf("a connection may hold at most %d subscriptions", wsMaxSubscriptions))
+	}
+
+	sub, replay, err := ws.h.hub.Subscribe(filter, data.LastEventID)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	id := uuid.NewString()
+	ws.subs[id] = sub
+	return id, sub, replay, nil
+}
+
+// forward writes the notifications of a subscription until it ends. A subscription the
+// hub dropped because the client fell behind closes the connection; the client should
+// reconnect and resume from the last notification it received.
+func (ws *wsConn) forward(id string, sub *stream.Subscription, replay []stream.Notification) {
+	for _, n := range replay {
+		if err := ws.send(wsResponse{Type: wsNotification, SubscriptionID: id, Data: n}); err != nil {
+			return
+		}
+	}
+	for n := range sub.Notifications() {
+		if err := ws.send(wsResponse{Type: wsNotification, SubscriptionID: id, Data: n}); err != nil {
+			return
+		}
+	}
+
+	ws.mu.Lock()
+	_, active := ws.subs[id]
+	ws.mu.Unlock()
+	if active {
+		ws.close(websocket.CloseTryAgainLater, "subscriber too slow")
+	}
+}
+
+func (ws *wsConn) unsubscribe(id string) bool {
+	ws.mu.Lock()
+	sub, ok := ws.subs[id]
+	delete(ws.subs, id)
+	ws.mu.Unlock()
+
+	if ok {
+		sub.Close()
+	}
+	return ok
+}
+
+func (ws *wsConn) unsubscribeAll() {
+	ws.mu.Lock()
+	subs := ws.subs
+	ws.subs = make(map[string]*stream.Subscription)
+	ws.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.Close()
+	}
+}
+
+func (ws *wsConn) send(msg wsResponse) error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+
+	ws.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return ws.conn.WriteJSON(msg)
+}
+
+// close sends a close frame and closes the connection, which ends the read loop
+func (ws *wsConn) close(code int, reason string) {
+	ws.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(wsWriteWait))
+	ws.conn.Close()
+}
+
+// errorBody classifies err into the error envelope used by the HTTP API
+func (ws *wsConn) errorBody(err error) *middleware.ErrorBody {
+	body := middleware.NewErrorBody(ws.c, err)
+	if body.Status == http.StatusInternalServerError {
+		log.Printf("request %s: websocket message failed: %v", body.RequestID, err)
+	}
+	return &body
+}
@@ -0,0 +1,226 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/vasu74/Call_Session_Management/internal"
+	"github.com/vasu74/Call_Session_Management/internal/handler"
+	"github.com/vasu74/Call_Session_Management/internal/middleware"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+)
+
+// dialWebSocket opens /api/ws on server with the token and extra header pairs
+func dialWebSocket(t *testing.T, server *httptest.Server, token string, header ...string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	h := http.Header{"Authorization": {"Bearer " + token}}
+	for i := 0; i+1 < len(header); i += 2 {
+		h.Set(header[i], header[i+1])
+	}
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/ws", h)
+	if conn != nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, resp, err
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	token := s.login("agent@example.com")
+
+	router := gin.New()
+	router.Use(middleware.RequestID(), middleware.ErrorHandler())
+	internal.Routes(router, s.svc, s.hub, handler.WithWebSocketOrigins("https://app.example.com/"))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		origin string
+		want   bool
+	}{
+		{name: "no origin", want: true},
+		{name: "own origin", origin: server.URL, want: true},
+		{name: "allowed origin", origin: "https://app.example.com", want: true},
+		{name: "allowed origin in other case", origin: "https://APP.example.com", want: true},
+		{name: "other origin", origin: "https://evil.example.com"},
+		{name: "other scheme", origin: "http://app.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header []string
+			if tt.origin != "" {
+				header = []string{"Origin", tt.origin}
+			}
+			_, resp, err := dialWebSocket(t, server, token, header...)
+			if tt.want && err != nil {
+				t.Fatalf("upgrade failed: %v", err)
+			}
+			if !tt.want && (err == nil || resp.StatusCode != http.StatusForbidden) {
+				t.Fatalf("upgrade from %s was not rejected: %v", tt.origin, err)
+			}
+		})
+	}
+}
+
+func TestWebSocketClosesOnLogout(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	token := s.login("agent@example.com")
+	server := httptest.NewServer(s.router)
+	defer server.Close()
+
+	conn, _, err := dialWebSocket(t, server, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	command := gin.H{"id": "1", "type": "start_session", "data": gin.H{"caller_id": "+14155550100", "callee_id": "+14155550199"}}
+	var reply struct {
+		ID    string                `json:"id"`
+		Type  string                `json:"type"`
+		Error *middleware.ErrorBody `json:"error"`
+	}
+	if err := conn.WriteJSON(command); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.ReadJSON(&reply); err != nil || reply.Type != "ack" {
+		t.Fatalf("reply %+v, %v, want an ack", reply, err)
+	}
+
+	expect(t, http.StatusNoContent, s.do(token, http.MethodPost, "/auth/logout", nil), nil)
+
+	command["id"] = "2"
+	if err := conn.WriteJSON(command); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.ReadJSON(&reply); err != nil || reply.ID != "2" || reply.Type != "error" || reply.Error.Code != model.ErrUnauthorized.Code {
+		t.Fatalf("reply %+v, %v, want an unauthorized error", reply, err)
+	}
+	assertClosed(t, conn, websocket.ClosePolicyViolation)
+}
+
+// authGraceServer serves s with a short grace period for expired WebSocket tokens
+func authGraceServer(s *testServer) *httptest.Server {
+	router := gin.New()
+	router.Use(middleware.RequestID(), middleware.ErrorHandler())
+	internal.Routes(router, s.svc, s.hub, handler.WithWebSocketAuthGrace(500*time.Millisecond))
+	return httptest.NewServer(router)
+}
+
+// wsMessage is a server message as read by the tests
+type wsMessage struct {
+	ID    string                 `json:"id"`
+	Type  string                 `json:"type"`
+	Data  map[string]interface{} `json:"data"`
+	Error *middleware.ErrorBody  `json:"error"`
+}
+
+func TestWebSocketClosesWhenTokenExpires(t *testing.T) {
+	s := newTestServer(t, service.WithTokenTTLs(2*time.Second, time.Hour))
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	token := s.login("agent@example.com")
+	server := authGraceServer(s)
+	defer server.Close()
+
+	conn, _, err := dialWebSocket(t, server, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != "auth_required" || msg.Data["grace_period"] != 0.5 {
+		t.Fatalf("message %+v, %v, want auth_required", msg, err)
+	}
+	assertClosed(t, conn, websocket.ClosePolicyViolation)
+}
+
+func TestWebSocketReauthenticates(t *testing.T) {
+	s := newTestServer(t, service.WithTokenTTLs(2*time.Second, time.Hour))
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	s.createUser("other@example.com", model.UserRoleUser, "")
+	token := s.login("agent@example.com")
+	server := authGraceServer(s)
+	defer server.Close()
+
+	conn, _, err := dialWebSocket(t, server, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	send := func(msg gin.H) wsMessage {
+		t.Helper()
+		if err := conn.WriteJSON(msg); err != nil {
+			t.Fatal(err)
+		}
+		var reply wsMessage
+		if err := conn.ReadJSON(&reply); err != nil {
+			t.Fatal(err)
+		}
+		return reply
+	}
+
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != "auth_required" {
+		t.Fatalf("message %+v, %v, want auth_required", msg, err)
+	}
+
+	// Commands are refused until a fresh token arrives, without closing the connection
+	command := gin.H{"id": "1", "type": "start_session", "data": gin.H{"caller_id": "+14155550100", "callee_id": "+14155550199"}}
+	if reply := send(command); reply.Type != "error" || reply.Error.Code != model.ErrUnauthorized.Code {
+		t.Fatalf("reply %+v, want an unauthorized error", reply)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		code  string
+	}{
+		{name: "missing token", code: model.ErrInvalidRequest.Code},
+		{name: "invalid token", token: "not-a-token", code: model.ErrUnauthorized.Code},
+		{name: "other user", token: s.login("other@example.com"), code: model.ErrForbidden.Code},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if reply := send(gin.H{"id": "auth", "type": "auth", "data": gin.H{"token": tt.token}}); reply.Type != "error" || reply.Error.Code != tt.code {
+				t.Fatalf("reply %+v, want a %s error", reply, tt.code)
+			}
+		})
+	}
+
+	if reply := send(gin.H{"id": "2", "type": "auth", "data": gin.H{"token": s.login("agent@example.com")}}); reply.ID != "2" || reply.Type != "ack" || reply.Data["expires_at"] == nil {
+		t.Fatalf("reply %+v, want an ack with the new expiry", reply)
+	}
+	command["id"] = "3"
+	if reply := send(command); reply.Type != "ack" {
+		t.Fatalf("reply %+v, want an ack", reply)
+	}
+
+	// The grace period of the first token passes without closing the connection
+	time.Sleep(600 * time.Millisecond)
+	command["id"] = "4"
+	if reply := send(command); reply.Type != "ack" {
+		t.Fatalf("reply %+v after the grace period, want an ack", reply)
+	}
+}
+
+// assertClosed reads from conn until the server closes it with the given code
+func assertClosed(t *testing.T, conn *websocket.Conn, code int) {
+	t.Helper()
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		if !websocket.IsCloseError(err, code) {
+			t.Fatalf("connection ended with %v, want close code %d", err, code)
+		}
+		return
+	}
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vasu74/Call_Session_Management/internal/middleware"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+func (h *Handler) CreateWebhookHandler(c *gin.Context) {
+	var req model.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	webhook, err := h.svc.CreateWebhook(c.Request.Context(), access, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Webhook created successfully",
+		"webhook": webhook,
+	})
+}
+
+func (h *Handler) ListWebhooksHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	webhooks, err := h.svc.ListWebhooks(c.Request.Context(), access)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": webhooks})
+}
+
+func (h *Handler) GetWebhookHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	webhook, err := h.svc.GetWebhook(c.Request.Context(), access, c.Param("webhookId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+func (h *Handler) UpdateWebhookHandler(c *gin.Context) {
+	var req model.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(invalidRequest(err))
+		return
+	}
+
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	webhook, err := h.svc.UpdateWebhook(c.Request.Context(), access, c.Param("webhookId"), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook updated successfully",
+		"webhook": webhook,
+	})
+}
+
+func (h *Handler) DeleteWebhookHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.svc.DeleteWebhook(c.Request.Context(), access, c.Param("webhookId")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) ListWebhookDeliveriesHandler(c *gin.Context) {
+	filter := model.WebhookDeliveryFilter{Limit: 50}
+
+	if status := c.Query("status"); status != "" {
+		filter.Status = model.WebhookDeliveryStatus(status)
+		if !filter.Status.IsValid() {
+			c.Error(model.ErrInvalidQuery.WithMessage("invalid status value"))
+			return
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if l, err := strconv.Atoi(limit); err == nil && l > 0 {
+			filter.Limit = l
+		}
+	}
+	if offset := c.Query("offset"); offset != "" {
+		if o, err := strconv.Atoi(offset); err == nil && o >= 0 {
+			filter.Offset = o
+		}
+	}
+
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	deliveries, err := h.svc.ListWebhookDeliveries(c.Request.Context(), access, c.Param("webhookId"), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"limit":      filter.Limit,
+		"offset":     filter.Offset,
+		"deliveries": deliveries,
+	})
+}
+
+func (h *Handler) GetWebhookDeliveryHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	delivery, err := h.svc.GetWebhookDelivery(c.Request.Context(), access, c.Param("webhookId"), c.Param("deliveryId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+func (h *Handler) RetryWebhookDeliveryHandler(c *gin.Context) {
+	access, err := middleware.SessionAccess(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	delivery, err := h.svc.RetryWebhookDelivery(c.Request.Context(), access, c.Param("webhookId"), c.Param("deliveryId"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Webhook delivery scheduled for retry",
+		"delivery": delivery,
+	})
+}
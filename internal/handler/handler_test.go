@@ -0,0 +1,144 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal"
+	"github.com/vasu74/Call_Session_Management/internal/middleware"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+	"github.com/vasu74/Call_Session_Management/internal/store/memory"
+	"github.com/vasu74/Call_Session_Management/internal/stream"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "secret123"
+
+// testServer serves the API routes from an in-memory store
+type testServer struct {
+	t      *testing.T
+	router *gin.Engine
+	store  *memory.Store
+	svc    *service.Service
+	hub    *stream.Hub
+}
+
+func newTestServer(t *testing.T, opts ...service.Option) *testServer {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "jwt-secret")
+	t.Setenv("CURSOR_SECRET", "cursor-secret")
+	gin.SetMode(gin.TestMode)
+
+	st := memory.New()
+	svc := service.New(st, opts...)
+	hub := stream.NewHub(svc, stream.Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go hub.Run(ctx, st)
+
+	router := gin.New()
+	router.Use(middleware.RequestID(), middleware.ErrorHandler())
+	internal.Routes(router, svc, hub)
+	return &testServer{t: t, router: router, store: st, svc: svc, hub: hub}
+}
+
+// createUser stores a user of the default organization with the test password
+func (s *testServer) createUser(email string, role model.UserRole, team string) *model.User {
+	s.t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	now := time.Now()
+	user := &model.User{ID: uuid.New(), OrgID: model.DefaultOrganizationID, Email: email, Password: string(hash), Role: role, Team: team, CreatedAt: now, UpdatedAt: now}
+	if err := s.store.CreateUser(context.Background(), user); err != nil {
+		s.t.Fatal(err)
+	}
+	return user
+}
+
+// login returns an access token of the user with the given email
+func (s *testServer) login(email string) string {
+	s.t.Helper()
+	var out model.LoginResponse
+	expect(s.t, http.StatusOK, s.do("", http.MethodPost, "/auth/login", gin.H{"email": email, "password": testPassword}), &out)
+	return out.Token
+}
+
+// do serves a request with an optional bearer token, body and header pairs. Bodies other
+// than an io.Reader are sent as JSON.
+func (s *testServer) do(token, method, path string, body interface{}, header ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	r, ok := body.(io.Reader)
+	if !ok {
+		var buf bytes.Buffer
+		if body != nil {
+			if err := json.NewEncoder(&buf).Encode(body); err != nil {
+				s.t.Fatal(err)
+			}
+		}
+		r = &buf
+	}
+	req := httptest.NewRequest(method, path, r)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(deadlineRecorder{w}, req)
+	return w
+}
+
+// deadlineRecorder accepts connection deadlines like the writer of a real server
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (deadlineRecorder) SetReadDeadline(time.Time) error  { return nil }
+func (deadlineRecorder) SetWriteDeadline(time.Time) error { return nil }
+
+// expect checks the status of a response and decodes its body into out, if given
+func expect(t *testing.T, status int, w *httptest.ResponseRecorder, out interface{}) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status %d, want %d: %s", w.Code, status, w.Body.String())
+	}
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("decoding %s: %v", w.Body.String(), err)
+		}
+	}
+}
+
+// expectError checks the status and code of an error envelope
+func expectError(t *testing.T, status int, code string, w *httptest.ResponseRecorder) middleware.ErrorResponse {
+	t.Helper()
+	var out middleware.ErrorResponse
+	expect(t, status, w, &out)
+	if out.Error.Code != code || out.Error.Status != status {
+		t.Fatalf("error %+v, want code %q", out.Error, code)
+	}
+	return out
+}
+
+// startSession starts a session and returns it
+func (s *testServer) startSession(token string, body gin.H) model.Session {
+	s.t.Helper()
+	var out struct {
+		Session model.Session `json:"session"`
+	}
+	expect(s.t, http.StatusCreated, s.do(token, http.MethodPost, "/api/sessions/start", body), &out)
+	return out.Session
+}
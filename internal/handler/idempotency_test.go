@@ -0,0 +1,59 @@
+package handler_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vasu74/Call_Session_Management/internal/middleware"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+func TestIdempotentStartSession(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("agent@example.com", model.UserRoleUser, "")
+	s.createUser("other@example.com", model.UserRoleUser, "")
+	token := s.login("agent@example.com")
+	start := func(token, key string, body gin.H) (*http.Response, model.Session) {
+		t.Helper()
+		w := s.do(token, http.MethodPost, "/api/sessions/start", body, middleware.IdempotencyKeyHeader, key)
+		var out struct {
+			Session model.Session `json:"session"`
+		}
+		expect(t, http.StatusCreated, w, &out)
+		return w.Result(), out.Session
+	}
+	body := gin.H{"caller_id": "+14155550100", "callee_id": "+14155550199"}
+
+	first, session := start(token, "key-1", body)
+	if first.Header.Get(middleware.IdempotentReplayedHeader) != "" {
+		t.Fatal("first request was marked as replayed")
+	}
+
+	// Key order and whitespace do not make a different request
+	replay, replayed := start(token, "key-1", gin.H{"callee_id": "+14155550199", "caller_id": "+14155550100"})
+	if replay.Header.Get(middleware.IdempotentReplayedHeader) != "true" || replayed.ID != session.ID {
+		t.Fatalf("retry started session %s, want a replay of %s", replayed.ID, session.ID)
+	}
+
+	var list model.SessionListResponse
+	expect(t, http.StatusOK, s.do(token, http.MethodGet, "/api/sessions", nil), &list)
+	if len(list.Sessions) != 1 {
+		t.Fatalf("retries started %d sessions, want 1", len(list.Sessions))
+	}
+
+	mismatch := s.do(token, http.MethodPost, "/api/sessions/start", gin.H{"caller_id": "+14155550101", "callee_id": "+14155550199"}, middleware.IdempotencyKeyHeader, "key-1")
+	expectError(t, http.StatusUnprocessableEntity, model.ErrIdempotencyKeyMismatch.Code, mismatch)
+
+	invalid := s.do(token, http.MethodPost, "/api/sessions/start", body, middleware.IdempotencyKeyHeader, "key with spaces")
+	expectError(t, http.StatusBadRequest, model.ErrInvalidRequest.Code, invalid)
+
+	// Keys are scoped to the user, so another user's key does not replay
+	if _, other := start(s.login("other@example.com"), "key-1", body); other.ID == session.ID {
+		t.Fatal("another user's request replayed the first user's response")
+	}
+
+	// Failed requests are not stored and can be retried with the same key
+	expectError(t, http.StatusBadRequest, model.ErrInvalidRequest.Code, s.do(token, http.MethodPost, "/api/sessions/start", gin.H{"caller_id": "+14155550100"}, middleware.IdempotencyKeyHeader, "key-2"))
+	start(token, "key-2", body)
+}
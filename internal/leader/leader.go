@@ -0,0 +1,70 @@
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// Lock elects a single leader among replicas with a session-level Postgres advisory
+// lock. The lock is held on a dedicated connection, so leadership is lost automatically
+// if the process dies or the connection drops.
+type Lock struct {
+	db  *sql.DB
+	key int64
+
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+// NewLock creates a leader lock for the given advisory lock key
+func NewLock(db *sql.DB, key int64) *Lock {
+	return &Lock{db: db, key: key}
+}
+
+// Acquire reports whether this replica is the leader, taking the lock if it is free.
+// It is cheap to call on every tick: once held, it only checks the connection is alive.
+func (l *Lock) Acquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn != nil {
+		if err := l.conn.PingContext(ctx); err == nil {
+			return true, nil
+		}
+		// The connection is gone and the lock with it
+		l.conn.Close()
+		l.conn = nil
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&acquired); err != nil {
+		conn.Close()
+		return false, err
+	}
+	if !acquired {
+		conn.Close()
+		return false, nil
+	}
+
+	l.conn = conn
+	return true, nil
+}
+
+// Release gives up leadership if it is held
+func (l *Lock) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return
+	}
+	l.conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", l.key)
+	l.conn.Close()
+	l.conn = nil
+}
@@ -1,45 +1,72 @@
 package middleware
 
 import (
-	"net/http"
+	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
 )
 
-// AuthMiddleware verifies the JWT token and sets the user in the context
-func AuthMiddleware() gin.HandlerFunc {
+// APIKeyHeader carries the API key of machine-to-machine clients
+const APIKeyHeader = "X-API-Key"
+
+// AuthMiddleware verifies the JWT token, rejects revoked tokens and sets the user, the
+// sessions visible to them, and the token's claims and permissions in the context. API
+// keys are accepted in an X-API-Key header or as "Authorization: ApiKey <key>"; they set
+// the key's service account as the user, and the key and its scopes as the permissions
+// in the context instead of claims.
+func AuthMiddleware(svc *service.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := c.GetHeader(APIKeyHeader); key != "" {
+			authenticateAPIKey(c, svc, key)
+			return
+		}
+
 		// Get Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header is required"})
-			c.Abort()
+			abortWithError(c, model.ErrUnauthorized.WithMessage("authorization header is required"))
 			return
 		}
 
-		// Check Bearer prefix
+		// Check Bearer or ApiKey prefix
 		parts := strings.Split(authHeader, " ")
+		if len(parts) == 2 && parts[0] == "ApiKey" {
+			authenticateAPIKey(c, svc, parts[1])
+			return
+		}
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header format"})
-			c.Abort()
+			abortWithError(c, model.ErrUnauthorized.WithMessage("invalid authorization header format"))
 			return
 		}
 
 		// Validate token
 		claims, err := model.ValidateToken(parts[1])
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-			c.Abort()
+			abortWithError(c, model.ErrUnauthorized.WithMessage("invalid token"))
+			return
+		}
+		if err := svc.CheckAccessToken(c.Request.Context(), claims); err != nil {
+			abortWithError(c, err)
 			return
 		}
 
 		// Get user from database
-		user, err := model.GetUserByID(claims.UserID)
+		user, err := svc.GetUser(c.Request.Context(), claims.UserID)
+		if err != nil {
+			if errors.Is(err, model.ErrUserNotFound) {
+				err = model.ErrUnauthorized.WithMessage("user not found")
+			}
+			abortWithError(c, err)
+			return
+		}
+
+		access, err := svc.SessionAccess(c.Request.Context(), user, claims.Permissions)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
-			c.Abort()
+			abortWithError(c, err)
 			return
 		}
 
@@ -47,34 +74,123 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Set("user", user)
 		c.Set("userID", user.ID.String())
 		c.Set("userRole", user.Role)
+		c.Set("claims", claims)
+		c.Set("permissions", claims.Permissions)
+		c.Set("sessionAccess", access)
 
 		c.Next()
 	}
 }
 
+func authenticateAPIKey(c *gin.Context, svc *service.Service, raw string) {
+	key, account, err := svc.AuthenticateAPIKey(c.Request.Context(), raw, c.ClientIP())
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	access, err := svc.SessionAccess(c.Request.Context(), account, key.Scopes)
+	if err != nil {
+		abortWithError(c, err)
+		return
+	}
+
+	c.Set("user", account)
+	c.Set("userID", account.ID.String())
+	c.Set("userRole", account.Role)
+	c.Set("apiKey", key)
+	c.Set("permissions", key.Scopes)
+	c.Set("sessionAccess", access)
+
+	c.Next()
+}
+
+// RequirePermission middleware rejects requests whose token or API key does not grant
+// the permission
+func RequirePermission(permission model.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := CheckPermission(c, permission); err != nil {
+			abortWithError(c, err)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// CheckPermission returns an error if the request's token or API key does not grant the
+// permission
+func CheckPermission(c *gin.Context, permission model.Permission) error {
+	permissions, err := Permissions(c)
+	if err != nil {
+		return err
+	}
+	if !permissions.Has(permission) {
+		return model.ErrForbidden.WithMessage(fmt.Sprintf("insufficient permissions: required permission %s", permission))
+	}
+	return nil
+}
+
+// Permissions returns the permissions granted to the request by its token or API key
+func Permissions(c *gin.Context) (model.Permissions, error) {
+	value, exists := c.Get("permissions")
+	if !exists {
+		return nil, model.ErrUnauthorized.WithMessage("permissions not found in context")
+	}
+	permissions, ok := value.(model.Permissions)
+	if !ok {
+		return nil, errors.New("invalid permissions type in context")
+	}
+	return permissions, nil
+}
+
+// CurrentUser returns the authenticated user, or the service account of the API key
+func CurrentUser(c *gin.Context) (*model.User, error) {
+	value, exists := c.Get("user")
+	if !exists {
+		return nil, model.ErrUnauthorized.WithMessage("user not found in context")
+	}
+	user, ok := value.(*model.User)
+	if !ok {
+		return nil, errors.New("invalid user type in context")
+	}
+	return user, nil
+}
+
+// SessionAccess returns the sessions visible to the authenticated user through their
+// role and the permissions of their token or API key
+func SessionAccess(c *gin.Context) (model.SessionAccess, error) {
+	value, exists := c.Get("sessionAccess")
+	if !exists {
+		return model.SessionAccess{}, model.ErrUnauthorized.WithMessage("session access not found in context")
+	}
+	access, ok := value.(model.SessionAccess)
+	if !ok {
+		return model.SessionAccess{}, errors.New("invalid session access type in context")
+	}
+	return access, nil
+}
+
 // RequireRole middleware checks if the authenticated user has the required role
 func RequireRole(requiredRole model.UserRole) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get user from context
 		user, exists := c.Get("user")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
-			c.Abort()
+			abortWithError(c, model.ErrUnauthorized.WithMessage("user not found in context"))
 			return
 		}
 
 		// Type assert user
 		u, ok := user.(*model.User)
 		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type in context"})
-			c.Abort()
+			abortWithError(c, errors.New("invalid user type in context"))
 			return
 		}
 
 		// Validate role
 		if err := u.ValidateRole(requiredRole); err != nil {
-			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-			c.Abort()
+			abortWithError(c, err)
 			return
 		}
 
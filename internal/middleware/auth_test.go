@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// serve runs a request through middleware after setting the context values
+func serve(t *testing.T, values map[string]interface{}, handlers ...gin.HandlerFunc) (int, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorHandler(), func(c *gin.Context) {
+		for key, value := range values {
+			c.Set(key, value)
+		}
+	})
+	router.GET("/", append(handlers, func(c *gin.Context) { c.Status(http.StatusNoContent) })...)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code == http.StatusNoContent {
+		return w.Code, ""
+	}
+	var out ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, out.Error.Code
+}
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions interface{}
+		status      int
+		code        string
+	}{
+		{name: "granted", permissions: model.Permissions{model.PermissionSessionsRead, model.PermissionUsersManage}, status: http.StatusNoContent},
+		{name: "not granted", permissions: model.Permissions{model.PermissionSessionsRead}, status: http.StatusForbidden, code: model.ErrForbidden.Code},
+		{name: "scoped down to nothing", permissions: model.Permissions{}, status: http.StatusForbidden, code: model.ErrForbidden.Code},
+		{name: "unauthenticated", status: http.StatusUnauthorized, code: model.ErrUnauthorized.Code},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := map[string]interface{}{}
+			if tt.permissions != nil {
+				values["permissions"] = tt.permissions
+			}
+			status, code := serve(t, values, RequirePermission(model.PermissionUsersManage))
+			if status != tt.status || code != tt.code {
+				t.Errorf("response %d %s, want %d %s", status, code, tt.status, tt.code)
+			}
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	user := func(role model.UserRole) *model.User {
+		return &model.User{ID: uuid.New(), Role: role}
+	}
+	tests := []struct {
+		name   string
+		user   *model.User
+		status int
+	}{
+		{name: "same role", user: user(model.UserRoleSupervisor), status: http.StatusNoContent},
+		{name: "admin", user: user(model.UserRoleAdmin), status: http.StatusNoContent},
+		{name: "other role", user: user(model.UserRoleUser), status: http.StatusForbidden},
+		{name: "unauthenticated", status: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := map[string]interface{}{}
+			if tt.user != nil {
+				values["user"] = tt.user
+			}
+			if status, _ := serve(t, values, RequireRole(model.UserRoleSupervisor)); status != tt.status {
+				t.Errorf("status %d, want %d", status, tt.status)
+			}
+		})
+	}
+}
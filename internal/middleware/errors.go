@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// errorStatus maps domain error codes to HTTP status codes
+var errorStatus = map[string]int{
+	model.ErrInvalidRequest.Code:          http.StatusBadRequest,
+	model.ErrInvalidID.Code:               http.StatusBadRequest,
+	model.ErrInvalidQuery.Code:            http.StatusBadRequest,
+	model.ErrConflict.Code:                http.StatusConflict,
+	model.ErrNotFound.Code:                http.StatusNotFound,
+	model.ErrUnauthorized.Code:            http.StatusUnauthorized,
+	model.ErrInvalidCredentials.Code:      http.StatusUnauthorized,
+	model.ErrForbidden.Code:               http.StatusForbidden,
+	model.ErrInvalidRefreshToken.Code:     http.StatusUnauthorized,
+	model.ErrRefreshTokenReused.Code:      http.StatusUnauthorized,
+	model.ErrUserNotFound.Code:            http.StatusNotFound,
+	model.ErrUserAlreadyExists.Code:       http.StatusConflict,
+	model.ErrRoleNotFound.Code:            http.StatusNotFound,
+	model.ErrRoleAlreadyExists.Code:       http.StatusConflict,
+	model.ErrRoleInUse.Code:               http.StatusConflict,
+	model.ErrOrganizationNotFound.Code:    http.StatusNotFound,
+	model.ErrAPIKeyNotFound.Code:          http.StatusNotFound,
+	model.ErrSessionNotFound.Code:         http.StatusNotFound,
+	model.ErrEventNotFound.Code:           http.StatusNotFound,
+	model.ErrSessionAlreadyEnded.Code:     http.StatusConflict,
+	model.ErrInvalidTransition.Code:       http.StatusConflict,
+	model.ErrExternalIDConflict.Code:      http.StatusConflict,
+	model.ErrIdempotencyKeyMismatch.Code:  http.StatusUnprocessableEntity,
+	model.ErrIdempotencyKeyInUse.Code:     http.StatusConflict,
+	model.ErrWebhookNotFound.Code:         http.StatusNotFound,
+	model.ErrWebhookDeliveryNotFound.Code: http.StatusNotFound,
+	model.ErrExportJobNotFound.Code:       http.StatusNotFound,
+	model.ErrExportNotReady.Code:          http.StatusConflict,
+	model.ErrInvalidTimeRange.Code:        http.StatusBadRequest,
+	model.ErrEventTimeOutOfRange.Code:     http.StatusBadRequest,
+	model.ErrEventTypeNotAllowed.Code:     http.StatusBadRequest,
+}
+
+// ErrorResponse is the JSON envelope rendered for every failed request
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes a failed request
+type ErrorBody struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Status    int         `json:"status"`
+	RequestID string      `json:"request_id,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+}
+
+// ErrorHandler renders the last error attached to the context with c.Error as an
+// ErrorResponse. Errors without a domain code are logged and reported as internal errors.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		body := NewErrorBody(c, err)
+		if body.Status == http.StatusInternalServerError {
+			log.Printf("request %s: %s %s failed: %v", body.RequestID, c.Request.Method, c.Request.URL.Path, err)
+		}
+
+		c.JSON(body.Status, ErrorResponse{Error: body})
+	}
+}
+
+// NewErrorBody classifies err into the error envelope fields
+func NewErrorBody(c *gin.Context, err error) ErrorBody {
+	body := ErrorBody{
+		Code:      model.ErrInternal.Code,
+		Message:   model.ErrInternal.Message,
+		Status:    http.StatusInternalServerError,
+		RequestID: c.GetString("requestID"),
+	}
+
+	code := model.ErrorCode(err)
+	if status, ok := errorStatus[code]; ok {
+		body.Code = code
+		body.Message = err.Error()
+		body.Status = status
+	}
+
+	var queryErr *model.QueryError
+	if errors.As(err, &queryErr) {
+		body.Details = queryErr
+	}
+
+	var importErr *model.ImportError
+	if errors.As(err, &importErr) {
+		body.Details = importErr.Result
+	}
+
+	return body
+}
+
+// abortWithError attaches err to the context and stops the handler chain
+func abortWithError(c *gin.Context, err error) {
+	c.Error(err)
+	c.Abort()
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+func TestErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		err         error
+		status      int
+		code        string
+		message     string
+		withDetails bool
+	}{
+		{name: "domain error", err: model.ErrSessionNotFound, status: http.StatusNotFound, code: "session_not_found", message: "session not found"},
+		{name: "specialised message", err: model.ErrInvalidRequest.WithMessage("caller_id is required"), status: http.StatusBadRequest, code: "invalid_request", message: "caller_id is required"},
+		{name: "wrapped with fmt", err: fmt.Errorf("ending session: %w", model.ErrSessionAlreadyEnded), status: http.StatusConflict, code: "session_already_ended", message: "ending session: session is already ended"},
+		{name: "wrapped driver error", err: model.WrapError(model.ErrConflict, errors.New("pq: duplicate key value")), status: http.StatusConflict, code: "conflict", message: "resource already exists"},
+		{name: "query error", err: &model.QueryError{Param: "sort", Value: "password", Reason: "unknown field"}, status: http.StatusBadRequest, code: "invalid_query", withDetails: true},
+		{name: "unknown error", err: errors.New("dial tcp: connection refused"), status: http.StatusInternalServerError, code: "internal_error", message: "internal server error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(RequestID(), ErrorHandler())
+			router.GET("/", func(c *gin.Context) { c.Error(tt.err) })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(RequestIDHeader, "req-1")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			var out ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tt.status || out.Error.Status != tt.status || out.Error.Code != tt.code {
+				t.Fatalf("response %d %+v, want %d %s", w.Code, out.Error, tt.status, tt.code)
+			}
+			if tt.message != "" && out.Error.Message != tt.message {
+				t.Errorf("message %q, want %q", out.Error.Message, tt.message)
+			}
+			if (out.Error.Details != nil) != tt.withDetails {
+				t.Errorf("details %v, want details: %v", out.Error.Details, tt.withDetails)
+			}
+			if out.Error.RequestID != "req-1" {
+				t.Errorf("request ID %q, want req-1", out.Error.RequestID)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerKeepsWrittenResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.GET("/", func(c *gin.Context) {
+		c.Error(model.ErrSessionNotFound)
+		c.String(http.StatusAccepted, "already written")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusAccepted || w.Body.String() != "already written" {
+		t.Fatalf("response %d %q, want the handler's response", w.Code, w.Body.String())
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/", func(c *gin.Context) { c.String(http.StatusOK, c.GetString("requestID")) })
+
+	tests := []struct {
+		header string
+		keep   bool
+	}{
+		{header: "abc-123", keep: true},
+		{header: "", keep: false},
+		{header: "bad id\n", keep: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(RequestIDHeader, tt.header)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			got := w.Header().Get(RequestIDHeader)
+			if got == "" || got != w.Body.String() {
+				t.Fatalf("echoed request ID %q, context request ID %q", got, w.Body.String())
+			}
+			if (got == tt.header) != tt.keep {
+				t.Errorf("request ID %q for header %q, keep: %v", got, tt.header, tt.keep)
+			}
+		})
+	}
+}
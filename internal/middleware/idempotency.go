@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+)
+
+const (
+	// IdempotencyKeyHeader carries the client chosen key identifying a request and its retries
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from a stored result
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+var validIdempotencyKey = regexp.MustCompile(`^[\x21-\x7e]{1,255}$`)
+
+// Idempotency makes mutating requests that carry an Idempotency-Key safe to retry. The
+// first successful response for a key is stored and replayed for later requests with
+// the same key and body; a different body with the same key is rejected. Failed
+// requests are not stored, so they can be retried with the same key. Keys are scoped
+// to the authenticated user, so this must run after AuthMiddleware.
+func Idempotency(svc *service.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || !isMutating(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if !validIdempotencyKey.MatchString(key) {
+			abortWithError(c, model.ErrInvalidRequest.WithMessage("Idempotency-Key must be 1 to 255 printable ASCII characters"))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortWithError(c, model.ErrInvalidRequest.WithMessage("failed to read request body"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		userID := c.GetString("userID")
+		record, err := svc.BeginIdempotentRequest(ctx, userID, key, requestHash(c.Request, body))
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+		if record != nil {
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(record.StatusCode, "application/json; charset=utf-8", record.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		// Hold the key for as long as the handler runs, however long that takes
+		stopRenewal := renewIdempotencyLock(ctx, svc, c.GetString("requestID"), userID, key)
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		stopRenewal()
+
+		// Store the outcome even if the client has gone away, so its retry is answered
+		ctx = context.WithoutCancel(ctx)
+		status := recorder.Status()
+		if recorder.Written() && status >= 200 && status < 300 {
+			err = svc.CompleteIdempotentRequest(ctx, userID, key, status, recorder.body.Bytes())
+		} else {
+			err = svc.AbandonIdempotentRequest(ctx, userID, key)
+		}
+		if err != nil {
+			log.Printf("request %s: failed to update idempotency key: %v", c.GetString("requestID"), err)
+		}
+	}
+}
+
+// renewIdempotencyLock renews the lock on key in the background until the returned
+// function is called. Renewal continues if the client goes away, since the handler may
+// still be running.
+func renewIdempotencyLock(ctx context.Context, svc *service.Service, requestID, userID, key string) (stop func()) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(svc.IdempotencyLockRenewal())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := svc.RenewIdempotentRequest(ctx, userID, key); err != nil && ctx.Err() == nil {
+					log.Printf("request %s: failed to renew idempotency key: %v", requestID, err)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// requestHash fingerprints the method, path and body of a request. JSON bodies are
+// compared by content, so retries that only differ in formatting or key order match.
+func requestHash(r *http.Request, body []byte) string {
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err == nil {
+		if canonical, err := json.Marshal(decoded); err == nil {
+			body = canonical
+		}
+	}
+
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the response body written by the handler
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Unwrap returns the wrapped writer, so http.ResponseController reaches the connection
+func (w *responseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
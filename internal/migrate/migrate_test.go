@@ -0,0 +1,97 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	file := func(contents string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(contents)} }
+
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		versions []int64
+		wantErr  string
+	}{
+		{
+			name: "ordered by version",
+			files: fstest.MapFS{
+				"migrations/0010_later.up.sql":    file("SELECT 10"),
+				"migrations/0002_second.up.sql":   file("SELECT 2"),
+				"migrations/0002_second.down.sql": file("SELECT -2"),
+				"migrations/0001_first.up.sql":    file("SELECT 1"),
+			},
+			versions: []int64{1, 2, 10},
+		},
+		{
+			name:    "invalid file name",
+			files:   fstest.MapFS{"migrations/first.up.sql": file("SELECT 1")},
+			wantErr: "invalid migration file name",
+		},
+		{
+			name:    "down without up",
+			files:   fstest.MapFS{"migrations/0001_first.down.sql": file("SELECT 1")},
+			wantErr: "has no up file",
+		},
+		{
+			name: "conflicting names",
+			files: fstest.MapFS{
+				"migrations/0001_first.up.sql":   file("SELECT 1"),
+				"migrations/0001_other.down.sql": file("SELECT 1"),
+			},
+			wantErr: "conflicting names",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := load(tt.files)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("load error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(migrations) != len(tt.versions) {
+				t.Fatalf("load returned %d migrations, want %d", len(migrations), len(tt.versions))
+			}
+			for i, m := range migrations {
+				if m.Version != tt.versions[i] {
+					t.Errorf("migration %d has version %d, want %d", i, m.Version, tt.versions[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	m, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, migration := range m.migrations {
+		if migration.Version != int64(i+1) {
+			t.Errorf("migration %s has version %d, want %d: versions must be contiguous", migration.Name, migration.Version, i+1)
+		}
+		if strings.TrimSpace(migration.Down) == "" {
+			t.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+		}
+		if strings.Contains(migration.Up, "CREATE ROLE") {
+			t.Errorf("migration %d_%s creates a role, which managed Postgres may not allow", migration.Version, migration.Name)
+		}
+	}
+	if m.Latest() != int64(len(m.migrations)) {
+		t.Errorf("Latest = %d, want %d", m.Latest(), len(m.migrations))
+	}
+	if !m.known(1) || m.known(m.Latest()+1) {
+		t.Error("known does not match the embedded versions")
+	}
+	if !strings.Contains(rowLevelSecurity, "CREATE POLICY") {
+		t.Error("row-level security setup is not embedded")
+	}
+}
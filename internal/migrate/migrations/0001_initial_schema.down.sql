@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS session_events;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
+
+DROP FUNCTION IF EXISTS update_updated_at_column();
+
+DROP TYPE IF EXISTS session_status;
+DROP TYPE IF EXISTS user_role;
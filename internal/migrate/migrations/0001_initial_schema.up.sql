@@ -0,0 +1,80 @@
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+CREATE EXTENSION IF NOT EXISTS "pgcrypto";
+
+DO $$ BEGIN
+	CREATE TYPE session_status AS ENUM ('ongoing', 'completed', 'failed');
+EXCEPTION
+	WHEN duplicate_object THEN null;
+END $$;
+
+DO $$ BEGIN
+	CREATE TYPE user_role AS ENUM ('user', 'admin');
+EXCEPTION
+	WHEN duplicate_object THEN null;
+END $$;
+
+CREATE TABLE IF NOT EXISTS users (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	email TEXT UNIQUE NOT NULL,
+	password TEXT NOT NULL,
+	role user_role NOT NULL DEFAULT 'user',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	started_at TIMESTAMP NOT NULL,
+	ended_at TIMESTAMP,
+	caller_id TEXT NOT NULL,
+	callee_id TEXT NOT NULL,
+	status session_status NOT NULL DEFAULT 'ongoing',
+	initial_metadata JSONB,
+	disposition TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT valid_session_times CHECK (ended_at IS NULL OR ended_at >= started_at)
+);
+
+CREATE TABLE IF NOT EXISTS session_events (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+	event_type TEXT NOT NULL,
+	event_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	metadata JSONB,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT valid_event_time CHECK (event_time >= CURRENT_TIMESTAMP - INTERVAL '1 year')
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
+CREATE INDEX IF NOT EXISTS idx_sessions_caller_id ON sessions(caller_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_callee_id ON sessions(callee_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_status ON sessions(status);
+CREATE INDEX IF NOT EXISTS idx_sessions_created_at ON sessions(created_at);
+CREATE INDEX IF NOT EXISTS idx_sessions_started_at_id ON sessions(started_at, id);
+CREATE INDEX IF NOT EXISTS idx_session_events_session_id ON session_events(session_id);
+CREATE INDEX IF NOT EXISTS idx_session_events_event_time ON session_events(event_time);
+CREATE INDEX IF NOT EXISTS idx_session_events_event_type ON session_events(event_type);
+CREATE INDEX IF NOT EXISTS idx_sessions_initial_metadata ON sessions USING GIN (initial_metadata);
+CREATE INDEX IF NOT EXISTS idx_session_events_metadata ON session_events USING GIN (metadata);
+
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+	NEW.updated_at = CURRENT_TIMESTAMP;
+	RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS update_users_updated_at ON users;
+CREATE TRIGGER update_users_updated_at
+	BEFORE UPDATE ON users
+	FOR EACH ROW
+	EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_sessions_updated_at ON sessions;
+CREATE TRIGGER update_sessions_updated_at
+	BEFORE UPDATE ON sessions
+	FOR EACH ROW
+	EXECUTE FUNCTION update_updated_at_column();
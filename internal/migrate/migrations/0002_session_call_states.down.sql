@@ -0,0 +1,12 @@
+-- Enum values cannot be dropped, so the type is rebuilt with the original values after
+-- folding the detailed states back into ongoing, completed and failed.
+UPDATE sessions SET status = 'ongoing' WHERE status IN ('initiated', 'ringing', 'answered', 'on_hold');
+UPDATE sessions SET status = 'completed' WHERE status = 'transferred';
+UPDATE sessions SET status = 'failed' WHERE status IN ('missed', 'busy', 'no_answer');
+
+ALTER TABLE sessions ALTER COLUMN status DROP DEFAULT;
+ALTER TYPE session_status RENAME TO session_status_old;
+CREATE TYPE session_status AS ENUM ('ongoing', 'completed', 'failed');
+ALTER TABLE sessions ALTER COLUMN status TYPE session_status USING status::text::session_status;
+ALTER TABLE sessions ALTER COLUMN status SET DEFAULT 'ongoing';
+DROP TYPE session_status_old;
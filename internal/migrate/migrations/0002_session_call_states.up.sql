@@ -0,0 +1,11 @@
+-- ALTER TYPE ... ADD VALUE may run inside a transaction (PostgreSQL 12+) as long as the
+-- new values are not used before it commits, so the column default stays 'ongoing' here
+-- and the application sets the initial state explicitly.
+ALTER TYPE session_status ADD VALUE IF NOT EXISTS 'initiated';
+ALTER TYPE session_status ADD VALUE IF NOT EXISTS 'ringing';
+ALTER TYPE session_status ADD VALUE IF NOT EXISTS 'answered';
+ALTER TYPE session_status ADD VALUE IF NOT EXISTS 'on_hold';
+ALTER TYPE session_status ADD VALUE IF NOT EXISTS 'transferred';
+ALTER TYPE session_status ADD VALUE IF NOT EXISTS 'missed';
+ALTER TYPE session_status ADD VALUE IF NOT EXISTS 'busy';
+ALTER TYPE session_status ADD VALUE IF NOT EXISTS 'no_answer';
@@ -0,0 +1,3 @@
+-- Supports the stale session reaper, which scans only sessions that can still change state
+CREATE INDEX IF NOT EXISTS idx_sessions_active_started_at ON sessions(started_at)
+	WHERE status IN ('initiated', 'ringing', 'answered', 'on_hold', 'ongoing');
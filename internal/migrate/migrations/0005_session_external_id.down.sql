@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_sessions_external_id;
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS valid_external_id;
+ALTER TABLE sessions DROP COLUMN IF EXISTS external_id;
+ALTER TABLE sessions DROP COLUMN IF EXISTS external_source;
@@ -0,0 +1,6 @@
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS external_source TEXT;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS external_id TEXT;
+ALTER TABLE sessions ADD CONSTRAINT valid_external_id CHECK ((external_source IS NULL) = (external_id IS NULL));
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_external_id ON sessions(external_source, external_id)
+	WHERE external_id IS NOT NULL;
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS session_events_notify_activity ON session_events;
+DROP TRIGGER IF EXISTS sessions_notify_activity ON sessions;
+DROP FUNCTION IF EXISTS notify_session_activity();
+DROP SEQUENCE IF EXISTS session_activity_seq;
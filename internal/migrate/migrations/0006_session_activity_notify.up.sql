@@ -0,0 +1,47 @@
+-- Announces session activity on the session_activity channel so every replica can
+-- push it to its stream subscribers. Notifications are delivered on commit.
+CREATE SEQUENCE IF NOT EXISTS session_activity_seq;
+
+CREATE OR REPLACE FUNCTION notify_session_activity() RETURNS trigger AS $$
+DECLARE
+	activity_type TEXT;
+	session_id UUID;
+	event_id UUID;
+BEGIN
+	IF TG_TABLE_NAME = 'session_events' THEN
+		activity_type := 'event.logged';
+		session_id := NEW.session_id;
+		event_id := NEW.id;
+	ELSIF TG_OP = 'INSERT' THEN
+		activity_type := 'session.started';
+		session_id := NEW.id;
+	ELSIF NEW.status IS DISTINCT FROM OLD.status THEN
+		IF NEW.status IN ('initiated', 'ringing', 'answered', 'on_hold', 'ongoing') THEN
+			activity_type := 'session.updated';
+		ELSE
+			activity_type := 'session.ended';
+		END IF;
+		session_id := NEW.id;
+	ELSE
+		RETURN NULL;
+	END IF;
+
+	PERFORM pg_notify('session_activity', json_build_object(
+		'id', nextval('session_activity_seq'),
+		'type', activity_type,
+		'session_id', session_id,
+		'event_id', event_id
+	)::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS sessions_notify_activity ON sessions;
+CREATE TRIGGER sessions_notify_activity
+	AFTER INSERT OR UPDATE ON sessions
+	FOR EACH ROW EXECUTE FUNCTION notify_session_activity();
+
+DROP TRIGGER IF EXISTS session_events_notify_activity ON session_events;
+CREATE TRIGGER session_events_notify_activity
+	AFTER INSERT ON session_events
+	FOR EACH ROW EXECUTE FUNCTION notify_session_activity();
@@ -0,0 +1,59 @@
+-- Domain events written in the same transaction as the session changes they describe
+CREATE TABLE IF NOT EXISTS outbox_messages (
+	id BIGSERIAL PRIMARY KEY,
+	type TEXT NOT NULL,
+	session_id UUID NOT NULL,
+	event_id UUID,
+	event_type TEXT,
+	data JSONB NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	webhooks_dispatched_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_webhooks_pending ON outbox_messages(id)
+	WHERE webhooks_dispatched_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS webhooks (
+	id UUID PRIMARY KEY,
+	url TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	secret TEXT NOT NULL,
+	events TEXT[] NOT NULL,
+	event_types TEXT[] NOT NULL DEFAULT '{}',
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id UUID PRIMARY KEY,
+	webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+	message_id BIGINT NOT NULL REFERENCES outbox_messages(id) ON DELETE CASCADE,
+	status TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMP,
+	last_error TEXT,
+	delivered_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT valid_webhook_delivery_status CHECK (status IN ('pending', 'succeeded', 'dead')),
+	CONSTRAINT webhook_deliveries_webhook_message_key UNIQUE (webhook_id, message_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at)
+	WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_created_at ON webhook_deliveries(webhook_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_message_id ON webhook_deliveries(message_id);
+
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+	id UUID PRIMARY KEY,
+	delivery_id UUID NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+	attempt INTEGER NOT NULL,
+	attempted_at TIMESTAMP NOT NULL,
+	duration_ms BIGINT NOT NULL,
+	status_code INTEGER,
+	error TEXT,
+	response_body TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_attempts_delivery_id ON webhook_attempts(delivery_id, attempt);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_outbox_messages_unpublished;
+
+ALTER TABLE outbox_messages DROP COLUMN IF EXISTS published_at;
@@ -0,0 +1,5 @@
+-- Tracks which outbox messages the relay has published to the message bus
+ALTER TABLE outbox_messages ADD COLUMN IF NOT EXISTS published_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_unpublished ON outbox_messages(id)
+	WHERE published_at IS NULL;
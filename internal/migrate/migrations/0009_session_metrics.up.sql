@@ -0,0 +1,13 @@
+-- Timeline metrics derived from the events of a session when it ends
+CREATE TABLE IF NOT EXISTS session_metrics (
+	session_id UUID PRIMARY KEY REFERENCES sessions(id) ON DELETE CASCADE,
+	time_to_answer_seconds DOUBLE PRECISION,
+	talk_time_seconds DOUBLE PRECISION,
+	hold_time_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+	hold_count INTEGER NOT NULL DEFAULT 0,
+	transfer_count INTEGER NOT NULL DEFAULT 0,
+	event_count INTEGER NOT NULL DEFAULT 0,
+	avg_event_gap_seconds DOUBLE PRECISION,
+	max_event_gap_seconds DOUBLE PRECISION,
+	computed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,26 @@
+-- Session exports written to disk in the background
+CREATE TABLE IF NOT EXISTS export_jobs (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	format TEXT NOT NULL,
+	dataset TEXT NOT NULL,
+	query TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	lease_until TIMESTAMP,
+	row_count BIGINT,
+	size_bytes BIGINT,
+	error TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	started_at TIMESTAMP,
+	finished_at TIMESTAMP,
+	expires_at TIMESTAMP,
+	CONSTRAINT valid_export_job_status CHECK (status IN ('pending', 'running', 'succeeded', 'failed')),
+	CONSTRAINT valid_export_format CHECK (format IN ('csv', 'ndjson', 'parquet')),
+	CONSTRAINT valid_export_dataset CHECK (dataset IN ('sessions', 'events'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_export_jobs_unfinished ON export_jobs(created_at)
+	WHERE status IN ('pending', 'running');
+CREATE INDEX IF NOT EXISTS idx_export_jobs_expires_at ON export_jobs(expires_at)
+	WHERE expires_at IS NOT NULL;
@@ -0,0 +1,16 @@
+DROP TRIGGER IF EXISTS session_events_notify_activity ON session_events;
+CREATE TRIGGER session_events_notify_activity
+	AFTER INSERT ON session_events
+	FOR EACH ROW EXECUTE FUNCTION notify_session_activity();
+
+DROP TRIGGER IF EXISTS sessions_notify_activity ON sessions;
+CREATE TRIGGER sessions_notify_activity
+	AFTER INSERT OR UPDATE ON sessions
+	FOR EACH ROW EXECUTE FUNCTION notify_session_activity();
+
+ALTER TABLE session_events DROP CONSTRAINT IF EXISTS valid_event_time;
+ALTER TABLE session_events ADD CONSTRAINT valid_event_time
+	CHECK (event_time >= CURRENT_TIMESTAMP - INTERVAL '1 year') NOT VALID;
+
+ALTER TABLE session_events DROP COLUMN IF EXISTS imported;
+ALTER TABLE sessions DROP COLUMN IF EXISTS imported;
@@ -0,0 +1,20 @@
+-- Sessions and events loaded from historical call detail records. Imported events are
+-- exempt from the one-year event time check, and imported rows are not announced on
+-- the session_activity channel. NOT VALID keeps events that have aged past a year since
+-- they were logged from failing the recreated check.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS imported BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE session_events ADD COLUMN IF NOT EXISTS imported BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE session_events DROP CONSTRAINT IF EXISTS valid_event_time;
+ALTER TABLE session_events ADD CONSTRAINT valid_event_time
+	CHECK (imported OR event_time >= CURRENT_TIMESTAMP - INTERVAL '1 year') NOT VALID;
+
+DROP TRIGGER IF EXISTS sessions_notify_activity ON sessions;
+CREATE TRIGGER sessions_notify_activity
+	AFTER INSERT OR UPDATE ON sessions
+	FOR EACH ROW WHEN (NOT NEW.imported) EXECUTE FUNCTION notify_session_activity();
+
+DROP TRIGGER IF EXISTS session_events_notify_activity ON session_events;
+CREATE TRIGGER session_events_notify_activity
+	AFTER INSERT ON session_events
+	FOR EACH ROW WHEN (NOT NEW.imported) EXECUTE FUNCTION notify_session_activity();
@@ -0,0 +1,25 @@
+-- Call detail records generated when sessions end, and the progress of writing them
+-- to CDR files
+CREATE TABLE IF NOT EXISTS cdrs (
+	id BIGSERIAL PRIMARY KEY,
+	session_id UUID NOT NULL UNIQUE REFERENCES sessions(id) ON DELETE CASCADE,
+	caller_id TEXT NOT NULL,
+	callee_id TEXT NOT NULL,
+	started_at TIMESTAMP NOT NULL,
+	answered_at TIMESTAMP,
+	ended_at TIMESTAMP NOT NULL,
+	duration BIGINT NOT NULL,
+	billsec BIGINT NOT NULL,
+	status session_status NOT NULL,
+	disposition TEXT,
+	external_source TEXT,
+	external_id TEXT,
+	initial_metadata JSONB,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	written_at TIMESTAMP,
+	CONSTRAINT valid_cdr_billsec CHECK (billsec >= 0 AND billsec <= duration)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cdrs_ended_at ON cdrs(ended_at, id);
+CREATE INDEX IF NOT EXISTS idx_cdrs_unwritten ON cdrs(id)
+	WHERE written_at IS NULL;
@@ -0,0 +1,7 @@
+-- The normalized IDs stay in caller_id and callee_id
+ALTER TABLE sessions DROP COLUMN IF EXISTS callee_number_type;
+ALTER TABLE sessions DROP COLUMN IF EXISTS caller_number_type;
+ALTER TABLE sessions DROP COLUMN IF EXISTS callee_id_type;
+ALTER TABLE sessions DROP COLUMN IF EXISTS caller_id_type;
+ALTER TABLE sessions DROP COLUMN IF EXISTS callee_id_raw;
+ALTER TABLE sessions DROP COLUMN IF EXISTS caller_id_raw;
@@ -0,0 +1,17 @@
+-- Caller and callee IDs are stored normalized in caller_id and callee_id, next to the
+-- IDs as they were given and their classification. Existing sessions keep their IDs as
+-- given in both columns until they are renormalized with the normalize command.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS caller_id_raw TEXT;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS callee_id_raw TEXT;
+UPDATE sessions SET caller_id_raw = caller_id, callee_id_raw = callee_id WHERE caller_id_raw IS NULL;
+ALTER TABLE sessions ALTER COLUMN caller_id_raw SET NOT NULL;
+ALTER TABLE sessions ALTER COLUMN callee_id_raw SET NOT NULL;
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS caller_id_type TEXT NOT NULL DEFAULT 'unknown'
+	CONSTRAINT valid_caller_id_type CHECK (caller_id_type IN ('e164', 'sip', 'extension', 'unknown'));
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS callee_id_type TEXT NOT NULL DEFAULT 'unknown'
+	CONSTRAINT valid_callee_id_type CHECK (callee_id_type IN ('e164', 'sip', 'extension', 'unknown'));
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS caller_number_type TEXT NOT NULL DEFAULT 'unknown'
+	CONSTRAINT valid_caller_number_type CHECK (caller_number_type IN ('mobile', 'fixed_line', 'fixed_line_or_mobile', 'toll_free', 'premium_rate', 'shared_cost', 'voip', 'unknown'));
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS callee_number_type TEXT NOT NULL DEFAULT 'unknown'
+	CONSTRAINT valid_callee_number_type CHECK (callee_number_type IN ('mobile', 'fixed_line', 'fixed_line_or_mobile', 'toll_free', 'premium_rate', 'shared_cost', 'voip', 'unknown'));
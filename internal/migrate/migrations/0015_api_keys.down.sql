@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS api_keys;
+
+-- Enum values cannot be dropped, so the type is rebuilt without the service role once
+-- the service accounts are gone
+DELETE FROM users WHERE role = 'service';
+
+ALTER TABLE users ALTER COLUMN role DROP DEFAULT;
+ALTER TYPE user_role RENAME TO user_role_old;
+CREATE TYPE user_role AS ENUM ('user', 'admin');
+ALTER TABLE users ALTER COLUMN role TYPE user_role USING role::text::user_role;
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'user';
+DROP TYPE user_role_old;
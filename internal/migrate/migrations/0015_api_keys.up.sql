@@ -0,0 +1,22 @@
+-- Service accounts are users that act through API keys. The new role is not used
+-- before the migration commits, so it may be added inside its transaction.
+ALTER TYPE user_role ADD VALUE IF NOT EXISTS 'service';
+
+-- API keys for machine-to-machine clients, looked up by their prefix and stored as
+-- SHA-256 hashes of the whole key
+CREATE TABLE IF NOT EXISTS api_keys (
+	id UUID PRIMARY KEY,
+	name TEXT NOT NULL,
+	prefix TEXT NOT NULL UNIQUE,
+	key_hash BYTEA NOT NULL,
+	service_account_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	scopes TEXT[] NOT NULL,
+	allowed_cidrs TEXT[] NOT NULL DEFAULT '{}',
+	expires_at TIMESTAMP,
+	last_used_at TIMESTAMP,
+	created_by UUID NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_service_account_id ON api_keys(service_account_id);
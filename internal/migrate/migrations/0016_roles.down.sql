@@ -0,0 +1,12 @@
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS scopes;
+
+-- Users of custom roles, including the supervisor role, fall back to the user role
+UPDATE users SET role = 'user' WHERE role NOT IN ('user', 'admin', 'service');
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_fkey;
+ALTER TABLE users ALTER COLUMN role DROP DEFAULT;
+CREATE TYPE user_role AS ENUM ('user', 'admin', 'service');
+ALTER TABLE users ALTER COLUMN role TYPE user_role USING role::user_role;
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'user';
+
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,27 @@
+-- Roles map to the permissions of the users holding them. The built-in roles are
+-- seeded here; installations may add their own.
+CREATE TABLE IF NOT EXISTS roles (
+	name TEXT PRIMARY KEY,
+	description TEXT NOT NULL DEFAULT '',
+	permissions TEXT[] NOT NULL DEFAULT '{}',
+	builtin BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO roles (name, description, permissions, builtin) VALUES
+	('admin', 'Full access', '{sessions:read,sessions:write,events:write,sessions:export,sessions:import,users:manage,webhooks:manage}', TRUE),
+	('user', 'Records, reads and exports call sessions', '{sessions:read,sessions:write,events:write,sessions:export}', TRUE),
+	('supervisor', 'Reads and exports all call sessions', '{sessions:read,sessions:export}', TRUE),
+	('service', 'Service accounts acting through API keys', '{}', TRUE)
+ON CONFLICT (name) DO NOTHING;
+
+-- The role of a user now refers to the roles table instead of a fixed enum
+ALTER TABLE users ALTER COLUMN role DROP DEFAULT;
+ALTER TABLE users ALTER COLUMN role TYPE TEXT USING role::text;
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'user';
+ALTER TABLE users ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles(name);
+DROP TYPE IF EXISTS user_role;
+
+-- Permissions a login was limited to; NULL grants all permissions of the user's role
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS scopes TEXT[];
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_sessions_team;
+DROP INDEX IF EXISTS idx_sessions_created_by;
+
+ALTER TABLE sessions DROP COLUMN IF EXISTS team;
+ALTER TABLE sessions DROP COLUMN IF EXISTS created_by;
+
+ALTER TABLE users DROP COLUMN IF EXISTS team;
+
+UPDATE roles SET description = 'Reads and exports all call sessions' WHERE name = 'supervisor';
+UPDATE roles SET description = 'Records, reads and exports call sessions' WHERE name = 'user';
+ALTER TABLE roles DROP COLUMN IF EXISTS session_scope;
@@ -0,0 +1,18 @@
+-- Roles see the sessions their users started, those of their team, or all of them
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS session_scope TEXT NOT NULL DEFAULT 'own'
+	CHECK (session_scope IN ('own', 'team', 'all'));
+
+UPDATE roles SET session_scope = 'all' WHERE name = 'admin';
+UPDATE roles SET session_scope = 'team', description = 'Reads and exports the call sessions of their team' WHERE name = 'supervisor';
+UPDATE roles SET description = 'Records, reads and exports their own call sessions' WHERE name = 'user';
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS team TEXT NOT NULL DEFAULT '';
+
+-- The user who started or imported a session, and their team at the time. Sessions
+-- recorded before ownership was tracked have no owner and are only visible to roles
+-- that see all sessions.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS created_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS team TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_sessions_created_by ON sessions(created_by);
+CREATE INDEX IF NOT EXISTS idx_sessions_team ON sessions(team) WHERE team <> '';
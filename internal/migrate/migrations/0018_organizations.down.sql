@@ -0,0 +1,51 @@
+-- Row-level security is set up apart from the migrations by `migrate rls`, but its
+-- policies refer to the org_id columns dropped below
+DROP POLICY IF EXISTS session_events_workers ON session_events;
+DROP POLICY IF EXISTS session_events_org_isolation ON session_events;
+ALTER TABLE session_events DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS sessions_workers ON sessions;
+DROP POLICY IF EXISTS sessions_org_isolation ON sessions;
+ALTER TABLE sessions DISABLE ROW LEVEL SECURITY;
+
+-- The session_workers role is shared by every database of the cluster, so when
+-- `migrate rls` created it, it is left in place with its grants in this one revoked
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'session_workers') THEN
+		ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE ALL ON SEQUENCES FROM session_workers;
+		ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE ALL ON TABLES FROM session_workers;
+		REVOKE ALL ON ALL SEQUENCES IN SCHEMA public FROM session_workers;
+		REVOKE ALL ON ALL TABLES IN SCHEMA public FROM session_workers;
+	END IF;
+END
+$$;
+
+-- Permissions and roles that only exist with organizations are taken away; holders of
+-- the org_admin role fall back to the user role
+UPDATE roles SET permissions = array_remove(array_remove(permissions, 'orgs:manage'), 'settings:manage');
+UPDATE roles SET description = 'Full access' WHERE name = 'admin';
+UPDATE users SET role = 'user' WHERE role = 'org_admin';
+DELETE FROM roles WHERE name = 'org_admin';
+UPDATE api_keys SET scopes = array_remove(array_remove(scopes, 'orgs:manage'), 'settings:manage');
+UPDATE refresh_tokens SET scopes = array_remove(array_remove(scopes, 'orgs:manage'), 'settings:manage') WHERE scopes IS NOT NULL;
+
+-- External call IDs become unique across the installation again, which fails if two
+-- organizations used the same one
+DROP INDEX IF EXISTS idx_sessions_external_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_external_id ON sessions(external_source, external_id)
+	WHERE external_id IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_sessions_org_id_ended_at;
+DROP INDEX IF EXISTS idx_sessions_org_id_started_at;
+DROP INDEX IF EXISTS idx_users_org_id;
+
+ALTER TABLE export_jobs DROP COLUMN IF EXISTS all_organizations;
+ALTER TABLE outbox_messages DROP COLUMN IF EXISTS org_id;
+ALTER TABLE session_events DROP COLUMN IF EXISTS org_id;
+ALTER TABLE sessions DROP COLUMN IF EXISTS org_id;
+ALTER TABLE webhooks DROP COLUMN IF EXISTS org_id;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS org_id;
+ALTER TABLE users DROP COLUMN IF EXISTS org_id;
+
+DROP TABLE IF EXISTS organizations;
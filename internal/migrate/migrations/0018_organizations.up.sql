@@ -0,0 +1,54 @@
+-- Organizations are the business units sharing an installation. Everything recorded
+-- before they were introduced belongs to the default organization.
+CREATE TABLE IF NOT EXISTS organizations (
+	id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+	name TEXT NOT NULL,
+	retention_days INTEGER NOT NULL DEFAULT 0 CHECK (retention_days >= 0),
+	allowed_event_types TEXT[] NOT NULL DEFAULT '{}',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO organizations (id, name) VALUES ('00000000-0000-0000-0000-000000000001', 'default')
+ON CONFLICT (id) DO NOTHING;
+
+-- Existing rows are backfilled with the default organization; the defaults are dropped
+-- afterwards so new rows always name their organization
+ALTER TABLE users ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id);
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id);
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id);
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id);
+ALTER TABLE session_events ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES organizations(id);
+ALTER TABLE outbox_messages ADD COLUMN IF NOT EXISTS org_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001';
+
+-- Export jobs run after their request, so they record whether it could see every
+-- organization; jobs queued before default to their owner's organization
+ALTER TABLE export_jobs ADD COLUMN IF NOT EXISTS all_organizations BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE users ALTER COLUMN org_id DROP DEFAULT;
+ALTER TABLE api_keys ALTER COLUMN org_id DROP DEFAULT;
+ALTER TABLE webhooks ALTER COLUMN org_id DROP DEFAULT;
+ALTER TABLE sessions ALTER COLUMN org_id DROP DEFAULT;
+ALTER TABLE session_events ALTER COLUMN org_id DROP DEFAULT;
+ALTER TABLE outbox_messages ALTER COLUMN org_id DROP DEFAULT;
+
+CREATE INDEX IF NOT EXISTS idx_users_org_id ON users(org_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_org_id_started_at ON sessions(org_id, started_at, id);
+CREATE INDEX IF NOT EXISTS idx_sessions_org_id_ended_at ON sessions(org_id, ended_at)
+	WHERE ended_at IS NOT NULL;
+
+-- External call IDs are unique within an organization
+DROP INDEX IF EXISTS idx_sessions_external_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_external_id ON sessions(org_id, external_source, external_id)
+	WHERE external_id IS NOT NULL;
+
+-- Organization admins run a single organization and its settings; managing
+-- organizations and the roles they share is left to the admins of the installation
+INSERT INTO roles (name, description, permissions, session_scope, builtin) VALUES
+	('org_admin', 'Full access to their organization', '{sessions:read,sessions:write,events:write,sessions:export,sessions:import,users:manage,webhooks:manage,settings:manage}', 'all', TRUE)
+ON CONFLICT (name) DO NOTHING;
+
+UPDATE roles SET permissions = array_append(permissions, 'settings:manage')
+WHERE name = 'admin' AND NOT 'settings:manage' = ANY(permissions);
+UPDATE roles SET permissions = array_append(permissions, 'orgs:manage'), description = 'Full access to every organization'
+WHERE name = 'admin' AND NOT 'orgs:manage' = ANY(permissions);
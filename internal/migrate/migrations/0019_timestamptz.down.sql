@@ -0,0 +1,72 @@
+ALTER TABLE users
+	ALTER COLUMN created_at TYPE TIMESTAMP,
+	ALTER COLUMN updated_at TYPE TIMESTAMP;
+
+ALTER TABLE sessions
+	ALTER COLUMN started_at TYPE TIMESTAMP,
+	ALTER COLUMN ended_at TYPE TIMESTAMP,
+	ALTER COLUMN created_at TYPE TIMESTAMP,
+	ALTER COLUMN updated_at TYPE TIMESTAMP;
+
+ALTER TABLE session_events
+	ALTER COLUMN event_time TYPE TIMESTAMP,
+	ALTER COLUMN created_at TYPE TIMESTAMP;
+
+ALTER TABLE idempotency_keys
+	ALTER COLUMN created_at TYPE TIMESTAMP,
+	ALTER COLUMN expires_at TYPE TIMESTAMP;
+
+ALTER TABLE outbox_messages
+	ALTER COLUMN created_at TYPE TIMESTAMP,
+	ALTER COLUMN webhooks_dispatched_at TYPE TIMESTAMP,
+	ALTER COLUMN published_at TYPE TIMESTAMP;
+
+ALTER TABLE webhooks
+	ALTER COLUMN created_at TYPE TIMESTAMP,
+	ALTER COLUMN updated_at TYPE TIMESTAMP;
+
+ALTER TABLE webhook_deliveries
+	ALTER COLUMN next_attempt_at TYPE TIMESTAMP,
+	ALTER COLUMN delivered_at TYPE TIMESTAMP,
+	ALTER COLUMN created_at TYPE TIMESTAMP,
+	ALTER COLUMN updated_at TYPE TIMESTAMP;
+
+ALTER TABLE webhook_attempts
+	ALTER COLUMN attempted_at TYPE TIMESTAMP;
+
+ALTER TABLE session_metrics
+	ALTER COLUMN computed_at TYPE TIMESTAMP;
+
+ALTER TABLE export_jobs
+	ALTER COLUMN lease_until TYPE TIMESTAMP,
+	ALTER COLUMN created_at TYPE TIMESTAMP,
+	ALTER COLUMN started_at TYPE TIMESTAMP,
+	ALTER COLUMN finished_at TYPE TIMESTAMP,
+	ALTER COLUMN expires_at TYPE TIMESTAMP;
+
+ALTER TABLE cdrs
+	ALTER COLUMN started_at TYPE TIMESTAMP,
+	ALTER COLUMN answered_at TYPE TIMESTAMP,
+	ALTER COLUMN ended_at TYPE TIMESTAMP,
+	ALTER COLUMN created_at TYPE TIMESTAMP,
+	ALTER COLUMN written_at TYPE TIMESTAMP;
+
+ALTER TABLE refresh_tokens
+	ALTER COLUMN access_token_expires_at TYPE TIMESTAMP,
+	ALTER COLUMN created_at TYPE TIMESTAMP,
+	ALTER COLUMN expires_at TYPE TIMESTAMP,
+	ALTER COLUMN used_at TYPE TIMESTAMP,
+	ALTER COLUMN revoked_at TYPE TIMESTAMP;
+
+ALTER TABLE revoked_access_tokens
+	ALTER COLUMN expires_at TYPE TIMESTAMP;
+
+ALTER TABLE api_keys
+	ALTER COLUMN expires_at TYPE TIMESTAMP,
+	ALTER COLUMN last_used_at TYPE TIMESTAMP,
+	ALTER COLUMN created_at TYPE TIMESTAMP,
+	ALTER COLUMN updated_at TYPE TIMESTAMP;
+
+ALTER TABLE roles
+	ALTER COLUMN created_at TYPE TIMESTAMP,
+	ALTER COLUMN updated_at TYPE TIMESTAMP;
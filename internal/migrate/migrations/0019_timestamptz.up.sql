@@ -0,0 +1,77 @@
+-- Stores every timestamp as an instant. TIMESTAMP columns dropped the offset of the
+-- times written to them and kept the wall clock of whichever zone the writer ran in.
+-- Existing values are read as wall clock times of the database's TimeZone setting,
+-- which is the zone they were written in when the server and the database shared it.
+
+ALTER TABLE users
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE sessions
+	ALTER COLUMN started_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN ended_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE session_events
+	ALTER COLUMN event_time TYPE TIMESTAMPTZ,
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE idempotency_keys
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN expires_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE outbox_messages
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN webhooks_dispatched_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN published_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE webhooks
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE webhook_deliveries
+	ALTER COLUMN next_attempt_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN delivered_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE webhook_attempts
+	ALTER COLUMN attempted_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE session_metrics
+	ALTER COLUMN computed_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE export_jobs
+	ALTER COLUMN lease_until TYPE TIMESTAMPTZ,
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN started_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN finished_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN expires_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE cdrs
+	ALTER COLUMN started_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN answered_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN ended_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN written_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE refresh_tokens
+	ALTER COLUMN access_token_expires_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN expires_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN used_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN revoked_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE revoked_access_tokens
+	ALTER COLUMN expires_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE api_keys
+	ALTER COLUMN expires_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN last_used_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE roles
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+	ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
@@ -0,0 +1,33 @@
+CREATE OR REPLACE FUNCTION notify_session_activity() RETURNS trigger AS $$
+DECLARE
+	activity_type TEXT;
+	session_id UUID;
+	event_id UUID;
+BEGIN
+	IF TG_TABLE_NAME = 'session_events' THEN
+		activity_type := 'event.logged';
+		session_id := NEW.session_id;
+		event_id := NEW.id;
+	ELSIF TG_OP = 'INSERT' THEN
+		activity_type := 'session.started';
+		session_id := NEW.id;
+	ELSIF NEW.status IS DISTINCT FROM OLD.status THEN
+		IF NEW.status IN ('initiated', 'ringing', 'answered', 'on_hold', 'ongoing') THEN
+			activity_type := 'session.updated';
+		ELSE
+			activity_type := 'session.ended';
+		END IF;
+		session_id := NEW.id;
+	ELSE
+		RETURN NULL;
+	END IF;
+
+	PERFORM pg_notify('session_activity', json_build_object(
+		'id', nextval('session_activity_seq'),
+		'type', activity_type,
+		'session_id', session_id,
+		'event_id', event_id
+	)::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
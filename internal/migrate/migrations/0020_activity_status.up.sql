@@ -0,0 +1,41 @@
+-- Carries the state of the session after the activity in session_activity
+-- notifications, so subscribers filtering on status match the state the activity
+-- produced rather than whatever state the session has reached when it is loaded.
+CREATE OR REPLACE FUNCTION notify_session_activity() RETURNS trigger AS $$
+DECLARE
+	activity_type TEXT;
+	session_id UUID;
+	event_id UUID;
+	session_status TEXT;
+BEGIN
+	IF TG_TABLE_NAME = 'session_events' THEN
+		activity_type := 'event.logged';
+		session_id := NEW.session_id;
+		event_id := NEW.id;
+		SELECT status INTO session_status FROM sessions WHERE id = NEW.session_id;
+	ELSIF TG_OP = 'INSERT' THEN
+		activity_type := 'session.started';
+		session_id := NEW.id;
+		session_status := NEW.status;
+	ELSIF NEW.status IS DISTINCT FROM OLD.status THEN
+		IF NEW.status IN ('initiated', 'ringing', 'answered', 'on_hold', 'ongoing') THEN
+			activity_type := 'session.updated';
+		ELSE
+			activity_type := 'session.ended';
+		END IF;
+		session_id := NEW.id;
+		session_status := NEW.status;
+	ELSE
+		RETURN NULL;
+	END IF;
+
+	PERFORM pg_notify('session_activity', json_build_object(
+		'id', nextval('session_activity_seq'),
+		'type', activity_type,
+		'session_id', session_id,
+		'event_id', event_id,
+		'status', session_status
+	)::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,3 @@
+-- Attempts keep only the status of the receiver's response. Storing and returning its
+-- body let a webhook pointed at an internal page read that page.
+ALTER TABLE webhook_attempts DROP COLUMN IF EXISTS response_body;
@@ -0,0 +1,44 @@
+-- Row-level security limits the sessions and events a transaction sees and writes to
+-- the organization named by app.org_id, which the server sets when
+-- DB_ROW_LEVEL_SECURITY is enabled. Transactions that do not set it see no rows.
+-- Background work acting for every organization switches to the session_workers role,
+-- whose policies only apply while it is the current role, so members do not bypass the
+-- isolation by inheriting it. The table owner is exempt.
+--
+-- This is not a migration: creating a role needs the CREATEROLE privilege, which managed
+-- Postgres services often withhold from the role that owns the schema, and the policies
+-- hide every session from a server that connects as another role without setting
+-- app.org_id. `migrate rls` applies it once the schema is current; it can be run again,
+-- and the grants it makes on existing tables are extended to later ones by the default
+-- privileges, as long as the same role creates them.
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'session_workers') THEN
+		CREATE ROLE session_workers NOLOGIN;
+	END IF;
+END
+$$;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO session_workers;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO session_workers;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO session_workers;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT USAGE, SELECT ON SEQUENCES TO session_workers;
+
+ALTER TABLE sessions ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS sessions_org_isolation ON sessions;
+CREATE POLICY sessions_org_isolation ON sessions
+	USING (org_id = NULLIF(current_setting('app.org_id', true), '')::uuid)
+	WITH CHECK (org_id = NULLIF(current_setting('app.org_id', true), '')::uuid);
+DROP POLICY IF EXISTS sessions_workers ON sessions;
+CREATE POLICY sessions_workers ON sessions TO session_workers
+	USING (current_user = 'session_workers')
+	WITH CHECK (current_user = 'session_workers');
+
+ALTER TABLE session_events ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS session_events_org_isolation ON session_events;
+CREATE POLICY session_events_org_isolation ON session_events
+	USING (org_id = NULLIF(current_setting('app.org_id', true), '')::uuid)
+	WITH CHECK (org_id = NULLIF(current_setting('app.org_id', true), '')::uuid);
+DROP POLICY IF EXISTS session_events_workers ON session_events;
+CREATE POLICY session_events_workers ON session_events TO session_workers
+	USING (current_user = 'session_workers')
+	WITH CHECK (current_user = 'session_workers');
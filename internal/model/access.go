@@ -0,0 +1,88 @@
+package model
+
+import (
+	"github.com/google/uuid"
+)
+
+// SessionScope is the range of sessions the holders of a role can see and act on
+type SessionScope string
+
+const (
+	// SessionScopeOwn covers the sessions the user started or imported
+	SessionScopeOwn SessionScope = "own"
+	// SessionScopeTeam adds the sessions of the user's team
+	SessionScopeTeam SessionScope = "team"
+	// SessionScopeAll covers every session
+	SessionScopeAll SessionScope = "all"
+)
+
+// Covers reports whether the scope includes every session of other
+func (s SessionScope) Covers(other SessionScope) bool {
+	return sessionScopeRank[s] >= sessionScopeRank[other]
+}
+
+var sessionScopeRank = map[SessionScope]int{SessionScopeOwn: 1, SessionScopeTeam: 2, SessionScopeAll: 3}
+
+// SessionAccess selects the sessions visible to a caller within their organization:
+// those they created, those created by members of their team, or all of them. The zero
+// value sees no sessions. Sessions outside it are reported as not found, so their
+// existence is not revealed.
+type SessionAccess struct {
+	Scope  SessionScope
+	UserID uuid.UUID
+	Team   string
+	// OrgID limits the access to the sessions of an organization; uuid.Nil spans every
+	// organization, for holders of orgs:manage and background work
+	OrgID uuid.UUID
+	// HomeOrgID is the organization the caller belongs to, which owns what they create
+	HomeOrgID uuid.UUID
+}
+
+// FullSessionAccess sees every session of every organization; it is meant for
+// background work acting on behalf of nobody in particular
+var FullSessionAccess = SessionAccess{Scope: SessionScopeAll}
+
+// NewSessionAccess returns the access of a user holding a role, acting with the granted
+// permissions of their token or API key. Only when both the role and the grant hold
+// orgs:manage does it span every organization.
+func NewSessionAccess(user *User, role *Role, granted Permissions) SessionAccess {
+	access := SessionAccess{Scope: role.SessionScope, UserID: user.ID, Team: user.Team, OrgID: user.OrgID, HomeOrgID: user.OrgID}
+	if role.Permissions.Has(PermissionOrgsManage) && granted.Has(PermissionOrgsManage) {
+		access.OrgID = uuid.Nil
+	}
+	return access
+}
+
+// Unrestricted reports whether the access sees every session of every organization
+func (a SessionAccess) Unrestricted() bool {
+	return a.Scope == SessionScopeAll && a.OrgID == uuid.Nil
+}
+
+// CanSee reports whether a session is visible. Users without a team see only their own
+// sessions under the team scope.
+func (a SessionAccess) CanSee(s *Session) bool {
+	switch {
+	case a.OrgID != uuid.Nil && s.OrgID != a.OrgID:
+		return false
+	case a.Scope == SessionScopeAll:
+		return true
+	case s.CreatedBy == nil || a.UserID == uuid.Nil:
+		return false
+	case *s.CreatedBy == a.UserID:
+		return true
+	default:
+		return a.Scope == SessionScopeTeam && a.Team != "" && s.Team == a.Team
+	}
+}
+
+// Stamp records the caller's organization, and the caller as the creator, of a new
+// session
+func (a SessionAccess) Stamp(s *Session) {
+	s.OrgID = a.HomeOrgID
+	if a.UserID == uuid.Nil {
+		return
+	}
+	id := a.UserID
+	s.CreatedBy = &id
+	s.Team = a.Team
+}
@@ -0,0 +1,111 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSessionAccessCanSee(t *testing.T) {
+	owner, teammate, stranger := uuid.New(), uuid.New(), uuid.New()
+	otherOrg := uuid.New()
+	session := func(createdBy *uuid.UUID, team string, orgID uuid.UUID) *Session {
+		return &Session{ID: uuid.New(), OrgID: orgID, CreatedBy: createdBy, Team: team}
+	}
+	own := session(&owner, "sales", DefaultOrganizationID)
+	unowned := session(nil, "", DefaultOrganizationID)
+	foreign := session(&owner, "sales", otherOrg)
+
+	tests := []struct {
+		name    string
+		access  SessionAccess
+		session *Session
+		want    bool
+	}{
+		{name: "own session", access: SessionAccess{Scope: SessionScopeOwn, UserID: owner, OrgID: DefaultOrganizationID}, session: own, want: true},
+		{name: "someone else's session", access: SessionAccess{Scope: SessionScopeOwn, UserID: stranger, OrgID: DefaultOrganizationID}, session: own},
+		{name: "team session", access: SessionAccess{Scope: SessionScopeTeam, UserID: teammate, Team: "sales", OrgID: DefaultOrganizationID}, session: own, want: true},
+		{name: "other team's session", access: SessionAccess{Scope: SessionScopeTeam, UserID: teammate, Team: "support", OrgID: DefaultOrganizationID}, session: own},
+		{name: "team scope without a team", access: SessionAccess{Scope: SessionScopeTeam, UserID: teammate, OrgID: DefaultOrganizationID}, session: session(&owner, "", DefaultOrganizationID)},
+		{name: "session without an owner", access: SessionAccess{Scope: SessionScopeTeam, UserID: teammate, Team: "sales", OrgID: DefaultOrganizationID}, session: unowned},
+		{name: "all sessions", access: SessionAccess{Scope: SessionScopeAll, UserID: stranger, OrgID: DefaultOrganizationID}, session: unowned, want: true},
+		{name: "other organization", access: SessionAccess{Scope: SessionScopeAll, UserID: stranger, OrgID: DefaultOrganizationID}, session: foreign},
+		{name: "own session in another organization", access: SessionAccess{Scope: SessionScopeOwn, UserID: owner, OrgID: DefaultOrganizationID}, session: foreign},
+		{name: "every organization", access: FullSessionAccess, session: foreign, want: true},
+		{name: "zero value", session: unowned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.access.CanSee(tt.session); got != tt.want {
+				t.Errorf("CanSee = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionScopeCovers(t *testing.T) {
+	tests := []struct {
+		scope, other SessionScope
+		want         bool
+	}{
+		{scope: SessionScopeAll, other: SessionScopeTeam, want: true},
+		{scope: SessionScopeTeam, other: SessionScopeTeam, want: true},
+		{scope: SessionScopeTeam, other: SessionScopeOwn, want: true},
+		{scope: SessionScopeOwn, other: SessionScopeTeam},
+		{scope: SessionScopeTeam, other: SessionScopeAll},
+		{scope: "", other: SessionScopeOwn},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.scope)+" "+string(tt.other), func(t *testing.T) {
+			if got := tt.scope.Covers(tt.other); got != tt.want {
+				t.Errorf("Covers = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionAccessStamp(t *testing.T) {
+	userID := uuid.New()
+	session := &Session{}
+	SessionAccess{Scope: SessionScopeOwn, UserID: userID, Team: "sales", OrgID: DefaultOrganizationID, HomeOrgID: DefaultOrganizationID}.Stamp(session)
+	if session.OrgID != DefaultOrganizationID || session.CreatedBy == nil || *session.CreatedBy != userID || session.Team != "sales" {
+		t.Errorf("stamped session %+v", session)
+	}
+
+	background := &Session{}
+	SessionAccess{Scope: SessionScopeAll, OrgID: DefaultOrganizationID, HomeOrgID: DefaultOrganizationID}.Stamp(background)
+	if background.OrgID != DefaultOrganizationID || background.CreatedBy != nil {
+		t.Errorf("session stamped without a user %+v", background)
+	}
+
+	// Callers seeing every organization create sessions in their own
+	spanning := &Session{}
+	SessionAccess{Scope: SessionScopeAll, UserID: userID, HomeOrgID: DefaultOrganizationID}.Stamp(spanning)
+	if spanning.OrgID != DefaultOrganizationID {
+		t.Errorf("session stamped in organization %s", spanning.OrgID)
+	}
+}
+
+func TestNewSessionAccess(t *testing.T) {
+	user := &User{ID: uuid.New(), Team: "sales", OrgID: uuid.New()}
+	manager := Role{SessionScope: SessionScopeAll, Permissions: Permissions{PermissionSessionsRead, PermissionOrgsManage}}
+	tests := []struct {
+		name      string
+		role      Role
+		granted   Permissions
+		wantOrgID uuid.UUID
+	}{
+		{name: "organization role", role: Role{SessionScope: SessionScopeAll, Permissions: Permissions{PermissionUsersManage}}, granted: Permissions{PermissionUsersManage}, wantOrgID: user.OrgID},
+		{name: "orgs:manage", role: manager, granted: Permissions{PermissionSessionsRead, PermissionOrgsManage}, wantOrgID: uuid.Nil},
+		{name: "orgs:manage scoped out of the token", role: manager, granted: Permissions{PermissionSessionsRead}, wantOrgID: user.OrgID},
+		{name: "orgs:manage granted beyond the role", role: Role{SessionScope: SessionScopeAll}, granted: Permissions{PermissionOrgsManage}, wantOrgID: user.OrgID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			access := NewSessionAccess(user, &tt.role, tt.granted)
+			if access.OrgID != tt.wantOrgID || access.HomeOrgID != user.OrgID || access.UserID != user.ID || access.Team != "sales" {
+				t.Errorf("access %+v", access)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// ActivityType classifies a change to a session
+type ActivityType string
+
+const (
+	ActivitySessionStarted ActivityType = "session.started"
+	ActivitySessionUpdated ActivityType = "session.updated"
+	ActivitySessionEnded   ActivityType = "session.ended"
+	ActivityEventLogged    ActivityType = "event.logged"
+)
+
+// Activity announces that a session was created or changed, or that an event was
+// logged against it. IDs increase across all replicas, so a client can resume a
+// stream from the last ID it saw regardless of which replica it reconnects to.
+// Status is the state of the session after the activity.
+type Activity struct {
+	ID        int64         `json:"id"`
+	Type      ActivityType  `json:"type"`
+	SessionID uuid.UUID     `json:"session_id"`
+	EventID   *uuid.UUID    `json:"event_id,omitempty"`
+	Status    SessionStatus `json:"status,omitempty"`
+}
+
+// ActivityFeed delivers the session activity of every replica as it is committed
+type ActivityFeed interface {
+	// ListenActivity calls fn for each activity, in commit order, until ctx is cancelled
+	ListenActivity(ctx context.Context, fn func(Activity)) error
+}
+
+// ActivityTypeForStatus returns the activity recorded when a session moves to status
+func ActivityTypeForStatus(status SessionStatus) ActivityType {
+	if status.IsTerminal() {
+		return ActivitySessionEnded
+	}
+	return ActivitySessionUpdated
+}
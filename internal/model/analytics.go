@@ -0,0 +1,185 @@
+package model
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// MaxAnalyticsBuckets bounds the number of time buckets an analytics query may return
+const MaxAnalyticsBuckets = 5000
+
+// AnalyticsInterval is the width of the time buckets of an analytics query
+type AnalyticsInterval string
+
+const (
+	IntervalMinute AnalyticsInterval = "minute"
+	IntervalHour   AnalyticsInterval = "hour"
+	IntervalDay    AnalyticsInterval = "day"
+	IntervalWeek   AnalyticsInterval = "week"
+)
+
+// IsValid checks if the interval is supported
+func (i AnalyticsInterval) IsValid() bool {
+	switch i {
+	case IntervalMinute, IntervalHour, IntervalDay, IntervalWeek:
+		return true
+	}
+	return false
+}
+
+// Truncate returns the start of the bucket containing t in loc. Weeks start on Monday,
+// as they do for Postgres date_trunc.
+func (i AnalyticsInterval) Truncate(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch i {
+	case IntervalMinute:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+	case IntervalHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case IntervalWeek:
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+}
+
+// Next returns the start of the bucket following the one starting at start
+func (i AnalyticsInterval) Next(start time.Time) time.Time {
+	switch i {
+	case IntervalMinute:
+		return start.Add(time.Minute)
+	case IntervalHour:
+		return start.Add(time.Hour)
+	case IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// SessionAnalyticsQuery selects the sessions to aggregate and how to bucket them.
+// Sessions are bucketed by their start time.
+type SessionAnalyticsQuery struct {
+	Filter   SessionFilter
+	Interval AnalyticsInterval
+	Location *time.Location
+	// Metadata restricts the sessions to those whose initial metadata has each key with
+	// the given value, compared as text
+	Metadata map[string]string
+}
+
+// DurationStats summarizes the durations, in seconds, of the ended sessions of a bucket
+type DurationStats struct {
+	Count int64    `json:"count"`
+	Avg   *float64 `json:"avg"`
+	P50   *float64 `json:"p50"`
+	P95   *float64 `json:"p95"`
+	P99   *float64 `json:"p99"`
+}
+
+// NewDurationStats computes duration statistics with the same interpolation as
+// Postgres percentile_cont
+func NewDurationStats(durations []float64) DurationStats {
+	stats := DurationStats{Count: int64(len(durations))}
+	if len(durations) == 0 {
+		return stats
+	}
+
+	sorted := append([]float64(nil), durations...)
+	sort.Float64s(sorted)
+	var sum float64
+	for _, d := range sorted {
+		sum += d
+	}
+	avg := sum / float64(len(sorted))
+	p50, p95, p99 := percentile(sorted, 0.5), percentile(sorted, 0.95), percentile(sorted, 0.99)
+	stats.Avg, stats.P50, stats.P95, stats.P99 = &avg, &p50, &p95, &p99
+	return stats
+}
+
+func percentile(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	return sorted[lower] + (pos-float64(lower))*(sorted[upper]-sorted[lower])
+}
+
+// AnalyticsBucket aggregates the sessions that started in one time bucket, or in the
+// whole range for the totals
+type AnalyticsBucket struct {
+	Start     *time.Time `json:"start,omitempty"`
+	Total     int64      `json:"total"`
+	Completed int64      `json:"completed"`
+	// Failed counts the sessions that ended in one of FailedSessionStatuses
+	Failed int64 `json:"failed"`
+	// SuccessRate is Completed / (Completed + Failed), or nil when both are zero
+	SuccessRate  *float64         `json:"success_rate"`
+	Duration     DurationStats    `json:"duration"`
+	Dispositions map[string]int64 `json:"dispositions"`
+}
+
+// FailedSessionStatuses returns the terminal states analytics count as failed: calls
+// that ended in an error or were never answered. Transferred sessions count as neither
+// completed nor failed.
+func FailedSessionStatuses() []SessionStatus {
+	return []SessionStatus{SessionStatusFailed, SessionStatusMissed, SessionStatusBusy, SessionStatusNoAnswer}
+}
+
+// SetSuccessRate derives the success rate from the completed and failed counts
+func (b *AnalyticsBucket) SetSuccessRate() {
+	b.SuccessRate = nil
+	if ended := b.Completed + b.Failed; ended > 0 {
+		rate := float64(b.Completed) / float64(ended)
+		b.SuccessRate = &rate
+	}
+}
+
+// SessionAnalytics is the result of an analytics query. Buckets are in ascending order
+// and include empty buckets between the first and last one.
+type SessionAnalytics struct {
+	Interval AnalyticsInterval `json:"interval"`
+	TimeZone string            `json:"time_zone"`
+	Totals   AnalyticsBucket   `json:"totals"`
+	Buckets  []AnalyticsBucket `json:"buckets"`
+}
+
+// FillBuckets inserts empty buckets into the gaps between the buckets found, and from
+// and to the bounds when they are given. It fails when the result would exceed
+// MaxAnalyticsBuckets.
+func (a *SessionAnalytics) FillBuckets(loc *time.Location, from, to *time.Time) error {
+	var first, last time.Time
+	if len(a.Buckets) > 0 {
+		first, last = *a.Buckets[0].Start, *a.Buckets[len(a.Buckets)-1].Start
+	}
+	if from != nil {
+		first = a.Interval.Truncate(*from, loc)
+	}
+	if to != nil {
+		last = a.Interval.Truncate(*to, loc)
+	}
+	if first.IsZero() || last.IsZero() || last.Before(first) {
+		return nil
+	}
+
+	found := make(map[int64]AnalyticsBucket, len(a.Buckets))
+	for _, b := range a.Buckets {
+		found[b.Start.Unix()] = b
+	}
+
+	filled := []AnalyticsBucket{}
+	for start := first; !start.After(last); start = a.Interval.Next(start) {
+		if len(filled) == MaxAnalyticsBuckets {
+			return ErrInvalidQuery.WithMessage("the requested range has too many buckets; narrow it or use a larger interval")
+		}
+		b, ok := found[start.Unix()]
+		if !ok {
+			bucketStart := start
+			b = AnalyticsBucket{Start: &bucketStart, Dispositions: map[string]int64{}}
+		}
+		filled = append(filled, b)
+	}
+	a.Buckets = filled
+	return nil
+}
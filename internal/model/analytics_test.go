@@ -0,0 +1,81 @@
+package model
+
+import (
+	"math"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestIntervalTruncate(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		interval AnalyticsInterval
+		at       time.Time
+		loc      *time.Location
+		want     time.Time
+	}{
+		{name: "minute", interval: IntervalMinute, at: time.Date(2024, 3, 20, 10, 42, 31, 5, time.UTC), loc: time.UTC, want: time.Date(2024, 3, 20, 10, 42, 0, 0, time.UTC)},
+		{name: "hour", interval: IntervalHour, at: time.Date(2024, 3, 20, 10, 42, 31, 0, time.UTC), loc: time.UTC, want: time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)},
+		{name: "day in another zone", interval: IntervalDay, at: time.Date(2024, 3, 20, 2, 0, 0, 0, time.UTC), loc: newYork, want: time.Date(2024, 3, 19, 0, 0, 0, 0, newYork)},
+		{name: "day after DST starts", interval: IntervalDay, at: time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC), loc: newYork, want: time.Date(2024, 3, 10, 5, 0, 0, 0, time.UTC)},
+		{name: "week from Sunday", interval: IntervalWeek, at: time.Date(2024, 3, 24, 23, 0, 0, 0, time.UTC), loc: time.UTC, want: time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC)},
+		{name: "week from Monday", interval: IntervalWeek, at: time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC), loc: time.UTC, want: time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.interval.Truncate(tt.at, tt.loc); !got.Equal(tt.want) {
+				t.Errorf("Truncate = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// The day DST starts in New York has 23 hours
+	start := IntervalDay.Truncate(time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC), newYork)
+	if next := IntervalDay.Next(start); next.Sub(start) != 23*time.Hour {
+		t.Errorf("Next = %s, want the next local midnight", next)
+	}
+}
+
+func TestNewDurationStats(t *testing.T) {
+	stats := NewDurationStats([]float64{50, 10, 40, 20, 30})
+	if stats.Count != 5 || stats.Avg == nil {
+		t.Fatalf("stats %+v", stats)
+	}
+	tests := []struct {
+		name      string
+		got, want float64
+	}{
+		{name: "avg", got: *stats.Avg, want: 30},
+		{name: "p50", got: *stats.P50, want: 30},
+		{name: "p95", got: *stats.P95, want: 48},
+		{name: "p99", got: *stats.P99, want: 49.6},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if empty := NewDurationStats(nil); empty.Count != 0 || empty.Avg != nil || empty.P99 != nil {
+		t.Errorf("stats without durations %+v", empty)
+	}
+}
+
+func TestSetSuccessRate(t *testing.T) {
+	b := AnalyticsBucket{Completed: 3, Failed: 1}
+	b.SetSuccessRate()
+	if b.SuccessRate == nil || *b.SuccessRate != 0.75 {
+		t.Errorf("success rate %v, want 0.75", b.SuccessRate)
+	}
+
+	b = AnalyticsBucket{Total: 2}
+	b.SetSuccessRate()
+	if b.SuccessRate != nil {
+		t.Errorf("success rate %v without ended sessions, want nil", *b.SuccessRate)
+	}
+}
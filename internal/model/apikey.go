@@ -0,0 +1,104 @@
+package model
+
+import (
+	"crypto/sha256"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// apiKeyPrefix starts every API key so leaked keys are easy to recognise
+	apiKeyPrefix = "csk_"
+	// APIKeyPrefixLength is the length of the public part of a key, apiKeyPrefix
+	// included, by which the key is looked up
+	APIKeyPrefixLength = len(apiKeyPrefix) + 16
+)
+
+// APIKey is a long-lived credential for machine-to-machine clients. A key acts as its
+// service account, to which everything done with the key is attributed, with the
+// permissions it was given as its scopes. Keys look like csk_<16 hex digits>_<secret>; the part before the second
+// underscore is the prefix, which is stored in the clear to identify the key, and only
+// a hash of the whole key is kept.
+type APIKey struct {
+	ID               uuid.UUID   `json:"id"`
+	Name             string      `json:"name"`
+	Prefix           string      `json:"prefix"`
+	KeyHash          []byte      `json:"-"`
+	ServiceAccountID uuid.UUID   `json:"service_account_id"`
+	OrgID            uuid.UUID   `json:"org_id"`
+	Scopes           Permissions `json:"scopes"`
+	// AllowedCIDRs restricts the addresses the key may be used from; empty allows all
+	AllowedCIDRs []string   `json:"allowed_cidrs"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	CreatedBy    uuid.UUID  `json:"created_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	// Key is the key itself; it is only returned when the key is created
+	Key string `json:"key,omitempty"`
+}
+
+// AllowsAddress reports whether the key may be used from an IP address
+func (k *APIKey) AllowsAddress(ip string) bool {
+	if len(k.AllowedCIDRs) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, cidr := range k.AllowedCIDRs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired reports whether the key has expired at the given time
+func (k *APIKey) Expired(at time.Time) bool {
+	return k.ExpiresAt != nil && !k.ExpiresAt.After(at)
+}
+
+// NewAPIKey formats a key from its random prefix and secret parts
+func NewAPIKey(prefixHex, secretHex string) string {
+	return apiKeyPrefix + prefixHex + "_" + secretHex
+}
+
+// APIKeyPrefix returns the prefix of a key, or false if the key is malformed
+func APIKeyPrefix(key string) (string, bool) {
+	if !strings.HasPrefix(key, apiKeyPrefix) || len(key) <= APIKeyPrefixLength+1 || key[APIKeyPrefixLength] != '_' {
+		return "", false
+	}
+	return key[:APIKeyPrefixLength], true
+}
+
+// HashAPIKey returns the hash under which an API key is stored
+func HashAPIKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+// CreateAPIKeyRequest represents the request body for creating an API key. Without a
+// service account ID a new service account is created for the key; passing the ID of
+// an existing one lets a replacement key act as the same account.
+type CreateAPIKeyRequest struct {
+	Name             string      `json:"name" binding:"required,max=255"`
+	ServiceAccountID string      `json:"service_account_id" binding:"omitempty,uuid"`
+	Scopes           Permissions `json:"scopes" binding:"required,min=1,dive,oneof=sessions:read sessions:write events:write sessions:export sessions:import users:manage webhooks:manage settings:manage orgs:manage"`
+	AllowedCIDRs     []string    `json:"allowed_cidrs" binding:"dive,cidr"`
+	ExpiresAt        *time.Time  `json:"expires_at"`
+}
+
+// UpdateAPIKeyRequest represents the request body for changing an API key; omitted
+// fields are left unchanged, and no_expiry removes the expiry
+type UpdateAPIKeyRequest struct {
+	Name         *string     `json:"name" binding:"omitempty,max=255"`
+	Scopes       Permissions `json:"scopes" binding:"omitempty,min=1,dive,oneof=sessions:read sessions:write events:write sessions:export sessions:import users:manage webhooks:manage settings:manage orgs:manage"`
+	AllowedCIDRs *[]string   `json:"allowed_cidrs" binding:"omitempty,dive,cidr"`
+	ExpiresAt    *time.Time  `json:"expires_at"`
+	NoExpiry     bool        `json:"no_expiry"`
+}
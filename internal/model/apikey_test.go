@@ -0,0 +1,70 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAPIKeyPrefix(t *testing.T) {
+	tests := []struct {
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{key: NewAPIKey("0123456789abcdef", "secret"), want: "csk_0123456789abcdef", wantOK: true},
+		{key: "csk_0123456789abcdef_"},
+		{key: "csk_0123456789abcdef"},
+		{key: "csk_0123456789abcde_secret"},
+		{key: "rt_0123456789abcdef_secret"},
+		{key: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, ok := APIKeyPrefix(tt.key)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("APIKeyPrefix(%q) = %q, %v, want %q, %v", tt.key, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAllowsAddress(t *testing.T) {
+	tests := []struct {
+		name  string
+		cidrs []string
+		ip    string
+		want  bool
+	}{
+		{name: "no allowlist", ip: "203.0.113.7", want: true},
+		{name: "inside a network", cidrs: []string{"10.0.0.0/8", "203.0.113.0/24"}, ip: "203.0.113.7", want: true},
+		{name: "outside every network", cidrs: []string{"10.0.0.0/8"}, ip: "203.0.113.7"},
+		{name: "single address", cidrs: []string{"203.0.113.7/32"}, ip: "203.0.113.7", want: true},
+		{name: "IPv6", cidrs: []string{"2001:db8::/32"}, ip: "2001:db8::1", want: true},
+		{name: "IPv4 against an IPv6 network", cidrs: []string{"2001:db8::/32"}, ip: "203.0.113.7"},
+		{name: "unparsable address", cidrs: []string{"10.0.0.0/8"}, ip: "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := &APIKey{AllowedCIDRs: tt.cidrs}
+			if got := key.AllowsAddress(tt.ip); got != tt.want {
+				t.Errorf("AllowsAddress(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIKeyExpired(t *testing.T) {
+	now := time.Now()
+	expiresAt := now.Add(time.Hour)
+	key := &APIKey{ExpiresAt: &expiresAt}
+
+	if key.Expired(now) {
+		t.Error("key expired before its expiry")
+	}
+	if !key.Expired(expiresAt) {
+		t.Error("key did not expire at its expiry")
+	}
+	if (&APIKey{}).Expired(now.Add(100 * 365 * 24 * time.Hour)) {
+		t.Error("key without an expiry expired")
+	}
+}
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CDR is the call detail record of an ended session. It is generated in the same
+// transaction that ends the session and is not changed afterwards.
+type CDR struct {
+	ID             int64           `json:"id" db:"id"`
+	SessionID      uuid.UUID       `json:"session_id" db:"session_id"`
+	CallerID       string          `json:"caller_id" db:"caller_id"`
+	CalleeID       string          `json:"callee_id" db:"callee_id"`
+	StartedAt      time.Time       `json:"started_at" db:"started_at"`
+	AnsweredAt     *time.Time      `json:"answered_at" db:"answered_at"`
+	EndedAt        time.Time       `json:"ended_at" db:"ended_at"`
+	Duration       int64           `json:"duration" db:"duration"`
+	Billsec        int64           `json:"billsec" db:"billsec"`
+	Status         SessionStatus   `json:"status" db:"status"`
+	Disposition    *string         `json:"disposition" db:"disposition"`
+	ExternalSource *string         `json:"external_source" db:"external_source"`
+	ExternalID     *string         `json:"external_id" db:"external_id"`
+	Metadata       SessionMetadata `json:"initial_metadata" db:"initial_metadata"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+}
+
+// NewCDR builds the record of an ended session from its metrics. The answer time is
+// that of the first answer event, so billsec runs from the answer to the end of the
+// call and is zero for calls that were never answered. Durations are whole seconds.
+func NewCDR(session *Session, metrics *SessionMetrics) *CDR {
+	cdr := &CDR{
+		SessionID:      session.ID,
+		CallerID:       session.CallerID,
+		CalleeID:       session.CalleeID,
+		StartedAt:      session.StartedAt,
+		Status:         session.Status,
+		Disposition:    session.Disposition,
+		ExternalSource: session.ExternalSource,
+		ExternalID:     session.ExternalID,
+		Metadata:       session.InitialMetadata,
+		CreatedAt:      time.Now(),
+	}
+	if session.EndedAt != nil {
+		cdr.EndedAt = *session.EndedAt
+	}
+	cdr.Duration = wholeSeconds(cdr.EndedAt.Sub(cdr.StartedAt))
+
+	if metrics != nil && metrics.TimeToAnswer != nil {
+		answeredAt := cdr.StartedAt.Add(time.Duration(*metrics.TimeToAnswer * float64(time.Second)))
+		cdr.AnsweredAt = &answeredAt
+		cdr.Billsec = wholeSeconds(cdr.EndedAt.Sub(answeredAt))
+	}
+	return cdr
+}
+
+func wholeSeconds(d time.Duration) int64 {
+	if d < 0 {
+		return 0
+	}
+	return int64(d.Round(time.Second) / time.Second)
+}
+
+// CDRQuery selects the records of the sessions that ended in [From, To); a zero time
+// leaves that side of the range open
+type CDRQuery struct {
+	From time.Time
+	To   time.Time
+	// Access restricts the records to those of sessions visible to the caller
+	Access SessionAccess
+}
+
+// CDRRequest describes a download of call detail records
+type CDRRequest struct {
+	Format ExportFormat
+	Query  CDRQuery
+}
+
+// FileName returns the name under which the records are downloaded
+func (r *CDRRequest) FileName(at time.Time) string {
+	return "cdr-" + at.UTC().Format("20060102T150405Z") + "." + string(r.Format)
+}
+
+// ParseCDRRequest parses the query parameters of a CDR download: the format and the
+// start_date and end_date of the range of end times
+func ParseCDRRequest(query url.Values) (*CDRRequest, error) {
+	req := &CDRRequest{Format: ExportFormat(query.Get("format"))}
+	if req.Format == "" {
+		req.Format = ExportCSV
+	}
+	if !req.Format.IsValid() {
+		return nil, ErrInvalidQuery.WithMessage("format must be csv, ndjson or parquet")
+	}
+
+	for _, param := range []struct {
+		name string
+		dst  *time.Time
+	}{{"start_date", &req.Query.From}, {"end_date", &req.Query.To}} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, ErrInvalidQuery.WithMessage(param.name + " must be an RFC 3339 time")
+		}
+		*param.dst = t
+	}
+	if !req.Query.From.IsZero() && !req.Query.To.IsZero() && !req.Query.To.After(req.Query.From) {
+		return nil, ErrInvalidQuery.WithMessage("end_date must be after start_date")
+	}
+
+	return req, nil
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNewCDR(t *testing.T) {
+	start := time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)
+	seconds := func(s float64) *float64 { return &s }
+
+	tests := []struct {
+		name         string
+		ended        time.Duration
+		timeToAnswer *float64
+		wantDuration int64
+		wantBillsec  int64
+		wantAnswered bool
+	}{
+		{name: "answered", ended: 90 * time.Second, timeToAnswer: seconds(12), wantDuration: 90, wantBillsec: 78, wantAnswered: true},
+		{name: "rounded to whole seconds", ended: 90*time.Second + 600*time.Millisecond, timeToAnswer: seconds(10.2), wantDuration: 91, wantBillsec: 80, wantAnswered: true},
+		{name: "never answered", ended: 30 * time.Second, wantDuration: 30},
+		{name: "answer after the end", ended: 5 * time.Second, timeToAnswer: seconds(8), wantDuration: 5, wantAnswered: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ended := start.Add(tt.ended)
+			disposition := "Answered"
+			session := &Session{ID: uuid.New(), StartedAt: start, EndedAt: &ended, CallerID: "+14155550100", CalleeID: "+14155550199", Status: SessionStatusCompleted, Disposition: &disposition}
+
+			cdr := NewCDR(session, &SessionMetrics{TimeToAnswer: tt.timeToAnswer})
+			if cdr.SessionID != session.ID || !cdr.EndedAt.Equal(ended) || cdr.Status != SessionStatusCompleted || cdr.Disposition != &disposition {
+				t.Errorf("record %+v does not match the session", cdr)
+			}
+			if cdr.Duration != tt.wantDuration || cdr.Billsec != tt.wantBillsec {
+				t.Errorf("duration %d, billsec %d, want %d and %d", cdr.Duration, cdr.Billsec, tt.wantDuration, tt.wantBillsec)
+			}
+			if (cdr.AnsweredAt != nil) != tt.wantAnswered {
+				t.Errorf("answered at %v, want answered: %v", cdr.AnsweredAt, tt.wantAnswered)
+			}
+		})
+	}
+
+	if cdr := NewCDR(&Session{StartedAt: start, EndedAt: &start}, nil); cdr.AnsweredAt != nil || cdr.Billsec != 0 {
+		t.Errorf("record without metrics %+v", cdr)
+	}
+}
@@ -0,0 +1,210 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SessionCursor is the keyset position of a page boundary in a session listing. Query
+// fingerprints the sort and filter of the listing, so a cursor cannot continue another.
+type SessionCursor struct {
+	StartedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+	Desc      bool      `json:"d"`
+	Backward  bool      `json:"b"`
+	Query     string    `json:"q"`
+}
+
+// Encode serializes the cursor into an opaque token signed with the cursor secret
+func (c SessionCursor) Encode() (string, error) {
+	secret, err := cursorSecret()
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(signCursor(secret, encoded)), nil
+}
+
+// DecodeSessionCursor verifies and parses a token produced by SessionCursor.Encode
+func DecodeSessionCursor(token string) (*SessionCursor, error) {
+	secret, err := cursorSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	invalid := &QueryError{Param: "cursor", Reason: "malformed or tampered cursor"}
+
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, invalid
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, signCursor(secret, encoded)) {
+		return nil, invalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, invalid
+	}
+	var cursor SessionCursor
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return nil, invalid
+	}
+
+	return &cursor, nil
+}
+
+func signCursor(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("session-cursor:" + payload))
+	return mac.Sum(nil)
+}
+
+// cursorSecret returns the key used to sign cursors, falling back to the JWT secret
+func cursorSecret() ([]byte, error) {
+	secret := os.Getenv("CURSOR_SECRET")
+	if secret == "" {
+		secret = os.Getenv("JWT_SECRET")
+	}
+	if secret == "" {
+		return nil, errors.New("CURSOR_SECRET or JWT_SECRET environment variable must be set")
+	}
+	return []byte(secret), nil
+}
+
+// CursorSortDirection validates that the requested sort can be served from the keyset
+// and returns whether the listing runs in descending started_at order
+func CursorSortDirection(filter SessionFilter) (bool, error) {
+	desc := true
+	if filter.Cursor != nil {
+		desc = filter.Cursor.Desc
+	}
+	if len(filter.Sort) > 0 {
+		if len(filter.Sort) > 1 || filter.Sort[0].Field != "started_at" {
+			return false, &QueryError{Param: "sort", Reason: "cursor pagination only supports sorting by started_at"}
+		}
+		desc = filter.Sort[0].Desc
+	}
+
+	if filter.Cursor != nil {
+		if filter.Cursor.Desc != desc {
+			return false, &QueryError{Param: "cursor", Reason: "cursor was issued for a different sort order"}
+		}
+		fingerprint, err := filter.cursorFingerprint(desc)
+		if err != nil {
+			return false, err
+		}
+		if filter.Cursor.Query != fingerprint {
+			return false, &QueryError{Param: "cursor", Reason: "cursor was issued for a different sort or filter"}
+		}
+	}
+
+	return desc, nil
+}
+
+// cursorFingerprint hashes the sort and the conditions of a cursor listing. Conditions
+// are hashed in a canonical order with times in UTC, so equivalent requests match.
+func (f SessionFilter) cursorFingerprint(desc bool) (string, error) {
+	conditions := f.AllConditions()
+	canonical := make([]string, len(conditions))
+	for i, c := range conditions {
+		values := make([]interface{}, len(c.Values))
+		for j, value := range c.Values {
+			if t, ok := value.(time.Time); ok {
+				value = t.UTC()
+			}
+			values[j] = value
+		}
+		c.Values = values
+
+		encoded, err := json.Marshal(c)
+		if err != nil {
+			return "", err
+		}
+		canonical[i] = string(encoded)
+	}
+	sort.Strings(canonical)
+
+	payload, err := json.Marshal(struct {
+		Sort       []SortField `json:"sort"`
+		Conditions []string    `json:"conditions"`
+	}{[]SortField{{Field: "started_at", Desc: desc}}, canonical})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(payload)
+	return base64.RawURLEncoding.EncodeToString(sum[:16]), nil
+}
+
+// ScanDescending reports the direction stores must scan the keyset in. Walking backwards
+// reverses the listing direction; NewCursorPage flips the page back afterwards.
+func (f SessionFilter) ScanDescending(desc bool) bool {
+	backward := f.Cursor != nil && f.Cursor.Backward
+	return desc != backward
+}
+
+// NewCursorPage assembles a keyset page from up to Limit+1 sessions fetched in scan
+// order past the filter's cursor, and issues the cursors for the adjacent pages
+func NewCursorPage(filter SessionFilter, desc bool, sessions []Session) (*SessionListResponse, error) {
+	response := &SessionListResponse{Limit: filter.Limit}
+	backward := filter.Cursor != nil && filter.Cursor.Backward
+
+	hasMore := len(sessions) > filter.Limit
+	if hasMore {
+		sessions = sessions[:filter.Limit]
+	}
+	if backward {
+		for i, j := 0, len(sessions)-1; i < j; i, j = i+1, j-1 {
+			sessions[i], sessions[j] = sessions[j], sessions[i]
+		}
+	}
+	response.Sessions = sessions
+
+	if len(sessions) == 0 {
+		return response, nil
+	}
+
+	// A forward page has a previous page whenever it was reached through a cursor,
+	// and a backward page always has the page it came from after it
+	hasNext := hasMore
+	hasPrev := filter.Cursor != nil
+	if backward {
+		hasNext, hasPrev = true, hasMore
+	}
+
+	fingerprint, err := filter.cursorFingerprint(desc)
+	if err != nil {
+		return nil, err
+	}
+	first, last := sessions[0], sessions[len(sessions)-1]
+	if hasNext {
+		cursor := SessionCursor{StartedAt: last.StartedAt, ID: last.ID, Desc: desc, Query: fingerprint}
+		if response.NextCursor, err = cursor.Encode(); err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		cursor := SessionCursor{StartedAt: first.StartedAt, ID: first.ID, Desc: desc, Backward: true, Query: fingerprint}
+		if response.PrevCursor, err = cursor.Encode(); err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
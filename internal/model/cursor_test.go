@@ -0,0 +1,177 @@
+package model
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSessionCursorRoundTrip(t *testing.T) {
+	t.Setenv("CURSOR_SECRET", "cursor-secret")
+
+	want := SessionCursor{
+		StartedAt: time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC),
+		ID:        uuid.New(),
+		Desc:      true,
+		Backward:  true,
+		Query:     "fingerprint",
+	}
+	token, err := want.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeSessionCursor(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.StartedAt.Equal(want.StartedAt) || got.ID != want.ID || got.Desc != want.Desc || got.Backward != want.Backward || got.Query != want.Query {
+		t.Errorf("DecodeSessionCursor = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeSessionCursorRejectsTampering(t *testing.T) {
+	t.Setenv("CURSOR_SECRET", "cursor-secret")
+
+	token, err := SessionCursor{StartedAt: time.Now(), ID: uuid.New(), Desc: true}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, signature, _ := strings.Cut(token, ".")
+
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"t":"2000-01-01T00:00:00Z","id":"` + uuid.NewString() + `","d":true}`))
+	otherSecret := func() string {
+		t.Setenv("CURSOR_SECRET", "another-secret")
+		defer t.Setenv("CURSOR_SECRET", "cursor-secret")
+		token, err := SessionCursor{StartedAt: time.Now(), ID: uuid.New()}.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}()
+
+	tests := map[string]string{
+		"no signature":         payload,
+		"empty":                "",
+		"forged payload":       forged + "." + signature,
+		"truncated signature":  payload + "." + signature[:len(signature)-2],
+		"signature not base64": payload + ".!!!",
+		"other secret":         otherSecret,
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := DecodeSessionCursor(token); err == nil {
+				t.Fatal("DecodeSessionCursor accepted a tampered cursor")
+			} else {
+				assertQueryError(t, err, "cursor", "malformed or tampered cursor")
+			}
+		})
+	}
+}
+
+func TestCursorIsBoundToSortAndFilter(t *testing.T) {
+	t.Setenv("CURSOR_SECRET", "cursor-secret")
+
+	start := time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)
+	sessions := []Session{
+		{ID: uuid.New(), StartedAt: start.Add(2 * time.Minute)},
+		{ID: uuid.New(), StartedAt: start.Add(time.Minute)},
+		{ID: uuid.New(), StartedAt: start},
+	}
+	conditions, err := ParseSessionFilter("status:in:completed,failed;started_at:gte:2024-03-20T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := SessionFilter{Limit: 2, UseCursor: true, Status: SessionStatusCompleted, Conditions: conditions}
+	page, err := NewCursorPage(first, true, sessions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.NextCursor == "" || page.PrevCursor != "" {
+		t.Fatalf("first page cursors: next %q, prev %q", page.NextCursor, page.PrevCursor)
+	}
+	cursor, err := DecodeSessionCursor(page.NextCursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reordered, err := ParseSessionFilter("started_at:gte:2024-03-20T01:00:00+01:00;status:in:completed,failed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherFilter, err := ParseSessionFilter("status:in:completed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		filter  SessionFilter
+		wantErr string
+	}{
+		{name: "same listing", filter: SessionFilter{Status: SessionStatusCompleted, Conditions: conditions}},
+		{name: "equivalent filter in another order", filter: SessionFilter{Status: SessionStatusCompleted, Conditions: reordered}},
+		{name: "same sort given explicitly", filter: SessionFilter{Status: SessionStatusCompleted, Conditions: conditions, Sort: []SortField{{Field: "started_at", Desc: true}}}},
+		{name: "other sort order", filter: SessionFilter{Status: SessionStatusCompleted, Conditions: conditions, Sort: []SortField{{Field: "started_at"}}}, wantErr: "cursor was issued for a different sort order"},
+		{name: "other sort field", filter: SessionFilter{Status: SessionStatusCompleted, Conditions: conditions, Sort: []SortField{{Field: "caller_id"}}}, wantErr: "cursor pagination only supports sorting by started_at"},
+		{name: "other filter", filter: SessionFilter{Status: SessionStatusCompleted, Conditions: otherFilter}, wantErr: "cursor was issued for a different sort or filter"},
+		{name: "filter dropped", filter: SessionFilter{Status: SessionStatusCompleted}, wantErr: "cursor was issued for a different sort or filter"},
+		{name: "other status", filter: SessionFilter{Status: SessionStatusFailed, Conditions: conditions}, wantErr: "cursor was issued for a different sort or filter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			filter.Cursor = cursor
+			desc, err := CursorSortDirection(filter)
+			if tt.wantErr != "" {
+				assertQueryError(t, err, paramOf(err), tt.wantErr)
+				return
+			}
+			if err != nil || !desc {
+				t.Fatalf("CursorSortDirection = %v, %v, want descending", desc, err)
+			}
+		})
+	}
+
+	unsigned := *cursor
+	unsigned.Query = ""
+	if _, err := CursorSortDirection(SessionFilter{Status: SessionStatusCompleted, Conditions: conditions, Cursor: &unsigned}); err == nil {
+		t.Error("CursorSortDirection accepted a cursor without a fingerprint")
+	}
+}
+
+func TestNewCursorPageBackward(t *testing.T) {
+	t.Setenv("CURSOR_SECRET", "cursor-secret")
+
+	start := time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)
+	// A backward page is fetched in the reverse of the listing order
+	scanned := []Session{
+		{ID: uuid.New(), StartedAt: start},
+		{ID: uuid.New(), StartedAt: start.Add(time.Minute)},
+		{ID: uuid.New(), StartedAt: start.Add(2 * time.Minute)},
+	}
+	filter := SessionFilter{Limit: 2, UseCursor: true, Cursor: &SessionCursor{Desc: true, Backward: true}}
+	if filter.ScanDescending(true) {
+		t.Fatal("a backward page of a descending listing scans ascending")
+	}
+
+	page, err := NewCursorPage(filter, true, scanned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Sessions) != 2 || page.Sessions[0].StartedAt.Before(page.Sessions[1].StartedAt) {
+		t.Fatalf("backward page is not in listing order: %v", page.Sessions)
+	}
+	if page.NextCursor == "" || page.PrevCursor == "" {
+		t.Errorf("backward page with more rows should link both ways: next %q, prev %q", page.NextCursor, page.PrevCursor)
+	}
+}
+
+func paramOf(err error) string {
+	if queryErr, ok := err.(*QueryError); ok {
+		return queryErr.Param
+	}
+	return ""
+}
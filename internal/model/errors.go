@@ -0,0 +1,110 @@
+package model
+
+import "errors"
+
+// Error is a domain error carrying a stable machine-readable code. Errors with the same
+// code match each other under errors.Is, so callers can compare against the sentinels
+// below even when the message has been specialised with WithMessage.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is a domain error with the same code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithMessage returns an error with the same code and a more specific message
+func (e *Error) WithMessage(message string) *Error {
+	return &Error{Code: e.Code, Message: message}
+}
+
+var (
+	// Request errors
+	ErrInvalidRequest = &Error{Code: "invalid_request", Message: "invalid request"}
+	ErrInvalidID      = &Error{Code: "invalid_id", Message: "invalid identifier"}
+	ErrInvalidQuery   = &Error{Code: "invalid_query", Message: "invalid query"}
+	ErrConflict       = &Error{Code: "conflict", Message: "resource already exists"}
+	ErrNotFound       = &Error{Code: "not_found", Message: "resource not found"}
+
+	// Authentication and authorization errors
+	ErrUnauthorized        = &Error{Code: "unauthorized", Message: "authentication required"}
+	ErrInvalidCredentials  = &Error{Code: "invalid_credentials", Message: "invalid credentials"}
+	ErrForbidden           = &Error{Code: "forbidden", Message: "insufficient permissions"}
+	ErrInvalidRefreshToken = &Error{Code: "invalid_refresh_token", Message: "refresh token is invalid, expired or revoked"}
+	ErrRefreshTokenReused  = &Error{Code: "refresh_token_reused", Message: "refresh token was already used; the login it belongs to has been revoked"}
+
+	// User errors
+	ErrUserNotFound      = &Error{Code: "user_not_found", Message: "user not found"}
+	ErrUserAlreadyExists = &Error{Code: "user_already_exists", Message: "user already exists"}
+
+	// Role errors
+	ErrRoleNotFound      = &Error{Code: "role_not_found", Message: "role not found"}
+	ErrRoleAlreadyExists = &Error{Code: "role_already_exists", Message: "role already exists"}
+	ErrRoleInUse         = &Error{Code: "role_in_use", Message: "role is held by users"}
+
+	// Organization errors
+	ErrOrganizationNotFound = &Error{Code: "organization_not_found", Message: "organization not found"}
+
+	// API key errors
+	ErrAPIKeyNotFound = &Error{Code: "api_key_not_found", Message: "API key not found"}
+
+	// Session errors
+	ErrSessionNotFound     = &Error{Code: "session_not_found", Message: "session not found"}
+	ErrSessionAlreadyEnded = &Error{Code: "session_already_ended", Message: "session is already ended"}
+	ErrInvalidTransition   = &Error{Code: "invalid_transition", Message: "invalid session state transition"}
+	ErrExternalIDConflict  = &Error{Code: "external_id_conflict", Message: "a session with this external ID already exists"}
+	ErrInvalidTimeRange    = &Error{Code: "invalid_time_range", Message: "end_time must be after or equal to started_at"}
+	ErrEventNotFound       = &Error{Code: "event_not_found", Message: "event not found"}
+	ErrEventTimeOutOfRange = &Error{Code: "event_time_out_of_range", Message: "event_time must be within the last year"}
+	ErrEventTypeNotAllowed = &Error{Code: "event_type_not_allowed", Message: "event type is not allowed in this organization"}
+
+	// Idempotency errors
+	ErrIdempotencyKeyMismatch = &Error{Code: "idempotency_key_mismatch", Message: "idempotency key was already used with a different request"}
+	ErrIdempotencyKeyInUse    = &Error{Code: "idempotency_key_in_use", Message: "a request with this idempotency key is still being processed"}
+
+	// Webhook errors
+	ErrWebhookNotFound         = &Error{Code: "webhook_not_found", Message: "webhook not found"}
+	ErrWebhookDeliveryNotFound = &Error{Code: "webhook_delivery_not_found", Message: "webhook delivery not found"}
+
+	// Export errors
+	ErrExportJobNotFound = &Error{Code: "export_job_not_found", Message: "export job not found"}
+	ErrExportNotReady    = &Error{Code: "export_not_ready", Message: "export job has not succeeded"}
+
+	ErrInternal = &Error{Code: "internal_error", Message: "internal server error"}
+)
+
+// wrappedError pairs a domain error with its underlying cause. Only the domain message
+// is exposed; the cause stays reachable through errors.Is and errors.As.
+type wrappedError struct {
+	kind  *Error
+	cause error
+}
+
+// WrapError attaches a domain error to an underlying cause such as a driver error
+func WrapError(kind *Error, cause error) error {
+	return &wrappedError{kind: kind, cause: cause}
+}
+
+func (e *wrappedError) Error() string {
+	return e.kind.Message
+}
+
+func (e *wrappedError) Unwrap() []error {
+	return []error{e.kind, e.cause}
+}
+
+// ErrorCode returns the domain code of err, or the internal error code if it has none
+func ErrorCode(err error) string {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Code
+	}
+	return ErrInternal.Code
+}
@@ -0,0 +1,158 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxMetadataColumns bounds the number of initial_metadata keys flattened into columns
+const maxMetadataColumns = 50
+
+// ExportFormat is the file format of a session export
+type ExportFormat string
+
+const (
+	ExportCSV     ExportFormat = "csv"
+	ExportNDJSON  ExportFormat = "ndjson"
+	ExportParquet ExportFormat = "parquet"
+)
+
+// IsValid reports whether the format is supported
+func (f ExportFormat) IsValid() bool {
+	switch f {
+	case ExportCSV, ExportNDJSON, ExportParquet:
+		return true
+	}
+	return false
+}
+
+// ContentType returns the media type of files in the format
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportCSV:
+		return "text/csv; charset=utf-8"
+	case ExportNDJSON:
+		return "application/x-ndjson"
+	}
+	return "application/vnd.apache.parquet"
+}
+
+// ExportDataset selects what an export contains: the matching sessions, or the events
+// of the matching sessions
+type ExportDataset string
+
+const (
+	ExportSessions ExportDataset = "sessions"
+	ExportEvents   ExportDataset = "events"
+)
+
+// IsValid reports whether the dataset is known
+func (d ExportDataset) IsValid() bool {
+	return d == ExportSessions || d == ExportEvents
+}
+
+// ExportRequest describes an export: the sessions selected by the filter and sort of
+// ListSessions, the dataset and format, and the initial_metadata keys flattened into
+// their own columns
+type ExportRequest struct {
+	Format          ExportFormat
+	Dataset         ExportDataset
+	Filter          SessionFilter
+	MetadataColumns []string
+}
+
+// FileName returns the name under which the export is downloaded
+func (r *ExportRequest) FileName(at time.Time) string {
+	return fmt.Sprintf("%s-%s.%s", r.Dataset, at.UTC().Format("20060102T150405Z"), r.Format)
+}
+
+// ParseExportRequest parses the query parameters of an export. Sessions are exported
+// oldest first unless a sort is given.
+func ParseExportRequest(query url.Values) (*ExportRequest, error) {
+	req := &ExportRequest{
+		Format:  ExportFormat(query.Get("format")),
+		Dataset: ExportDataset(query.Get("dataset")),
+		Filter:  SessionFilter{Sort: []SortField{{Field: "started_at"}}},
+	}
+	if req.Format == "" {
+		req.Format = ExportCSV
+	}
+	if !req.Format.IsValid() {
+		return nil, ErrInvalidQuery.WithMessage("format must be csv, ndjson or parquet")
+	}
+	if req.Dataset == "" {
+		req.Dataset = ExportSessions
+	}
+	if !req.Dataset.IsValid() {
+		return nil, ErrInvalidQuery.WithMessage("dataset must be sessions or events")
+	}
+
+	if err := req.Filter.BindQuery(query); err != nil {
+		return nil, err
+	}
+	if err := req.Filter.BindSortQuery(query); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, key := range strings.Split(query.Get("metadata_columns"), ",") {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		req.MetadataColumns = append(req.MetadataColumns, key)
+	}
+	if len(req.MetadataColumns) > maxMetadataColumns {
+		return nil, ErrInvalidQuery.WithMessage(fmt.Sprintf("at most %d metadata columns are allowed", maxMetadataColumns))
+	}
+	if len(req.MetadataColumns) > 0 && req.Dataset != ExportSessions {
+		return nil, ErrInvalidQuery.WithMessage("metadata_columns only applies to the sessions dataset")
+	}
+
+	return req, nil
+}
+
+// ExportJobStatus represents the progress of an export job
+type ExportJobStatus string
+
+const (
+	ExportJobPending   ExportJobStatus = "pending"
+	ExportJobRunning   ExportJobStatus = "running"
+	ExportJobSucceeded ExportJobStatus = "succeeded"
+	ExportJobFailed    ExportJobStatus = "failed"
+)
+
+// ExportJob is an export written to disk in the background. Query holds the export's
+// query parameters, which are parsed again when the job runs. AllOrganizations records
+// that the job was created with access to every organization; otherwise it exports only
+// the sessions of its owner's.
+type ExportJob struct {
+	ID               uuid.UUID       `json:"id" db:"id"`
+	UserID           uuid.UUID       `json:"user_id" db:"user_id"`
+	AllOrganizations bool            `json:"-" db:"all_organizations"`
+	Format           ExportFormat    `json:"format" db:"format"`
+	Dataset          ExportDataset   `json:"dataset" db:"dataset"`
+	Query            string          `json:"query" db:"query"`
+	Status           ExportJobStatus `json:"status" db:"status"`
+	Attempts         int             `json:"attempts" db:"attempts"`
+	RowCount         *int64          `json:"row_count,omitempty" db:"row_count"`
+	SizeBytes        *int64          `json:"size_bytes,omitempty" db:"size_bytes"`
+	Error            *string         `json:"error,omitempty" db:"error"`
+	CreatedAt        time.Time       `json:"created_at" db:"created_at"`
+	StartedAt        *time.Time      `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt       *time.Time      `json:"finished_at,omitempty" db:"finished_at"`
+	ExpiresAt        *time.Time      `json:"expires_at,omitempty" db:"expires_at"`
+
+	// DownloadURL is set by the API for succeeded jobs
+	DownloadURL string `json:"download_url,omitempty" db:"-"`
+}
+
+// FileName returns the name of the job's file in the export directory
+func (j *ExportJob) FileName() string {
+	return j.ID.String() + "." + string(j.Format)
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// IdempotencyRecord stores the outcome of a request made with an Idempotency-Key so a
+// retry of the same request can be answered without executing it again
+type IdempotencyRecord struct {
+	UserID      string
+	Key         string
+	RequestHash string
+	// StatusCode is zero while the original request is still being processed
+	StatusCode   int
+	ResponseBody []byte
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
+
+// Completed reports whether the original request has finished and its response is stored
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
@@ -0,0 +1,68 @@
+package model
+
+// IdentityType classifies a caller or callee ID
+type IdentityType string
+
+const (
+	// IdentityE164 is a telephone number in E.164 form, such as +14155550100
+	IdentityE164 IdentityType = "e164"
+	// IdentitySIP is a SIP or SIPS URI, such as sip:alice@example.com
+	IdentitySIP IdentityType = "sip"
+	// IdentityExtension is a short internal number of a PBX
+	IdentityExtension IdentityType = "extension"
+	// IdentityUnknown is an ID that could not be recognised; it is kept as given
+	IdentityUnknown IdentityType = "unknown"
+)
+
+// IsValid reports whether the identity type is one of the known types
+func (t IdentityType) IsValid() bool {
+	switch t {
+	case IdentityE164, IdentitySIP, IdentityExtension, IdentityUnknown:
+		return true
+	}
+	return false
+}
+
+// NumberType classifies an E.164 number by the kind of line it reaches, as far as the
+// numbering plan of its country tells
+type NumberType string
+
+const (
+	NumberTypeMobile            NumberType = "mobile"
+	NumberTypeFixedLine         NumberType = "fixed_line"
+	NumberTypeFixedLineOrMobile NumberType = "fixed_line_or_mobile"
+	NumberTypeTollFree          NumberType = "toll_free"
+	NumberTypePremiumRate       NumberType = "premium_rate"
+	NumberTypeSharedCost        NumberType = "shared_cost"
+	NumberTypeVoIP              NumberType = "voip"
+	NumberTypeUnknown           NumberType = "unknown"
+)
+
+// IsValid reports whether the number type is one of the known types
+func (t NumberType) IsValid() bool {
+	switch t {
+	case NumberTypeMobile, NumberTypeFixedLine, NumberTypeFixedLineOrMobile, NumberTypeTollFree,
+		NumberTypePremiumRate, NumberTypeSharedCost, NumberTypeVoIP, NumberTypeUnknown:
+		return true
+	}
+	return false
+}
+
+// Identity is a caller or callee ID in normalized form. Normalized is the E.164 number
+// or the canonical SIP URI, and the ID as given for extensions and unknown IDs.
+// NumberType is unknown for everything but E.164 numbers.
+type Identity struct {
+	Normalized string
+	Type       IdentityType
+	NumberType NumberType
+}
+
+// SetCaller stores the normalized caller ID alongside the ID as given
+func (s *Session) SetCaller(raw string, id Identity) {
+	s.CallerID, s.CallerIDRaw, s.CallerIDType, s.CallerNumberType = id.Normalized, raw, id.Type, id.NumberType
+}
+
+// SetCallee stores the normalized callee ID alongside the ID as given
+func (s *Session) SetCallee(raw string, id Identity) {
+	s.CalleeID, s.CalleeIDRaw, s.CalleeIDType, s.CalleeNumberType = id.Normalized, raw, id.Type, id.NumberType
+}
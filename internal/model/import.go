@@ -0,0 +1,144 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MaxImportErrors bounds the number of row errors reported for one import
+const MaxImportErrors = 1000
+
+// ImportFormat is the file format of call detail records to import
+type ImportFormat string
+
+const (
+	// ImportAsteriskCSV is the Master.csv written by Asterisk's cdr_csv module
+	ImportAsteriskCSV ImportFormat = "asterisk_csv"
+	// ImportFreeSWITCHCSV is the CSV written by FreeSWITCH's mod_cdr_csv
+	ImportFreeSWITCHCSV ImportFormat = "freeswitch_csv"
+	// ImportFreeSWITCHXML is the XML written by FreeSWITCH's mod_xml_cdr
+	ImportFreeSWITCHXML ImportFormat = "freeswitch_xml"
+)
+
+// IsValid reports whether the format is supported
+func (f ImportFormat) IsValid() bool {
+	switch f {
+	case ImportAsteriskCSV, ImportFreeSWITCHCSV, ImportFreeSWITCHXML:
+		return true
+	}
+	return false
+}
+
+// DefaultSource returns the external source recorded for imported sessions when the
+// import does not name one
+func (f ImportFormat) DefaultSource() string {
+	if f == ImportAsteriskCSV {
+		return "asterisk"
+	}
+	return "freeswitch"
+}
+
+// ImportOptions describes how a CDR file is imported. Source becomes the external source
+// of the sessions, so records are deduplicated on their unique ID within it. Location is
+// the time zone of timestamps written without one. Columns names the fields of a
+// FreeSWITCH CSV written with a custom template and no header row.
+type ImportOptions struct {
+	Format   ImportFormat
+	Source   string
+	Location *time.Location
+	Columns  []string
+}
+
+// ParseImportOptions parses the query parameters of an import: format, source, tz
+// and columns
+func ParseImportOptions(query url.Values) (ImportOptions, error) {
+	opts := ImportOptions{
+		Format:   ImportFormat(query.Get("format")),
+		Source:   strings.TrimSpace(query.Get("source")),
+		Location: time.UTC,
+	}
+	if !opts.Format.IsValid() {
+		return opts, ErrInvalidQuery.WithMessage("format must be asterisk_csv, freeswitch_csv or freeswitch_xml")
+	}
+	if tz := query.Get("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return opts, ErrInvalidQuery.WithMessage(fmt.Sprintf("unknown time zone %q", tz))
+		}
+		opts.Location = loc
+	}
+	if columns := query.Get("columns"); columns != "" {
+		if opts.Format != ImportFreeSWITCHCSV {
+			return opts, ErrInvalidQuery.WithMessage("columns only applies to the freeswitch_csv format")
+		}
+		for _, column := range strings.Split(columns, ",") {
+			opts.Columns = append(opts.Columns, strings.TrimSpace(column))
+		}
+	}
+	return opts, nil
+}
+
+// ImportedSession is a session built from a call detail record, with the events and
+// metrics derived from it
+type ImportedSession struct {
+	Session Session
+	Events  []SessionEvent
+	Metrics *SessionMetrics
+}
+
+// ImportRowError reports why a record of an import was rejected. Row is the line of a
+// CSV file or the position of the cdr element in an XML file, counting from 1.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ImportResult summarises an import. Rows counts the records read, which were either
+// imported, skipped as duplicates of sessions imported before, or failed.
+type ImportResult struct {
+	Format          ImportFormat     `json:"format"`
+	Source          string           `json:"source"`
+	Rows            int              `json:"rows"`
+	Imported        int              `json:"imported"`
+	Duplicates      int              `json:"duplicates"`
+	Failed          int              `json:"failed"`
+	Errors          []ImportRowError `json:"errors"`
+	ErrorsTruncated bool             `json:"errors_truncated,omitempty"`
+}
+
+// Reject records a failed row with the domain error's code and message
+func (r *ImportResult) Reject(row int, err error) {
+	r.Failed++
+	if len(r.Errors) == MaxImportErrors {
+		r.ErrorsTruncated = true
+		return
+	}
+
+	rowErr := ImportRowError{Row: row, Code: ErrInternal.Code, Message: ErrInternal.Message}
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		rowErr.Code = domainErr.Code
+		rowErr.Message = err.Error()
+	}
+	r.Errors = append(r.Errors, rowErr)
+}
+
+// ImportError is an import that failed part way. Result holds the rows read until then;
+// the sessions counted as imported stay stored.
+type ImportError struct {
+	Result *ImportResult
+	Err    error
+}
+
+func (e *ImportError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap classifies the import by the error that stopped it
+func (e *ImportError) Unwrap() error {
+	return e.Err
+}
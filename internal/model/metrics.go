@@ -0,0 +1,183 @@
+package model
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SessionMetrics are the timeline figures derived from the events of an ended session.
+// Durations are in seconds; the answer based figures are nil for sessions that were
+// never answered.
+type SessionMetrics struct {
+	SessionID    uuid.UUID `json:"session_id" db:"session_id"`
+	TimeToAnswer *float64  `json:"time_to_answer_seconds" db:"time_to_answer_seconds"`
+	// TalkTime runs from the first answer to the end of the session, less time on hold
+	TalkTime   *float64 `json:"talk_time_seconds" db:"talk_time_seconds"`
+	HoldTime   float64  `json:"hold_time_seconds" db:"hold_time_seconds"`
+	HoldCount  int      `json:"hold_count" db:"hold_count"`
+	Transfers  int      `json:"transfer_count" db:"transfer_count"`
+	EventCount int      `json:"event_count" db:"event_count"`
+	// The gaps are measured between consecutive events and are nil with fewer than two
+	AvgEventGap *float64  `json:"avg_event_gap_seconds" db:"avg_event_gap_seconds"`
+	MaxEventGap *float64  `json:"max_event_gap_seconds" db:"max_event_gap_seconds"`
+	ComputedAt  time.Time `json:"computed_at" db:"computed_at"`
+}
+
+// EventRule matches events by type and, for state transitions, by the state entered
+type EventRule struct {
+	EventType string
+	// To restricts the rule to events whose "to" metadata is this state
+	To SessionStatus
+}
+
+// Matches reports whether the event satisfies the rule
+func (r EventRule) Matches(e *SessionEvent) bool {
+	if e.EventType != r.EventType {
+		return false
+	}
+	if r.To == "" {
+		return true
+	}
+	// Transitions read back from storage hold the state as a plain string
+	switch to := e.Metadata["to"].(type) {
+	case SessionStatus:
+		return to == r.To
+	case string:
+		return SessionStatus(to) == r.To
+	}
+	return false
+}
+
+// ParseEventRules parses a comma separated list of event types, each optionally followed
+// by a colon and the state a transition enters, such as "answered,state_transition:answered"
+func ParseEventRules(s string) []EventRule {
+	var rules []EventRule
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		eventType, to, _ := strings.Cut(item, ":")
+		rules = append(rules, EventRule{EventType: strings.TrimSpace(eventType), To: SessionStatus(strings.TrimSpace(to))})
+	}
+	return rules
+}
+
+// MetricRules decide which events mark the answer, the start and end of a hold and a
+// transfer when deriving session metrics
+type MetricRules struct {
+	Answer    []EventRule
+	HoldStart []EventRule
+	HoldEnd   []EventRule
+	Transfer  []EventRule
+}
+
+// DefaultMetricRules recognise the state transitions recorded by the service together
+// with the event types commonly logged by telephony integrations
+var DefaultMetricRules = MetricRules{
+	Answer: []EventRule{
+		{EventType: "answered"},
+		{EventType: EventTypeStateTransition, To: SessionStatusAnswered},
+	},
+	HoldStart: []EventRule{
+		{EventType: "hold"},
+		{EventType: "hold_start"},
+		{EventType: EventTypeStateTransition, To: SessionStatusOnHold},
+	},
+	HoldEnd: []EventRule{
+		{EventType: "unhold"},
+		{EventType: "hold_end"},
+		{EventType: EventTypeStateTransition, To: SessionStatusAnswered},
+	},
+	Transfer: []EventRule{
+		{EventType: "transfer"},
+		{EventType: EventTypeStateTransition, To: SessionStatusTransferred},
+	},
+}
+
+func matchesAny(rules []EventRule, e *SessionEvent) bool {
+	for _, rule := range rules {
+		if rule.Matches(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// ComputeSessionMetrics derives the metrics of a session that ended at endedAt from its
+// events. Only the first answer counts, a hold lasts until its end event or the end of
+// the session, and hold events outside the answered part of the call are ignored.
+func ComputeSessionMetrics(session *Session, events []SessionEvent, endedAt time.Time, rules MetricRules, now time.Time) *SessionMetrics {
+	ordered := make([]SessionEvent, len(events))
+	copy(ordered, events)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].EventTime.Before(ordered[j].EventTime)
+	})
+
+	metrics := &SessionMetrics{SessionID: session.ID, EventCount: len(ordered), ComputedAt: now}
+
+	var answeredAt, holdStartedAt *time.Time
+	var hold time.Duration
+	for i := range ordered {
+		e := &ordered[i]
+		at := clampTime(e.EventTime, session.StartedAt, endedAt)
+
+		if answeredAt == nil && matchesAny(rules.Answer, e) {
+			answeredAt = &at
+		}
+		switch {
+		case answeredAt == nil:
+		case holdStartedAt == nil && matchesAny(rules.HoldStart, e):
+			holdStartedAt = &at
+			metrics.HoldCount++
+		case holdStartedAt != nil && matchesAny(rules.HoldEnd, e):
+			hold += at.Sub(*holdStartedAt)
+			holdStartedAt = nil
+		}
+		if matchesAny(rules.Transfer, e) {
+			metrics.Transfers++
+		}
+	}
+	if holdStartedAt != nil {
+		hold += endedAt.Sub(*holdStartedAt)
+	}
+	metrics.HoldTime = hold.Seconds()
+
+	if answeredAt != nil {
+		timeToAnswer := answeredAt.Sub(session.StartedAt).Seconds()
+		talkTime := (endedAt.Sub(*answeredAt) - hold).Seconds()
+		metrics.TimeToAnswer = &timeToAnswer
+		metrics.TalkTime = &talkTime
+	}
+
+	if len(ordered) > 1 {
+		var total, longest time.Duration
+		for i := 1; i < len(ordered); i++ {
+			gap := ordered[i].EventTime.Sub(ordered[i-1].EventTime)
+			total += gap
+			if gap > longest {
+				longest = gap
+			}
+		}
+		avg := total.Seconds() / float64(len(ordered)-1)
+		max := longest.Seconds()
+		metrics.AvgEventGap = &avg
+		metrics.MaxEventGap = &max
+	}
+
+	return metrics
+}
+
+// clampTime bounds t to the [from, to] interval
+func clampTime(t, from, to time.Time) time.Time {
+	if t.Before(from) {
+		return from
+	}
+	if t.After(to) {
+		return to
+	}
+	return t
+}
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParseEventRules(t *testing.T) {
+	rules := ParseEventRules(" answered, state_transition : answered ,,hold")
+	want := []EventRule{{EventType: "answered"}, {EventType: EventTypeStateTransition, To: SessionStatusAnswered}, {EventType: "hold"}}
+	if len(rules) != len(want) {
+		t.Fatalf("rules %+v, want %+v", rules, want)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("rule %d = %+v, want %+v", i, rules[i], want[i])
+		}
+	}
+	if rules := ParseEventRules(""); rules != nil {
+		t.Errorf("rules %+v for an empty list", rules)
+	}
+}
+
+func TestEventRuleMatches(t *testing.T) {
+	rule := EventRule{EventType: EventTypeStateTransition, To: SessionStatusOnHold}
+	tests := []struct {
+		name  string
+		event SessionEvent
+		want  bool
+	}{
+		{name: "recorded transition", event: SessionEvent{EventType: EventTypeStateTransition, Metadata: EventMetadata{"to": SessionStatusOnHold}}, want: true},
+		{name: "stored transition", event: SessionEvent{EventType: EventTypeStateTransition, Metadata: EventMetadata{"to": "on_hold"}}, want: true},
+		{name: "other state", event: SessionEvent{EventType: EventTypeStateTransition, Metadata: EventMetadata{"to": "answered"}}},
+		{name: "no state", event: SessionEvent{EventType: EventTypeStateTransition}},
+		{name: "other type", event: SessionEvent{EventType: "hold", Metadata: EventMetadata{"to": "on_hold"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rule.Matches(&tt.event); got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeSessionMetrics(t *testing.T) {
+	start := time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)
+	end := start.Add(100 * time.Second)
+	// event is logged the given number of seconds after the start of the session
+	event := func(eventType string, seconds int) SessionEvent {
+		return SessionEvent{ID: uuid.New(), EventType: eventType, EventTime: start.Add(time.Duration(seconds) * time.Second)}
+	}
+	seconds := func(s float64) *float64 { return &s }
+
+	tests := []struct {
+		name         string
+		events       []SessionEvent
+		timeToAnswer *float64
+		talkTime     *float64
+		holdTime     float64
+		holdCount    int
+		transfers    int
+		maxGap       *float64
+	}{
+		{
+			name:         "answered with a hold",
+			events:       []SessionEvent{event("ringing", 0), event("answered", 10), event("hold", 30), event("unhold", 50), event("hangup", 100)},
+			timeToAnswer: seconds(10), talkTime: seconds(70), holdTime: 20, holdCount: 1, maxGap: seconds(50),
+		},
+		{
+			name:         "events out of order",
+			events:       []SessionEvent{event("unhold", 50), event("answered", 10), event("hold", 30)},
+			timeToAnswer: seconds(10), talkTime: seconds(70), holdTime: 20, holdCount: 1, maxGap: seconds(20),
+		},
+		{
+			name:         "hold until the end",
+			events:       []SessionEvent{event("answered", 10), event("hold", 60), event("transfer", 70)},
+			timeToAnswer: seconds(10), talkTime: seconds(50), holdTime: 40, holdCount: 1, transfers: 1, maxGap: seconds(50),
+		},
+		{
+			name:         "hold before the answer",
+			events:       []SessionEvent{event("hold", 5), event("answered", 10), event("answered", 20)},
+			timeToAnswer: seconds(10), talkTime: seconds(90), maxGap: seconds(10),
+		},
+		{
+			name:   "never answered",
+			events: []SessionEvent{event("ringing", 0), event("hold", 30)},
+			maxGap: seconds(30),
+		},
+		{
+			name:         "answer logged before the start",
+			events:       []SessionEvent{event("answered", -5)},
+			timeToAnswer: seconds(0), talkTime: seconds(100),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &Session{ID: uuid.New(), StartedAt: start}
+			m := ComputeSessionMetrics(session, tt.events, end, DefaultMetricRules, end)
+
+			if m.SessionID != session.ID || m.EventCount != len(tt.events) {
+				t.Errorf("metrics of session %s with %d events", m.SessionID, m.EventCount)
+			}
+			assertSeconds(t, "time to answer", m.TimeToAnswer, tt.timeToAnswer)
+			assertSeconds(t, "talk time", m.TalkTime, tt.talkTime)
+			assertSeconds(t, "max event gap", m.MaxEventGap, tt.maxGap)
+			if m.HoldTime != tt.holdTime || m.HoldCount != tt.holdCount || m.Transfers != tt.transfers {
+				t.Errorf("hold time %v, holds %d, transfers %d, want %v, %d, %d", m.HoldTime, m.HoldCount, m.Transfers, tt.holdTime, tt.holdCount, tt.transfers)
+			}
+		})
+	}
+}
+
+func assertSeconds(t *testing.T, name string, got, want *float64) {
+	t.Helper()
+	switch {
+	case got == nil && want == nil:
+	case got == nil || want == nil:
+		t.Errorf("%s = %v, want %v", name, got, want)
+	case *got != *want:
+		t.Errorf("%s = %v, want %v", name, *got, *want)
+	}
+}
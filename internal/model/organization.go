@@ -0,0 +1,60 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultOrganizationID identifies the organization that the data recorded before
+// organizations were introduced belongs to
+var DefaultOrganizationID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+// Organization is a business unit sharing the installation. Users, API keys, webhooks
+// and sessions belong to exactly one organization and never see those of another.
+type Organization struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	// RetentionDays is how long ended sessions are kept; 0 keeps them forever
+	RetentionDays int `json:"retention_days"`
+	// AllowedEventTypes limits the event types that can be logged; empty allows any.
+	// State transitions are always recorded.
+	AllowedEventTypes []string  `json:"allowed_event_types"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// AllowsEventType reports whether events of the given type can be logged
+func (o *Organization) AllowsEventType(eventType string) bool {
+	if len(o.AllowedEventTypes) == 0 || eventType == EventTypeStateTransition {
+		return true
+	}
+	for _, allowed := range o.AllowedEventTypes {
+		if allowed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateOrganizationRequest represents the request body for creating an organization
+type CreateOrganizationRequest struct {
+	Name              string   `json:"name" binding:"required,max=255"`
+	RetentionDays     int      `json:"retention_days" binding:"min=0"`
+	AllowedEventTypes []string `json:"allowed_event_types" binding:"dive,required,max=255"`
+}
+
+// UpdateOrganizationSettingsRequest represents the request body for changing the
+// settings of the caller's own organization; omitted fields are left unchanged
+type UpdateOrganizationSettingsRequest struct {
+	RetentionDays     *int      `json:"retention_days" binding:"omitempty,min=0"`
+	AllowedEventTypes *[]string `json:"allowed_event_types" binding:"omitempty,dive,required,max=255"`
+}
+
+// UpdateOrganizationRequest represents the request body for changing an organization;
+// omitted fields are left unchanged
+type UpdateOrganizationRequest struct {
+	Name              *string   `json:"name" binding:"omitempty,min=1,max=255"`
+	RetentionDays     *int      `json:"retention_days" binding:"omitempty,min=0"`
+	AllowedEventTypes *[]string `json:"allowed_event_types" binding:"omitempty,dive,required,max=255"`
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxMessage is a domain event recorded in the same transaction as the change it
+// describes, so integrations are notified of exactly the changes that were committed
+type OutboxMessage struct {
+	ID        int64        `json:"id"`
+	Type      ActivityType `json:"type"`
+	SessionID uuid.UUID    `json:"session_id"`
+	OrgID     uuid.UUID    `json:"org_id"`
+	EventID   *uuid.UUID   `json:"event_id,omitempty"`
+	// EventType is the type of the logged event for event.logged messages
+	EventType string          `json:"event_type,omitempty"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// NewSessionOutboxMessage describes a change to a session, carrying the session as stored
+func NewSessionOutboxMessage(activityType ActivityType, session *Session) (OutboxMessage, error) {
+	data, err := json.Marshal(struct {
+		Session *Session `json:"session"`
+	}{session})
+	if err != nil {
+		return OutboxMessage{}, err
+	}
+	return OutboxMessage{
+		Type:      activityType,
+		SessionID: session.ID,
+		OrgID:     session.OrgID,
+		Data:      data,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// NewEventOutboxMessage describes an event logged against a session
+func NewEventOutboxMessage(event *SessionEvent) (OutboxMessage, error) {
+	data, err := json.Marshal(struct {
+		Event *SessionEvent `json:"event"`
+	}{event})
+	if err != nil {
+		return OutboxMessage{}, err
+	}
+	eventID := event.ID
+	return OutboxMessage{
+		Type:      ActivityEventLogged,
+		SessionID: event.SessionID,
+		OrgID:     event.OrgID,
+		EventID:   &eventID,
+		EventType: event.EventType,
+		Data:      data,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// OutboxConsumers lists the consumers reading the outbox that are enabled. A message
+// is only purged once every enabled consumer has processed it.
+type OutboxConsumers struct {
+	Webhooks  bool
+	Publisher bool
+}
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Permission allows an operation on a group of endpoints. Users hold the permissions of
+// their role, access tokens may be scoped down to a subset of them, and API keys hold
+// the permissions they were created with.
+type Permission string
+
+const (
+	// PermissionSessionsRead reads sessions, their streams, analytics and CDRs
+	PermissionSessionsRead Permission = "sessions:read"
+	// PermissionSessionsWrite starts, ends and transitions sessions
+	PermissionSessionsWrite Permission = "sessions:write"
+	// PermissionEventsWrite logs session events
+	PermissionEventsWrite Permission = "events:write"
+	// PermissionSessionsExport exports sessions, directly or through export jobs
+	PermissionSessionsExport Permission = "sessions:export"
+	// PermissionSessionsImport imports sessions from CDR files
+	PermissionSessionsImport Permission = "sessions:import"
+	// PermissionUsersManage manages users, their roles and API keys
+	PermissionUsersManage Permission = "users:manage"
+	// PermissionWebhooksManage manages webhooks and their deliveries
+	PermissionWebhooksManage Permission = "webhooks:manage"
+	// PermissionSettingsManage changes the settings of the caller's own organization
+	PermissionSettingsManage Permission = "settings:manage"
+	// PermissionOrgsManage manages organizations and roles, and the users of every
+	// organization
+	PermissionOrgsManage Permission = "orgs:manage"
+)
+
+// AllPermissions lists every permission, in the order they are documented
+var AllPermissions = Permissions{
+	PermissionSessionsRead,
+	PermissionSessionsWrite,
+	PermissionEventsWrite,
+	PermissionSessionsExport,
+	PermissionSessionsImport,
+	PermissionUsersManage,
+	PermissionWebhooksManage,
+	PermissionSettingsManage,
+	PermissionOrgsManage,
+}
+
+// IsValid reports whether the permission is one of the known permissions
+func (p Permission) IsValid() bool {
+	return AllPermissions.Has(p)
+}
+
+// Permissions is a set of permissions
+type Permissions []Permission
+
+// Has reports whether the set contains a permission
+func (ps Permissions) Has(p Permission) bool {
+	for _, candidate := range ps {
+		if candidate == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Missing returns the permissions of other that the set does not contain
+func (ps Permissions) Missing(other Permissions) Permissions {
+	var missing Permissions
+	for _, p := range other {
+		if !ps.Has(p) {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// Intersect returns the permissions of the set that other contains too. The result is
+// never nil, so it can be told apart from a missing set.
+func (ps Permissions) Intersect(other Permissions) Permissions {
+	both := Permissions{}
+	for _, p := range ps {
+		if other.Has(p) {
+			both = append(both, p)
+		}
+	}
+	return both
+}
+
+// Role maps a role name to the permissions of the users holding it and the sessions
+// they can see. Built-in roles cannot be deleted, and the permissions of the admin role
+// cannot be changed.
+type Role struct {
+	Name         UserRole     `json:"name"`
+	Description  string       `json:"description"`
+	Permissions  Permissions  `json:"permissions"`
+	SessionScope SessionScope `json:"session_scope"`
+	Builtin      bool         `json:"builtin"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+// BuiltinRoles returns the roles every installation starts with. Organization admins
+// hold every permission but orgs:manage. Service accounts hold no permissions through
+// their role; their API keys carry them.
+func BuiltinRoles() []Role {
+	return []Role{
+		{Name: UserRoleAdmin, Description: "Full access to every organization", Permissions: append(Permissions{}, AllPermissions...), SessionScope: SessionScopeAll, Builtin: true},
+		{Name: UserRoleOrgAdmin, Description: "Full access to their organization", Permissions: Permissions{
+			PermissionSessionsRead, PermissionSessionsWrite, PermissionEventsWrite, PermissionSessionsExport,
+			PermissionSessionsImport, PermissionUsersManage, PermissionWebhooksManage, PermissionSettingsManage,
+		}, SessionScope: SessionScopeAll, Builtin: true},
+		{Name: UserRoleUser, Description: "Records, reads and exports their own call sessions", Permissions: Permissions{
+			PermissionSessionsRead, PermissionSessionsWrite, PermissionEventsWrite, PermissionSessionsExport,
+		}, SessionScope: SessionScopeOwn, Builtin: true},
+		{Name: UserRoleSupervisor, Description: "Reads and exports the call sessions of their team", Permissions: Permissions{
+			PermissionSessionsRead, PermissionSessionsExport,
+		}, SessionScope: SessionScopeTeam, Builtin: true},
+		{Name: UserRoleService, Description: "Service accounts acting through API keys", Permissions: Permissions{}, SessionScope: SessionScopeOwn, Builtin: true},
+	}
+}
+
+// CreateRoleRequest represents the request body for creating a role
+type CreateRoleRequest struct {
+	Name        string      `json:"name" binding:"required,max=64"`
+	Description string      `json:"description" binding:"max=255"`
+	Permissions Permissions `json:"permissions" binding:"required,dive,oneof=sessions:read sessions:write events:write sessions:export sessions:import users:manage webhooks:manage settings:manage orgs:manage"`
+	// SessionScope defaults to own
+	SessionScope SessionScope `json:"session_scope" binding:"omitempty,oneof=own team all"`
+}
+
+// UpdateRoleRequest represents the request body for changing a role; omitted fields are
+// left unchanged
+type UpdateRoleRequest struct {
+	Description  *string       `json:"description" binding:"omitempty,max=255"`
+	Permissions  *Permissions  `json:"permissions" binding:"omitempty,dive,oneof=sessions:read sessions:write events:write sessions:export sessions:import users:manage webhooks:manage settings:manage orgs:manage"`
+	SessionScope *SessionScope `json:"session_scope" binding:"omitempty,oneof=own team all"`
+}
+
+// CreateUserRequest represents the request body for adding a user to an organization.
+// Role defaults to the user role, and OrgID to the caller's organization.
+type CreateUserRequest struct {
+	Email    string     `json:"email" binding:"required,email"`
+	Password string     `json:"password" binding:"required,min=6"`
+	Role     UserRole   `json:"role" binding:"max=64"`
+	Team     string     `json:"team" binding:"max=64"`
+	OrgID    *uuid.UUID `json:"org_id"`
+}
+
+// UpdateUserRequest represents the request body for changing the role, team or
+// organization of a user; omitted fields are left unchanged, and an empty team removes
+// the user from their team
+type UpdateUserRequest struct {
+	Role  *UserRole  `json:"role" binding:"omitempty,min=1,max=64"`
+	Team  *string    `json:"team" binding:"omitempty,max=64"`
+	OrgID *uuid.UUID `json:"org_id"`
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPermissions(t *testing.T) {
+	held := Permissions{PermissionSessionsRead, PermissionSessionsExport}
+
+	tests := []struct {
+		name          string
+		other         Permissions
+		wantMissing   Permissions
+		wantIntersect Permissions
+	}{
+		{name: "subset", other: Permissions{PermissionSessionsRead}, wantIntersect: Permissions{PermissionSessionsRead}},
+		{name: "overlapping", other: Permissions{PermissionSessionsExport, PermissionUsersManage}, wantMissing: Permissions{PermissionUsersManage}, wantIntersect: Permissions{PermissionSessionsExport}},
+		{name: "disjoint", other: Permissions{PermissionOrgsManage}, wantMissing: Permissions{PermissionOrgsManage}, wantIntersect: Permissions{}},
+		{name: "empty", other: Permissions{}, wantIntersect: Permissions{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := held.Missing(tt.other); !reflect.DeepEqual(got, tt.wantMissing) {
+				t.Errorf("Missing = %v, want %v", got, tt.wantMissing)
+			}
+			got := held.Intersect(tt.other)
+			if got == nil || !reflect.DeepEqual(got, tt.wantIntersect) {
+				t.Errorf("Intersect = %#v, want %#v", got, tt.wantIntersect)
+			}
+		})
+	}
+
+	if !PermissionWebhooksManage.IsValid() || Permission("sessions:delete").IsValid() {
+		t.Error("IsValid does not match AllPermissions")
+	}
+}
+
+func TestBuiltinRoles(t *testing.T) {
+	roles := make(map[UserRole]Role)
+	for _, role := range BuiltinRoles() {
+		if !role.Builtin {
+			t.Errorf("built-in role %s is not marked built in", role.Name)
+		}
+		roles[role.Name] = role
+	}
+
+	tests := []struct {
+		role   UserRole
+		has    Permissions
+		hasNot Permissions
+		scope  SessionScope
+	}{
+		{role: UserRoleAdmin, has: AllPermissions, scope: SessionScopeAll},
+		{role: UserRoleOrgAdmin, has: Permissions{PermissionUsersManage, PermissionWebhooksManage}, hasNot: Permissions{PermissionOrgsManage}, scope: SessionScopeAll},
+		{role: UserRoleUser, has: Permissions{PermissionSessionsWrite, PermissionEventsWrite}, hasNot: Permissions{PermissionUsersManage, PermissionSessionsImport}, scope: SessionScopeOwn},
+		{role: UserRoleSupervisor, has: Permissions{PermissionSessionsRead, PermissionSessionsExport}, hasNot: Permissions{PermissionSessionsWrite, PermissionUsersManage}, scope: SessionScopeTeam},
+		{role: UserRoleService, hasNot: AllPermissions, scope: SessionScopeOwn},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			role, ok := roles[tt.role]
+			if !ok {
+				t.Fatal("role is not built in")
+			}
+			if missing := role.Permissions.Missing(tt.has); len(missing) > 0 {
+				t.Errorf("missing %v", missing)
+			}
+			for _, p := range tt.hasNot {
+				if role.Permissions.Has(p) {
+					t.Errorf("holds %s", p)
+				}
+			}
+			if role.SessionScope != tt.scope {
+				t.Errorf("session scope %s, want %s", role.SessionScope, tt.scope)
+			}
+		})
+	}
+}
@@ -1,26 +1,48 @@
 package model
 
 import (
-	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/vasu74/Call_Session_Management/internal/config"
 )
 
 // SessionStatus represents the possible states of a session
 type SessionStatus string
 
 const (
-	SessionStatusOngoing   SessionStatus = "ongoing"
-	SessionStatusCompleted SessionStatus = "completed"
-	SessionStatusFailed    SessionStatus = "failed"
+	// Active states
+	SessionStatusInitiated SessionStatus = "initiated"
+	SessionStatusRinging   SessionStatus = "ringing"
+	SessionStatusAnswered  SessionStatus = "answered"
+	SessionStatusOnHold    SessionStatus = "on_hold"
+
+	// SessionStatusOngoing is the generic active state of sessions created before the
+	// detailed call states were introduced
+	SessionStatusOngoing SessionStatus = "ongoing"
+
+	// Terminal states
+	SessionStatusTransferred SessionStatus = "transferred"
+	SessionStatusCompleted   SessionStatus = "completed"
+	SessionStatusFailed      SessionStatus = "failed"
+	SessionStatusMissed      SessionStatus = "missed"
+	SessionStatusBusy        SessionStatus = "busy"
+	SessionStatusNoAnswer    SessionStatus = "no_answer"
 )
 
+// IsValid reports whether the status is one of the known session states
+func (s SessionStatus) IsValid() bool {
+	switch s {
+	case SessionStatusInitiated, SessionStatusRinging, SessionStatusAnswered, SessionStatusOnHold,
+		SessionStatusOngoing, SessionStatusTransferred, SessionStatusCompleted, SessionStatusFailed,
+		SessionStatusMissed, SessionStatusBusy, SessionStatusNoAnswer:
+		return true
+	}
+	return false
+}
+
 // SessionMetadata represents the flexible metadata structure for sessions
 type SessionMetadata map[string]interface{}
 
@@ -47,18 +69,50 @@ func (m *SessionMetadata) Scan(value interface{}) error {
 	return json.Unmarshal(bytes, m)
 }
 
-// Session represents a call session in the system
+// Text renders a metadata value as text the way the Postgres ->> operator does;
+// ok is false for missing keys and JSON null
+func (m SessionMetadata) Text(key string) (string, bool) {
+	value, present := m[key]
+	if !present || value == nil {
+		return "", false
+	}
+	if s, isString := value.(string); isString {
+		return s, true
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+	return string(encoded), true
+}
+
+// Session represents a call session in the system. CallerID and CalleeID hold the
+// normalized IDs and the Raw fields the IDs as they were given.
 type Session struct {
-	ID              uuid.UUID       `json:"id" db:"id"`
-	StartedAt       time.Time       `json:"started_at" db:"started_at"`
-	EndedAt         *time.Time      `json:"ended_at,omitempty" db:"ended_at"`
-	CallerID        string          `json:"caller_id" db:"caller_id"`
-	CalleeID        string          `json:"callee_id" db:"callee_id"`
-	Status          SessionStatus   `json:"status" db:"status"`
-	InitialMetadata SessionMetadata `json:"initial_metadata" db:"initial_metadata"`
-	Disposition     *string         `json:"disposition,omitempty" db:"disposition"`
-	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time       `json:"updated_at" db:"updated_at"`
+	ID               uuid.UUID       `json:"id" db:"id"`
+	StartedAt        time.Time       `json:"started_at" db:"started_at"`
+	EndedAt          *time.Time      `json:"ended_at,omitempty" db:"ended_at"`
+	CallerID         string          `json:"caller_id" db:"caller_id"`
+	CallerIDRaw      string          `json:"caller_id_raw" db:"caller_id_raw"`
+	CallerIDType     IdentityType    `json:"caller_id_type" db:"caller_id_type"`
+	CallerNumberType NumberType      `json:"caller_number_type" db:"caller_number_type"`
+	CalleeID         string          `json:"callee_id" db:"callee_id"`
+	CalleeIDRaw      string          `json:"callee_id_raw" db:"callee_id_raw"`
+	CalleeIDType     IdentityType    `json:"callee_id_type" db:"callee_id_type"`
+	CalleeNumberType NumberType      `json:"callee_number_type" db:"callee_number_type"`
+	Status           SessionStatus   `json:"status" db:"status"`
+	InitialMetadata  SessionMetadata `json:"initial_metadata" db:"initial_metadata"`
+	Disposition      *string         `json:"disposition,omitempty" db:"disposition"`
+	ExternalSource   *string         `json:"external_source,omitempty" db:"external_source"`
+	ExternalID       *string         `json:"external_id,omitempty" db:"external_id"`
+	// OrgID is the organization the session belongs to; it is never visible to others
+	OrgID uuid.UUID `json:"org_id" db:"org_id"`
+	// CreatedBy is the user who started or imported the session and Team their team at
+	// the time; sessions recorded before ownership was tracked have neither
+	CreatedBy *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	Team      string     `json:"team,omitempty" db:"team"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
 }
 
 // StartSessionRequest represents the request body for starting a new session
@@ -66,233 +120,106 @@ type StartSessionRequest struct {
 	CallerID        string          `json:"caller_id" binding:"required"`
 	CalleeID        string          `json:"callee_id" binding:"required"`
 	InitialMetadata SessionMetadata `json:"initial_metadata"`
+	// ExternalID correlates the session with a call in another system, such as a SIP
+	// Call-ID; it is unique per ExternalSource
+	ExternalSource string `json:"external_source" binding:"required_with=ExternalID,max=64"`
+	ExternalID     string `json:"external_id" binding:"required_with=ExternalSource,max=255"`
 }
 
 // EndSessionRequest represents the request body for ending a session
 type EndSessionRequest struct {
-	Status      SessionStatus `json:"status" binding:"required,oneof=completed failed"`
+	Status      SessionStatus `json:"status" binding:"required,oneof=completed failed transferred missed busy no_answer"`
 	Disposition string        `json:"disposition" binding:"required"`
 	EndTime     time.Time     `json:"end_time" binding:"required"`
 }
 
-// SessionListResponse represents the paginated response for listing sessions
+// SessionListResponse represents the paginated response for listing sessions.
+// Offset is only set for offset pagination and the cursors only for cursor pagination.
 type SessionListResponse struct {
-	Total    int64     `json:"total"`
-	Limit    int       `json:"limit"`
-	Offset   int       `json:"offset"`
-	Sessions []Session `json:"sessions"`
+	Total      *int64    `json:"total,omitempty"`
+	Limit      int       `json:"limit"`
+	Offset     *int      `json:"offset,omitempty"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	PrevCursor string    `json:"prev_cursor,omitempty"`
+	Sessions   []Session `json:"sessions"`
 }
 
 // SessionFilter represents the filter parameters for listing sessions
 type SessionFilter struct {
-	StartDate *time.Time    `form:"start_date"`
-	EndDate   *time.Time    `form:"end_date"`
-	Status    SessionStatus `form:"status"`
-	CallerID  string        `form:"caller_id"`
-	CalleeID  string        `form:"callee_id"`
-	Limit     int           `form:"limit,default=50"`
-	Offset    int           `form:"offset,default=0"`
-	SortBy    string        `form:"sort_by,default=started_at"`
-	SortOrder string        `form:"sort_order,default=desc"`
-}
-
-// SessionDetails represents the detailed view of a session with its events
-type SessionDetails struct {
-	Session Session        `json:"session"`
-	Events  []SessionEvent `json:"events"`
+	StartDate  *time.Time        `form:"start_date"`
+	EndDate    *time.Time        `form:"end_date"`
+	Status     SessionStatus     `form:"status"`
+	CallerID   string            `form:"caller_id"`
+	CalleeID   string            `form:"callee_id"`
+	Limit      int               `form:"limit,default=50"`
+	Offset     int               `form:"offset,default=0"`
+	Sort       []SortField       `form:"-"`
+	Conditions []FilterCondition `form:"-"`
+
+	// Keyset pagination on (started_at, id); Cursor is nil for the first page
+	UseCursor    bool           `form:"-"`
+	Cursor       *SessionCursor `form:"-"`
+	IncludeTotal bool           `form:"include_total,default=true"`
+
+	// Access restricts the sessions to those visible to the caller
+	Access SessionAccess `form:"-"`
 }
 
-// StartSession creates a new session with the given request data
-func (s *Session) StartSession(req StartSessionRequest) error {
-	now := time.Now()
-	s.ID = uuid.New()
-	s.StartedAt = now
-	s.CallerID = req.CallerID
-	s.CalleeID = req.CalleeID
-	s.Status = SessionStatusOngoing
-	s.InitialMetadata = req.InitialMetadata
-	s.CreatedAt = now
-	s.UpdatedAt = now
+// DefaultSessionSort is the ordering used when no sort expression is given
+var DefaultSessionSort = []SortField{{Field: "started_at", Desc: true}}
 
-	// Insert into database
-	query := `
-		INSERT INTO sessions (id, started_at, caller_id, callee_id, status, initial_metadata, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id, started_at, caller_id, callee_id, status, initial_metadata, created_at, updated_at`
+// AllConditions merges the simple filter parameters with the parsed filter expression
+func (f SessionFilter) AllConditions() []FilterCondition {
+	var conditions []FilterCondition
 
-	err := config.DB.QueryRow(
-		query,
-		s.ID, s.StartedAt, s.CallerID, s.CalleeID, s.Status, s.InitialMetadata, s.CreatedAt, s.UpdatedAt,
-	).Scan(&s.ID, &s.StartedAt, &s.CallerID, &s.CalleeID, &s.Status, &s.InitialMetadata, &s.CreatedAt, &s.UpdatedAt)
-
-	if err != nil {
-		return err
+	if f.StartDate != nil {
+		conditions = append(conditions, FilterCondition{Field: "started_at", Operator: OpGte, Values: []interface{}{*f.StartDate}})
 	}
-
-	return nil
-}
-
-// EndSession marks the session as ended with the given status and disposition
-func (s *Session) EndSession(sessionID string, req EndSessionRequest) error {
-	// First get the session
-	query := `SELECT id, started_at, caller_id, callee_id, status, initial_metadata, created_at, updated_at 
-		FROM sessions WHERE id = $1`
-
-	err := config.DB.QueryRow(query, sessionID).Scan(
-		&s.ID, &s.StartedAt, &s.CallerID, &s.CalleeID, &s.Status, &s.InitialMetadata, &s.CreatedAt, &s.UpdatedAt,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return errors.New("session not found")
-		}
-		return err
+	if f.EndDate != nil {
+		conditions = append(conditions, FilterCondition{Field: "started_at", Operator: OpLte, Values: []interface{}{*f.EndDate}})
 	}
-
-	// Check if session is already ended
-	if s.Status != SessionStatusOngoing {
-		return fmt.Errorf("session is already ended with status: %s", s.Status)
+	if f.Status != "" {
+		conditions = append(conditions, FilterCondition{Field: "status", Operator: OpEq, Values: []interface{}{f.Status}})
 	}
-
-	// Update session
-	updateQuery := `
-		UPDATE sessions 
-		SET status = $1, disposition = $2, ended_at = $3, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $4 AND status = 'ongoing'
-		RETURNING id, started_at, ended_at, caller_id, callee_id, status, initial_metadata, disposition, created_at, updated_at`
-
-	err = config.DB.QueryRow(
-		updateQuery,
-		req.Status, req.Disposition, req.EndTime, sessionID,
-	).Scan(
-		&s.ID, &s.StartedAt, &s.EndedAt, &s.CallerID, &s.CalleeID, &s.Status, &s.InitialMetadata, &s.Disposition, &s.CreatedAt, &s.UpdatedAt,
-	)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return errors.New("session could not be ended - it may have been ended by another request")
-		}
-		return err
+	if f.CallerID != "" {
+		conditions = append(conditions, FilterCondition{Field: "caller_id", Operator: OpEq, Values: []interface{}{f.CallerID}})
 	}
-
-	return nil
-}
-
-// GetSessionDetails retrieves a session and its events
-func GetSessionDetails(sessionID string) (*SessionDetails, error) {
-	var details SessionDetails
-
-	// Get session
-	query := `SELECT id, started_at, ended_at, caller_id, callee_id, status, initial_metadata, disposition, created_at, updated_at 
-		FROM sessions WHERE id = $1`
-
-	err := config.DB.QueryRow(query, sessionID).Scan(
-		&details.Session.ID, &details.Session.StartedAt, &details.Session.EndedAt,
-		&details.Session.CallerID, &details.Session.CalleeID, &details.Session.Status,
-		&details.Session.InitialMetadata, &details.Session.Disposition,
-		&details.Session.CreatedAt, &details.Session.UpdatedAt,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("session not found")
-		}
-		return nil, err
-	}
-
-	// Get events
-	eventsQuery := `SELECT id, session_id, event_type, event_time, metadata, created_at 
-		FROM session_events WHERE session_id = $1 ORDER BY event_time ASC`
-
-	rows, err := config.DB.Query(eventsQuery, sessionID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var event SessionEvent
-		err := rows.Scan(
-			&event.ID, &event.SessionID, &event.EventType, &event.EventTime,
-			&event.Metadata, &event.CreatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		details.Events = append(details.Events, event)
+	if f.CalleeID != "" {
+		conditions = append(conditions, FilterCondition{Field: "callee_id", Operator: OpEq, Values: []interface{}{f.CalleeID}})
 	}
 
-	return &details, nil
+	return append(conditions, f.Conditions...)
 }
 
-// ListSessions retrieves sessions based on filter criteria
-func ListSessions(filter SessionFilter) (*SessionListResponse, error) {
-	var response SessionListResponse
-	response.Limit = filter.Limit
-	response.Offset = filter.Offset
-
-	// Build query
-	query := `SELECT id, started_at, ended_at, caller_id, callee_id, status, initial_metadata, disposition, created_at, updated_at 
-		FROM sessions WHERE 1=1`
-	args := []interface{}{}
-	argCount := 1
-
-	if filter.StartDate != nil {
-		query += fmt.Sprintf(" AND started_at >= $%d", argCount)
-		args = append(args, filter.StartDate)
-		argCount++
-	}
-	if filter.EndDate != nil {
-		query += fmt.Sprintf(" AND started_at <= $%d", argCount)
-		args = append(args, filter.EndDate)
-		argCount++
-	}
-	if filter.Status != "" {
-		query += fmt.Sprintf(" AND status = $%d", argCount)
-		args = append(args, filter.Status)
-		argCount++
-	}
-	if filter.CallerID != "" {
-		query += fmt.Sprintf(" AND caller_id = $%d", argCount)
-		args = append(args, filter.CallerID)
-		argCount++
-	}
-	if filter.CalleeID != "" {
-		query += fmt.Sprintf(" AND callee_id = $%d", argCount)
-		args = append(args, filter.CalleeID)
-		argCount++
-	}
-
-	// Get total count
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s) as count_query", query)
-	err := config.DB.QueryRow(countQuery, args...).Scan(&response.Total)
-	if err != nil {
-		return nil, err
-	}
-
-	// Add sorting and pagination
-	query += fmt.Sprintf(" ORDER BY %s %s LIMIT $%d OFFSET $%d",
-		filter.SortBy, filter.SortOrder, argCount, argCount+1)
-	args = append(args, filter.Limit, filter.Offset)
-
-	// Get sessions
-	rows, err := config.DB.Query(query, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var session Session
-		err := rows.Scan(
-			&session.ID, &session.StartedAt, &session.EndedAt,
-			&session.CallerID, &session.CalleeID, &session.Status,
-			&session.InitialMetadata, &session.Disposition,
-			&session.CreatedAt, &session.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
+// NormalizeIdentities rewrites the caller and callee IDs the filter compares for
+// equality with normalize, so that they match the stored IDs however they are written.
+// Substring matches are left as given.
+func (f *SessionFilter) NormalizeIdentities(normalize func(string) string) {
+	if f.CallerID != "" {
+		f.CallerID = normalize(f.CallerID)
+	}
+	if f.CalleeID != "" {
+		f.CalleeID = normalize(f.CalleeID)
+	}
+
+	conditions := make([]FilterCondition, len(f.Conditions))
+	for i, c := range f.Conditions {
+		if (c.Field == "caller_id" || c.Field == "callee_id") && c.Operator != OpContains {
+			values := make([]interface{}, len(c.Values))
+			for j, value := range c.Values {
+				values[j] = normalize(value.(string))
+			}
+			c.Values = values
 		}
-		response.Sessions = append(response.Sessions, session)
+		conditions[i] = c
 	}
+	f.Conditions = conditions
+}
 
-	return &response, nil
+// SessionDetails represents the detailed view of a session with its events and, once
+// the session has ended, its metrics
+type SessionDetails struct {
+	Session Session         `json:"session"`
+	Events  []SessionEvent  `json:"events"`
+	Metrics *SessionMetrics `json:"metrics,omitempty"`
 }
@@ -1,16 +1,18 @@
 package model
 
 import (
-	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/vasu74/Call_Session_Management/internal/config"
 )
 
+// MaxEventAge mirrors the valid_event_time constraint of the session_events table
+const MaxEventAge = 365 * 24 * time.Hour
+
 // EventMetadata represents the flexible metadata structure for session events
 type EventMetadata map[string]interface{}
 
@@ -41,6 +43,7 @@ func (m *EventMetadata) Scan(value interface{}) error {
 type SessionEvent struct {
 	ID        uuid.UUID     `json:"id" db:"id"`
 	SessionID uuid.UUID     `json:"session_id" db:"session_id"`
+	OrgID     uuid.UUID     `json:"org_id" db:"org_id"`
 	EventType string        `json:"event_type" db:"event_type"`
 	EventTime time.Time     `json:"event_time" db:"event_time"`
 	Metadata  EventMetadata `json:"metadata" db:"metadata"`
@@ -54,44 +57,93 @@ type LogEventRequest struct {
 	Metadata  EventMetadata `json:"metadata"`
 }
 
-// LogEvent creates a new session event with the given request data
-func (e *SessionEvent) LogEvent(sessionID string, req LogEventRequest) error {
-	// First verify the session exists and is not ended
-	var status SessionStatus
-	err := config.DB.QueryRow("SELECT status FROM sessions WHERE id = $1", sessionID).Scan(&status)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return errors.New("session not found")
-		}
-		return err
+// Validate applies the same checks as the binding tags, plus the event time range, for
+// events that are not bound one at a time such as the items of a batch
+func (r *LogEventRequest) Validate(now time.Time) error {
+	if r.EventType == "" {
+		return ErrInvalidRequest.WithMessage("event_type is required")
+	}
+	if r.EventTime.IsZero() {
+		return ErrInvalidRequest.WithMessage("event_time is required")
 	}
+	return ValidateEventTime(r.EventTime, now)
+}
 
-	if status != SessionStatusOngoing {
-		return errors.New("cannot log events for ended session")
+// ValidateEventTime checks that an event time is not older than MaxEventAge
+func ValidateEventTime(eventTime, now time.Time) error {
+	if eventTime.Before(now.Add(-MaxEventAge)) {
+		return ErrEventTimeOutOfRange
 	}
+	return nil
+}
+
+// BatchLogEventsRequest represents the request body for logging several events of one session
+type BatchLogEventsRequest struct {
+	Events []LogEventRequest `json:"events" binding:"required"`
+}
+
+// BatchEventItem is an event of a cross-session batch together with its session
+type BatchEventItem struct {
+	SessionID string `json:"session_id"`
+	LogEventRequest
+}
+
+// CrossSessionBatchRequest represents the request body for logging events of many sessions
+type CrossSessionBatchRequest struct {
+	Events []BatchEventItem `json:"events" binding:"required"`
+}
+
+// Batch item outcomes
+const (
+	BatchItemCreated = "created"
+	BatchItemFailed  = "failed"
+)
+
+// BatchItemResult reports the outcome of a single event of a batch
+type BatchItemResult struct {
+	Index  int             `json:"index"`
+	Status string          `json:"status"`
+	Event  *SessionEvent   `json:"event,omitempty"`
+	Error  *BatchItemError `json:"error,omitempty"`
+}
+
+// BatchItemError describes why an event of a batch was rejected
+type BatchItemError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// BatchLogEventsResponse reports the outcome of every event of a batch, in request order
+type BatchLogEventsResponse struct {
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []BatchItemResult `json:"results"`
+}
 
-	// Create event
-	e.ID = uuid.New()
-	e.SessionID = uuid.MustParse(sessionID)
-	e.EventType = req.EventType
-	e.EventTime = req.EventTime
-	e.Metadata = req.Metadata
-	e.CreatedAt = time.Now()
-
-	// Insert into database
-	query := `
-		INSERT INTO session_events (id, session_id, event_type, event_time, metadata, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, session_id, event_type, event_time, metadata, created_at`
-
-	err = config.DB.QueryRow(
-		query,
-		e.ID, e.SessionID, e.EventType, e.EventTime, e.Metadata, e.CreatedAt,
-	).Scan(&e.ID, &e.SessionID, &e.EventType, &e.EventTime, &e.Metadata, &e.CreatedAt)
-
-	if err != nil {
-		return err
+// Reject records an item as failed with the domain error's code and message
+func (r *BatchLogEventsResponse) Reject(index int, err error) {
+	itemErr := &BatchItemError{Code: ErrInternal.Code, Message: ErrInternal.Message}
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		itemErr = &BatchItemError{Code: domainErr.Code, Message: err.Error()}
 	}
+	r.Results[index] = BatchItemResult{Index: index, Status: BatchItemFailed, Error: itemErr}
+	r.Failed++
+}
+
+// Accept records an item as created
+func (r *BatchLogEventsResponse) Accept(index int, event *SessionEvent) {
+	r.Results[index] = BatchItemResult{Index: index, Status: BatchItemCreated, Event: event}
+	r.Succeeded++
+}
 
+// ValidateBatchSize rejects empty batches and batches above the limit
+func ValidateBatchSize(size, limit int) error {
+	if size == 0 {
+		return ErrInvalidRequest.WithMessage("events must contain at least one event")
+	}
+	if size > limit {
+		return ErrInvalidRequest.WithMessage(fmt.Sprintf("batch contains %d events, the maximum is %d", size, limit))
+	}
 	return nil
 }
@@ -0,0 +1,591 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FieldType describes how values for a queryable field are parsed
+type FieldType string
+
+const (
+	FieldTypeString FieldType = "string"
+	FieldTypeUUID   FieldType = "uuid"
+	FieldTypeTime   FieldType = "time"
+	FieldTypeNumber FieldType = "number"
+	FieldTypeStatus FieldType = "status"
+	// FieldTypeIdentity and FieldTypeNumberType are the classifications of caller
+	// and callee IDs
+	FieldTypeIdentity   FieldType = "identity_type"
+	FieldTypeNumberType FieldType = "number_type"
+)
+
+// FilterOperator represents a comparison operator in a filter expression
+type FilterOperator string
+
+const (
+	OpEq       FilterOperator = "eq"
+	OpNe       FilterOperator = "ne"
+	OpGt       FilterOperator = "gt"
+	OpGte      FilterOperator = "gte"
+	OpLt       FilterOperator = "lt"
+	OpLte      FilterOperator = "lte"
+	OpIn       FilterOperator = "in"
+	OpNotIn    FilterOperator = "nin"
+	OpContains FilterOperator = "contains"
+	OpIsNull   FilterOperator = "null"
+)
+
+const (
+	maxFilterConditions = 20
+	maxFilterValues     = 100
+	maxSortFields       = 5
+)
+
+// MaxSessionLimit is the largest page of sessions a listing returns
+const MaxSessionLimit = 1000
+
+// QueryField describes a field of the sessions table that clients may filter or sort on.
+// Column is the SQL expression for the field and value extracts the same value from a
+// Session so that non-SQL stores evaluate expressions identically.
+type QueryField struct {
+	Name      string
+	Column    string
+	Type      FieldType
+	Sortable  bool
+	Nullable  bool
+	Operators []FilterOperator
+	value     func(s *Session) interface{}
+}
+
+var (
+	equalityOperators   = []FilterOperator{OpEq, OpNe, OpIn, OpNotIn}
+	comparableOperators = []FilterOperator{OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpIn, OpNotIn}
+	textOperators       = []FilterOperator{OpEq, OpNe, OpIn, OpNotIn, OpContains}
+)
+
+// sessionQueryFields is the declared schema of the sessions columns exposed to the query language
+var sessionQueryFields = map[string]QueryField{
+	"id": {
+		Column: "id", Type: FieldTypeUUID, Sortable: true, Operators: equalityOperators,
+		value: func(s *Session) interface{} { return s.ID },
+	},
+	"started_at": {
+		Column: "started_at", Type: FieldTypeTime, Sortable: true, Operators: comparableOperators,
+		value: func(s *Session) interface{} { return s.StartedAt },
+	},
+	"ended_at": {
+		Column: "ended_at", Type: FieldTypeTime, Sortable: true, Nullable: true, Operators: comparableOperators,
+		value: func(s *Session) interface{} {
+			if s.EndedAt == nil {
+				return nil
+			}
+			return *s.EndedAt
+		},
+	},
+	"created_at": {
+		Column: "created_at", Type: FieldTypeTime, Sortable: true, Operators: comparableOperators,
+		value: func(s *Session) interface{} { return s.CreatedAt },
+	},
+	"updated_at": {
+		Column: "updated_at", Type: FieldTypeTime, Sortable: true, Operators: comparableOperators,
+		value: func(s *Session) interface{} { return s.UpdatedAt },
+	},
+	"caller_id": {
+		Column: "caller_id", Type: FieldTypeString, Sortable: true, Operators: textOperators,
+		value: func(s *Session) interface{} { return s.CallerID },
+	},
+	"callee_id": {
+		Column: "callee_id", Type: FieldTypeString, Sortable: true, Operators: textOperators,
+		value: func(s *Session) interface{} { return s.CalleeID },
+	},
+	"caller_id_raw": {
+		Column: "caller_id_raw", Type: FieldTypeString, Sortable: true, Operators: textOperators,
+		value: func(s *Session) interface{} { return s.CallerIDRaw },
+	},
+	"callee_id_raw": {
+		Column: "callee_id_raw", Type: FieldTypeString, Sortable: true, Operators: textOperators,
+		value: func(s *Session) interface{} { return s.CalleeIDRaw },
+	},
+	"caller_id_type": {
+		Column: "caller_id_type", Type: FieldTypeIdentity, Sortable: true, Operators: equalityOperators,
+		value: func(s *Session) interface{} { return string(s.CallerIDType) },
+	},
+	"callee_id_type": {
+		Column: "callee_id_type", Type: FieldTypeIdentity, Sortable: true, Operators: equalityOperators,
+		value: func(s *Session) interface{} { return string(s.CalleeIDType) },
+	},
+	"caller_number_type": {
+		Column: "caller_number_type", Type: FieldTypeNumberType, Sortable: true, Operators: equalityOperators,
+		value: func(s *Session) interface{} { return string(s.CallerNumberType) },
+	},
+	"callee_number_type": {
+		Column: "callee_number_type", Type: FieldTypeNumberType, Sortable: true, Operators: equalityOperators,
+		value: func(s *Session) interface{} { return string(s.CalleeNumberType) },
+	},
+	"status": {
+		Column: "status", Type: FieldTypeStatus, Sortable: true, Operators: equalityOperators,
+		value: func(s *Session) interface{} { return s.Status },
+	},
+	"disposition": {
+		Column: "disposition", Type: FieldTypeString, Sortable: true, Nullable: true, Operators: textOperators,
+		value: func(s *Session) interface{} {
+			if s.Disposition == nil {
+				return nil
+			}
+			return *s.Disposition
+		},
+	},
+	"external_source": {
+		Column: "external_source", Type: FieldTypeString, Sortable: true, Nullable: true, Operators: equalityOperators,
+		value: func(s *Session) interface{} {
+			if s.ExternalSource == nil {
+				return nil
+			}
+			return *s.ExternalSource
+		},
+	},
+	"external_id": {
+		Column: "external_id", Type: FieldTypeString, Sortable: true, Nullable: true, Operators: equalityOperators,
+		value: func(s *Session) interface{} {
+			if s.ExternalID == nil {
+				return nil
+			}
+			return *s.ExternalID
+		},
+	},
+	"duration": {
+		Column:    "EXTRACT(EPOCH FROM (ended_at - started_at))",
+		Type:      FieldTypeNumber,
+		Sortable:  true,
+		Nullable:  true,
+		Operators: comparableOperators,
+		value: func(s *Session) interface{} {
+			if s.EndedAt == nil {
+				return nil
+			}
+			return s.EndedAt.Sub(s.StartedAt).Seconds()
+		},
+	},
+}
+
+func init() {
+	for name, field := range sessionQueryFields {
+		field.Name = name
+		if field.Nullable {
+			field.Operators = append(field.Operators, OpIsNull)
+		}
+		sessionQueryFields[name] = field
+	}
+}
+
+// LookupSessionField returns the declared schema entry for a session field
+func LookupSessionField(name string) (QueryField, bool) {
+	field, ok := sessionQueryFields[name]
+	return field, ok
+}
+
+// SortField represents a single key of a multi-key sort
+type SortField struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc"`
+}
+
+// FilterCondition represents a single validated clause of a filter expression
+type FilterCondition struct {
+	Field    string         `json:"field"`
+	Operator FilterOperator `json:"operator"`
+	Values   []interface{}  `json:"values"`
+}
+
+// QueryError describes why a sort or filter expression was rejected
+type QueryError struct {
+	Param    string `json:"param"`
+	Field    string `json:"field,omitempty"`
+	Operator string `json:"operator,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+func (e *QueryError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("invalid %s: %s (field %q)", e.Param, e.Reason, e.Field)
+	}
+	return fmt.Sprintf("invalid %s: %s", e.Param, e.Reason)
+}
+
+// Unwrap classifies every query error as ErrInvalidQuery
+func (e *QueryError) Unwrap() error {
+	return ErrInvalidQuery
+}
+
+// ParseSessionSort parses a sort expression such as "-started_at,caller_id"
+func ParseSessionSort(expr string) ([]SortField, error) {
+	var fields []SortField
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		sort := SortField{Field: part}
+		switch part[0] {
+		case '-':
+			sort.Field, sort.Desc = part[1:], true
+		case '+':
+			sort.Field = part[1:]
+		}
+
+		field, ok := sessionQueryFields[sort.Field]
+		if !ok {
+			return nil, &QueryError{Param: "sort", Field: sort.Field, Reason: "unknown field"}
+		}
+		if !field.Sortable {
+			return nil, &QueryError{Param: "sort", Field: sort.Field, Reason: "field is not sortable"}
+		}
+		if seen[sort.Field] {
+			return nil, &QueryError{Param: "sort", Field: sort.Field, Reason: "field listed more than once"}
+		}
+		seen[sort.Field] = true
+		fields = append(fields, sort)
+	}
+
+	if len(fields) > maxSortFields {
+		return nil, &QueryError{Param: "sort", Reason: fmt.Sprintf("at most %d sort fields are allowed", maxSortFields)}
+	}
+
+	return fields, nil
+}
+
+// ParseLegacySort validates the sort_by and sort_order query parameters
+func ParseLegacySort(sortBy, sortOrder string) ([]SortField, error) {
+	field, ok := sessionQueryFields[sortBy]
+	if !ok || !field.Sortable {
+		return nil, &QueryError{Param: "sort_by", Field: sortBy, Reason: "unknown field"}
+	}
+
+	switch strings.ToLower(sortOrder) {
+	case "", "desc":
+		return []SortField{{Field: sortBy, Desc: true}}, nil
+	case "asc":
+		return []SortField{{Field: sortBy}}, nil
+	default:
+		return nil, &QueryError{Param: "sort_order", Value: sortOrder, Reason: "must be asc or desc"}
+	}
+}
+
+// ParseSessionFilter parses a filter expression such as
+// "status:in:completed,failed;duration:gt:60"
+func ParseSessionFilter(expr string) ([]FilterCondition, error) {
+	var conditions []FilterCondition
+
+	for _, clause := range strings.Split(expr, ";") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+
+		parts := strings.SplitN(clause, ":", 3)
+		if len(parts) != 3 {
+			return nil, &QueryError{Param: "filter", Value: clause, Reason: "expected field:operator:value"}
+		}
+		name, op, raw := parts[0], FilterOperator(parts[1]), parts[2]
+
+		field, ok := sessionQueryFields[name]
+		if !ok {
+			return nil, &QueryError{Param: "filter", Field: name, Reason: "unknown field"}
+		}
+		if !field.allows(op) {
+			return nil, &QueryError{Param: "filter", Field: name, Operator: string(op), Reason: "operator not supported for field"}
+		}
+
+		condition, err := field.parseCondition(op, raw)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if len(conditions) > maxFilterConditions {
+		return nil, &QueryError{Param: "filter", Reason: fmt.Sprintf("at most %d filter clauses are allowed", maxFilterConditions)}
+	}
+
+	return conditions, nil
+}
+
+// BindQuery parses the query parameters that select sessions: start_date, end_date,
+// status, caller_id, callee_id and the filter expression
+func (f *SessionFilter) BindQuery(query url.Values) error {
+	for _, param := range []struct {
+		name string
+		dst  **time.Time
+	}{{"start_date", &f.StartDate}, {"end_date", &f.EndDate}} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return &QueryError{Param: param.name, Value: value, Reason: "expected an RFC3339 timestamp"}
+		}
+		*param.dst = &t
+	}
+	if status := query.Get("status"); status != "" {
+		f.Status = SessionStatus(status)
+	}
+	if callerID := query.Get("caller_id"); callerID != "" {
+		f.CallerID = callerID
+	}
+	if calleeID := query.Get("callee_id"); calleeID != "" {
+		f.CalleeID = calleeID
+	}
+
+	// Validate status if provided
+	if f.Status != "" && !f.Status.IsValid() {
+		return ErrInvalidQuery.WithMessage("invalid status value")
+	}
+
+	// Parse the filter expression against the sessions schema
+	conditions, err := ParseSessionFilter(query.Get("filter"))
+	if err != nil {
+		return err
+	}
+	f.Conditions = conditions
+	return nil
+}
+
+// ParseSessionLimit parses the limit query parameter, returning def when it is empty.
+// Values that are not positive integers or are above MaxSessionLimit are rejected.
+func ParseSessionLimit(raw string, def int) (int, error) {
+	if raw == "" {
+		return def, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		return 0, &QueryError{Param: "limit", Value: raw, Reason: "expected a positive integer"}
+	}
+	if limit > MaxSessionLimit {
+		return 0, &QueryError{Param: "limit", Value: raw, Reason: fmt.Sprintf("at most %d sessions are returned per page", MaxSessionLimit)}
+	}
+	return limit, nil
+}
+
+// ParseSessionOffset parses the offset query parameter, which must be a non-negative
+// integer when given
+func ParseSessionOffset(raw string) (int, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	offset, err := strconv.Atoi(raw)
+	if err != nil || offset < 0 {
+		return 0, &QueryError{Param: "offset", Value: raw, Reason: "expected a non-negative integer"}
+	}
+	return offset, nil
+}
+
+// BindSortQuery parses the sort expression, or the legacy sort_by and sort_order
+// parameters, against the sessions schema
+func (f *SessionFilter) BindSortQuery(query url.Values) error {
+	var err error
+	if sort := query.Get("sort"); sort != "" {
+		f.Sort, err = ParseSessionSort(sort)
+	} else if query.Get("sort_by") != "" || query.Get("sort_order") != "" {
+		sortBy := "started_at"
+		if values, ok := query["sort_by"]; ok {
+			sortBy = values[0]
+		}
+		f.Sort, err = ParseLegacySort(sortBy, query.Get("sort_order"))
+	}
+	return err
+}
+
+func (f QueryField) allows(op FilterOperator) bool {
+	for _, allowed := range f.Operators {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (f QueryField) parseCondition(op FilterOperator, raw string) (FilterCondition, error) {
+	condition := FilterCondition{Field: f.Name, Operator: op}
+
+	if op == OpIsNull {
+		isNull, err := strconv.ParseBool(raw)
+		if err != nil {
+			return condition, &QueryError{Param: "filter", Field: f.Name, Operator: string(op), Value: raw, Reason: "expected true or false"}
+		}
+		condition.Values = []interface{}{isNull}
+		return condition, nil
+	}
+
+	rawValues := []string{raw}
+	if op == OpIn || op == OpNotIn {
+		rawValues = strings.Split(raw, ",")
+		if len(rawValues) > maxFilterValues {
+			return condition, &QueryError{Param: "filter", Field: f.Name, Operator: string(op), Reason: fmt.Sprintf("at most %d values are allowed", maxFilterValues)}
+		}
+	}
+
+	for _, rawValue := range rawValues {
+		value, err := f.parseValue(rawValue)
+		if err != nil {
+			return condition, &QueryError{Param: "filter", Field: f.Name, Operator: string(op), Value: rawValue, Reason: err.Error()}
+		}
+		condition.Values = append(condition.Values, value)
+	}
+
+	return condition, nil
+}
+
+func (f QueryField) parseValue(raw string) (interface{}, error) {
+	switch f.Type {
+	case FieldTypeUUID:
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("expected a UUID")
+		}
+		return id, nil
+	case FieldTypeTime:
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, fmt.Errorf("expected an RFC3339 timestamp")
+		}
+		return t, nil
+	case FieldTypeNumber:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected a number")
+		}
+		return n, nil
+	case FieldTypeStatus:
+		status := SessionStatus(raw)
+		if !status.IsValid() {
+			return nil, fmt.Errorf("unknown session status")
+		}
+		return status, nil
+	case FieldTypeIdentity:
+		if !IdentityType(raw).IsValid() {
+			return nil, fmt.Errorf("expected e164, sip, extension or unknown")
+		}
+		return raw, nil
+	case FieldTypeNumberType:
+		if !NumberType(raw).IsValid() {
+			return nil, fmt.Errorf("unknown number type")
+		}
+		return raw, nil
+	default:
+		if raw == "" {
+			return nil, fmt.Errorf("value must not be empty")
+		}
+		return raw, nil
+	}
+}
+
+// Matches reports whether the session satisfies the condition, following SQL semantics
+// where comparisons against NULL are never true
+func (c FilterCondition) Matches(s *Session) bool {
+	value := sessionQueryFields[c.Field].value(s)
+
+	switch c.Operator {
+	case OpIsNull:
+		return (value == nil) == c.Values[0].(bool)
+	case OpIn, OpNotIn:
+		if value == nil {
+			return false
+		}
+		found := false
+		for _, candidate := range c.Values {
+			if compareValues(value, candidate) == 0 {
+				found = true
+				break
+			}
+		}
+		return found == (c.Operator == OpIn)
+	case OpContains:
+		text, ok := value.(string)
+		return ok && strings.Contains(strings.ToLower(text), strings.ToLower(c.Values[0].(string)))
+	}
+
+	if value == nil {
+		return false
+	}
+	cmp := compareValues(value, c.Values[0])
+	switch c.Operator {
+	case OpEq:
+		return cmp == 0
+	case OpNe:
+		return cmp != 0
+	case OpGt:
+		return cmp > 0
+	case OpGte:
+		return cmp >= 0
+	case OpLt:
+		return cmp < 0
+	case OpLte:
+		return cmp <= 0
+	}
+	return false
+}
+
+// CompareSessions orders two sessions by the sort fields with id as the final tiebreak,
+// placing NULLs last in ascending and first in descending order as Postgres does
+func CompareSessions(a, b *Session, sort []SortField) int {
+	for _, key := range sort {
+		field := sessionQueryFields[key.Field]
+		va, vb := field.value(a), field.value(b)
+
+		var cmp int
+		switch {
+		case va == nil && vb == nil:
+			cmp = 0
+		case va == nil:
+			cmp = 1
+		case vb == nil:
+			cmp = -1
+		default:
+			cmp = compareValues(va, vb)
+		}
+
+		if key.Desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp
+		}
+	}
+	return bytes.Compare(a.ID[:], b.ID[:])
+}
+
+func compareValues(a, b interface{}) int {
+	switch av := a.(type) {
+	case string:
+		return strings.Compare(av, b.(string))
+	case SessionStatus:
+		return strings.Compare(string(av), string(b.(SessionStatus)))
+	case time.Time:
+		return av.Compare(b.(time.Time))
+	case float64:
+		bv := b.(float64)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+		return 0
+	case uuid.UUID:
+		bv := b.(uuid.UUID)
+		return bytes.Compare(av[:], bv[:])
+	}
+	return 0
+}
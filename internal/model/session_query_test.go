@@ -0,0 +1,343 @@
+package model
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParseSessionSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		want    []SortField
+		wantErr string
+	}{
+		{name: "empty", expr: "", want: nil},
+		{name: "descending", expr: "-started_at", want: []SortField{{Field: "started_at", Desc: true}}},
+		{name: "explicit ascending", expr: "+caller_id", want: []SortField{{Field: "caller_id"}}},
+		{
+			name: "multiple keys",
+			expr: "status, -duration ,id",
+			want: []SortField{{Field: "status"}, {Field: "duration", Desc: true}, {Field: "id"}},
+		},
+		{name: "unknown field", expr: "password", wantErr: "unknown field"},
+		{name: "sql injection", expr: "started_at;DROP TABLE sessions", wantErr: "unknown field"},
+		{name: "raw column expression", expr: "EXTRACT(EPOCH FROM ended_at)", wantErr: "unknown field"},
+		{name: "duplicate field", expr: "status,-status", wantErr: "field listed more than once"},
+		{name: "too many fields", expr: "id,status,caller_id,callee_id,started_at,ended_at", wantErr: "at most 5 sort fields are allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSessionSort(tt.expr)
+			if tt.wantErr != "" {
+				assertQueryError(t, err, "sort", tt.wantErr)
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSessionSort(%q) returned error: %v", tt.expr, err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseSessionSort(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ParseSessionSort(%q)[%d] = %v, want %v", tt.expr, i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseLegacySort(t *testing.T) {
+	tests := []struct {
+		sortBy, sortOrder string
+		want              SortField
+		wantParam         string
+	}{
+		{sortBy: "started_at", want: SortField{Field: "started_at", Desc: true}},
+		{sortBy: "caller_id", sortOrder: "ASC", want: SortField{Field: "caller_id"}},
+		{sortBy: "caller_id", sortOrder: "sideways", wantParam: "sort_order"},
+		{sortBy: "1; DROP TABLE sessions", wantParam: "sort_by"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy+" "+tt.sortOrder, func(t *testing.T) {
+			got, err := ParseLegacySort(tt.sortBy, tt.sortOrder)
+			if tt.wantParam != "" {
+				var queryErr *QueryError
+				if !errors.As(err, &queryErr) || queryErr.Param != tt.wantParam {
+					t.Fatalf("ParseLegacySort(%q, %q) error = %v, want a %s query error", tt.sortBy, tt.sortOrder, err, tt.wantParam)
+				}
+				return
+			}
+			if err != nil || len(got) != 1 || got[0] != tt.want {
+				t.Fatalf("ParseLegacySort(%q, %q) = %v, %v, want %v", tt.sortBy, tt.sortOrder, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSessionFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		want    int
+		wantErr string
+	}{
+		{name: "empty", expr: "", want: 0},
+		{name: "equality", expr: "status:eq:completed", want: 1},
+		{name: "several clauses", expr: "status:in:completed,failed; duration:gt:60", want: 2},
+		{name: "value containing colons", expr: "caller_id:eq:sip:alice@example.com", want: 1},
+		{name: "null check", expr: "ended_at:null:true", want: 1},
+		{name: "missing operator", expr: "status", wantErr: "expected field:operator:value"},
+		{name: "unknown field", expr: "password:eq:x", wantErr: "unknown field"},
+		{name: "unsupported operator", expr: "status:gt:completed", wantErr: "operator not supported for field"},
+		{name: "null on non-nullable field", expr: "started_at:null:true", wantErr: "operator not supported for field"},
+		{name: "contains on id", expr: "id:contains:abc", wantErr: "operator not supported for field"},
+		{name: "invalid uuid", expr: "id:eq:nope", wantErr: "expected a UUID"},
+		{name: "invalid time", expr: "started_at:gt:yesterday", wantErr: "expected an RFC3339 timestamp"},
+		{name: "invalid number", expr: "duration:gt:long", wantErr: "expected a number"},
+		{name: "invalid status", expr: "status:eq:lost", wantErr: "unknown session status"},
+		{name: "invalid null flag", expr: "ended_at:null:maybe", wantErr: "expected true or false"},
+		{name: "empty string value", expr: "caller_id:eq:", wantErr: "value must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSessionFilter(tt.expr)
+			if tt.wantErr != "" {
+				assertQueryError(t, err, "filter", tt.wantErr)
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSessionFilter(%q) returned error: %v", tt.expr, err)
+			}
+			if len(got) != tt.want {
+				t.Fatalf("ParseSessionFilter(%q) returned %d conditions, want %d", tt.expr, len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSessionFilterLimits(t *testing.T) {
+	clauses := ""
+	for i := 0; i <= maxFilterConditions; i++ {
+		clauses += "status:eq:completed;"
+	}
+	if _, err := ParseSessionFilter(clauses); err == nil {
+		t.Errorf("ParseSessionFilter accepted %d clauses", maxFilterConditions+1)
+	}
+
+	values := "completed"
+	for i := 0; i < maxFilterValues; i++ {
+		values += ",failed"
+	}
+	if _, err := ParseSessionFilter("status:in:" + values); err == nil {
+		t.Errorf("ParseSessionFilter accepted %d values", maxFilterValues+1)
+	}
+}
+
+func TestParseSessionLimit(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    int
+		wantErr string
+	}{
+		{raw: "", want: 50},
+		{raw: "10", want: 10},
+		{raw: "1000", want: 1000},
+		{raw: "1001", wantErr: "at most 1000 sessions are returned per page"},
+		{raw: "100000000", wantErr: "at most 1000 sessions are returned per page"},
+		{raw: "0", wantErr: "expected a positive integer"},
+		{raw: "-5", wantErr: "expected a positive integer"},
+		{raw: "many", wantErr: "expected a positive integer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseSessionLimit(tt.raw, 50)
+			if tt.wantErr != "" {
+				assertQueryError(t, err, "limit", tt.wantErr)
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ParseSessionLimit(%q) = %d, %v, want %d", tt.raw, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSessionOffset(t *testing.T) {
+	for raw, want := range map[string]int{"": 0, "0": 0, "25": 25} {
+		if got, err := ParseSessionOffset(raw); err != nil || got != want {
+			t.Errorf("ParseSessionOffset(%q) = %d, %v, want %d", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"-1", "ten", "1.5"} {
+		_, err := ParseSessionOffset(raw)
+		assertQueryError(t, err, "offset", "expected a non-negative integer")
+	}
+}
+
+func TestBindQueryRejectsMalformedDates(t *testing.T) {
+	for _, param := range []string{"start_date", "end_date"} {
+		var filter SessionFilter
+		err := filter.BindQuery(url.Values{param: {"2024-03-20"}})
+		assertQueryError(t, err, param, "expected an RFC3339 timestamp")
+	}
+
+	var filter SessionFilter
+	if err := filter.BindQuery(url.Values{"start_date": {"2024-03-20T00:00:00Z"}}); err != nil || filter.StartDate == nil {
+		t.Fatalf("BindQuery = %v, start date %v", err, filter.StartDate)
+	}
+}
+
+func TestBindQueryRejectsInvalidStatus(t *testing.T) {
+	var filter SessionFilter
+	err := filter.BindQuery(url.Values{"status": {"lost"}})
+	if !errors.Is(err, ErrInvalidQuery) {
+		t.Fatalf("BindQuery error = %v, want ErrInvalidQuery", err)
+	}
+}
+
+func TestFilterConditionMatches(t *testing.T) {
+	start := time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)
+	end := start.Add(90 * time.Second)
+	disposition := "Answered"
+	ended := &Session{ID: uuid.New(), StartedAt: start, EndedAt: &end, CallerID: "+14155550100", Status: SessionStatusCompleted, Disposition: &disposition}
+	active := &Session{ID: uuid.New(), StartedAt: start, CallerID: "+14155550199", Status: SessionStatusOngoing}
+
+	tests := []struct {
+		expr          string
+		ended, active bool
+	}{
+		{expr: "status:eq:completed", ended: true},
+		{expr: "status:ne:completed", active: true},
+		{expr: "status:in:completed,ongoing", ended: true, active: true},
+		{expr: "status:nin:completed", active: true},
+		{expr: "duration:gt:60", ended: true},
+		{expr: "duration:lte:60"},
+		{expr: "duration:ne:0", ended: true},
+		{expr: "ended_at:null:true", active: true},
+		{expr: "ended_at:null:false", ended: true},
+		{expr: "disposition:contains:answer", ended: true},
+		{expr: "disposition:nin:busy", ended: true},
+		{expr: "caller_id:contains:0100", ended: true},
+		{expr: "started_at:gte:2024-03-20T10:00:00Z", ended: true, active: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			conditions, err := ParseSessionFilter(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := conditions[0].Matches(ended); got != tt.ended {
+				t.Errorf("ended session: Matches = %v, want %v", got, tt.ended)
+			}
+			if got := conditions[0].Matches(active); got != tt.active {
+				t.Errorf("active session: Matches = %v, want %v", got, tt.active)
+			}
+		})
+	}
+}
+
+func TestCompareSessionsOrdersNullsLikePostgres(t *testing.T) {
+	start := time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Minute)
+	ended := &Session{ID: uuid.New(), StartedAt: start, EndedAt: &end}
+	active := &Session{ID: uuid.New(), StartedAt: start}
+
+	if cmp := CompareSessions(ended, active, []SortField{{Field: "ended_at"}}); cmp >= 0 {
+		t.Errorf("ascending: NULL ended_at should sort last, got %d", cmp)
+	}
+	if cmp := CompareSessions(ended, active, []SortField{{Field: "ended_at", Desc: true}}); cmp <= 0 {
+		t.Errorf("descending: NULL ended_at should sort first, got %d", cmp)
+	}
+	if cmp := CompareSessions(ended, ended, []SortField{{Field: "started_at"}}); cmp != 0 {
+		t.Errorf("identical sessions compare as %d", cmp)
+	}
+}
+
+func assertQueryError(t *testing.T, err error, param, reason string) {
+	t.Helper()
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) {
+		t.Fatalf("error = %v, want a QueryError", err)
+	}
+	if queryErr.Param != param || queryErr.Reason != reason {
+		t.Fatalf("error = %+v, want param %q and reason %q", queryErr, param, reason)
+	}
+	if !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("error %v does not unwrap to ErrInvalidQuery", err)
+	}
+}
+
+func TestNormalizeIdentities(t *testing.T) {
+	conditions, err := ParseSessionFilter("caller_id:in:(415) 555-0100,+14155550101; callee_id:contains:555 01; caller_id_raw:eq:(415) 555-0100; caller_id_type:eq:e164")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter := SessionFilter{CallerID: "(415) 555-0100", CalleeID: "1001", Conditions: conditions}
+	digits := func(s string) string {
+		var b []rune
+		for _, r := range s {
+			if r >= '0' && r <= '9' {
+				b = append(b, r)
+			}
+		}
+		return string(b)
+	}
+
+	filter.NormalizeIdentities(digits)
+	if filter.CallerID != "4155550100" || filter.CalleeID != "1001" {
+		t.Errorf("caller %q, callee %q, want both normalized", filter.CallerID, filter.CalleeID)
+	}
+	want := [][]interface{}{
+		{"4155550100", "14155550101"},
+		// Substring matches and the IDs as given are compared as written
+		{"555 01"},
+		{"(415) 555-0100"},
+		{"e164"},
+	}
+	for i, c := range filter.Conditions {
+		if len(c.Values) != len(want[i]) {
+			t.Fatalf("%s values %v, want %v", c.Field, c.Values, want[i])
+		}
+		for j, value := range c.Values {
+			if value != want[i][j] {
+				t.Errorf("%s value %d = %v, want %v", c.Field, j, value, want[i][j])
+			}
+		}
+	}
+	if conditions[0].Values[0] != "(415) 555-0100" {
+		t.Error("NormalizeIdentities changed the conditions it was given")
+	}
+}
+
+func TestParseIdentityFilters(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{expr: "caller_id_type:eq:sip"},
+		{expr: "callee_id_type:in:e164,extension"},
+		{expr: "caller_number_type:eq:toll_free"},
+		{expr: "callee_number_type:ne:fixed_line_or_mobile"},
+		{expr: "caller_id_type:eq:pstn", wantErr: true},
+		{expr: "caller_number_type:eq:satellite", wantErr: true},
+		{expr: "caller_id_type:contains:e1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if _, err := ParseSessionFilter(tt.expr); (err != nil) != tt.wantErr {
+				t.Errorf("ParseSessionFilter error = %v, want error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
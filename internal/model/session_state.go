@@ -0,0 +1,147 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// EventTypeStateTransition is the event type recorded for every session state change
+	EventTypeStateTransition = "state_transition"
+	// EventTypeAutoEnded is recorded instead when a stale session is ended automatically
+	EventTypeAutoEnded = "auto_ended"
+)
+
+// sessionTransitions lists the states each active state may move to; terminal states
+// have no entry. A session may go from initiated straight to completed when the
+// intermediate states were never reported, and legacy ongoing sessions may move to
+// any state since their progress is unknown.
+var sessionTransitions = map[SessionStatus][]SessionStatus{
+	SessionStatusInitiated: {
+		SessionStatusRinging, SessionStatusAnswered, SessionStatusCompleted, SessionStatusFailed,
+		SessionStatusMissed, SessionStatusBusy, SessionStatusNoAnswer,
+	},
+	SessionStatusRinging: {
+		SessionStatusAnswered, SessionStatusFailed, SessionStatusMissed, SessionStatusBusy, SessionStatusNoAnswer,
+	},
+	SessionStatusAnswered: {
+		SessionStatusOnHold, SessionStatusTransferred, SessionStatusCompleted, SessionStatusFailed,
+	},
+	SessionStatusOnHold: {
+		SessionStatusAnswered, SessionStatusTransferred, SessionStatusCompleted, SessionStatusFailed,
+	},
+	SessionStatusOngoing: {
+		SessionStatusRinging, SessionStatusAnswered, SessionStatusOnHold, SessionStatusTransferred,
+		SessionStatusCompleted, SessionStatusFailed, SessionStatusMissed, SessionStatusBusy, SessionStatusNoAnswer,
+	},
+}
+
+// ErrConcurrentTransition is returned by stores when the session left the expected state
+// before a transition could be applied
+var ErrConcurrentTransition = ErrInvalidTransition.WithMessage("session state was changed by another request")
+
+// IsActive reports whether the session can still change state
+func (s SessionStatus) IsActive() bool {
+	_, ok := sessionTransitions[s]
+	return ok
+}
+
+// IsTerminal reports whether the status ends a session
+func (s SessionStatus) IsTerminal() bool {
+	return s.IsValid() && !s.IsActive()
+}
+
+// CanTransitionTo reports whether a session may move from s to next
+func (s SessionStatus) CanTransitionTo(next SessionStatus) bool {
+	for _, allowed := range sessionTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTransition checks a state change against the session state machine
+func ValidateTransition(from, to SessionStatus) error {
+	if !to.IsValid() {
+		return ErrInvalidRequest.WithMessage("invalid status value")
+	}
+	if !from.IsActive() {
+		return ErrSessionAlreadyEnded.WithMessage(fmt.Sprintf("session is already ended with status: %s", from))
+	}
+	if !from.CanTransitionTo(to) {
+		return ErrInvalidTransition.WithMessage(fmt.Sprintf("cannot transition session from %s to %s", from, to))
+	}
+	return nil
+}
+
+// TransitionSessionRequest represents the request body for changing a session's state
+type TransitionSessionRequest struct {
+	Status      SessionStatus `json:"status" binding:"required"`
+	Disposition string        `json:"disposition"`
+	EventTime   *time.Time    `json:"event_time"`
+	Metadata    EventMetadata `json:"metadata"`
+}
+
+// SessionTransition is a validated state change that a store applies atomically: the
+// session moves from From to To only if it is still in From, and Event is recorded
+// alongside it. Reaching a terminal state sets ended_at to the event time and stores
+// Metrics when they are set.
+type SessionTransition struct {
+	From        SessionStatus
+	To          SessionStatus
+	Disposition *string
+	Event       SessionEvent
+	Metrics     *SessionMetrics
+}
+
+// NewSessionTransition validates a state change of the session and builds the
+// transition event. Caller supplied metadata is kept, with from and to added.
+func NewSessionTransition(session *Session, to SessionStatus, at time.Time, disposition *string, metadata EventMetadata) (*SessionTransition, error) {
+	if err := ValidateTransition(session.Status, to); err != nil {
+		return nil, err
+	}
+
+	eventMetadata := EventMetadata{}
+	for key, value := range metadata {
+		eventMetadata[key] = value
+	}
+	eventMetadata["from"] = session.Status
+	eventMetadata["to"] = to
+	if disposition != nil {
+		eventMetadata["disposition"] = *disposition
+	}
+
+	return &SessionTransition{
+		From:        session.Status,
+		To:          to,
+		Disposition: disposition,
+		Event: SessionEvent{
+			ID:        uuid.New(),
+			SessionID: session.ID,
+			OrgID:     session.OrgID,
+			EventType: EventTypeStateTransition,
+			EventTime: at,
+			Metadata:  eventMetadata,
+			CreatedAt: time.Now(),
+		},
+	}, nil
+}
+
+// ActiveSessionStatuses returns the states from which a session can still change state
+func ActiveSessionStatuses() []SessionStatus {
+	return []SessionStatus{
+		SessionStatusInitiated, SessionStatusRinging, SessionStatusAnswered, SessionStatusOnHold, SessionStatusOngoing,
+	}
+}
+
+// StaleSessionQuery selects active sessions that should be ended automatically: those
+// whose last event (or start, without events) is before InactiveSince, or that started
+// before StartedBefore. A zero time disables that criterion.
+type StaleSessionQuery struct {
+	InactiveSince time.Time
+	StartedBefore time.Time
+	Limit         int
+}
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateTransition(t *testing.T) {
+	tests := []struct {
+		from, to SessionStatus
+		wantErr  error
+	}{
+		{from: SessionStatusInitiated, to: SessionStatusRinging},
+		{from: SessionStatusInitiated, to: SessionStatusCompleted},
+		{from: SessionStatusRinging, to: SessionStatusAnswered},
+		{from: SessionStatusRinging, to: SessionStatusNoAnswer},
+		{from: SessionStatusAnswered, to: SessionStatusOnHold},
+		{from: SessionStatusOnHold, to: SessionStatusAnswered},
+		{from: SessionStatusOngoing, to: SessionStatusBusy},
+		{from: SessionStatusRinging, to: SessionStatusOnHold, wantErr: ErrInvalidTransition},
+		{from: SessionStatusAnswered, to: SessionStatusRinging, wantErr: ErrInvalidTransition},
+		{from: SessionStatusAnswered, to: SessionStatusMissed, wantErr: ErrInvalidTransition},
+		{from: SessionStatusInitiated, to: SessionStatusInitiated, wantErr: ErrInvalidTransition},
+		{from: SessionStatusCompleted, to: SessionStatusAnswered, wantErr: ErrSessionAlreadyEnded},
+		{from: SessionStatusFailed, to: SessionStatusCompleted, wantErr: ErrSessionAlreadyEnded},
+		{from: SessionStatusAnswered, to: "hung_up", wantErr: ErrInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+" to "+string(tt.to), func(t *testing.T) {
+			if err := ValidateTransition(tt.from, tt.to); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ValidateTransition error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSessionStatusClasses(t *testing.T) {
+	for _, status := range ActiveSessionStatuses() {
+		if !status.IsActive() || status.IsTerminal() {
+			t.Errorf("%s should be active", status)
+		}
+	}
+	for _, status := range []SessionStatus{SessionStatusCompleted, SessionStatusFailed, SessionStatusTransferred, SessionStatusMissed, SessionStatusBusy, SessionStatusNoAnswer} {
+		if status.IsActive() || !status.IsTerminal() {
+			t.Errorf("%s should be terminal", status)
+		}
+	}
+	if SessionStatus("hung_up").IsTerminal() {
+		t.Error("an unknown status should not be terminal")
+	}
+}
+
+func TestNewSessionTransition(t *testing.T) {
+	session := &Session{ID: uuid.New(), OrgID: DefaultOrganizationID, Status: SessionStatusRinging}
+	at := time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)
+	disposition := "Answered"
+
+	transition, err := NewSessionTransition(session, SessionStatusAnswered, at, &disposition, EventMetadata{"agent": "42", "from": "spoofed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transition.From != SessionStatusRinging || transition.To != SessionStatusAnswered {
+		t.Errorf("transition from %s to %s", transition.From, transition.To)
+	}
+	event := transition.Event
+	if event.EventType != EventTypeStateTransition || event.SessionID != session.ID || event.OrgID != session.OrgID || !event.EventTime.Equal(at) {
+		t.Errorf("transition event %+v", event)
+	}
+	want := EventMetadata{"agent": "42", "from": SessionStatusRinging, "to": SessionStatusAnswered, "disposition": disposition}
+	for key, value := range want {
+		if event.Metadata[key] != value {
+			t.Errorf("metadata[%s] = %v, want %v", key, event.Metadata[key], value)
+		}
+	}
+
+	if _, err := NewSessionTransition(session, SessionStatusOnHold, at, nil, nil); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("invalid transition error = %v, want ErrInvalidTransition", err)
+	}
+}
@@ -0,0 +1,251 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+// SessionStore persists call sessions
+type SessionStore interface {
+	// CreateSession inserts a new session; the session is updated with the stored values.
+	// It fails with ErrExternalIDConflict if the external ID is already taken.
+	CreateSession(ctx context.Context, session *Session) error
+	// GetSession returns a single session by ID
+	GetSession(ctx context.Context, sessionID string) (*Session, error)
+	// GetSessions returns the sessions with the given IDs; unknown IDs are skipped
+	GetSessions(ctx context.Context, sessionIDs []string) ([]Session, error)
+	// GetSessionByExternalID returns the session of an organization correlated with a
+	// call in another system
+	GetSessionByExternalID(ctx context.Context, orgID, source, externalID string) (*Session, error)
+	// TransitionSession applies a state change and records its event, and the metrics and
+	// CDR of a session it ends, in one step, failing with ErrConcurrentTransition if the
+	// session is no longer in t.From
+	TransitionSession(ctx context.Context, sessionID string, t *SessionTransition) (*Session, error)
+	// GetSessionMetrics returns the metrics stored when a session ended, or
+	// ErrNotFound for sessions without them
+	GetSessionMetrics(ctx context.Context, sessionID string) (*SessionMetrics, error)
+	// ImportSessions stores ended sessions built from call detail records together with
+	// their events and metrics, skipping sessions whose external ID is already taken, and
+	// returns how many were stored. Imported events are exempt from the event time
+	// check, and imported rows are neither written to the outbox nor announced as
+	// activity. Either all sessions are stored or skipped, or none are.
+	ImportSessions(ctx context.Context, sessions []ImportedSession) (int, error)
+	// UpdateSessionIdentities stores the normalized caller and callee IDs of sessions and
+	// their classification; the IDs as given are left alone
+	UpdateSessionIdentities(ctx context.Context, sessions []Session) error
+	// ListSessions returns a page of sessions matching the filter
+	ListSessions(ctx context.Context, filter SessionFilter) (*SessionListResponse, error)
+	// ListStaleSessions returns active sessions matching the stale session query, oldest first
+	ListStaleSessions(ctx context.Context, query StaleSessionQuery) ([]Session, error)
+	// ExportSessions calls fn for every session matching the filter, in the filter's sort
+	// order, reading them in batches so memory use does not depend on the number of
+	// sessions. It stops at the first error returned by fn.
+	ExportSessions(ctx context.Context, filter SessionFilter, fn func(*Session) error) error
+	// SessionAnalytics aggregates the matching sessions into the totals and the non-empty
+	// buckets, in ascending order
+	SessionAnalytics(ctx context.Context, query SessionAnalyticsQuery) (*SessionAnalytics, error)
+	// PurgeSessions removes the sessions of an organization that ended before the given
+	// time together with their events, metrics and CDRs
+	PurgeSessions(ctx context.Context, orgID string, before time.Time) (int64, error)
+}
+
+// EventStore persists the events logged against sessions
+type EventStore interface {
+	// CreateEvent inserts a new event; the event is updated with the stored values
+	CreateEvent(ctx context.Context, event *SessionEvent) error
+	// CreateEvents inserts several events in one transaction; either all or none are stored
+	CreateEvents(ctx context.Context, events []SessionEvent) error
+	// GetEvent returns a single event by ID
+	GetEvent(ctx context.Context, eventID string) (*SessionEvent, error)
+	// ListEvents returns the events of a session ordered by event time
+	ListEvents(ctx context.Context, sessionID string) ([]SessionEvent, error)
+	// ExportEvents calls fn for every event of the sessions matching the filter, grouped
+	// by session in the filter's sort order and ordered by event time within a session
+	ExportEvents(ctx context.Context, filter SessionFilter, fn func(*SessionEvent) error) error
+}
+
+// UserStore persists user accounts
+type UserStore interface {
+	// CreateUser inserts a new user, failing if the email is already registered
+	CreateUser(ctx context.Context, user *User) error
+	// CreateUserWithOrganization inserts a new organization and its first user in one
+	// transaction, storing neither if the email is already registered
+	CreateUserWithOrganization(ctx context.Context, user *User, org *Organization) error
+	// GetUserByID returns a user by ID without the password hash
+	GetUserByID(ctx context.Context, userID string) (*User, error)
+	// GetUserByEmail returns a user by email including the password hash
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	// ListUsers returns the users of an organization, or of all of them if orgID is
+	// empty, without their password hashes, oldest first
+	ListUsers(ctx context.Context, orgID string) ([]User, error)
+	// UpdateUser stores the role, team and organization of a user, failing with
+	// ErrRoleNotFound for an unknown role
+	UpdateUser(ctx context.Context, user *User) error
+}
+
+// OrganizationStore persists organizations and their settings
+type OrganizationStore interface {
+	ListOrganizations(ctx context.Context) ([]Organization, error)
+	GetOrganization(ctx context.Context, orgID string) (*Organization, error)
+	// CreateOrganization inserts a new organization. Names need not be unique;
+	// organizations are identified by their ID.
+	CreateOrganization(ctx context.Context, org *Organization) error
+	// UpdateOrganization stores the name and settings of an organization
+	UpdateOrganization(ctx context.Context, org *Organization) error
+}
+
+// RoleStore persists roles and their permissions
+type RoleStore interface {
+	ListRoles(ctx context.Context) ([]Role, error)
+	GetRole(ctx context.Context, name string) (*Role, error)
+	// CreateRole inserts a new role, failing with ErrRoleAlreadyExists if the name is taken
+	CreateRole(ctx context.Context, role *Role) error
+	// UpdateRole stores the description, permissions and session scope of a role
+	UpdateRole(ctx context.Context, role *Role) error
+	// DeleteRole removes a role, failing with ErrRoleInUse while users hold it
+	DeleteRole(ctx context.Context, name string) error
+}
+
+// TokenStore persists refresh tokens and the denylist of revoked access tokens
+type TokenStore interface {
+	// CreateRefreshToken stores the first token of a new family
+	CreateRefreshToken(ctx context.Context, token *RefreshToken) error
+	// RotateRefreshToken marks the token with the given hash used and stores next as its
+	// successor in the same family, returning the used token. Unknown, expired and
+	// revoked tokens fail with ErrInvalidRefreshToken. A token that was already used
+	// revokes its family and fails with ErrRefreshTokenReused.
+	RotateRefreshToken(ctx context.Context, tokenHash []byte, next *RefreshToken) (*RefreshToken, error)
+	// RevokeRefreshTokenFamily revokes the tokens of a family of the user and denies the
+	// access tokens issued with them
+	RevokeRefreshTokenFamily(ctx context.Context, userID, familyID string, at time.Time) error
+	// RevokeUserRefreshTokens revokes every token family of a user and denies the access
+	// tokens issued with them
+	RevokeUserRefreshTokens(ctx context.Context, userID string, at time.Time) error
+	// RevokeRoleRefreshTokens revokes every token family of the users holding a role and
+	// denies the access tokens issued with them
+	RevokeRoleRefreshTokens(ctx context.Context, role string, at time.Time) error
+	// IsAccessTokenRevoked reports whether the access token with the given jti is denied
+	IsAccessTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+	// DeleteExpiredTokens removes refresh tokens and denylist entries that expired
+	// before the given time
+	DeleteExpiredTokens(ctx context.Context, before time.Time) (int64, error)
+}
+
+// APIKeyStore persists API keys
+type APIKeyStore interface {
+	// CreateAPIKey stores a new key; a non-nil service account is created together with
+	// the key
+	CreateAPIKey(ctx context.Context, key *APIKey, serviceAccount *User) error
+	GetAPIKey(ctx context.Context, keyID string) (*APIKey, error)
+	// GetAPIKeyByPrefix returns a key by its prefix including its hash
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*APIKey, error)
+	// ListAPIKeys returns the keys of an organization
+	ListAPIKeys(ctx context.Context, orgID string) ([]APIKey, error)
+	// UpdateAPIKey stores the name, scopes, allowlist and expiry of a key
+	UpdateAPIKey(ctx context.Context, key *APIKey) error
+	// DeleteAPIKey removes a key; its service account is kept
+	DeleteAPIKey(ctx context.Context, keyID string) error
+	// TouchAPIKey records when a key was last used
+	TouchAPIKey(ctx context.Context, keyID string, at time.Time) error
+}
+
+// IdempotencyStore persists the responses of requests made with an Idempotency-Key
+type IdempotencyStore interface {
+	// ReserveIdempotencyKey claims the record's key for a new request. A key whose record
+	// has expired, or is still unfinished and was created before staleBefore, is taken
+	// over. Otherwise the existing record is returned and nothing is claimed.
+	ReserveIdempotencyKey(ctx context.Context, record *IdempotencyRecord, staleBefore time.Time) (*IdempotencyRecord, error)
+	// RenewIdempotencyKey moves the creation time of an unfinished key to at, extending
+	// the lock of the request holding it
+	RenewIdempotencyKey(ctx context.Context, userID, key string, at time.Time) error
+	// CompleteIdempotencyKey stores the response of the request holding the key
+	CompleteIdempotencyKey(ctx context.Context, userID, key string, statusCode int, body []byte) error
+	// ReleaseIdempotencyKey drops an unfinished key so the request can be retried
+	ReleaseIdempotencyKey(ctx context.Context, userID, key string) error
+	// DeleteExpiredIdempotencyKeys removes records that expired before the given time
+	DeleteExpiredIdempotencyKeys(ctx context.Context, before time.Time) (int64, error)
+}
+
+// WebhookStore persists webhooks and the deliveries of outbox messages to them. The
+// session and event stores append to the outbox in the same transaction as each change.
+type WebhookStore interface {
+	CreateWebhook(ctx context.Context, webhook *Webhook) error
+	GetWebhook(ctx context.Context, webhookID string) (*Webhook, error)
+	// ListWebhooks returns the webhooks of an organization
+	ListWebhooks(ctx context.Context, orgID string) ([]Webhook, error)
+	// UpdateWebhook stores the changed fields of a webhook; the secret is not changed
+	UpdateWebhook(ctx context.Context, webhook *Webhook) error
+	// DeleteWebhook removes a webhook together with its deliveries
+	DeleteWebhook(ctx context.Context, webhookID string) error
+	// DispatchOutbox creates a pending delivery for every active webhook matching each of
+	// up to limit undispatched outbox messages, oldest first, and returns how many
+	// messages were dispatched
+	DispatchOutbox(ctx context.Context, limit int) (int, error)
+	// ClaimWebhookDeliveries returns up to limit pending deliveries due at now, leasing
+	// them until leaseUntil so they are not attempted twice
+	ClaimWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]WebhookTask, error)
+	// RecordWebhookAttempt logs an attempt and applies its outcome to the delivery
+	RecordWebhookAttempt(ctx context.Context, attempt *WebhookAttempt, update WebhookDeliveryUpdate) error
+	// ListWebhookDeliveries returns a page of the deliveries of a webhook, newest first
+	ListWebhookDeliveries(ctx context.Context, webhookID string, filter WebhookDeliveryFilter) ([]WebhookDelivery, error)
+	// GetWebhookDelivery returns a delivery of a webhook with its attempt log
+	GetWebhookDelivery(ctx context.Context, webhookID, deliveryID string) (*WebhookDeliveryDetails, error)
+	// RetryWebhookDelivery makes a delivery pending again with a fresh set of attempts
+	RetryWebhookDelivery(ctx context.Context, webhookID, deliveryID string, at time.Time) (*WebhookDelivery, error)
+	// PurgeWebhookDeliveries removes finished deliveries last updated before the given time
+	PurgeWebhookDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
+
+// OutboxStore gives the message bus relay access to the outbox. Messages of a session
+// are numbered in the order their transactions committed.
+type OutboxStore interface {
+	// ListUnpublishedOutbox returns the oldest messages not yet published, by ID
+	ListUnpublishedOutbox(ctx context.Context, limit int) ([]OutboxMessage, error)
+	// MarkOutboxPublished records that messages were accepted by the message bus
+	MarkOutboxPublished(ctx context.Context, ids []int64, at time.Time) error
+	// PurgeOutbox removes messages created before the given time that every enabled
+	// consumer has processed and no webhook delivery refers to
+	PurgeOutbox(ctx context.Context, before time.Time, consumers OutboxConsumers) (int64, error)
+}
+
+// ExportJobStore persists background export jobs
+type ExportJobStore interface {
+	CreateExportJob(ctx context.Context, job *ExportJob) error
+	GetExportJob(ctx context.Context, jobID string) (*ExportJob, error)
+	// ClaimExportJobs returns up to limit jobs that are pending, or running with an
+	// expired lease, oldest first, marking them running until leaseUntil
+	ClaimExportJobs(ctx context.Context, now, leaseUntil time.Time, limit int) ([]ExportJob, error)
+	// FinishExportJob stores the outcome of a claimed job
+	FinishExportJob(ctx context.Context, job *ExportJob) error
+	// DeleteExpiredExportJobs removes jobs that expired before the given time and
+	// returns them so their files can be removed
+	DeleteExpiredExportJobs(ctx context.Context, before time.Time) ([]ExportJob, error)
+}
+
+// CDRStore gives access to the call detail records generated when sessions end
+type CDRStore interface {
+	// ExportCDRs calls fn for every record of a session that ended in the query's range,
+	// ordered by end time, reading them in batches. It stops at the first error returned
+	// by fn.
+	ExportCDRs(ctx context.Context, query CDRQuery, fn func(*CDR) error) error
+	// ListUnwrittenCDRs returns the oldest records not yet written to a CDR file, by ID
+	ListUnwrittenCDRs(ctx context.Context, limit int) ([]CDR, error)
+	// MarkCDRsWritten records that records were written to a CDR file
+	MarkCDRsWritten(ctx context.Context, ids []int64, at time.Time) error
+}
+
+// Store groups the stores a storage backend provides
+type Store interface {
+	SessionStore
+	EventStore
+	UserStore
+	OrganizationStore
+	RoleStore
+	TokenStore
+	APIKeyStore
+	IdempotencyStore
+	WebhookStore
+	OutboxStore
+	ExportJobStore
+	CDRStore
+}
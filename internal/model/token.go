@@ -0,0 +1,41 @@
+package model
+
+import (
+	"crypto/sha256"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken is a single-use token that exchanges for a new access token and its own
+// successor. The tokens descending from one login form a family: using a token that was
+// already exchanged means it leaked, and revokes the whole family. Only a hash of the
+// token is stored. AccessTokenID is the jti of the access token issued with the token,
+// which is denied once the family is revoked. Scopes are the permissions the login was
+// limited to, or nil for all those of the user's role.
+type RefreshToken struct {
+	ID                   uuid.UUID
+	UserID               uuid.UUID
+	FamilyID             uuid.UUID
+	TokenHash            []byte
+	AccessTokenID        uuid.UUID
+	AccessTokenExpiresAt time.Time
+	CreatedAt            time.Time
+	ExpiresAt            time.Time
+	UsedAt               *time.Time
+	RevokedAt            *time.Time
+	Scopes               Permissions
+}
+
+// HashRefreshToken returns the hash under which a refresh token is stored. The tokens
+// are long random strings, so a fast hash is enough to keep them from being usable if
+// the table leaks.
+func HashRefreshToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// RefreshRequest represents the request body for exchanging a refresh token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
@@ -1,7 +1,6 @@
 package model
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
 	"os"
@@ -9,168 +8,104 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"github.com/vasu74/Call_Session_Management/internal/config"
-	"golang.org/x/crypto/bcrypt"
 )
 
-// UserRole represents the possible roles a user can have
+// UserRole names the role of a user. Roles are stored with their permissions; these
+// are the built-in ones.
 type UserRole string
 
 const (
-	UserRoleUser  UserRole = "user"
+	UserRoleUser UserRole = "user"
+	// UserRoleAdmin administers the installation and every organization in it
 	UserRoleAdmin UserRole = "admin"
+	// UserRoleOrgAdmin administers a single organization and its users
+	UserRoleOrgAdmin UserRole = "org_admin"
+	// UserRoleSupervisor reads and exports the sessions of their team but manages nothing
+	UserRoleSupervisor UserRole = "supervisor"
+	// UserRoleService is the role of service accounts, which act through API keys and
+	// cannot log in
+	UserRoleService UserRole = "service"
 )
 
-// JWTClaims represents the claims in the JWT token
+// JWTClaims represents the claims in the JWT token. The registered ID (jti) identifies
+// the token so it can be revoked, and FamilyID the refresh token family it was issued
+// with. Permissions are those of the user's role when the token was issued, narrowed
+// to the scopes requested at login.
 type JWTClaims struct {
-	UserID string   `json:"user_id"`
-	Email  string   `json:"email"`
-	Role   UserRole `json:"role"`
+	UserID      string      `json:"user_id"`
+	Email       string      `json:"email"`
+	Role        UserRole    `json:"role"`
+	Permissions Permissions `json:"perms"`
+	FamilyID    string      `json:"fid,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // User represents a user in the system
 type User struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	Email     string    `json:"email" db:"email"`
-	Password  string    `json:"-" db:"password"` // "-" means this field won't be included in JSON
-	Role      UserRole  `json:"role" db:"role"`
+	ID       uuid.UUID `json:"id" db:"id"`
+	Email    string    `json:"email" db:"email"`
+	Password string    `json:"-" db:"password"` // "-" means this field won't be included in JSON
+	Role     UserRole  `json:"role" db:"role"`
+	// OrgID is the organization the user belongs to; they see only its data
+	OrgID uuid.UUID `json:"org_id" db:"org_id"`
+	// Team groups users whose sessions are visible to the supervisors of the team
+	Team      string    `json:"team,omitempty" db:"team"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
-// RegisterRequest represents the request body for user registration
+// RegisterRequest represents the request body for user registration. Registrants join
+// the default organization as users, unless organization signup is enabled and they
+// name a new organization of their own, which they administer.
 type RegisterRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Email        string `json:"email" binding:"required,email"`
+	Password     string `json:"password" binding:"required,min=6"`
+	Organization string `json:"organization" binding:"max=255"`
 }
 
-// LoginRequest represents the request body for user login
+// LoginRequest represents the request body for user login. Scopes, if given, limit the
+// tokens of the login to those of the role's permissions.
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
+	Email    string      `json:"email" binding:"required,email"`
+	Password string      `json:"password" binding:"required"`
+	Scopes   Permissions `json:"scopes" binding:"omitempty,dive,oneof=sessions:read sessions:write events:write sessions:export sessions:import users:manage webhooks:manage settings:manage orgs:manage"`
 }
 
-// LoginResponse represents the response body for successful login
+// LoginResponse represents the response body for a successful login or token refresh.
+// Token is the short-lived access token and RefreshToken exchanges for the next one.
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token                 string      `json:"token"`
+	ExpiresAt             time.Time   `json:"expires_at"`
+	RefreshToken          string      `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time   `json:"refresh_token_expires_at"`
+	Permissions           Permissions `json:"permissions"`
+	User                  User        `json:"user"`
 }
 
-// Register creates a new user account
-func (u *User) Register(req RegisterRequest) error {
-	// Check if user already exists
-	var exists bool
-	err := config.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)", req.Email).Scan(&exists)
-	if err != nil {
-		return err
-	}
-	if exists {
-		return errors.New("user already exists")
-	}
-
-	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		return err
-	}
-
-	// Create user
-	u.ID = uuid.New()
-	u.Email = req.Email
-	u.Password = string(hashedPassword)
-	u.Role = UserRoleUser
-	u.CreatedAt = time.Now()
-	u.UpdatedAt = time.Now()
-
-	// Insert into database
-	query := `
-		INSERT INTO users (id, email, password, role, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, email, role, created_at, updated_at`
-
-	err = config.DB.QueryRow(
-		query,
-		u.ID, u.Email, u.Password, u.Role, u.CreatedAt, u.UpdatedAt,
-	).Scan(&u.ID, &u.Email, &u.Role, &u.CreatedAt, &u.UpdatedAt)
-
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// Login authenticates a user and returns a JWT token
-func (u *User) Login(req LoginRequest) (*LoginResponse, error) {
-	// Get user from database
-	query := `SELECT id, email, password, role, created_at, updated_at FROM users WHERE email = $1`
-	err := config.DB.QueryRow(query, req.Email).Scan(
-		&u.ID, &u.Email, &u.Password, &u.Role, &u.CreatedAt, &u.UpdatedAt,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("invalid credentials")
-		}
-		return nil, err
-	}
-
-	// Verify password
-	err = bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(req.Password))
-	if err != nil {
-		return nil, errors.New("invalid credentials")
-	}
-
-	// Generate JWT token
-	token, err := generateJWT(u)
-	if err != nil {
-		return nil, err
-	}
-
-	return &LoginResponse{
-		Token: token,
-		User:  *u,
-	}, nil
-}
-
-// GetUserByID retrieves a user by their ID
-func GetUserByID(userID string) (*User, error) {
-	var user User
-	query := `SELECT id, email, role, created_at, updated_at FROM users WHERE id = $1`
-	err := config.DB.QueryRow(query, userID).Scan(
-		&user.ID, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("user not found")
-		}
-		return nil, err
-	}
-	return &user, nil
-}
-
-// generateJWT creates a JWT token for the user
-func generateJWT(user *User) (string, error) {
+// GenerateToken creates a JWT access token for the user with the given ID and
+// permissions that expires at expiresAt
+func GenerateToken(user *User, permissions Permissions, tokenID, familyID uuid.UUID, expiresAt time.Time) (string, error) {
 	// Get JWT secret from environment variable
 	jwtSecret := os.Getenv("JWT_SECRET")
 	if jwtSecret == "" {
 		return "", errors.New("JWT_SECRET environment variable is not set")
 	}
 
-	// Set token expiration time (24 hours)
-	expirationTime := time.Now().Add(24 * time.Hour)
-
 	// Create claims
+	now := time.Now()
 	claims := &JWTClaims{
-		UserID: user.ID.String(),
-		Email:  user.Email,
-		Role:   user.Role,
+		UserID:      user.ID.String(),
+		Email:       user.Email,
+		Role:        user.Role,
+		Permissions: permissions,
+		FamilyID:    familyID.String(),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "call-session-management",
 			Subject:   user.ID.String(),
+			ID:        tokenID.String(),
 		},
 	}
 
@@ -234,7 +169,7 @@ func (u *User) HasRole(requiredRole UserRole) bool {
 // ValidateRole checks if a user has the required role and returns an error if not
 func (u *User) ValidateRole(requiredRole UserRole) error {
 	if !u.HasRole(requiredRole) {
-		return fmt.Errorf("insufficient permissions: required role %s", requiredRole)
+		return ErrForbidden.WithMessage(fmt.Sprintf("insufficient permissions: required role %s", requiredRole))
 	}
 	return nil
 }
@@ -0,0 +1,163 @@
+package model
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook is an integration endpoint notified of session activity
+type Webhook struct {
+	ID          uuid.UUID `json:"id"`
+	OrgID       uuid.UUID `json:"org_id"`
+	URL         string    `json:"url"`
+	Description string    `json:"description,omitempty"`
+	// Secret signs deliveries; it is only returned when the webhook is created
+	Secret string         `json:"secret,omitempty"`
+	Events []ActivityType `json:"events"`
+	// EventTypes limits event.logged deliveries to these session event types
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Matches reports whether the message should be delivered to the webhook. Webhooks only
+// receive the messages of their own organization.
+func (w *Webhook) Matches(m *OutboxMessage) bool {
+	if !w.Active || w.OrgID != m.OrgID || !containsActivity(w.Events, m.Type) {
+		return false
+	}
+	if m.Type != ActivityEventLogged || len(w.EventTypes) == 0 {
+		return true
+	}
+	for _, eventType := range w.EventTypes {
+		if eventType == m.EventType {
+			return true
+		}
+	}
+	return false
+}
+
+func containsActivity(types []ActivityType, t ActivityType) bool {
+	for _, candidate := range types {
+		if candidate == t {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateWebhookRequest represents the request body for creating a webhook. A secret is
+// generated when none is given.
+type CreateWebhookRequest struct {
+	URL         string         `json:"url" binding:"required,url,max=2048"`
+	Description string         `json:"description" binding:"max=255"`
+	Secret      string         `json:"secret" binding:"omitempty,min=16,max=255"`
+	Events      []ActivityType `json:"events" binding:"required,min=1,dive,oneof=session.started session.updated session.ended event.logged"`
+	EventTypes  []string       `json:"event_types" binding:"dive,required,max=255"`
+	Active      *bool          `json:"active"`
+}
+
+// ValidateWebhookURL checks that a webhook URL is an absolute http or https URL. The
+// addresses its host resolves to are checked each time a delivery connects.
+func ValidateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Hostname() == "" {
+		return ErrInvalidRequest.WithMessage("url must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// UpdateWebhookRequest represents the request body for changing a webhook; omitted
+// fields are left unchanged
+type UpdateWebhookRequest struct {
+	URL         *string        `json:"url" binding:"omitempty,url,max=2048"`
+	Description *string        `json:"description" binding:"omitempty,max=255"`
+	Events      []ActivityType `json:"events" binding:"omitempty,min=1,dive,oneof=session.started session.updated session.ended event.logged"`
+	EventTypes  *[]string      `json:"event_types" binding:"omitempty,dive,required,max=255"`
+	Active      *bool          `json:"active"`
+}
+
+// WebhookDeliveryStatus is the state of the delivery of one message to one webhook
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending deliveries are waiting for their next attempt
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+	// WebhookDeliverySucceeded deliveries were accepted by the receiver
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	// WebhookDeliveryDead deliveries failed every attempt and are no longer retried
+	WebhookDeliveryDead WebhookDeliveryStatus = "dead"
+)
+
+// IsValid reports whether the status is one of the known delivery states
+func (s WebhookDeliveryStatus) IsValid() bool {
+	switch s {
+	case WebhookDeliveryPending, WebhookDeliverySucceeded, WebhookDeliveryDead:
+		return true
+	}
+	return false
+}
+
+// WebhookDelivery tracks the delivery of one outbox message to one webhook
+type WebhookDelivery struct {
+	ID            uuid.UUID             `json:"id"`
+	WebhookID     uuid.UUID             `json:"webhook_id"`
+	MessageID     int64                 `json:"message_id"`
+	MessageType   ActivityType          `json:"message_type"`
+	SessionID     uuid.UUID             `json:"session_id"`
+	Status        WebhookDeliveryStatus `json:"status"`
+	Attempts      int                   `json:"attempts"`
+	NextAttemptAt *time.Time            `json:"next_attempt_at,omitempty"`
+	LastError     *string               `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time            `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time             `json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
+}
+
+// WebhookAttempt records one attempt to deliver a message
+type WebhookAttempt struct {
+	ID          uuid.UUID `json:"id"`
+	DeliveryID  uuid.UUID `json:"delivery_id"`
+	Attempt     int       `json:"attempt"`
+	AttemptedAt time.Time `json:"attempted_at"`
+	DurationMs  int64     `json:"duration_ms"`
+	// StatusCode is nil when no response was received
+	StatusCode *int    `json:"status_code,omitempty"`
+	Error      *string `json:"error,omitempty"`
+}
+
+// Succeeded reports whether the receiver accepted the delivery
+func (a *WebhookAttempt) Succeeded() bool {
+	return a.Error == nil && a.StatusCode != nil && *a.StatusCode >= 200 && *a.StatusCode < 300
+}
+
+// WebhookDeliveryDetails is a delivery together with its attempts, oldest first
+type WebhookDeliveryDetails struct {
+	WebhookDelivery
+	AttemptLog []WebhookAttempt `json:"attempt_log"`
+}
+
+// WebhookTask is a claimed delivery with everything needed to attempt it
+type WebhookTask struct {
+	Delivery WebhookDelivery
+	URL      string
+	Secret   string
+	Message  OutboxMessage
+}
+
+// WebhookDeliveryUpdate is the outcome of an attempt: the delivery's new status and,
+// for pending deliveries, when to try again
+type WebhookDeliveryUpdate struct {
+	Status        WebhookDeliveryStatus
+	NextAttemptAt *time.Time
+}
+
+// WebhookDeliveryFilter selects the deliveries of a webhook, newest first
+type WebhookDeliveryFilter struct {
+	Status WebhookDeliveryStatus
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestWebhookMatches(t *testing.T) {
+	orgID := uuid.New()
+	started := &OutboxMessage{Type: ActivitySessionStarted, OrgID: orgID}
+	dtmf := &OutboxMessage{Type: ActivityEventLogged, OrgID: orgID, EventType: "dtmf"}
+	otherOrg := &OutboxMessage{Type: ActivitySessionStarted, OrgID: uuid.New()}
+
+	tests := []struct {
+		name    string
+		webhook Webhook
+		message *OutboxMessage
+		want    bool
+	}{
+		{name: "subscribed", webhook: Webhook{OrgID: orgID, Events: []ActivityType{ActivitySessionStarted}, Active: true}, message: started, want: true},
+		{name: "inactive", webhook: Webhook{OrgID: orgID, Events: []ActivityType{ActivitySessionStarted}}, message: started},
+		{name: "other organization", webhook: Webhook{OrgID: orgID, Events: []ActivityType{ActivitySessionStarted}, Active: true}, message: otherOrg},
+		{name: "other activity", webhook: Webhook{OrgID: orgID, Events: []ActivityType{ActivitySessionEnded}, Active: true}, message: started},
+		{name: "any event type", webhook: Webhook{OrgID: orgID, Events: []ActivityType{ActivityEventLogged}, Active: true}, message: dtmf, want: true},
+		{name: "listed event type", webhook: Webhook{OrgID: orgID, Events: []ActivityType{ActivityEventLogged}, EventTypes: []string{"hold", "dtmf"}, Active: true}, message: dtmf, want: true},
+		{name: "unlisted event type", webhook: Webhook{OrgID: orgID, Events: []ActivityType{ActivityEventLogged}, EventTypes: []string{"hold"}, Active: true}, message: dtmf},
+		{name: "event types only limit logged events", webhook: Webhook{OrgID: orgID, Events: []ActivityType{ActivitySessionStarted}, EventTypes: []string{"hold"}, Active: true}, message: started, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.webhook.Matches(tt.message); got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{url: "https://example.com/hooks", valid: true},
+		{url: "http://example.com:8080/hooks", valid: true},
+		{url: "ftp://example.com/hooks"},
+		{url: "file:///etc/passwd"},
+		{url: "gopher://127.0.0.1:6379/_INFO"},
+		{url: "https:///hooks"},
+		{url: "example.com/hooks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if err := ValidateWebhookURL(tt.url); (err == nil) != tt.valid {
+				t.Errorf("ValidateWebhookURL = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package phone
+
+import (
+	"strings"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// countryCodes are the assigned country calling codes. They form a prefix code, so the
+// code of a number is the only one of its first one, two or three digits listed here.
+var countryCodes = codeSet(
+	"1 7",
+	"20 27 30 31 32 33 34 36 39 40 41 43 44 45 46 47 48 49 51 52 53 54 55 56 57 58",
+	"60 61 62 63 64 65 66 81 82 84 86 90 91 92 93 94 95 98",
+	"211 212 213 216 218 220 221 222 223 224 225 226 227 228 229 230 231 232 233 234",
+	"235 236 237 238 239 240 241 242 243 244 245 246 247 248 249 250 251 252 253 254",
+	"255 256 257 258 260 261 262 263 264 265 266 267 268 269 290 291 297 298 299",
+	"350 351 352 353 354 355 356 357 358 359 370 371 372 373 374 375 376 377 378",
+	"380 381 382 383 385 386 387 389 420 421 423",
+	"500 501 502 503 504 505 506 507 508 509 590 591 592 593 594 595 596 597 598 599",
+	"670 672 673 674 675 676 677 678 679 680 681 682 683 685 686 687 688 689 690 691 692",
+	"800 808 850 852 853 855 856 870 880 881 882 883 886 888",
+	"960 961 962 963 964 965 966 967 968 970 971 972 973 974 975 976 977 979",
+	"992 993 994 995 996 998",
+)
+
+func codeSet(lines ...string) map[string]bool {
+	set := make(map[string]bool)
+	for _, line := range lines {
+		for _, code := range strings.Fields(line) {
+			set[code] = true
+		}
+	}
+	return set
+}
+
+// region is how numbers are dialled within a country: its calling code, the prefix
+// that dials abroad and the trunk prefix that precedes national numbers. Where the
+// trunk prefix is empty a leading zero is part of the national number.
+type region struct {
+	code  string
+	idd   string
+	trunk string
+}
+
+// regions are the countries that can be configured as the default region, by ISO
+// 3166-1 alpha-2 code
+var regions = map[string]region{
+	"AE": {code: "971", idd: "00", trunk: "0"},
+	"AR": {code: "54", idd: "00", trunk: "0"},
+	"AT": {code: "43", idd: "00", trunk: "0"},
+	"AU": {code: "61", idd: "0011", trunk: "0"},
+	"BE": {code: "32", idd: "00", trunk: "0"},
+	"BR": {code: "55", idd: "00", trunk: "0"},
+	"CA": {code: "1", idd: "011", trunk: "1"},
+	"CH": {code: "41", idd: "00", trunk: "0"},
+	"CN": {code: "86", idd: "00", trunk: "0"},
+	"CZ": {code: "420", idd: "00"},
+	"DE": {code: "49", idd: "00", trunk: "0"},
+	"DK": {code: "45", idd: "00"},
+	"EG": {code: "20", idd: "00", trunk: "0"},
+	"ES": {code: "34", idd: "00"},
+	"FI": {code: "358", idd: "00", trunk: "0"},
+	"FR": {code: "33", idd: "00", trunk: "0"},
+	"GB": {code: "44", idd: "00", trunk: "0"},
+	"GR": {code: "30", idd: "00"},
+	"HK": {code: "852", idd: "001"},
+	"HU": {code: "36", idd: "00", trunk: "06"},
+	"ID": {code: "62", idd: "001", trunk: "0"},
+	"IE": {code: "353", idd: "00", trunk: "0"},
+	"IL": {code: "972", idd: "00", trunk: "0"},
+	"IN": {code: "91", idd: "00", trunk: "0"},
+	"IT": {code: "39", idd: "00"},
+	"JP": {code: "81", idd: "010", trunk: "0"},
+	"KR": {code: "82", idd: "001", trunk: "0"},
+	"MX": {code: "52", idd: "00"},
+	"MY": {code: "60", idd: "00", trunk: "0"},
+	"NG": {code: "234", idd: "009", trunk: "0"},
+	"NL": {code: "31", idd: "00", trunk: "0"},
+	"NO": {code: "47", idd: "00"},
+	"NZ": {code: "64", idd: "00", trunk: "0"},
+	"PH": {code: "63", idd: "00", trunk: "0"},
+	"PK": {code: "92", idd: "00", trunk: "0"},
+	"PL": {code: "48", idd: "00"},
+	"PR": {code: "1", idd: "011", trunk: "1"},
+	"PT": {code: "351", idd: "00"},
+	"RU": {code: "7", idd: "810", trunk: "8"},
+	"SA": {code: "966", idd: "00", trunk: "0"},
+	"SE": {code: "46", idd: "00", trunk: "0"},
+	"SG": {code: "65", idd: "000"},
+	"TR": {code: "90", idd: "00", trunk: "0"},
+	"US": {code: "1", idd: "011", trunk: "1"},
+	"ZA": {code: "27", idd: "00", trunk: "0"},
+}
+
+// plan is the part of a country's numbering plan needed to check and classify its
+// national significant numbers
+type plan struct {
+	minLength, maxLength int
+	// valid, if set, further checks the digits of a number
+	valid func(nsn string) bool
+	// types map number prefixes to the type of line they reach; the longest matching
+	// prefix wins and numbers no prefix matches have the fallback type
+	types    map[string]model.NumberType
+	fallback model.NumberType
+}
+
+const (
+	mobile            = model.NumberTypeMobile
+	fixedLine         = model.NumberTypeFixedLine
+	fixedLineOrMobile = model.NumberTypeFixedLineOrMobile
+	tollFree          = model.NumberTypeTollFree
+	premiumRate       = model.NumberTypePremiumRate
+	sharedCost        = model.NumberTypeSharedCost
+	voip              = model.NumberTypeVoIP
+)
+
+// plans hold the numbering plans known by calling code. Numbers of other countries
+// are accepted by length alone and their type is unknown.
+var plans = map[string]plan{
+	// North American Numbering Plan: area codes and exchanges never start with 0 or 1,
+	// and mobile numbers share the geographic area codes
+	"1": {
+		minLength: 10, maxLength: 10,
+		valid: func(nsn string) bool { return nsn[0] >= '2' && nsn[3] >= '2' },
+		types: prefixes(
+			"800 833 844 855 866 877 888", tollFree,
+			"900", premiumRate,
+		),
+		fallback: fixedLineOrMobile,
+	},
+	"7": {
+		minLength: 10, maxLength: 10,
+		types: prefixes(
+			"3 4 8", fixedLine,
+			"9", mobile,
+			"800", tollFree,
+			"809", premiumRate,
+		),
+	},
+	"31": {
+		minLength: 9, maxLength: 9,
+		types: prefixes(
+			"1 2 3 4 5 7", fixedLine,
+			"6", mobile,
+			"85 88", voip,
+			"800", tollFree,
+			"900 906 909", premiumRate,
+		),
+	},
+	"33": {
+		minLength: 9, maxLength: 9,
+		types: prefixes(
+			"1 2 3 4 5", fixedLine,
+			"6 7", mobile,
+			"80", tollFree,
+			"81 82", sharedCost,
+			"89", premiumRate,
+			"9", voip,
+		),
+	},
+	"34": {
+		minLength: 9, maxLength: 9,
+		types: prefixes(
+			"8 9", fixedLine,
+			"6 71 72 73 74", mobile,
+			"800 900", tollFree,
+			"901 902", sharedCost,
+			"803 806 807 905", premiumRate,
+			"51", voip,
+		),
+	},
+	"39": {
+		minLength: 6, maxLength: 11,
+		types: prefixes(
+			"0", fixedLine,
+			"3", mobile,
+			"800 803", tollFree,
+			"84", sharedCost,
+			"89", premiumRate,
+		),
+	},
+	"44": {
+		minLength: 9, maxLength: 10,
+		types: prefixes(
+			"1 2 3", fixedLine,
+			"71 72 73 74 75 77 78 79", mobile,
+			"800 808", tollFree,
+			"84 87", sharedCost,
+			"9", premiumRate,
+			"56", voip,
+		),
+	},
+	"49": {
+		minLength: 6, maxLength: 13,
+		types: prefixes(
+			"2 3 4 5 6 7 8 9", fixedLine,
+			"15 16 17", mobile,
+			"800", tollFree,
+			"180", sharedCost,
+			"900", premiumRate,
+			"32", voip,
+		),
+	},
+	"61": {
+		minLength: 6, maxLength: 9,
+		types: prefixes(
+			"2 3 7 8", fixedLine,
+			"4", mobile,
+			"180", tollFree,
+			"13", sharedCost,
+			"19", premiumRate,
+		),
+	},
+	"81": {
+		minLength: 9, maxLength: 10,
+		types: prefixes(
+			"1 2 3 4 5 6 7 8 9", fixedLine,
+			"70 80 90", mobile,
+			"120 800", tollFree,
+			"570", sharedCost,
+			"990", premiumRate,
+			"50", voip,
+		),
+	},
+	"86": {
+		minLength: 7, maxLength: 11,
+		types: prefixes(
+			"10 2 3 4 5 6 7 8 9", fixedLine,
+			"13 14 15 16 17 18 19", mobile,
+			"800", tollFree,
+			"400", sharedCost,
+		),
+	},
+	"91": {
+		minLength: 10, maxLength: 11,
+		types: prefixes(
+			"1 2 3 4 5", fixedLine,
+			"6 7 8 9", mobile,
+			"1800", tollFree,
+			"1860", sharedCost,
+			"1900", premiumRate,
+		),
+	},
+	"353": {
+		minLength: 7, maxLength: 10,
+		types: prefixes(
+			"1 2 4 5 6 7 9", fixedLine,
+			"83 85 86 87 89", mobile,
+			"1800", tollFree,
+			"1850 1890", sharedCost,
+			"15", premiumRate,
+			"76", voip,
+		),
+	},
+}
+
+// prefixes builds a prefix table from pairs of space separated prefixes and their type
+func prefixes(pairs ...interface{}) map[string]model.NumberType {
+	types := make(map[string]model.NumberType)
+	for i := 0; i < len(pairs); i += 2 {
+		for _, prefix := range strings.Fields(pairs[i].(string)) {
+			types[prefix] = pairs[i+1].(model.NumberType)
+		}
+	}
+	return types
+}
+
+// classify returns the type of line a national significant number reaches
+func (p plan) classify(nsn string) model.NumberType {
+	for n := len(nsn); n > 0; n-- {
+		if t, ok := p.types[nsn[:n]]; ok {
+			return t
+		}
+	}
+	if p.fallback != "" {
+		return p.fallback
+	}
+	return model.NumberTypeUnknown
+}
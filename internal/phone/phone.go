@@ -0,0 +1,230 @@
+// Package phone normalizes caller and callee IDs. Telephone numbers are parsed to E.164
+// against a default region for numbers dialled nationally and classified by the type
+// of line they reach, using numbering plan metadata embedded in the package. SIP URIs
+// are brought to a canonical form and short all-digit IDs are taken as extensions.
+package phone
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+const (
+	// DefaultRegion is the region national numbers are taken to belong to when none
+	// is configured
+	DefaultRegion = "US"
+	// DefaultExtensionMaxDigits is the longest ID taken as an extension when no limit
+	// is configured
+	DefaultExtensionMaxDigits = 6
+
+	// maxE164Digits is the longest number E.164 allows, calling code included
+	maxE164Digits = 15
+	// minNSNDigits is the shortest national number accepted for countries without a
+	// known numbering plan
+	minNSNDigits = 4
+)
+
+// Normalizer parses caller and callee IDs
+type Normalizer struct {
+	regionCode         string
+	region             region
+	extensionMaxDigits int
+}
+
+// NewNormalizer creates a normalizer that takes national numbers to belong to the
+// region with the given ISO 3166-1 alpha-2 code and IDs of up to extensionMaxDigits
+// digits to be extensions. A limit of zero or less selects the default.
+func NewNormalizer(defaultRegion string, extensionMaxDigits int) (*Normalizer, error) {
+	code := strings.ToUpper(strings.TrimSpace(defaultRegion))
+	r, ok := regions[code]
+	if !ok {
+		return nil, fmt.Errorf("unknown region %q", defaultRegion)
+	}
+	if extensionMaxDigits <= 0 {
+		extensionMaxDigits = DefaultExtensionMaxDigits
+	}
+	return &Normalizer{regionCode: code, region: r, extensionMaxDigits: extensionMaxDigits}, nil
+}
+
+// MustNewNormalizer is like NewNormalizer but panics if the region is unknown
+func MustNewNormalizer(defaultRegion string, extensionMaxDigits int) *Normalizer {
+	n, err := NewNormalizer(defaultRegion, extensionMaxDigits)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// Region returns the ISO code of the default region
+func (n *Normalizer) Region() string {
+	return n.regionCode
+}
+
+// Parse normalizes an ID. Anything that is neither a SIP URI, an extension nor a valid
+// telephone number is returned unchanged, apart from surrounding spaces, as an unknown
+// identity.
+func (n *Normalizer) Parse(raw string) model.Identity {
+	id := strings.TrimSpace(raw)
+	unknown := model.Identity{Normalized: id, Type: model.IdentityUnknown, NumberType: model.NumberTypeUnknown}
+
+	switch scheme, rest, _ := strings.Cut(unbracket(id), ":"); strings.ToLower(scheme) {
+	case "sip", "sips":
+		uri, ok := canonicalSIP(strings.ToLower(scheme), rest)
+		if !ok {
+			return unknown
+		}
+		return model.Identity{Normalized: uri, Type: model.IdentitySIP, NumberType: model.NumberTypeUnknown}
+	case "tel":
+		// Only global numbers are meaningful without their phone-context
+		number, _, _ := strings.Cut(rest, ";")
+		if !strings.HasPrefix(strings.TrimSpace(number), "+") {
+			return unknown
+		}
+		id = number
+	}
+
+	if isDigits(id) && len(id) <= n.extensionMaxDigits {
+		return model.Identity{Normalized: id, Type: model.IdentityExtension, NumberType: model.NumberTypeUnknown}
+	}
+
+	e164, numberType, ok := n.parseNumber(id)
+	if !ok {
+		return unknown
+	}
+	return model.Identity{Normalized: e164, Type: model.IdentityE164, NumberType: numberType}
+}
+
+// Normalize returns the normalized form of an ID
+func (n *Normalizer) Normalize(raw string) string {
+	return n.Parse(raw).Normalized
+}
+
+// parseNumber parses a telephone number written in international form, with the
+// international prefix of the default region or nationally
+func (n *Normalizer) parseNumber(s string) (e164 string, numberType model.NumberType, ok bool) {
+	s = strings.TrimSpace(s)
+	international := strings.HasPrefix(s, "+")
+	digits, ok := stripSeparators(strings.TrimPrefix(s, "+"))
+	if !ok || digits == "" {
+		return "", "", false
+	}
+
+	if !international && strings.HasPrefix(digits, n.region.idd) {
+		digits, international = strings.TrimPrefix(digits, n.region.idd), true
+	}
+	if international {
+		return splitInternational(digits)
+	}
+
+	// A trunk prefix is only dropped when what follows is a whole national number, as
+	// in the North American 1 415 555 0100 against 415 555 0100
+	if n.region.trunk != "" && strings.HasPrefix(digits, n.region.trunk) {
+		if national := strings.TrimPrefix(digits, n.region.trunk); validNSN(n.region.code, national) {
+			return format(n.region.code, national)
+		}
+	}
+	if !validNSN(n.region.code, digits) {
+		return "", "", false
+	}
+	return format(n.region.code, digits)
+}
+
+// splitInternational splits the digits of an international number into its calling
+// code and national significant number
+func splitInternational(digits string) (string, model.NumberType, bool) {
+	for size := 1; size <= 3 && size < len(digits); size++ {
+		code := digits[:size]
+		if !countryCodes[code] {
+			continue
+		}
+		if !validNSN(code, digits[size:]) {
+			return "", "", false
+		}
+		return format(code, digits[size:])
+	}
+	return "", "", false
+}
+
+func validNSN(code, nsn string) bool {
+	if len(code)+len(nsn) > maxE164Digits {
+		return false
+	}
+	p, ok := plans[code]
+	if !ok {
+		return len(nsn) >= minNSNDigits
+	}
+	if len(nsn) < p.minLength || len(nsn) > p.maxLength {
+		return false
+	}
+	return p.valid == nil || p.valid(nsn)
+}
+
+func format(code, nsn string) (string, model.NumberType, bool) {
+	numberType := model.NumberTypeUnknown
+	if p, ok := plans[code]; ok {
+		numberType = p.classify(nsn)
+	}
+	return "+" + code + nsn, numberType, true
+}
+
+// stripSeparators removes the spaces, dashes, dots, slashes and parentheses numbers are
+// written with; ok is false if anything else but digits remains
+func stripSeparators(s string) (digits string, ok bool) {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '/' || r == '(' || r == ')' || r == '\u00a0':
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// unbracket returns the URI of a name-addr such as "Alice" <sip:alice@example.com>
+func unbracket(s string) string {
+	if start := strings.IndexByte(s, '<'); start >= 0 {
+		if end := strings.IndexByte(s[start:], '>'); end > 0 {
+			return strings.TrimSpace(s[start+1 : start+end])
+		}
+	}
+	return s
+}
+
+// canonicalSIP reduces a SIP URI to its scheme, user and host: the scheme and host are
+// lower-cased and the password, URI parameters and headers dropped, as they do not
+// identify the party
+func canonicalSIP(scheme, rest string) (string, bool) {
+	rest, _, _ = strings.Cut(rest, "?")
+	user, host, hasUser := strings.Cut(rest, "@")
+	if !hasUser {
+		host, user = user, ""
+	}
+	user, _, _ = strings.Cut(user, ":")
+	host, _, _ = strings.Cut(host, ";")
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "" || strings.ContainsAny(host, " <>\"") {
+		return "", false
+	}
+
+	if user == "" {
+		return scheme + ":" + host, true
+	}
+	return scheme + ":" + user + "@" + host, true
+}
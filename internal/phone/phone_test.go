@@ -0,0 +1,81 @@
+package phone
+
+import (
+	"testing"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		region     string
+		raw        string
+		want       string
+		idType     model.IdentityType
+		numberType model.NumberType
+	}{
+		// The same North American number however it is written
+		{region: "US", raw: "+14155550100", want: "+14155550100", idType: model.IdentityE164, numberType: model.NumberTypeFixedLineOrMobile},
+		{region: "US", raw: "14155550100", want: "+14155550100", idType: model.IdentityE164, numberType: model.NumberTypeFixedLineOrMobile},
+		{region: "US", raw: "(415) 555-0100", want: "+14155550100", idType: model.IdentityE164, numberType: model.NumberTypeFixedLineOrMobile},
+		{region: "US", raw: " +1 415.555.0100 ", want: "+14155550100", idType: model.IdentityE164, numberType: model.NumberTypeFixedLineOrMobile},
+		{region: "US", raw: "tel:+1-415-555-0100;ext=12", want: "+14155550100", idType: model.IdentityE164, numberType: model.NumberTypeFixedLineOrMobile},
+		{region: "US", raw: "1-800-555-0100", want: "+18005550100", idType: model.IdentityE164, numberType: model.NumberTypeTollFree},
+		{region: "US", raw: "900 555 0100", want: "+19005550100", idType: model.IdentityE164, numberType: model.NumberTypePremiumRate},
+		// Dialled with the international prefix of the default region
+		{region: "US", raw: "011 44 7911 123456", want: "+447911123456", idType: model.IdentityE164, numberType: model.NumberTypeMobile},
+		{region: "US", raw: "+44 20 7946 0958", want: "+442079460958", idType: model.IdentityE164, numberType: model.NumberTypeFixedLine},
+		// Countries without a known numbering plan are accepted by length alone
+		{region: "US", raw: "+420 601 123 456", want: "+420601123456", idType: model.IdentityE164, numberType: model.NumberTypeUnknown},
+		{region: "GB", raw: "020 7946 0958", want: "+442079460958", idType: model.IdentityE164, numberType: model.NumberTypeFixedLine},
+		{region: "GB", raw: "0800 123 4567", want: "+448001234567", idType: model.IdentityE164, numberType: model.NumberTypeTollFree},
+		{region: "GB", raw: "00 1 415 555 0100", want: "+14155550100", idType: model.IdentityE164, numberType: model.NumberTypeFixedLineOrMobile},
+		{region: "DE", raw: "0151 23456789", want: "+4915123456789", idType: model.IdentityE164, numberType: model.NumberTypeMobile},
+
+		{region: "US", raw: `"Alice" <sip:alice:secret@Example.COM;transport=tcp?subject=x>`, want: "sip:alice@example.com", idType: model.IdentitySIP, numberType: model.NumberTypeUnknown},
+		{region: "US", raw: "SIPS:Gateway.Example.com", want: "sips:gateway.example.com", idType: model.IdentitySIP, numberType: model.NumberTypeUnknown},
+		{region: "US", raw: "1001", want: "1001", idType: model.IdentityExtension, numberType: model.NumberTypeUnknown},
+		{region: "US", raw: "123456", want: "123456", idType: model.IdentityExtension, numberType: model.NumberTypeUnknown},
+
+		// Kept as given, apart from surrounding spaces
+		{region: "US", raw: " anonymous ", want: "anonymous", idType: model.IdentityUnknown, numberType: model.NumberTypeUnknown},
+		{region: "US", raw: "sip:", want: "sip:", idType: model.IdentityUnknown, numberType: model.NumberTypeUnknown},
+		{region: "US", raw: "tel:555-0100;phone-context=example.com", want: "tel:555-0100;phone-context=example.com", idType: model.IdentityUnknown, numberType: model.NumberTypeUnknown},
+		{region: "US", raw: "(123) 555-0100", want: "(123) 555-0100", idType: model.IdentityUnknown, numberType: model.NumberTypeUnknown},
+		{region: "US", raw: "+1 415 555 01000", want: "+1 415 555 01000", idType: model.IdentityUnknown, numberType: model.NumberTypeUnknown},
+		{region: "US", raw: "+999 1234567", want: "+999 1234567", idType: model.IdentityUnknown, numberType: model.NumberTypeUnknown},
+		{region: "US", raw: "415-555-0100 x12", want: "415-555-0100 x12", idType: model.IdentityUnknown, numberType: model.NumberTypeUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.region+" "+tt.raw, func(t *testing.T) {
+			got := MustNewNormalizer(tt.region, 0).Parse(tt.raw)
+			want := model.Identity{Normalized: tt.want, Type: tt.idType, NumberType: tt.numberType}
+			if got != want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.raw, got, want)
+			}
+		})
+	}
+}
+
+func TestExtensionMaxDigits(t *testing.T) {
+	n := MustNewNormalizer("US", 3)
+	if got := n.Parse("100"); got.Type != model.IdentityExtension {
+		t.Errorf("Parse(100) = %+v, want an extension", got)
+	}
+	if got := n.Parse("1000"); got.Type != model.IdentityUnknown {
+		t.Errorf("Parse(1000) = %+v, want an unknown ID", got)
+	}
+}
+
+func TestNewNormalizer(t *testing.T) {
+	n, err := NewNormalizer(" gb ", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Region() != "GB" {
+		t.Errorf("Region = %s, want GB", n.Region())
+	}
+	if _, err := NewNormalizer("XX", 0); err == nil {
+		t.Error("NewNormalizer accepted an unknown region")
+	}
+}
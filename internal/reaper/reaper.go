@@ -0,0 +1,130 @@
+package reaper
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+)
+
+// LockKey identifies the advisory lock that elects the replica running the reaper
+const LockKey int64 = 0x63736d5f727072 // "csm_rpr"
+
+// Leader decides whether this replica should run the reaper
+type Leader interface {
+	Acquire(ctx context.Context) (bool, error)
+	Release()
+}
+
+// Config controls how often the reaper runs and which sessions it considers stale.
+// A zero Inactivity or MaxDuration disables that criterion.
+type Config struct {
+	Interval    time.Duration
+	Inactivity  time.Duration
+	MaxDuration time.Duration
+	BatchSize   int
+}
+
+// Enabled reports whether the reaper should run at all
+func (c Config) Enabled() bool {
+	return c.Interval > 0
+}
+
+// Reaper periodically ends active sessions that were abandoned by their clients and
+// purges expired idempotency keys, processed outbox messages and expired tokens
+type Reaper struct {
+	svc    *service.Service
+	leader Leader
+	cfg    Config
+	logger *log.Logger
+}
+
+// New creates a reaper; leader may be nil when only a single replica runs
+func New(svc *service.Service, leader Leader, cfg Config, logger *log.Logger) *Reaper {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	return &Reaper{svc: svc, leader: leader, cfg: cfg, logger: logger}
+}
+
+// Run reaps stale sessions every interval until ctx is cancelled
+func (r *Reaper) Run(ctx context.Context) {
+	if r.leader != nil {
+		defer r.leader.Release()
+	}
+
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.tick(ctx)
+		}
+	}
+}
+
+func (r *Reaper) tick(ctx context.Context) {
+	if r.leader != nil {
+		leading, err := r.leader.Acquire(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				r.logger.Printf("Reaper leader election failed: %v", err)
+			}
+			return
+		}
+		if !leading {
+			return
+		}
+	}
+
+	if _, err := r.svc.PurgeExpiredIdempotencyKeys(ctx); err != nil && ctx.Err() == nil {
+		r.logger.Printf("Reaper failed to purge idempotency keys: %v", err)
+	}
+	if _, err := r.svc.PurgeOutbox(ctx); err != nil && ctx.Err() == nil {
+		r.logger.Printf("Reaper failed to purge outbox messages: %v", err)
+	}
+	if _, err := r.svc.PurgeExpiredTokens(ctx); err != nil && ctx.Err() == nil {
+		r.logger.Printf("Reaper failed to purge expired tokens: %v", err)
+	}
+	if purged, err := r.svc.PurgeExpiredSessions(ctx); err != nil && ctx.Err() == nil {
+		r.logger.Printf("Reaper failed to purge sessions past their retention period: %v", err)
+	} else if purged > 0 {
+		r.logger.Printf("Reaper purged %d sessions past their retention period", purged)
+	}
+
+	if r.cfg.Inactivity <= 0 && r.cfg.MaxDuration <= 0 {
+		return
+	}
+
+	// Work through the backlog in batches so a large pile of stale sessions is
+	// cleared in one tick without loading it all at once
+	for ctx.Err() == nil {
+		now := time.Now()
+		query := model.StaleSessionQuery{Limit: r.cfg.BatchSize}
+		if r.cfg.Inactivity > 0 {
+			query.InactiveSince = now.Add(-r.cfg.Inactivity)
+		}
+		if r.cfg.MaxDuration > 0 {
+			query.StartedBefore = now.Add(-r.cfg.MaxDuration)
+		}
+
+		ended, err := r.svc.ReapStaleSessions(ctx, query)
+		if ended > 0 {
+			r.logger.Printf("Reaper ended %d stale sessions", ended)
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				r.logger.Printf("Reaper failed: %v", err)
+			}
+			return
+		}
+		if ended < r.cfg.BatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package reaper
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+	"github.com/vasu74/Call_Session_Management/internal/store/memory"
+)
+
+type fakeLeader struct {
+	leading  bool
+	acquired int
+}
+
+func (l *fakeLeader) Acquire(ctx context.Context) (bool, error) {
+	l.acquired++
+	return l.leading, nil
+}
+
+func (l *fakeLeader) Release() {}
+
+func TestReaperEndsStaleSessions(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	// Sessions by how long ago they started and whether they have a recent event
+	sessions := []struct {
+		name        string
+		startedAgo  time.Duration
+		recentEvent bool
+		status      model.SessionStatus
+		wantStatus  model.SessionStatus
+		wantReason  string
+	}{
+		{name: "idle", startedAgo: 20 * time.Minute, status: model.SessionStatusAnswered, wantStatus: model.SessionStatusFailed, wantReason: "inactivity"},
+		{name: "idle on hold", startedAgo: 15 * time.Minute, status: model.SessionStatusOnHold, wantStatus: model.SessionStatusFailed, wantReason: "inactivity"},
+		{name: "running too long", startedAgo: 5 * time.Hour, recentEvent: true, status: model.SessionStatusAnswered, wantStatus: model.SessionStatusFailed, wantReason: "max_duration"},
+		{name: "active", startedAgo: 20 * time.Minute, recentEvent: true, status: model.SessionStatusAnswered, wantStatus: model.SessionStatusAnswered},
+		{name: "new", startedAgo: time.Minute, status: model.SessionStatusRinging, wantStatus: model.SessionStatusRinging},
+		{name: "ended", startedAgo: 5 * time.Hour, status: model.SessionStatusCompleted, wantStatus: model.SessionStatusCompleted},
+	}
+
+	st := memory.New()
+	ids := make([]uuid.UUID, len(sessions))
+	for i, tt := range sessions {
+		startedAt := now.Add(-tt.startedAgo)
+		session := &model.Session{ID: uuid.New(), OrgID: model.DefaultOrganizationID, StartedAt: startedAt, CallerID: "+14155550100", CalleeID: "+14155550199", Status: tt.status, CreatedAt: startedAt, UpdatedAt: startedAt}
+		if err := st.CreateSession(ctx, session); err != nil {
+			t.Fatal(err)
+		}
+		if tt.recentEvent {
+			event := &model.SessionEvent{ID: uuid.New(), SessionID: session.ID, OrgID: session.OrgID, EventType: "dtmf", EventTime: now, CreatedAt: now}
+			if err := st.CreateEvent(ctx, event); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ids[i] = session.ID
+	}
+
+	leader := &fakeLeader{}
+	// A batch size of one makes a single tick work through several batches
+	r := New(service.New(st), leader, Config{Interval: time.Minute, Inactivity: 10 * time.Minute, MaxDuration: 4 * time.Hour, BatchSize: 1}, log.New(io.Discard, "", 0))
+
+	r.tick(ctx)
+	for i, tt := range sessions {
+		if session, _ := st.GetSession(ctx, ids[i].String()); session.Status != tt.status {
+			t.Fatalf("%s session was reaped by a follower", tt.name)
+		}
+	}
+
+	leader.leading = true
+	r.tick(ctx)
+	for i, tt := range sessions {
+		t.Run(tt.name, func(t *testing.T) {
+			session, err := st.GetSession(ctx, ids[i].String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if session.Status != tt.wantStatus {
+				t.Fatalf("status %s, want %s", session.Status, tt.wantStatus)
+			}
+			if tt.wantReason == "" {
+				return
+			}
+			if session.EndedAt == nil || session.Disposition == nil || *session.Disposition != "timeout" {
+				t.Errorf("reaped session ended_at %v, disposition %v", session.EndedAt, session.Disposition)
+			}
+			events, err := st.ListEvents(ctx, ids[i].String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			last := events[len(events)-1]
+			if last.EventType != model.EventTypeAutoEnded || last.Metadata["reason"] != tt.wantReason {
+				t.Errorf("last event %s with reason %v, want %s with reason %s", last.EventType, last.Metadata["reason"], model.EventTypeAutoEnded, tt.wantReason)
+			}
+		})
+	}
+	if leader.acquired != 2 {
+		t.Errorf("leadership was checked %d times, want once per tick", leader.acquired)
+	}
+}
@@ -0,0 +1,60 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// FilePublisher appends messages as JSON lines to a file, or writes them to standard
+// output. It suits local development and setups where a log shipper forwards the file.
+type FilePublisher struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFilePublisher opens path for appending; "-" or an empty path selects standard output
+func NewFilePublisher(path string) (*FilePublisher, error) {
+	if path == "" || path == "-" {
+		return &FilePublisher{file: os.Stdout}, nil
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FilePublisher{file: file}, nil
+}
+
+// Publish writes the messages and, for a file, flushes them to disk
+func (p *FilePublisher) Publish(ctx context.Context, messages []model.OutboxMessage) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, m := range messages {
+		if err := enc.Encode(m); err != nil {
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err := p.file.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if p.file == os.Stdout {
+		return nil
+	}
+	return p.file.Sync()
+}
+
+// Close closes the file
+func (p *FilePublisher) Close() error {
+	if p.file == os.Stdout {
+		return nil
+	}
+	return p.file.Close()
+}
@@ -0,0 +1,100 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// KafkaRESTPublisher produces messages to a Kafka topic through the REST Proxy v2 API,
+// as served by Confluent REST Proxy and the Redpanda HTTP Proxy; it does not speak the
+// Kafka protocol, so such a proxy must run in front of the cluster. Records are keyed
+// by session ID, so the messages of a session share a partition and keep their order.
+// The proxy's producer should use acks=all.
+type KafkaRESTPublisher struct {
+	endpoint string
+	client   *http.Client
+}
+
+type kafkaRecord struct {
+	Key   string              `json:"key"`
+	Value model.OutboxMessage `json:"value"`
+}
+
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		Partition int     `json:"partition"`
+		Offset    int64   `json:"offset"`
+		ErrorCode *int    `json:"error_code"`
+		Error     *string `json:"error"`
+	} `json:"offsets"`
+}
+
+// NewKafkaRESTPublisher produces to topic through the REST proxy at restURL
+func NewKafkaRESTPublisher(restURL, topic string, timeout time.Duration) *KafkaRESTPublisher {
+	return &KafkaRESTPublisher{
+		endpoint: strings.TrimRight(restURL, "/") + "/topics/" + url.PathEscape(topic),
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Publish produces the messages in one request
+func (p *KafkaRESTPublisher) Publish(ctx context.Context, messages []model.OutboxMessage) error {
+	records := make([]kafkaRecord, len(messages))
+	for i, m := range messages {
+		records[i] = kafkaRecord{Key: m.SessionID.String(), Value: m}
+	}
+	body, err := json.Marshal(struct {
+		Records []kafkaRecord `json:"records"`
+	}{records})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("kafka rest proxy responded with status %d: %s", resp.StatusCode, bytes.TrimSpace(excerpt))
+	}
+
+	var result kafkaProduceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("kafka rest proxy response: %w", err)
+	}
+	for i, offset := range result.Offsets {
+		if offset.ErrorCode != nil {
+			message := ""
+			if offset.Error != nil {
+				message = *offset.Error
+			}
+			return fmt.Errorf("outbox message %d: kafka error %d: %s", messages[i].ID, *offset.ErrorCode, message)
+		}
+	}
+	return nil
+}
+
+// Close releases idle connections to the proxy
+func (p *KafkaRESTPublisher) Close() error {
+	p.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,63 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// SessionIDHeader carries the session ID of a message on buses with message headers
+const SessionIDHeader = "Session-ID"
+
+// NATSPublisher publishes messages to NATS JetStream on the subject <prefix>.<type>,
+// e.g. csm.session.ended, waiting for the stream to acknowledge each one. A stream
+// must capture the subjects. Messages carry their outbox ID as Nats-Msg-Id, so the
+// stream drops messages published again within its duplicate window.
+type NATSPublisher struct {
+	conn   *nats.Conn
+	js     jetstream.JetStream
+	prefix string
+}
+
+// NewNATSPublisher connects to the NATS server at url
+func NewNATSPublisher(url, prefix string) (*NATSPublisher, error) {
+	conn, err := nats.Connect(url, nats.Name("call-session-management"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &NATSPublisher{conn: conn, js: js, prefix: prefix}, nil
+}
+
+// Publish sends the messages one at a time so each is stored before the next is sent
+func (p *NATSPublisher) Publish(ctx context.Context, messages []model.OutboxMessage) error {
+	for _, m := range messages {
+		data, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+
+		msg := nats.NewMsg(p.prefix + "." + string(m.Type))
+		msg.Data = data
+		msg.Header.Set(SessionIDHeader, m.SessionID.String())
+		if _, err := p.js.PublishMsg(ctx, msg, jetstream.WithMsgID(strconv.FormatInt(m.ID, 10))); err != nil {
+			return fmt.Errorf("outbox message %d: %w", m.ID, err)
+		}
+	}
+	return nil
+}
+
+// Close closes the connection
+func (p *NATSPublisher) Close() error {
+	p.conn.Close()
+	return nil
+}
@@ -0,0 +1,149 @@
+package relay
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+)
+
+// LockKey identifies the advisory lock that elects the replica running the relay
+const LockKey int64 = 0x63736d5f726c79 // "csm_rly"
+
+// Publisher sends outbox messages to a message bus
+type Publisher interface {
+	// Publish sends the messages in order and returns once the bus has accepted all of
+	// them. When it fails any prefix of the messages may have been published.
+	Publish(ctx context.Context, messages []model.OutboxMessage) error
+	Close() error
+}
+
+// Leader decides whether this replica should run the relay
+type Leader interface {
+	Acquire(ctx context.Context) (bool, error)
+	Release()
+}
+
+// Config controls how often the outbox is polled and how publishing is retried
+type Config struct {
+	Interval  time.Duration
+	BatchSize int
+	// BackoffMax bounds the delay before retrying after the publisher failed; the delay
+	// starts at Interval and doubles with every consecutive failure
+	BackoffMax time.Duration
+}
+
+// Enabled reports whether the relay should run at all
+func (c Config) Enabled() bool {
+	return c.Interval > 0
+}
+
+// Relay publishes outbox messages to a message bus. Messages are published in outbox
+// order, a batch at a time, and only marked published once the publisher accepted the
+// whole batch, so delivery is at least once and ordered per session; a failed batch
+// is retried from its first message.
+type Relay struct {
+	svc       *service.Service
+	publisher Publisher
+	leader    Leader
+	cfg       Config
+	logger    *log.Logger
+
+	failures int
+	retryAt  time.Time
+}
+
+// New creates a relay; leader may be nil when only a single replica runs
+func New(svc *service.Service, publisher Publisher, leader Leader, cfg Config, logger *log.Logger) *Relay {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.BackoffMax <= 0 {
+		cfg.BackoffMax = time.Minute
+	}
+	return &Relay{svc: svc, publisher: publisher, leader: leader, cfg: cfg, logger: logger}
+}
+
+// Run publishes new outbox messages every interval until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	if r.leader != nil {
+		defer r.leader.Release()
+	}
+
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.tick(ctx)
+		}
+	}
+}
+
+func (r *Relay) tick(ctx context.Context) {
+	if r.leader != nil {
+		leading, err := r.leader.Acquire(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				r.logger.Printf("Outbox relay leader election failed: %v", err)
+			}
+			return
+		}
+		if !leading {
+			return
+		}
+	}
+
+	if time.Now().Before(r.retryAt) {
+		return
+	}
+
+	for ctx.Err() == nil {
+		messages, err := r.svc.ListUnpublishedOutbox(ctx, r.cfg.BatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				r.logger.Printf("Outbox relay failed to read the outbox: %v", err)
+			}
+			return
+		}
+		if len(messages) == 0 {
+			return
+		}
+
+		if err := r.publisher.Publish(ctx, messages); err != nil {
+			if ctx.Err() == nil {
+				r.failures++
+				delay := r.backoff()
+				r.retryAt = time.Now().Add(delay)
+				r.logger.Printf("Outbox relay failed to publish %d messages, retrying in %s: %v", len(messages), delay, err)
+			}
+			return
+		}
+		r.failures = 0
+		r.retryAt = time.Time{}
+
+		// The messages are on the bus; record that even if shutdown has begun so they
+		// are not published again
+		if err := r.svc.MarkOutboxPublished(context.WithoutCancel(ctx), messages); err != nil {
+			r.logger.Printf("Outbox relay failed to mark %d messages published: %v", len(messages), err)
+			return
+		}
+		if len(messages) < r.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+// backoff returns the delay before retrying after the current run of failures
+func (r *Relay) backoff() time.Duration {
+	delay := r.cfg.Interval
+	for i := 1; i < r.failures && delay < r.cfg.BackoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, r.cfg.BackoffMax)
+}
@@ -0,0 +1,197 @@
+package relay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+	"github.com/vasu74/Call_Session_Management/internal/store/memory"
+)
+
+var orgAccess = model.SessionAccess{Scope: model.SessionScopeAll, OrgID: model.DefaultOrganizationID, HomeOrgID: model.DefaultOrganizationID}
+
+// fakePublisher records the IDs of published messages after failing a number of times
+type fakePublisher struct {
+	fails     int
+	batches   int
+	published []int64
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, messages []model.OutboxMessage) error {
+	p.batches++
+	if p.fails > 0 {
+		p.fails--
+		return errors.New("bus unavailable")
+	}
+	for _, m := range messages {
+		p.published = append(p.published, m.ID)
+	}
+	return nil
+}
+
+func (p *fakePublisher) Close() error { return nil }
+
+func messages(ids ...int64) []model.OutboxMessage {
+	sessionID := uuid.New()
+	out := make([]model.OutboxMessage, len(ids))
+	for i, id := range ids {
+		out[i] = model.OutboxMessage{ID: id, Type: model.ActivitySessionStarted, SessionID: sessionID, OrgID: model.DefaultOrganizationID, Data: json.RawMessage(`{}`)}
+	}
+	return out
+}
+
+func TestRelayPublishesInOrderAndRetries(t *testing.T) {
+	ctx := context.Background()
+	svc := service.New(memory.New(), service.WithOutboxConsumers(model.OutboxConsumers{Publisher: true}))
+	for i := 0; i < 3; i++ {
+		session, _, err := svc.StartSession(ctx, orgAccess, model.StartSessionRequest{CallerID: "+14155550100", CalleeID: "+14155550199"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.LogEvent(ctx, orgAccess, session.ID.String(), model.LogEventRequest{EventType: "dtmf", EventTime: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	publisher := &fakePublisher{fails: 1}
+	r := New(svc, publisher, nil, Config{Interval: 10 * time.Millisecond, BatchSize: 4}, log.New(io.Discard, "", 0))
+
+	r.tick(ctx)
+	r.tick(ctx)
+	if publisher.batches != 1 || len(publisher.published) != 0 {
+		t.Fatalf("%d batches and %v published, want one failed batch and no retry before the backoff", publisher.batches, publisher.published)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	r.tick(ctx)
+	want := []int64{1, 2, 3, 4, 5, 6}
+	if len(publisher.published) != len(want) {
+		t.Fatalf("published %v, want %v", publisher.published, want)
+	}
+	for i, id := range want {
+		if publisher.published[i] != id {
+			t.Fatalf("published %v, want %v", publisher.published, want)
+		}
+	}
+	if left, err := svc.ListUnpublishedOutbox(ctx, 10); err != nil || len(left) != 0 {
+		t.Errorf("%d messages left unpublished, %v", len(left), err)
+	}
+}
+
+func TestRelayBackoff(t *testing.T) {
+	r := New(nil, nil, nil, Config{Interval: time.Second, BackoffMax: 5 * time.Second}, nil)
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 1, want: time.Second},
+		{failures: 2, want: 2 * time.Second},
+		{failures: 3, want: 4 * time.Second},
+		{failures: 4, want: 5 * time.Second},
+		{failures: 40, want: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		r.failures = tt.failures
+		if got := r.backoff(); got != tt.want {
+			t.Errorf("backoff after %d failures = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestKafkaRESTPublisher(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		wantErr  bool
+	}{
+		{name: "accepted", status: http.StatusOK, response: `{"offsets":[{"partition":0,"offset":7},{"partition":0,"offset":8}]}`},
+		{name: "proxy error", status: http.StatusInternalServerError, response: `{"error_code":50001,"message":"broker unavailable"}`, wantErr: true},
+		{name: "record error", status: http.StatusOK, response: `{"offsets":[{"partition":0,"offset":7},{"error_code":40403,"error":"not leader"}]}`, wantErr: true},
+		{name: "malformed response", status: http.StatusOK, response: `offsets`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path, contentType string
+			var body struct {
+				Records []struct {
+					Key   string              `json:"key"`
+					Value model.OutboxMessage `json:"value"`
+				} `json:"records"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path, contentType = r.URL.EscapedPath(), r.Header.Get("Content-Type")
+				json.NewDecoder(r.Body).Decode(&body)
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.response)
+			}))
+			defer server.Close()
+
+			publisher := NewKafkaRESTPublisher(server.URL+"/", "call sessions", time.Second)
+			defer publisher.Close()
+			sent := messages(1, 2)
+			err := publisher.Publish(context.Background(), sent)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Publish error = %v, want error: %v", err, tt.wantErr)
+			}
+
+			if path != "/topics/call%20sessions" || contentType != "application/vnd.kafka.json.v2+json" {
+				t.Errorf("request to %s with %s", path, contentType)
+			}
+			if len(body.Records) != len(sent) {
+				t.Fatalf("%d records, want %d", len(body.Records), len(sent))
+			}
+			for i, record := range body.Records {
+				if record.Key != sent[i].SessionID.String() || record.Value.ID != sent[i].ID {
+					t.Errorf("record %d keyed %s with message %d", i, record.Key, record.Value.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestFilePublisher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.ndjson")
+	for _, batch := range [][]model.OutboxMessage{messages(1, 2), messages(3)} {
+		publisher, err := NewFilePublisher(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := publisher.Publish(context.Background(), batch); err != nil {
+			t.Fatal(err)
+		}
+		if err := publisher.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var ids []int64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var m model.OutboxMessage
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, m.ID)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 3 {
+		t.Errorf("file holds messages %v, want 1, 2 and 3 appended in order", ids)
+	}
+}
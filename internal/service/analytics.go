@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// approximateBucketWidth is used to reject ranges with too many buckets before querying
+var approximateBucketWidth = map[model.AnalyticsInterval]time.Duration{
+	model.IntervalMinute: time.Minute,
+	model.IntervalHour:   time.Hour,
+	model.IntervalDay:    24 * time.Hour,
+	model.IntervalWeek:   7 * 24 * time.Hour,
+}
+
+// SessionAnalytics aggregates the sessions visible to the caller that match the query
+// into time buckets
+func (s *Service) SessionAnalytics(ctx context.Context, access model.SessionAccess, q model.SessionAnalyticsQuery) (*model.SessionAnalytics, error) {
+	if !q.Interval.IsValid() {
+		return nil, model.ErrInvalidQuery.WithMessage("interval must be minute, hour, day or week")
+	}
+	if q.Location == nil {
+		q.Location = time.UTC
+	}
+	q.Filter.Access = access
+	q.Filter.NormalizeIdentities(s.numbers.Normalize)
+
+	from, to := q.Filter.StartDate, q.Filter.EndDate
+	if from != nil && to != nil {
+		if to.Before(*from) {
+			return nil, model.ErrInvalidQuery.WithMessage("end_date must not be before start_date")
+		}
+		if to.Sub(*from)/approximateBucketWidth[q.Interval] >= model.MaxAnalyticsBuckets {
+			return nil, model.ErrInvalidQuery.WithMessage("the requested range has too many buckets; narrow it or use a larger interval")
+		}
+	}
+
+	analytics, err := s.sessions.SessionAnalytics(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	analytics.Interval = q.Interval
+	analytics.TimeZone = q.Location.String()
+	if err := analytics.FillBuckets(q.Location, from, to); err != nil {
+		return nil, err
+	}
+	return analytics, nil
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// apiKeyTouchInterval is how often the last use of a key is written, so busy clients do
+// not cost a write per request
+const apiKeyTouchInterval = time.Minute
+
+// CreateAPIKey creates an API key in the caller's organization on behalf of a caller
+// holding the permissions of its scopes, together with a new service account unless
+// the request names an existing one. The returned key carries the key itself, which is
+// not returned again.
+func (s *Service) CreateAPIKey(ctx context.Context, caller model.SessionAccess, granted model.Permissions, req model.CreateAPIKeyRequest) (*model.APIKey, error) {
+	if err := checkGrantable(granted, req.Scopes); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, model.ErrInvalidRequest.WithMessage("expires_at must be in the future")
+	}
+
+	prefix, secret, err := newAPIKeyParts()
+	if err != nil {
+		return nil, err
+	}
+	raw := model.NewAPIKey(prefix, secret)
+	keyPrefix, _ := model.APIKeyPrefix(raw)
+
+	key := &model.APIKey{
+		ID:           uuid.New(),
+		Name:         req.Name,
+		Prefix:       keyPrefix,
+		KeyHash:      model.HashAPIKey(raw),
+		OrgID:        caller.HomeOrgID,
+		Scopes:       req.Scopes,
+		AllowedCIDRs: req.AllowedCIDRs,
+		ExpiresAt:    req.ExpiresAt,
+		CreatedBy:    caller.UserID,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if key.AllowedCIDRs == nil {
+		key.AllowedCIDRs = []string{}
+	}
+
+	var account *model.User
+	if req.ServiceAccountID != "" {
+		existing, err := s.users.GetUserByID(ctx, req.ServiceAccountID)
+		if err == nil && existing.OrgID != caller.HomeOrgID {
+			err = model.ErrUserNotFound
+		}
+		if err != nil {
+			if errors.Is(err, model.ErrUserNotFound) {
+				return nil, model.ErrInvalidRequest.WithMessage("service account not found")
+			}
+			return nil, err
+		}
+		if existing.Role != model.UserRoleService {
+			return nil, model.ErrInvalidRequest.WithMessage("service_account_id must refer to a service account")
+		}
+		key.ServiceAccountID = existing.ID
+	} else {
+		// Service accounts have no password and cannot log in; the reserved .invalid
+		// domain keeps their addresses from colliding with real users
+		account = &model.User{
+			ID:        uuid.New(),
+			Email:     keyPrefix + "@service-accounts.invalid",
+			Role:      model.UserRoleService,
+			OrgID:     caller.HomeOrgID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		key.ServiceAccountID = account.ID
+	}
+
+	if err := s.apiKeys.CreateAPIKey(ctx, key, account); err != nil {
+		return nil, err
+	}
+	key.Key = raw
+	return key, nil
+}
+
+func newAPIKeyParts() (prefix, secret string, err error) {
+	b := make([]byte, 8+32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(b[:8]), hex.EncodeToString(b[8:]), nil
+}
+
+// GetAPIKey retrieves an API key of the caller's organization
+func (s *Service) GetAPIKey(ctx context.Context, caller model.SessionAccess, keyID string) (*model.APIKey, error) {
+	key, err := s.apiKeys.GetAPIKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if !inOrganization(caller, key.OrgID) {
+		return nil, model.ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+// ListAPIKeys retrieves the API keys of the caller's organization
+func (s *Service) ListAPIKeys(ctx context.Context, caller model.SessionAccess) ([]model.APIKey, error) {
+	return s.apiKeys.ListAPIKeys(ctx, caller.HomeOrgID.String())
+}
+
+// UpdateAPIKey applies the fields set in req to an API key
+func (s *Service) UpdateAPIKey(ctx context.Context, caller model.SessionAccess, granted model.Permissions, keyID string, req model.UpdateAPIKeyRequest) (*model.APIKey, error) {
+	key, err := s.GetAPIKey(ctx, caller, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if req.Name != nil {
+		key.Name = *req.Name
+	}
+	if req.Scopes != nil {
+		if err := checkGrantable(granted, req.Scopes); err != nil {
+			return nil, err
+		}
+		key.Scopes = req.Scopes
+	}
+	if req.AllowedCIDRs != nil {
+		key.AllowedCIDRs = *req.AllowedCIDRs
+		if key.AllowedCIDRs == nil {
+			key.AllowedCIDRs = []string{}
+		}
+	}
+	switch {
+	case req.NoExpiry && req.ExpiresAt != nil:
+		return nil, model.ErrInvalidRequest.WithMessage("expires_at and no_expiry are mutually exclusive")
+	case req.NoExpiry:
+		key.ExpiresAt = nil
+	case req.ExpiresAt != nil:
+		if !req.ExpiresAt.After(now) {
+			return nil, model.ErrInvalidRequest.WithMessage("expires_at must be in the future")
+		}
+		key.ExpiresAt = req.ExpiresAt
+	}
+	key.UpdatedAt = now
+
+	if err := s.apiKeys.UpdateAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// DeleteAPIKey revokes an API key. Its service account is kept, so what it did stays
+// attributed and other keys of the account keep working.
+func (s *Service) DeleteAPIKey(ctx context.Context, caller model.SessionAccess, keyID string) error {
+	if _, err := s.GetAPIKey(ctx, caller, keyID); err != nil {
+		return err
+	}
+	return s.apiKeys.DeleteAPIKey(ctx, keyID)
+}
+
+// CheckAPIKey rejects an API key that has been deleted or has expired since it was
+// authenticated, e.g. by a long-lived connection
+func (s *Service) CheckAPIKey(ctx context.Context, key *model.APIKey) error {
+	current, err := s.apiKeys.GetAPIKey(ctx, key.ID.String())
+	if errors.Is(err, model.ErrAPIKeyNotFound) {
+		return model.ErrUnauthorized.WithMessage("API key has been revoked")
+	}
+	if err != nil {
+		return err
+	}
+	if current.Expired(time.Now()) {
+		return model.ErrUnauthorized.WithMessage("API key has expired")
+	}
+	return nil
+}
+
+// AuthenticateAPIKey checks an API key presented from the given client address and
+// returns it with its service account
+func (s *Service) AuthenticateAPIKey(ctx context.Context, raw, clientIP string) (*model.APIKey, *model.User, error) {
+	invalid := model.ErrUnauthorized.WithMessage("invalid API key")
+
+	prefix, ok := model.APIKeyPrefix(raw)
+	if !ok {
+		return nil, nil, invalid
+	}
+	key, err := s.apiKeys.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, model.ErrAPIKeyNotFound) {
+			return nil, nil, invalid
+		}
+		return nil, nil, err
+	}
+	if subtle.ConstantTimeCompare(key.KeyHash, model.HashAPIKey(raw)) != 1 {
+		return nil, nil, invalid
+	}
+
+	now := time.Now()
+	if key.Expired(now) {
+		return nil, nil, model.ErrUnauthorized.WithMessage("API key has expired")
+	}
+	if !key.AllowsAddress(clientIP) {
+		return nil, nil, model.ErrForbidden.WithMessage("API key is not allowed from this address")
+	}
+
+	account, err := s.users.GetUserByID(ctx, key.ServiceAccountID.String())
+	if err != nil {
+		if errors.Is(err, model.ErrUserNotFound) {
+			return nil, nil, invalid
+		}
+		return nil, nil, err
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.apiKeys.TouchAPIKey(ctx, key.ID.String(), now); err != nil {
+			return nil, nil, err
+		}
+		key.LastUsedAt = &now
+	}
+	return key, account, nil
+}
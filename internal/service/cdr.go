@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/cdr"
+	"github.com/vasu74/Call_Session_Management/internal/export"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// CDRTemplate returns the layout of call detail records
+func (s *Service) CDRTemplate() cdr.Template {
+	return s.cdrTemplate
+}
+
+// ExportCDRs writes the records of the sessions visible to the caller that ended in the
+// requested range to w, laid out by the CDR template, and returns how many were written
+func (s *Service) ExportCDRs(ctx context.Context, access model.SessionAccess, w io.Writer, req *model.CDRRequest) (int64, error) {
+	enc, err := export.NewEncoder(req.Format, w, s.cdrTemplate.Columns())
+	if err != nil {
+		return 0, err
+	}
+
+	query := req.Query
+	query.Access = access
+	var rows int64
+	err = s.cdrs.ExportCDRs(ctx, query, func(c *model.CDR) error {
+		rows++
+		return enc.WriteRow(s.cdrTemplate.Row(c))
+	})
+	if err != nil {
+		return rows, err
+	}
+	return rows, enc.Close()
+}
+
+// ListUnwrittenCDRs returns the oldest records not yet written to a CDR file
+func (s *Service) ListUnwrittenCDRs(ctx context.Context, limit int) ([]model.CDR, error) {
+	return s.cdrs.ListUnwrittenCDRs(ctx, limit)
+}
+
+// MarkCDRsWritten records that records were written to a CDR file
+func (s *Service) MarkCDRsWritten(ctx context.Context, cdrs []model.CDR) error {
+	ids := make([]int64, len(cdrs))
+	for i, c := range cdrs {
+		ids[i] = c.ID
+	}
+	return s.cdrs.MarkCDRsWritten(ctx, ids, time.Now())
+}
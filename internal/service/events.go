@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// LogEventBatch records a batch of events, which may belong to different sessions.
+// Every event is validated on its own and rejected events are reported without
+// affecting the others; the accepted events are inserted in a single transaction.
+// Sessions the caller cannot see are rejected as not found, and events of a type their
+// organization does not allow are rejected too.
+func (s *Service) LogEventBatch(ctx context.Context, access model.SessionAccess, items []model.BatchEventItem) (*model.BatchLogEventsResponse, error) {
+	if err := model.ValidateBatchSize(len(items), s.eventBatchLimit); err != nil {
+		return nil, err
+	}
+
+	response := &model.BatchLogEventsResponse{Results: make([]model.BatchItemResult, len(items))}
+	now := time.Now()
+
+	// Validate the items and look up all referenced sessions in one query
+	sessionIDs := make([]uuid.UUID, len(items))
+	var lookup []string
+	seen := make(map[uuid.UUID]bool)
+	for i := range items {
+		if err := items[i].Validate(now); err != nil {
+			response.Reject(i, err)
+			continue
+		}
+		id, err := uuid.Parse(items[i].SessionID)
+		if err != nil {
+			response.Reject(i, model.ErrInvalidID.WithMessage("invalid session_id"))
+			continue
+		}
+		sessionIDs[i] = id
+		if !seen[id] {
+			seen[id] = true
+			lookup = append(lookup, id.String())
+		}
+	}
+
+	sessions := make(map[uuid.UUID]model.Session)
+	if len(lookup) > 0 {
+		found, err := s.sessions.GetSessions(ctx, lookup)
+		if err != nil {
+			return nil, err
+		}
+		for _, session := range found {
+			if access.CanSee(&session) {
+				sessions[session.ID] = session
+			}
+		}
+	}
+
+	var events []model.SessionEvent
+	var indexes []int
+	orgs := make(map[uuid.UUID]*model.Organization)
+	for i := range items {
+		if response.Results[i].Status == model.BatchItemFailed {
+			continue
+		}
+		session, ok := sessions[sessionIDs[i]]
+		if !ok {
+			response.Reject(i, model.ErrSessionNotFound)
+			continue
+		}
+		if !session.Status.IsActive() {
+			response.Reject(i, model.ErrSessionAlreadyEnded.WithMessage("cannot log events for ended session"))
+			continue
+		}
+		if err := s.checkEventType(ctx, orgs, session.OrgID, items[i].EventType); err != nil {
+			if !errors.Is(err, model.ErrEventTypeNotAllowed) {
+				return nil, err
+			}
+			response.Reject(i, err)
+			continue
+		}
+
+		events = append(events, model.SessionEvent{
+			ID:        uuid.New(),
+			SessionID: session.ID,
+			OrgID:     session.OrgID,
+			EventType: items[i].EventType,
+			EventTime: items[i].EventTime,
+			Metadata:  items[i].Metadata,
+			CreatedAt: now,
+		})
+		indexes = append(indexes, i)
+	}
+
+	if len(events) > 0 {
+		if err := s.events.CreateEvents(ctx, events); err != nil {
+			return nil, err
+		}
+	}
+	for n, i := range indexes {
+		response.Accept(i, &events[n])
+	}
+
+	return response, nil
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/export"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// maxExportAttempts is how often a job is started again after the replica running it
+// stopped before the job finished
+const maxExportAttempts = 3
+
+// Export writes the sessions visible to the caller, or their events, selected by the
+// request to w and returns how many rows were written
+func (s *Service) Export(ctx context.Context, access model.SessionAccess, w io.Writer, req *model.ExportRequest) (int64, error) {
+	var rows int64
+	filter := req.Filter
+	filter.Access = access
+	filter.NormalizeIdentities(s.numbers.Normalize)
+
+	if req.Dataset == model.ExportEvents {
+		enc, err := export.NewEncoder(req.Format, w, export.EventColumns)
+		if err != nil {
+			return 0, err
+		}
+		err = s.events.ExportEvents(ctx, filter, func(e *model.SessionEvent) error {
+			row, err := export.EventRow(e)
+			if err != nil {
+				return err
+			}
+			rows++
+			return enc.WriteRow(row)
+		})
+		if err != nil {
+			return rows, err
+		}
+		return rows, enc.Close()
+	}
+
+	enc, err := export.NewEncoder(req.Format, w, export.SessionColumns(req.MetadataColumns))
+	if err != nil {
+		return 0, err
+	}
+	err = s.sessions.ExportSessions(ctx, filter, func(session *model.Session) error {
+		row, err := export.SessionRow(session, req.MetadataColumns)
+		if err != nil {
+			return err
+		}
+		rows++
+		return enc.WriteRow(row)
+	})
+	if err != nil {
+		return rows, err
+	}
+	return rows, enc.Close()
+}
+
+// CreateExportJob validates the query parameters of an export and queues a job that
+// writes it to the export directory
+func (s *Service) CreateExportJob(ctx context.Context, access model.SessionAccess, query url.Values) (*model.ExportJob, error) {
+	if s.exportDir == "" {
+		return nil, model.ErrInvalidRequest.WithMessage("export jobs are not enabled on this server")
+	}
+
+	req, err := model.ParseExportRequest(query)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &model.ExportJob{
+		ID:               uuid.New(),
+		UserID:           access.UserID,
+		AllOrganizations: access.OrgID == uuid.Nil,
+		Format:           req.Format,
+		Dataset:          req.Dataset,
+		Query:            query.Encode(),
+		Status:           model.ExportJobPending,
+		CreatedAt:        time.Now(),
+	}
+	if err := s.exportJobs.CreateExportJob(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// GetExportJob retrieves an export job. Users only see their own jobs, unless they can
+// see all sessions of the organization of the job's owner.
+func (s *Service) GetExportJob(ctx context.Context, access model.SessionAccess, jobID string) (*model.ExportJob, error) {
+	job, err := s.exportJobs.GetExportJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.UserID == access.UserID {
+		return job, nil
+	}
+	if access.Scope != model.SessionScopeAll {
+		return nil, model.ErrExportJobNotFound
+	}
+	owner, err := s.users.GetUserByID(ctx, job.UserID.String())
+	if err != nil {
+		return nil, err
+	}
+	if !inOrganization(access, owner.OrgID) {
+		return nil, model.ErrExportJobNotFound
+	}
+	return job, nil
+}
+
+// ExportJobFile returns a succeeded export job together with the path of its file
+func (s *Service) ExportJobFile(ctx context.Context, access model.SessionAccess, jobID string) (*model.ExportJob, string, error) {
+	job, err := s.GetExportJob(ctx, access, jobID)
+	if err != nil {
+		return nil, "", err
+	}
+	if job.Status != model.ExportJobSucceeded {
+		return nil, "", model.ErrExportNotReady.WithMessage(fmt.Sprintf("export job is %s", job.Status))
+	}
+	return job, filepath.Join(s.exportDir, job.FileName()), nil
+}
+
+// ClaimExportJobs reserves up to limit runnable export jobs for the given lease
+func (s *Service) ClaimExportJobs(ctx context.Context, limit int, lease time.Duration) ([]model.ExportJob, error) {
+	now := time.Now()
+	return s.exportJobs.ClaimExportJobs(ctx, now, now.Add(lease), limit)
+}
+
+// RunExportJob writes the export of a claimed job to the export directory and records
+// the outcome. A job whose ctx is cancelled is left running, so it is claimed again once
+// its lease expires; one that runs past the deadline of ctx fails.
+func (s *Service) RunExportJob(ctx context.Context, job *model.ExportJob) error {
+	var rows, size int64
+	err := errors.New("export was interrupted too many times")
+	if job.Attempts <= maxExportAttempts {
+		rows, size, err = s.writeExportFile(ctx, job)
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return ctx.Err()
+		}
+	}
+
+	finished := time.Now()
+	expires := finished.Add(s.exportRetention)
+	job.FinishedAt = &finished
+	job.ExpiresAt = &expires
+	if err != nil {
+		message := model.ErrInternal.Message
+		var domainErr *model.Error
+		if errors.As(err, &domainErr) {
+			message = err.Error()
+		} else if ctx.Err() != nil {
+			message = "export timed out"
+		}
+		job.Status = model.ExportJobFailed
+		job.Error = &message
+	} else {
+		job.Status = model.ExportJobSucceeded
+		job.RowCount = &rows
+		job.SizeBytes = &size
+	}
+
+	if finishErr := s.exportJobs.FinishExportJob(context.WithoutCancel(ctx), job); finishErr != nil {
+		return finishErr
+	}
+	return err
+}
+
+// writeExportFile writes the export to a temporary file that is renamed into place once
+// complete, so a job's file is only ever seen whole. The job exports the sessions its
+// owner can see when it runs.
+func (s *Service) writeExportFile(ctx context.Context, job *model.ExportJob) (rows, size int64, err error) {
+	query, err := url.ParseQuery(job.Query)
+	if err != nil {
+		return 0, 0, err
+	}
+	req, err := model.ParseExportRequest(query)
+	if err != nil {
+		return 0, 0, err
+	}
+	owner, err := s.users.GetUserByID(ctx, job.UserID.String())
+	if err != nil {
+		return 0, 0, err
+	}
+	granted, err := s.permissionsOf(ctx, owner, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	access, err := s.SessionAccess(ctx, owner, granted)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !job.AllOrganizations {
+		access.OrgID = access.HomeOrgID
+	}
+
+	path := filepath.Join(s.exportDir, job.FileName())
+	tmp, err := os.CreateTemp(s.exportDir, job.FileName()+".*.tmp")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	w := bufio.NewWriterSize(tmp, 64<<10)
+	if rows, err = s.Export(ctx, access, w, req); err != nil {
+		return 0, 0, err
+	}
+	if err = w.Flush(); err != nil {
+		return 0, 0, err
+	}
+	if err = tmp.Sync(); err != nil {
+		return 0, 0, err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	if err = tmp.Close(); err != nil {
+		return 0, 0, err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return 0, 0, err
+	}
+	return rows, info.Size(), nil
+}
+
+// PurgeExportJobs deletes expired export jobs together with their files
+func (s *Service) PurgeExportJobs(ctx context.Context) (int, error) {
+	jobs, err := s.exportJobs.DeleteExpiredExportJobs(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	for _, job := range jobs {
+		err := os.Remove(filepath.Join(s.exportDir, job.FileName()))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return len(jobs), err
+		}
+	}
+	return len(jobs), nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// BeginIdempotentRequest claims key for a request with the given hash. It returns nil
+// when the request should be executed, or the stored record when it is a replay of a
+// completed request. Reusing a key for a different request fails with
+// ErrIdempotencyKeyMismatch, and retrying while the original is still running fails
+// with ErrIdempotencyKeyInUse.
+func (s *Service) BeginIdempotentRequest(ctx context.Context, userID, key, requestHash string) (*model.IdempotencyRecord, error) {
+	now := time.Now()
+	record := &model.IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.idempotencyTTL),
+	}
+
+	existing, err := s.idempotency.ReserveIdempotencyKey(ctx, record, now.Add(-s.idempotencyLock))
+	if err != nil || existing == nil {
+		return nil, err
+	}
+	if existing.RequestHash != requestHash {
+		return nil, model.ErrIdempotencyKeyMismatch
+	}
+	if !existing.Completed() {
+		return nil, model.ErrIdempotencyKeyInUse
+	}
+	return existing, nil
+}
+
+// IdempotencyLockRenewal returns how often a running request renews the lock on its key,
+// leaving room for a few missed renewals before the lock times out
+func (s *Service) IdempotencyLockRenewal() time.Duration {
+	return s.idempotencyLock / 3
+}
+
+// RenewIdempotentRequest extends the lock a running request holds on its key, so a
+// retry cannot take the key over however long the request takes
+func (s *Service) RenewIdempotentRequest(ctx context.Context, userID, key string) error {
+	return s.idempotency.RenewIdempotencyKey(ctx, userID, key, time.Now())
+}
+
+// CompleteIdempotentRequest stores the response to replay for later uses of the key
+func (s *Service) CompleteIdempotentRequest(ctx context.Context, userID, key string, statusCode int, body []byte) error {
+	return s.idempotency.CompleteIdempotencyKey(ctx, userID, key, statusCode, body)
+}
+
+// AbandonIdempotentRequest releases the key of a request that did not succeed so that
+// the client can retry it
+func (s *Service) AbandonIdempotentRequest(ctx context.Context, userID, key string) error {
+	return s.idempotency.ReleaseIdempotencyKey(ctx, userID, key)
+}
+
+// PurgeExpiredIdempotencyKeys deletes the idempotency records that have expired
+func (s *Service) PurgeExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	return s.idempotency.DeleteExpiredIdempotencyKeys(ctx, time.Now())
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/store/memory"
+)
+
+func TestBeginIdempotentRequest(t *testing.T) {
+	ctx := context.Background()
+	svc := New(memory.New(), WithIdempotencyLockTimeout(time.Hour))
+
+	if record, err := svc.BeginIdempotentRequest(ctx, "user", "key", "hash"); record != nil || err != nil {
+		t.Fatalf("first use = %v, %v, want the key claimed", record, err)
+	}
+	if _, err := svc.BeginIdempotentRequest(ctx, "user", "key", "hash"); !errors.Is(err, model.ErrIdempotencyKeyInUse) {
+		t.Fatalf("retry while running error = %v, want ErrIdempotencyKeyInUse", err)
+	}
+	if _, err := svc.BeginIdempotentRequest(ctx, "user", "key", "other"); !errors.Is(err, model.ErrIdempotencyKeyMismatch) {
+		t.Fatalf("different request error = %v, want ErrIdempotencyKeyMismatch", err)
+	}
+	if record, err := svc.BeginIdempotentRequest(ctx, "other-user", "key", "other"); record != nil || err != nil {
+		t.Fatalf("key of another user = %v, %v, want the key claimed", record, err)
+	}
+
+	if err := svc.CompleteIdempotentRequest(ctx, "user", "key", 201, []byte(`{"ok":true}`)); err != nil {
+		t.Fatal(err)
+	}
+	record, err := svc.BeginIdempotentRequest(ctx, "user", "key", "hash")
+	if err != nil || record == nil || record.StatusCode != 201 || string(record.ResponseBody) != `{"ok":true}` {
+		t.Fatalf("replay = %+v, %v, want the stored response", record, err)
+	}
+
+	if _, err := svc.BeginIdempotentRequest(ctx, "user", "failed", "hash"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.AbandonIdempotentRequest(ctx, "user", "failed"); err != nil {
+		t.Fatal(err)
+	}
+	if record, err := svc.BeginIdempotentRequest(ctx, "user", "failed", "hash"); record != nil || err != nil {
+		t.Fatalf("retry of a failed request = %v, %v, want the key claimed", record, err)
+	}
+}
+
+func TestIdempotencyLockRenewal(t *testing.T) {
+	ctx := context.Background()
+	lock := 30 * time.Millisecond
+	svc := New(memory.New(), WithIdempotencyLockTimeout(lock))
+	if svc.IdempotencyLockRenewal() >= lock {
+		t.Fatalf("renewal interval %s is not shorter than the lock timeout %s", svc.IdempotencyLockRenewal(), lock)
+	}
+
+	if _, err := svc.BeginIdempotentRequest(ctx, "user", "renewed", "hash"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.BeginIdempotentRequest(ctx, "user", "abandoned", "hash"); err != nil {
+		t.Fatal(err)
+	}
+	// Renew one key for longer than the lock timeout while the other lapses
+	for deadline := time.Now().Add(2 * lock); time.Now().Before(deadline); time.Sleep(svc.IdempotencyLockRenewal()) {
+		if err := svc.RenewIdempotentRequest(ctx, "user", "renewed"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := svc.BeginIdempotentRequest(ctx, "user", "renewed", "hash"); !errors.Is(err, model.ErrIdempotencyKeyInUse) {
+		t.Errorf("retry of a renewed request error = %v, want ErrIdempotencyKeyInUse", err)
+	}
+	if record, err := svc.BeginIdempotentRequest(ctx, "user", "abandoned", "hash"); record != nil || err != nil {
+		t.Errorf("retry of an abandoned request = %v, %v, want the key taken over", record, err)
+	}
+}
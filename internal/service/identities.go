@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// NormalizeIdentity returns the normalized form of a caller or callee ID, as it is
+// stored and compared
+func (s *Service) NormalizeIdentity(raw string) string {
+	return s.numbers.Normalize(raw)
+}
+
+// setIdentities stores the caller and callee IDs of a session as given and normalized
+func (s *Service) setIdentities(session *model.Session, callerID, calleeID string) {
+	session.SetCaller(callerID, s.numbers.Parse(callerID))
+	session.SetCallee(calleeID, s.numbers.Parse(calleeID))
+}
+
+// NormalizeSessionIdentities normalizes the caller and callee IDs of every stored
+// session again from the IDs as given, for sessions stored before IDs were normalized
+// or after the default region changed. Sessions whose IDs change are updated batchSize
+// at a time; it returns how many were updated.
+func (s *Service) NormalizeSessionIdentities(ctx context.Context, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = importBatchSize
+	}
+
+	updated := 0
+	batch := make([]model.Session, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := s.sessions.UpdateSessionIdentities(ctx, batch); err != nil {
+			return err
+		}
+		updated += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	filter := model.SessionFilter{Access: model.FullSessionAccess, Sort: []model.SortField{{Field: "created_at"}}}
+	err := s.sessions.ExportSessions(ctx, filter, func(session *model.Session) error {
+		normalized := *session
+		s.setIdentities(&normalized, session.CallerIDRaw, session.CalleeIDRaw)
+		if normalized.CallerID == session.CallerID && normalized.CallerIDType == session.CallerIDType &&
+			normalized.CallerNumberType == session.CallerNumberType && normalized.CalleeID == session.CalleeID &&
+			normalized.CalleeIDType == session.CalleeIDType && normalized.CalleeNumberType == session.CalleeNumberType {
+			return nil
+		}
+
+		batch = append(batch, normalized)
+		if len(batch) == batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return updated, err
+	}
+	return updated, flush()
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/phone"
+	"github.com/vasu74/Call_Session_Management/internal/store/memory"
+)
+
+func TestNormalizeSessionIdentities(t *testing.T) {
+	ctx := context.Background()
+	st := memory.New()
+	now := time.Now()
+
+	// Sessions as stored before IDs were normalized, by the IDs they were given
+	sessions := []struct {
+		caller, callee string
+		wantCaller     string
+		wantType       model.IdentityType
+	}{
+		{caller: "020 7946 0958", callee: "1001", wantCaller: "+442079460958", wantType: model.IdentityE164},
+		{caller: "+44 7911 123456", callee: "1002", wantCaller: "+447911123456", wantType: model.IdentityE164},
+		{caller: "sip:Alice@Example.com", callee: "1003", wantCaller: "sip:Alice@example.com", wantType: model.IdentitySIP},
+		{caller: "anonymous", callee: "1004", wantCaller: "anonymous", wantType: model.IdentityUnknown},
+	}
+	ids := make([]uuid.UUID, len(sessions))
+	for i, s := range sessions {
+		session := &model.Session{ID: uuid.New(), OrgID: model.DefaultOrganizationID, StartedAt: now, CallerID: s.caller, CallerIDRaw: s.caller, CalleeID: s.callee, CalleeIDRaw: s.callee, Status: model.SessionStatusRinging, CreatedAt: now.Add(time.Duration(i)), UpdatedAt: now}
+		if err := st.CreateSession(ctx, session); err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = session.ID
+	}
+	// Stored in normalized form already, so left alone
+	normalized := &model.Session{ID: uuid.New(), OrgID: model.DefaultOrganizationID, StartedAt: now, Status: model.SessionStatusRinging, CreatedAt: now, UpdatedAt: now}
+	normalized.SetCaller("1005", model.Identity{Normalized: "1005", Type: model.IdentityExtension, NumberType: model.NumberTypeUnknown})
+	normalized.SetCallee("1006", model.Identity{Normalized: "1006", Type: model.IdentityExtension, NumberType: model.NumberTypeUnknown})
+	if err := st.CreateSession(ctx, normalized); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := New(st, WithNumberNormalizer(phone.MustNewNormalizer("GB", 0)))
+	updated, err := svc.NormalizeSessionIdentities(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated != len(sessions) {
+		t.Errorf("updated %d sessions, want %d", updated, len(sessions))
+	}
+	for i, s := range sessions {
+		session, err := st.GetSession(ctx, ids[i].String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if session.CallerID != s.wantCaller || session.CallerIDType != s.wantType || session.CallerIDRaw != s.caller {
+			t.Errorf("caller %q normalized to %q (%s), want %q (%s)", s.caller, session.CallerID, session.CallerIDType, s.wantCaller, s.wantType)
+		}
+		if session.CalleeIDType != model.IdentityExtension {
+			t.Errorf("callee %q has type %s, want an extension", s.callee, session.CalleeIDType)
+		}
+	}
+
+	if updated, err := svc.NormalizeSessionIdentities(ctx, 3); err != nil || updated != 0 {
+		t.Errorf("second run updated %d sessions, %v, want none", updated, err)
+	}
+}
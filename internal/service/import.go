@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/cdr"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// importBatchSize is the number of sessions stored per transaction during an import
+const importBatchSize = 500
+
+// ImportSessions reads a file of call detail records and stores each record as an ended
+// session owned by the caller, skipping records whose unique ID was imported from the
+// same source before.
+// Rejected records are reported in the result and do not stop the import. An error is
+// returned when the file cannot be read any further; the sessions stored until then
+// are kept, so the import can simply be run again.
+func (s *Service) ImportSessions(ctx context.Context, access model.SessionAccess, r io.Reader, opts model.ImportOptions) (*model.ImportResult, error) {
+	if !opts.Format.IsValid() {
+		return nil, model.ErrInvalidRequest.WithMessage("format must be asterisk_csv, freeswitch_csv or freeswitch_xml")
+	}
+	if opts.Source == "" {
+		opts.Source = opts.Format.DefaultSource()
+	}
+	if len(opts.Source) > 64 {
+		return nil, model.ErrInvalidRequest.WithMessage("source must be at most 64 characters")
+	}
+
+	reader, err := cdr.NewReader(r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.ImportResult{Format: opts.Format, Source: opts.Source, Errors: []model.ImportRowError{}}
+	batch := make([]cdr.Record, 0, importBatchSize)
+	now := time.Now()
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var rowErr *cdr.RowError
+		if errors.As(err, &rowErr) {
+			result.Rows++
+			result.Reject(rowErr.Row, rowErr.Err)
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+
+		result.Rows++
+		session := &record.Session
+		s.setIdentities(session, session.CallerID, session.CalleeID)
+		access.Stamp(session)
+		for i := range record.Events {
+			record.Events[i].OrgID = session.OrgID
+		}
+		record.Metrics = model.ComputeSessionMetrics(session, record.Events, *session.EndedAt, s.metricRules, now)
+		batch = append(batch, *record)
+		if len(batch) == importBatchSize {
+			if err := s.storeImportBatch(ctx, batch, result); err != nil {
+				return result, err
+			}
+			batch = batch[:0]
+		}
+	}
+
+	if err := s.storeImportBatch(ctx, batch, result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// storeImportBatch stores a batch of records in one transaction. When the store rejects
+// the batch, its records are stored one at a time so the offending rows can be reported.
+func (s *Service) storeImportBatch(ctx context.Context, batch []cdr.Record, result *model.ImportResult) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	sessions := make([]model.ImportedSession, len(batch))
+	for i := range batch {
+		sessions[i] = batch[i].ImportedSession
+	}
+	imported, err := s.sessions.ImportSessions(ctx, sessions)
+	if err == nil {
+		result.Imported += imported
+		result.Duplicates += len(sessions) - imported
+		return nil
+	}
+
+	var domainErr *model.Error
+	if !errors.As(err, &domainErr) || ctx.Err() != nil {
+		return err
+	}
+	for i := range batch {
+		imported, err := s.sessions.ImportSessions(ctx, sessions[i:i+1])
+		if err != nil {
+			if !errors.As(err, &domainErr) {
+				return err
+			}
+			result.Reject(batch[i].Row, err)
+			continue
+		}
+		result.Imported += imported
+		result.Duplicates += 1 - imported
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// inOrganization reports whether something belonging to an organization is visible to
+// the caller. Callers whose access spans every organization see everything.
+func inOrganization(caller model.SessionAccess, orgID uuid.UUID) bool {
+	return caller.OrgID == uuid.Nil || caller.OrgID == orgID
+}
+
+// ListOrganizations retrieves all organizations
+func (s *Service) ListOrganizations(ctx context.Context) ([]model.Organization, error) {
+	return s.orgs.ListOrganizations(ctx)
+}
+
+// GetOrganization retrieves an organization by ID
+func (s *Service) GetOrganization(ctx context.Context, orgID string) (*model.Organization, error) {
+	return s.orgs.GetOrganization(ctx, orgID)
+}
+
+// CreateOrganization creates an organization. Users are added to it by moving them
+// there, typically starting with its first organization admin.
+func (s *Service) CreateOrganization(ctx context.Context, req model.CreateOrganizationRequest) (*model.Organization, error) {
+	org := newOrganization(req, time.Now())
+	if err := s.orgs.CreateOrganization(ctx, org); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// newOrganization returns the organization described by req, created at now
+func newOrganization(req model.CreateOrganizationRequest, now time.Time) *model.Organization {
+	org := &model.Organization{
+		ID:                uuid.New(),
+		Name:              req.Name,
+		RetentionDays:     req.RetentionDays,
+		AllowedEventTypes: req.AllowedEventTypes,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if org.AllowedEventTypes == nil {
+		org.AllowedEventTypes = []string{}
+	}
+	return org
+}
+
+// UpdateOrganization applies the fields set in req to an organization
+func (s *Service) UpdateOrganization(ctx context.Context, orgID string, req model.UpdateOrganizationRequest) (*model.Organization, error) {
+	org, err := s.orgs.GetOrganization(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		org.Name = *req.Name
+	}
+	if req.RetentionDays != nil {
+		org.RetentionDays = *req.RetentionDays
+	}
+	if req.AllowedEventTypes != nil {
+		org.AllowedEventTypes = *req.AllowedEventTypes
+		if org.AllowedEventTypes == nil {
+			org.AllowedEventTypes = []string{}
+		}
+	}
+	org.UpdatedAt = time.Now()
+
+	if err := s.orgs.UpdateOrganization(ctx, org); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// UpdateOwnOrganization applies the settings set in req to the caller's organization
+func (s *Service) UpdateOwnOrganization(ctx context.Context, caller model.SessionAccess, req model.UpdateOrganizationSettingsRequest) (*model.Organization, error) {
+	return s.UpdateOrganization(ctx, caller.HomeOrgID.String(), model.UpdateOrganizationRequest{
+		RetentionDays:     req.RetentionDays,
+		AllowedEventTypes: req.AllowedEventTypes,
+	})
+}
+
+// checkEventType rejects events of a type the session's organization does not allow,
+// looking organizations up through cache so a batch reads each of them once
+func (s *Service) checkEventType(ctx context.Context, cache map[uuid.UUID]*model.Organization, orgID uuid.UUID, eventType string) error {
+	org, ok := cache[orgID]
+	if !ok {
+		var err error
+		if org, err = s.orgs.GetOrganization(ctx, orgID.String()); err != nil {
+			return err
+		}
+		cache[orgID] = org
+	}
+	if !org.AllowsEventType(eventType) {
+		return model.ErrEventTypeNotAllowed.WithMessage(fmt.Sprintf("event type %q is not allowed in this organization", eventType))
+	}
+	return nil
+}
+
+// PurgeExpiredSessions removes the ended sessions, with their events, metrics and
+// CDRs, of every organization with a retention period that ended before it, and
+// returns how many were removed
+func (s *Service) PurgeExpiredSessions(ctx context.Context) (int64, error) {
+	orgs, err := s.orgs.ListOrganizations(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var purged int64
+	now := time.Now()
+	for _, org := range orgs {
+		if org.RetentionDays == 0 {
+			continue
+		}
+		n, err := s.sessions.PurgeSessions(ctx, org.ID.String(), now.AddDate(0, 0, -org.RetentionDays))
+		if err != nil {
+			return purged, err
+		}
+		purged += n
+	}
+	return purged, nil
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// ListUnpublishedOutbox returns the oldest outbox messages not yet published
+func (s *Service) ListUnpublishedOutbox(ctx context.Context, limit int) ([]model.OutboxMessage, error) {
+	return s.outbox.ListUnpublishedOutbox(ctx, limit)
+}
+
+// MarkOutboxPublished records that messages were accepted by the message bus
+func (s *Service) MarkOutboxPublished(ctx context.Context, messages []model.OutboxMessage) error {
+	ids := make([]int64, len(messages))
+	for i, m := range messages {
+		ids[i] = m.ID
+	}
+	return s.outbox.MarkOutboxPublished(ctx, ids, time.Now())
+}
+
+// PurgeOutbox removes processed outbox messages older than the retention period
+func (s *Service) PurgeOutbox(ctx context.Context) (int64, error) {
+	return s.outbox.PurgeOutbox(ctx, time.Now().Add(-s.outboxRetention), s.outboxConsumers)
+}
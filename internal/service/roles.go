@@ -0,0 +1,327 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// roleNamePattern restricts role names to what reads well in tokens and URLs
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// permissionsOf returns the permissions a user holds through their role, limited to the
+// scopes unless they are nil
+func (s *Service) permissionsOf(ctx context.Context, user *model.User, scopes model.Permissions) (model.Permissions, error) {
+	role, err := s.roles.GetRole(ctx, string(user.Role))
+	if err != nil {
+		return nil, err
+	}
+	if scopes == nil {
+		return append(model.Permissions{}, role.Permissions...), nil
+	}
+	return role.Permissions.Intersect(scopes), nil
+}
+
+// SessionAccess returns the sessions a user can see through their role when acting with
+// the granted permissions of a token or API key
+func (s *Service) SessionAccess(ctx context.Context, user *model.User, granted model.Permissions) (model.SessionAccess, error) {
+	role, err := s.roles.GetRole(ctx, string(user.Role))
+	if err != nil {
+		return model.SessionAccess{}, err
+	}
+	return model.NewSessionAccess(user, role, granted), nil
+}
+
+// checkGrantable keeps callers from handing out permissions they do not hold
+func checkGrantable(granted, requested model.Permissions) error {
+	if missing := granted.Missing(requested); len(missing) > 0 {
+		return model.ErrForbidden.WithMessage(fmt.Sprintf("insufficient permissions: cannot grant %s", missing[0]))
+	}
+	return nil
+}
+
+// checkSessionScope keeps callers from handing out access to more sessions than they see
+func checkSessionScope(caller model.SessionAccess, scope model.SessionScope) error {
+	if !caller.Scope.Covers(scope) {
+		return model.ErrForbidden.WithMessage(fmt.Sprintf("insufficient permissions: cannot grant session scope %s", scope))
+	}
+	return nil
+}
+
+// fixedPermissions reports whether the permissions of a role cannot be changed: admins
+// always hold every permission, and service accounts those of their API keys
+func fixedPermissions(name model.UserRole) bool {
+	return name == model.UserRoleAdmin || name == model.UserRoleService
+}
+
+// ListRoles retrieves all roles
+func (s *Service) ListRoles(ctx context.Context) ([]model.Role, error) {
+	return s.roles.ListRoles(ctx)
+}
+
+// GetRole retrieves a role by name
+func (s *Service) GetRole(ctx context.Context, name string) (*model.Role, error) {
+	return s.roles.GetRole(ctx, name)
+}
+
+// CreateRole creates a role with permissions and a session scope the caller holds
+func (s *Service) CreateRole(ctx context.Context, caller model.SessionAccess, granted model.Permissions, req model.CreateRoleRequest) (*model.Role, error) {
+	if !roleNamePattern.MatchString(req.Name) {
+		return nil, model.ErrInvalidRequest.WithMessage("name must start with a lowercase letter and contain only lowercase letters, digits, '_' and '-'")
+	}
+	if req.SessionScope == "" {
+		req.SessionScope = model.SessionScopeOwn
+	}
+	if err := checkGrantable(granted, req.Permissions); err != nil {
+		return nil, err
+	}
+	if err := checkSessionScope(caller, req.SessionScope); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	role := &model.Role{
+		Name:         model.UserRole(req.Name),
+		Description:  req.Description,
+		Permissions:  req.Permissions,
+		SessionScope: req.SessionScope,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.roles.CreateRole(ctx, role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// UpdateRole applies the fields set in req to a role. Changing its permissions or session
+// scope revokes the logins of the users holding it, so the change applies at once.
+func (s *Service) UpdateRole(ctx context.Context, caller model.SessionAccess, granted model.Permissions, name string, req model.UpdateRoleRequest) (*model.Role, error) {
+	role, err := s.roles.GetRole(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Description != nil {
+		role.Description = *req.Description
+	}
+	if req.Permissions != nil {
+		if fixedPermissions(role.Name) {
+			return nil, model.ErrForbidden.WithMessage(fmt.Sprintf("the permissions of the %s role cannot be changed", role.Name))
+		}
+		if err := checkGrantable(granted, *req.Permissions); err != nil {
+			return nil, err
+		}
+		role.Permissions = append(model.Permissions{}, *req.Permissions...)
+	}
+	if req.SessionScope != nil {
+		if role.Name == model.UserRoleAdmin {
+			return nil, model.ErrForbidden.WithMessage("the session scope of the admin role cannot be changed")
+		}
+		if err := checkSessionScope(caller, *req.SessionScope); err != nil {
+			return nil, err
+		}
+		role.SessionScope = *req.SessionScope
+	}
+	now := time.Now()
+	role.UpdatedAt = now
+
+	if err := s.roles.UpdateRole(ctx, role); err != nil {
+		return nil, err
+	}
+	if req.Permissions != nil || req.SessionScope != nil {
+		if err := s.tokens.RevokeRoleRefreshTokens(ctx, string(role.Name), now); err != nil {
+			return nil, err
+		}
+	}
+	return role, nil
+}
+
+// DeleteRole removes a role that is not built in and no user holds
+func (s *Service) DeleteRole(ctx context.Context, name string) error {
+	role, err := s.roles.GetRole(ctx, name)
+	if err != nil {
+		return err
+	}
+	if role.Builtin {
+		return model.ErrForbidden.WithMessage("built-in roles cannot be deleted")
+	}
+	return s.roles.DeleteRole(ctx, name)
+}
+
+// ListUsers retrieves the users of an organization on behalf of a caller, or of all
+// organizations if orgID is empty. Callers without orgs:manage only see the users of
+// their own organization.
+func (s *Service) ListUsers(ctx context.Context, caller model.SessionAccess, granted model.Permissions, orgID string) ([]model.User, error) {
+	if !granted.Has(model.PermissionOrgsManage) {
+		if orgID != "" && orgID != caller.HomeOrgID.String() {
+			return nil, model.ErrForbidden.WithMessage(fmt.Sprintf("insufficient permissions: required permission %s", model.PermissionOrgsManage))
+		}
+		orgID = caller.HomeOrgID.String()
+	}
+	return s.users.ListUsers(ctx, orgID)
+}
+
+// GetManagedUser retrieves a user on behalf of a caller. Users of other organizations
+// are reported as not found unless the caller holds orgs:manage.
+func (s *Service) GetManagedUser(ctx context.Context, caller model.SessionAccess, granted model.Permissions, userID string) (*model.User, error) {
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !granted.Has(model.PermissionOrgsManage) && !inOrganization(caller, user.OrgID) {
+		return nil, model.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// CreateUser adds a user to the caller's organization, or to another one for callers
+// holding orgs:manage. The caller must hold the permissions and session scope of the
+// user's role, and only callers who see all sessions place users in a team.
+func (s *Service) CreateUser(ctx context.Context, caller model.SessionAccess, granted model.Permissions, req model.CreateUserRequest) (*model.User, error) {
+	orgID := caller.HomeOrgID
+	if req.OrgID != nil && *req.OrgID != orgID {
+		if !granted.Has(model.PermissionOrgsManage) {
+			return nil, model.ErrForbidden.WithMessage(fmt.Sprintf("insufficient permissions: required permission %s", model.PermissionOrgsManage))
+		}
+		if _, err := s.orgs.GetOrganization(ctx, req.OrgID.String()); err != nil {
+			if errors.Is(err, model.ErrOrganizationNotFound) {
+				return nil, model.ErrInvalidRequest.WithMessage("organization not found")
+			}
+			return nil, err
+		}
+		orgID = *req.OrgID
+	}
+
+	if req.Role == "" {
+		req.Role = model.UserRoleUser
+	}
+	if req.Role == model.UserRoleService {
+		return nil, model.ErrInvalidRequest.WithMessage("service accounts are managed through their API keys")
+	}
+	role, err := s.roles.GetRole(ctx, string(req.Role))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGrantable(granted, role.Permissions); err != nil {
+		return nil, err
+	}
+	if err := checkSessionScope(caller, role.SessionScope); err != nil {
+		return nil, err
+	}
+
+	if req.Team != "" {
+		if !roleNamePattern.MatchString(req.Team) {
+			return nil, model.ErrInvalidRequest.WithMessage("team must start with a lowercase letter and contain only lowercase letters, digits, '_' and '-'")
+		}
+		if caller.Scope != model.SessionScopeAll {
+			return nil, model.ErrForbidden.WithMessage("insufficient permissions: placing users in a team requires the all session scope")
+		}
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	user := &model.User{
+		ID:        uuid.New(),
+		Email:     req.Email,
+		Password:  string(hashedPassword),
+		Role:      role.Name,
+		Team:      req.Team,
+		OrgID:     orgID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.users.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	user.Password = ""
+	return user, nil
+}
+
+// UpdateUser applies the fields set in req to a user on behalf of a caller. Moving a
+// user to another role requires the permissions and session scope of both the old and
+// the new role, and revokes the user's logins so the new permissions apply at once.
+// Teams can only be changed by callers who see all sessions, and organizations by
+// callers holding orgs:manage, which also revokes the user's logins.
+func (s *Service) UpdateUser(ctx context.Context, caller model.SessionAccess, granted model.Permissions, userID string, req model.UpdateUserRequest) (*model.User, error) {
+	user, err := s.GetManagedUser(ctx, caller, granted, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roleChanged := req.Role != nil && *req.Role != user.Role
+	if roleChanged {
+		if user.ID == caller.UserID {
+			return nil, model.ErrForbidden.WithMessage("cannot change your own role")
+		}
+		if user.Role == model.UserRoleService || *req.Role == model.UserRoleService {
+			return nil, model.ErrInvalidRequest.WithMessage("service accounts are managed through their API keys")
+		}
+		current, err := s.roles.GetRole(ctx, string(user.Role))
+		if err != nil {
+			return nil, err
+		}
+		next, err := s.roles.GetRole(ctx, string(*req.Role))
+		if err != nil {
+			return nil, err
+		}
+		for _, role := range []*model.Role{current, next} {
+			if err := checkGrantable(granted, role.Permissions); err != nil {
+				return nil, err
+			}
+			if err := checkSessionScope(caller, role.SessionScope); err != nil {
+				return nil, err
+			}
+		}
+		user.Role = next.Name
+	}
+	if req.Team != nil && *req.Team != user.Team {
+		if *req.Team != "" && !roleNamePattern.MatchString(*req.Team) {
+			return nil, model.ErrInvalidRequest.WithMessage("team must start with a lowercase letter and contain only lowercase letters, digits, '_' and '-'")
+		}
+		if caller.Scope != model.SessionScopeAll {
+			return nil, model.ErrForbidden.WithMessage("insufficient permissions: changing teams requires the all session scope")
+		}
+		user.Team = *req.Team
+	}
+	orgChanged := req.OrgID != nil && *req.OrgID != user.OrgID
+	if orgChanged {
+		if !granted.Has(model.PermissionOrgsManage) {
+			return nil, model.ErrForbidden.WithMessage(fmt.Sprintf("insufficient permissions: required permission %s", model.PermissionOrgsManage))
+		}
+		if user.ID == caller.UserID {
+			return nil, model.ErrForbidden.WithMessage("cannot change your own organization")
+		}
+		if user.Role == model.UserRoleService {
+			return nil, model.ErrInvalidRequest.WithMessage("service accounts are managed through their API keys")
+		}
+		if _, err := s.orgs.GetOrganization(ctx, req.OrgID.String()); err != nil {
+			if errors.Is(err, model.ErrOrganizationNotFound) {
+				return nil, model.ErrInvalidRequest.WithMessage("organization not found")
+			}
+			return nil, err
+		}
+		user.OrgID = *req.OrgID
+	}
+
+	now := time.Now()
+	user.UpdatedAt = now
+	if err := s.users.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	if roleChanged || orgChanged {
+		if err := s.tokens.RevokeUserRefreshTokens(ctx, userID, now); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
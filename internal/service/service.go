@@ -0,0 +1,161 @@
+package service
+
+import (
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/cdr"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/phone"
+)
+
+// Service implements the session and user operations on top of the model stores
+type Service struct {
+	sessions    model.SessionStore
+	events      model.EventStore
+	users       model.UserStore
+	orgs        model.OrganizationStore
+	roles       model.RoleStore
+	tokens      model.TokenStore
+	apiKeys     model.APIKeyStore
+	idempotency model.IdempotencyStore
+	webhooks    model.WebhookStore
+	outbox      model.OutboxStore
+	exportJobs  model.ExportJobStore
+	cdrs        model.CDRStore
+
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+	idempotencyTTL  time.Duration
+	idempotencyLock time.Duration
+	eventBatchLimit int
+	outboxRetention time.Duration
+	outboxConsumers model.OutboxConsumers
+	metricRules     model.MetricRules
+	exportDir       string
+	exportRetention time.Duration
+	cdrTemplate     cdr.Template
+	numbers         *phone.Normalizer
+	orgSignup       bool
+}
+
+// Option configures optional Service behaviour
+type Option func(*Service)
+
+// WithTokenTTLs sets how long access tokens and refresh tokens are valid
+func WithTokenTTLs(access, refresh time.Duration) Option {
+	return func(s *Service) {
+		s.accessTokenTTL = access
+		s.refreshTokenTTL = refresh
+	}
+}
+
+// WithIdempotencyTTL sets how long idempotency keys and their stored responses are kept
+func WithIdempotencyTTL(ttl time.Duration) Option {
+	return func(s *Service) {
+		s.idempotencyTTL = ttl
+	}
+}
+
+// WithIdempotencyLockTimeout sets how long a running request holds its idempotency key
+// after its last renewal; non-positive values keep the default of one minute
+func WithIdempotencyLockTimeout(timeout time.Duration) Option {
+	return func(s *Service) {
+		if timeout > 0 {
+			s.idempotencyLock = timeout
+		}
+	}
+}
+
+// WithEventBatchLimit sets the maximum number of events accepted in one batch
+func WithEventBatchLimit(limit int) Option {
+	return func(s *Service) {
+		s.eventBatchLimit = limit
+	}
+}
+
+// WithOutboxRetention sets how long processed outbox messages are kept
+func WithOutboxRetention(retention time.Duration) Option {
+	return func(s *Service) {
+		s.outboxRetention = retention
+	}
+}
+
+// WithOutboxConsumers sets which outbox consumers run, so messages are only purged
+// once all of them have processed them
+func WithOutboxConsumers(consumers model.OutboxConsumers) Option {
+	return func(s *Service) {
+		s.outboxConsumers = consumers
+	}
+}
+
+// WithMetricRules sets the event rules used to derive the metrics of ended sessions
+func WithMetricRules(rules model.MetricRules) Option {
+	return func(s *Service) {
+		s.metricRules = rules
+	}
+}
+
+// WithExportJobs enables background export jobs, which write their files to dir and
+// keep them for retention after they finish
+func WithExportJobs(dir string, retention time.Duration) Option {
+	return func(s *Service) {
+		s.exportDir = dir
+		s.exportRetention = retention
+	}
+}
+
+// WithCDRTemplate sets the layout of the call detail records written to CDR files and
+// returned by the API
+func WithCDRTemplate(template cdr.Template) Option {
+	return func(s *Service) {
+		s.cdrTemplate = template
+	}
+}
+
+// WithNumberNormalizer sets how caller and callee IDs are normalized
+func WithNumberNormalizer(numbers *phone.Normalizer) Option {
+	return func(s *Service) {
+		s.numbers = numbers
+	}
+}
+
+// WithOrganizationSignup lets registrants create an organization of their own, which
+// they administer. Without it they join the default organization as users.
+func WithOrganizationSignup(enabled bool) Option {
+	return func(s *Service) {
+		s.orgSignup = enabled
+	}
+}
+
+// New creates a Service backed by the given store
+func New(store model.Store, opts ...Option) *Service {
+	s := &Service{
+		sessions:    store,
+		events:      store,
+		users:       store,
+		orgs:        store,
+		roles:       store,
+		tokens:      store,
+		apiKeys:     store,
+		idempotency: store,
+		webhooks:    store,
+		outbox:      store,
+		exportJobs:  store,
+		cdrs:        store,
+
+		accessTokenTTL:  15 * time.Minute,
+		refreshTokenTTL: 30 * 24 * time.Hour,
+		idempotencyTTL:  24 * time.Hour,
+		idempotencyLock: time.Minute,
+		eventBatchLimit: 500,
+		outboxRetention: 24 * time.Hour,
+		metricRules:     model.DefaultMetricRules,
+		exportRetention: 24 * time.Hour,
+		cdrTemplate:     cdr.MustParseTemplate(cdr.DefaultTemplate),
+		numbers:         phone.MustNewNormalizer(phone.DefaultRegion, phone.DefaultExtensionMaxDigits),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
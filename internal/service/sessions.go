@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// StartSession creates a new session owned by the caller with the given request data,
+// storing the caller and callee IDs normalized. When the request carries an external ID
+// that is already known, the existing session is returned instead and created is false.
+func (s *Service) StartSession(ctx context.Context, access model.SessionAccess, req model.StartSessionRequest) (session *model.Session, created bool, err error) {
+	now := time.Now()
+	session = &model.Session{
+		ID:              uuid.New(),
+		StartedAt:       now,
+		Status:          model.SessionStatusInitiated,
+		InitialMetadata: req.InitialMetadata,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	s.setIdentities(session, req.CallerID, req.CalleeID)
+	access.Stamp(session)
+	if req.ExternalID != "" {
+		session.ExternalSource = &req.ExternalSource
+		session.ExternalID = &req.ExternalID
+	}
+
+	err = s.sessions.CreateSession(ctx, session)
+	if errors.Is(err, model.ErrExternalIDConflict) {
+		session, err = s.GetSessionByExternalID(ctx, access, req.ExternalSource, req.ExternalID)
+		if errors.Is(err, model.ErrSessionNotFound) {
+			// The session belongs to someone else; its existence is all that is revealed
+			return nil, false, model.ErrExternalIDConflict
+		}
+		return session, false, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return session, true, nil
+}
+
+// GetSession retrieves a session by ID, reporting sessions the caller cannot see as not
+// found
+func (s *Service) GetSession(ctx context.Context, access model.SessionAccess, sessionID string) (*model.Session, error) {
+	session, err := s.sessions.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !access.CanSee(session) {
+		return nil, model.ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// GetEvent retrieves an event by ID
+func (s *Service) GetEvent(ctx context.Context, eventID string) (*model.SessionEvent, error) {
+	return s.events.GetEvent(ctx, eventID)
+}
+
+// GetSessionByExternalID retrieves a session of the caller's own organization by the ID
+// its source system assigned
+func (s *Service) GetSessionByExternalID(ctx context.Context, access model.SessionAccess, source, externalID string) (*model.Session, error) {
+	session, err := s.sessions.GetSessionByExternalID(ctx, access.HomeOrgID.String(), source, externalID)
+	if err != nil {
+		return nil, err
+	}
+	if !access.CanSee(session) {
+		return nil, model.ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// LogEvent records an event against an active session, if its organization allows the
+// event type
+func (s *Service) LogEvent(ctx context.Context, access model.SessionAccess, sessionID string, req model.LogEventRequest) (*model.SessionEvent, error) {
+	// First verify the session exists and is not ended
+	session, err := s.GetSession(ctx, access, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !session.Status.IsActive() {
+		return nil, model.ErrSessionAlreadyEnded.WithMessage("cannot log events for ended session")
+	}
+	if err := s.checkEventType(ctx, map[uuid.UUID]*model.Organization{}, session.OrgID, req.EventType); err != nil {
+		return nil, err
+	}
+
+	event := &model.SessionEvent{
+		ID:        uuid.New(),
+		SessionID: session.ID,
+		OrgID:     session.OrgID,
+		EventType: req.EventType,
+		EventTime: req.EventTime,
+		Metadata:  req.Metadata,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.events.CreateEvent(ctx, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// EndSession moves the session to a terminal status with the given disposition
+func (s *Service) EndSession(ctx context.Context, access model.SessionAccess, sessionID string, req model.EndSessionRequest) (*model.Session, error) {
+	if !req.Status.IsTerminal() {
+		return nil, model.ErrInvalidRequest.WithMessage("status must be a terminal session state")
+	}
+
+	session, err := s.GetSession(ctx, access, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	disposition := req.Disposition
+	return s.transition(ctx, session, req.Status, req.EndTime, &disposition, nil)
+}
+
+// TransitionSession moves the session to a new state, recording the transition as an event
+func (s *Service) TransitionSession(ctx context.Context, access model.SessionAccess, sessionID string, req model.TransitionSessionRequest) (*model.Session, error) {
+	session, err := s.GetSession(ctx, access, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	at := time.Now()
+	if req.EventTime != nil {
+		at = *req.EventTime
+	}
+	var disposition *string
+	if req.Disposition != "" {
+		disposition = &req.Disposition
+	}
+
+	return s.transition(ctx, session, req.Status, at, disposition, req.Metadata)
+}
+
+func (s *Service) transition(ctx context.Context, session *model.Session, to model.SessionStatus, at time.Time, disposition *string, metadata model.EventMetadata) (*model.Session, error) {
+	t, err := model.NewSessionTransition(session, to, at, disposition, metadata)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachMetrics(ctx, session, t); err != nil {
+		return nil, err
+	}
+	return s.sessions.TransitionSession(ctx, session.ID.String(), t)
+}
+
+// attachMetrics derives the metrics of a session from its events, including the
+// transition event, when the transition ends the session
+func (s *Service) attachMetrics(ctx context.Context, session *model.Session, t *model.SessionTransition) error {
+	if !t.To.IsTerminal() {
+		return nil
+	}
+	events, err := s.events.ListEvents(ctx, session.ID.String())
+	if err != nil {
+		return err
+	}
+	events = append(events, t.Event)
+	t.Metrics = model.ComputeSessionMetrics(session, events, t.Event.EventTime, s.metricRules, time.Now())
+	return nil
+}
+
+// GetSessionDetails retrieves a session and its events, with the metrics of ended sessions
+func (s *Service) GetSessionDetails(ctx context.Context, access model.SessionAccess, sessionID string) (*model.SessionDetails, error) {
+	session, err := s.GetSession(ctx, access, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.events.ListEvents(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &model.SessionDetails{Session: *session, Events: events}
+	if session.EndedAt == nil {
+		return details, nil
+	}
+
+	details.Metrics, err = s.sessions.GetSessionMetrics(ctx, sessionID)
+	if errors.Is(err, model.ErrNotFound) {
+		// Sessions that ended before metrics were stored get them derived on the fly
+		details.Metrics = model.ComputeSessionMetrics(session, events, *session.EndedAt, s.metricRules, time.Now())
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+// ListSessions retrieves the sessions visible to the caller based on filter criteria;
+// caller and callee IDs match however they are written
+func (s *Service) ListSessions(ctx context.Context, access model.SessionAccess, filter model.SessionFilter) (*model.SessionListResponse, error) {
+	filter.Access = access
+	filter.NormalizeIdentities(s.numbers.Normalize)
+	return s.sessions.ListSessions(ctx, filter)
+}
+
+// ReapStaleSessions ends the sessions selected by the query as failed with a timeout
+// disposition and returns how many were ended. Sessions that change state while being
+// reaped are skipped.
+func (s *Service) ReapStaleSessions(ctx context.Context, query model.StaleSessionQuery) (int, error) {
+	sessions, err := s.sessions.ListStaleSessions(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	ended := 0
+	for i := range sessions {
+		session := &sessions[i]
+
+		reason := "inactivity"
+		if !query.StartedBefore.IsZero() && session.StartedAt.Before(query.StartedBefore) {
+			reason = "max_duration"
+		}
+
+		disposition := "timeout"
+		t, err := model.NewSessionTransition(session, model.SessionStatusFailed, time.Now(), &disposition, model.EventMetadata{"reason": reason})
+		if err != nil {
+			return ended, err
+		}
+		t.Event.EventType = model.EventTypeAutoEnded
+		if err := s.attachMetrics(ctx, session, t); err != nil {
+			return ended, err
+		}
+
+		if _, err := s.sessions.TransitionSession(ctx, session.ID.String(), t); err != nil {
+			if errors.Is(err, model.ErrInvalidTransition) {
+				continue
+			}
+			return ended, err
+		}
+		ended++
+	}
+
+	return ended, nil
+}
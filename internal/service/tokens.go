@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// issueTokens starts a new refresh token family for a user who just logged in, limited
+// to the given scopes unless they are nil
+func (s *Service) issueTokens(ctx context.Context, user *model.User, scopes model.Permissions) (*model.LoginResponse, error) {
+	permissions, err := s.permissionsOf(ctx, user, nil)
+	if err != nil {
+		return nil, err
+	}
+	if missing := permissions.Missing(scopes); len(missing) > 0 {
+		return nil, model.ErrForbidden.WithMessage(fmt.Sprintf("role %s does not grant %s", user.Role, missing[0]))
+	}
+	if scopes != nil {
+		permissions = permissions.Intersect(scopes)
+	}
+
+	raw, token, err := s.newRefreshToken(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	token.UserID, token.FamilyID, token.Scopes = user.ID, uuid.New(), scopes
+	if err := s.tokens.CreateRefreshToken(ctx, token); err != nil {
+		return nil, err
+	}
+	return s.tokenResponse(user, permissions, raw, token)
+}
+
+// newRefreshToken creates the next token of a family together with the ID and expiry of
+// the access token issued with it
+func (s *Service) newRefreshToken(now time.Time) (string, *model.RefreshToken, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	raw := "rt_" + hex.EncodeToString(b)
+	return raw, &model.RefreshToken{
+		ID:                   uuid.New(),
+		TokenHash:            model.HashRefreshToken(raw),
+		AccessTokenID:        uuid.New(),
+		AccessTokenExpiresAt: now.Add(s.accessTokenTTL),
+		CreatedAt:            now,
+		ExpiresAt:            now.Add(s.refreshTokenTTL),
+	}, nil
+}
+
+func (s *Service) tokenResponse(user *model.User, permissions model.Permissions, raw string, token *model.RefreshToken) (*model.LoginResponse, error) {
+	access, err := model.GenerateToken(user, permissions, token.AccessTokenID, token.FamilyID, token.AccessTokenExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &model.LoginResponse{
+		Token:                 access,
+		ExpiresAt:             token.AccessTokenExpiresAt,
+		RefreshToken:          raw,
+		RefreshTokenExpiresAt: token.ExpiresAt,
+		Permissions:           permissions,
+		User:                  *user,
+	}, nil
+}
+
+// RefreshTokens exchanges a refresh token for a new access token and the next refresh
+// token of its family. A refresh token can be used once; presenting it again revokes
+// the family, including the access tokens issued with it, and fails with
+// ErrRefreshTokenReused. The new access token carries the permissions the user's role
+// grants now, within the scopes of the login.
+func (s *Service) RefreshTokens(ctx context.Context, refreshToken string) (*model.LoginResponse, error) {
+	raw, next, err := s.newRefreshToken(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.tokens.RotateRefreshToken(ctx, model.HashRefreshToken(refreshToken), next); err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.GetUserByID(ctx, next.UserID.String())
+	if errors.Is(err, model.ErrUserNotFound) {
+		return nil, model.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := s.permissionsOf(ctx, user, next.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	return s.tokenResponse(user, permissions, raw, next)
+}
+
+// Logout revokes the refresh token family the access token was issued with, together
+// with every access token issued in that family
+func (s *Service) Logout(ctx context.Context, claims *model.JWTClaims) error {
+	return s.tokens.RevokeRefreshTokenFamily(ctx, claims.UserID, claims.FamilyID, time.Now())
+}
+
+// LogoutAll revokes every refresh token family of a user and the access tokens issued
+// with them, signing the user out everywhere
+func (s *Service) LogoutAll(ctx context.Context, userID string) error {
+	return s.tokens.RevokeUserRefreshTokens(ctx, userID, time.Now())
+}
+
+// CheckAccessToken rejects access tokens that have been revoked, and tokens without an
+// ID or permissions, which were issued before tokens could be revoked or carried
+// permissions
+func (s *Service) CheckAccessToken(ctx context.Context, claims *model.JWTClaims) error {
+	if claims.ID == "" || claims.Permissions == nil {
+		return model.ErrUnauthorized.WithMessage("token can no longer be used; log in again")
+	}
+	revoked, err := s.tokens.IsAccessTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return model.ErrUnauthorized.WithMessage("token has been revoked")
+	}
+	return nil
+}
+
+// PurgeExpiredTokens deletes the refresh tokens and denylist entries that have expired
+func (s *Service) PurgeExpiredTokens(ctx context.Context) (int64, error) {
+	return s.tokens.DeleteExpiredTokens(ctx, time.Now())
+}
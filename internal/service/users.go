@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Register creates a new user account in the default organization. With organization
+// signup enabled, registrants naming an organization get a new one, which they
+// administer. Other organizations are only joined by being added or moved there.
+func (s *Service) Register(ctx context.Context, req model.RegisterRequest) (*model.User, error) {
+	if req.Organization != "" && !s.orgSignup {
+		return nil, model.ErrForbidden.WithMessage("registering an organization is disabled; ask an administrator of your organization to add you")
+	}
+
+	// Hash password
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &model.User{
+		ID:        uuid.New(),
+		Email:     req.Email,
+		Password:  string(hashedPassword),
+		Role:      model.UserRoleUser,
+		OrgID:     model.DefaultOrganizationID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if req.Organization == "" {
+		err = s.users.CreateUser(ctx, user)
+	} else {
+		// The organization and its first admin are stored together, so a failed
+		// registration leaves no organization behind
+		org := newOrganization(model.CreateOrganizationRequest{Name: req.Organization}, now)
+		user.Role, user.OrgID = model.UserRoleOrgAdmin, org.ID
+		err = s.users.CreateUserWithOrganization(ctx, user, org)
+	}
+	if err != nil {
+		return nil, err
+	}
+	user.Password = ""
+	return user, nil
+}
+
+// Login authenticates a user and returns a short-lived access token and the first
+// refresh token of a new family, carrying the permissions of the user's role within
+// the requested scopes
+func (s *Service) Login(ctx context.Context, req model.LoginRequest) (*model.LoginResponse, error) {
+	user, err := s.users.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, model.ErrUserNotFound) {
+			return nil, model.ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	// Service accounts only act through their API keys
+	if user.Role == model.UserRoleService {
+		return nil, model.ErrInvalidCredentials
+	}
+
+	// Verify password
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		return nil, model.ErrInvalidCredentials
+	}
+
+	user.Password = ""
+	return s.issueTokens(ctx, user, req.Scopes)
+}
+
+// GetUser retrieves a user by their ID
+func (s *Service) GetUser(ctx context.Context, userID string) (*model.User, error) {
+	return s.users.GetUserByID(ctx, userID)
+}
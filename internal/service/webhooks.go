@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// CreateWebhook registers a webhook receiving the activity of the caller's
+// organization. The returned webhook carries its signing secret, which is not returned
+// again.
+func (s *Service) CreateWebhook(ctx context.Context, caller model.SessionAccess, req model.CreateWebhookRequest) (*model.Webhook, error) {
+	if err := model.ValidateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = newWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	webhook := &model.Webhook{
+		ID:          uuid.New(),
+		OrgID:       caller.HomeOrgID,
+		URL:         req.URL,
+		Description: req.Description,
+		Secret:      secret,
+		Events:      req.Events,
+		EventTypes:  req.EventTypes,
+		Active:      req.Active == nil || *req.Active,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if webhook.EventTypes == nil {
+		webhook.EventTypes = []string{}
+	}
+
+	if err := s.webhooks.CreateWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
+	webhook.Secret = secret
+	return webhook, nil
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// GetWebhook retrieves a webhook of the caller's organization without its secret
+func (s *Service) GetWebhook(ctx context.Context, caller model.SessionAccess, webhookID string) (*model.Webhook, error) {
+	webhook, err := s.orgWebhook(ctx, caller, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// orgWebhook retrieves a webhook, reporting those of other organizations as not found
+func (s *Service) orgWebhook(ctx context.Context, caller model.SessionAccess, webhookID string) (*model.Webhook, error) {
+	webhook, err := s.webhooks.GetWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if !inOrganization(caller, webhook.OrgID) {
+		return nil, model.ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// ListWebhooks retrieves the webhooks of the caller's organization without their
+// secrets
+func (s *Service) ListWebhooks(ctx context.Context, caller model.SessionAccess) ([]model.Webhook, error) {
+	webhooks, err := s.webhooks.ListWebhooks(ctx, caller.HomeOrgID.String())
+	if err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+// UpdateWebhook applies the fields set in req to a webhook
+func (s *Service) UpdateWebhook(ctx context.Context, caller model.SessionAccess, webhookID string, req model.UpdateWebhookRequest) (*model.Webhook, error) {
+	webhook, err := s.orgWebhook(ctx, caller, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != nil {
+		if err := model.ValidateWebhookURL(*req.URL); err != nil {
+			return nil, err
+		}
+		webhook.URL = *req.URL
+	}
+	if req.Description != nil {
+		webhook.Description = *req.Description
+	}
+	if req.Events != nil {
+		webhook.Events = req.Events
+	}
+	if req.EventTypes != nil {
+		webhook.EventTypes = *req.EventTypes
+		if webhook.EventTypes == nil {
+			webhook.EventTypes = []string{}
+		}
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	webhook.UpdatedAt = time.Now()
+
+	if err := s.webhooks.UpdateWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook and its delivery history
+func (s *Service) DeleteWebhook(ctx context.Context, caller model.SessionAccess, webhookID string) error {
+	if _, err := s.orgWebhook(ctx, caller, webhookID); err != nil {
+		return err
+	}
+	return s.webhooks.DeleteWebhook(ctx, webhookID)
+}
+
+// ListWebhookDeliveries retrieves a page of the deliveries of a webhook, newest first
+func (s *Service) ListWebhookDeliveries(ctx context.Context, caller model.SessionAccess, webhookID string, filter model.WebhookDeliveryFilter) ([]model.WebhookDelivery, error) {
+	if _, err := s.orgWebhook(ctx, caller, webhookID); err != nil {
+		return nil, err
+	}
+	return s.webhooks.ListWebhookDeliveries(ctx, webhookID, filter)
+}
+
+// GetWebhookDelivery retrieves a delivery of a webhook with its attempt log
+func (s *Service) GetWebhookDelivery(ctx context.Context, caller model.SessionAccess, webhookID, deliveryID string) (*model.WebhookDeliveryDetails, error) {
+	if _, err := s.orgWebhook(ctx, caller, webhookID); err != nil {
+		return nil, err
+	}
+	return s.webhooks.GetWebhookDelivery(ctx, webhookID, deliveryID)
+}
+
+// RetryWebhookDelivery schedules a delivery, typically a dead-lettered one, to be
+// attempted again right away with a fresh set of attempts
+func (s *Service) RetryWebhookDelivery(ctx context.Context, caller model.SessionAccess, webhookID, deliveryID string) (*model.WebhookDelivery, error) {
+	if _, err := s.orgWebhook(ctx, caller, webhookID); err != nil {
+		return nil, err
+	}
+	return s.webhooks.RetryWebhookDelivery(ctx, webhookID, deliveryID, time.Now())
+}
+
+// DispatchOutbox turns up to limit new outbox messages into webhook deliveries and
+// returns how many messages were dispatched
+func (s *Service) DispatchOutbox(ctx context.Context, limit int) (int, error) {
+	return s.webhooks.DispatchOutbox(ctx, limit)
+}
+
+// ClaimWebhookDeliveries returns up to limit deliveries that are due, leased for the
+// given duration
+func (s *Service) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]model.WebhookTask, error) {
+	now := time.Now()
+	return s.webhooks.ClaimWebhookDeliveries(ctx, now, now.Add(lease), limit)
+}
+
+// RecordWebhookAttempt logs a delivery attempt and applies its outcome
+func (s *Service) RecordWebhookAttempt(ctx context.Context, attempt *model.WebhookAttempt, update model.WebhookDeliveryUpdate) error {
+	return s.webhooks.RecordWebhookAttempt(ctx, attempt, update)
+}
+
+// PurgeWebhookDeliveries removes finished deliveries older than the retention period
+func (s *Service) PurgeWebhookDeliveries(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.webhooks.PurgeWebhookDeliveries(ctx, time.Now().Add(-retention))
+}
@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// activityBuffer bounds how far a listener may fall behind before activity is dropped
+const activityBuffer = 1024
+
+// ListenActivity delivers the activity of this store, mirroring the notify triggers of
+// the Postgres schema
+func (st *Store) ListenActivity(ctx context.Context, fn func(model.Activity)) error {
+	ch := make(chan model.Activity, activityBuffer)
+
+	st.mu.Lock()
+	st.listeners[ch] = struct{}{}
+	st.mu.Unlock()
+
+	defer func() {
+		st.mu.Lock()
+		delete(st.listeners, ch)
+		st.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case activity := <-ch:
+			fn(activity)
+		}
+	}
+}
+
+// emit announces activity to the listeners; the caller must hold st.mu
+func (st *Store) emit(activityType model.ActivityType, sessionID uuid.UUID, eventID *uuid.UUID) {
+	st.activitySeq++
+	activity := model.Activity{ID: st.activitySeq, Type: activityType, SessionID: sessionID, Status: st.sessions[sessionID].Status}
+	if eventID != nil {
+		id := *eventID
+		activity.EventID = &id
+	}
+
+	for ch := range st.listeners {
+		select {
+		case ch <- activity:
+		default:
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// sessionAggregate collects the sessions of one analytics bucket
+type sessionAggregate struct {
+	bucket    model.AnalyticsBucket
+	durations []float64
+}
+
+func (a *sessionAggregate) add(s *model.Session) {
+	a.bucket.Total++
+	switch {
+	case s.Status == model.SessionStatusCompleted:
+		a.bucket.Completed++
+	case slices.Contains(model.FailedSessionStatuses(), s.Status):
+		a.bucket.Failed++
+	}
+	if s.EndedAt != nil {
+		a.durations = append(a.durations, s.EndedAt.Sub(s.StartedAt).Seconds())
+	}
+	if s.Disposition != nil {
+		a.bucket.Dispositions[*s.Disposition]++
+	}
+}
+
+func (a *sessionAggregate) result() model.AnalyticsBucket {
+	a.bucket.Duration = model.NewDurationStats(a.durations)
+	a.bucket.SetSuccessRate()
+	return a.bucket
+}
+
+// SessionAnalytics aggregates the matching sessions by the bucket of their start time
+func (st *Store) SessionAnalytics(ctx context.Context, q model.SessionAnalyticsQuery) (*model.SessionAnalytics, error) {
+	st.mu.RLock()
+	matches := st.matchingSessions(q.Filter)
+	st.mu.RUnlock()
+
+	totals := &sessionAggregate{bucket: model.AnalyticsBucket{Dispositions: map[string]int64{}}}
+	buckets := make(map[time.Time]*sessionAggregate)
+	for i := range matches {
+		s := &matches[i]
+		if !matchesMetadata(s, q.Metadata) {
+			continue
+		}
+
+		start := q.Interval.Truncate(s.StartedAt, q.Location)
+		bucket, ok := buckets[start]
+		if !ok {
+			bucket = &sessionAggregate{bucket: model.AnalyticsBucket{Start: &start, Dispositions: map[string]int64{}}}
+			buckets[start] = bucket
+		}
+		bucket.add(s)
+		totals.add(s)
+	}
+
+	result := &model.SessionAnalytics{Totals: totals.result(), Buckets: []model.AnalyticsBucket{}}
+	for _, bucket := range buckets {
+		result.Buckets = append(result.Buckets, bucket.result())
+	}
+	sort.Slice(result.Buckets, func(i, j int) bool {
+		return result.Buckets[i].Start.Before(*result.Buckets[j].Start)
+	})
+	return result, nil
+}
+
+func matchesMetadata(s *model.Session, metadata map[string]string) bool {
+	for key, want := range metadata {
+		if got, ok := s.InitialMetadata.Text(key); !ok || got != want {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// CreateAPIKey stores a new API key, and its service account when one is given
+func (st *Store) CreateAPIKey(ctx context.Context, k *model.APIKey, serviceAccount *model.User) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, exists := st.apiKeys[k.ID]; exists {
+		return model.ErrConflict
+	}
+	for _, existing := range st.apiKeys {
+		if existing.Prefix == k.Prefix {
+			return model.ErrConflict
+		}
+	}
+	if _, ok := st.orgs[k.OrgID]; !ok {
+		return model.ErrOrganizationNotFound
+	}
+	if serviceAccount != nil {
+		if _, exists := st.emails[serviceAccount.Email]; exists {
+			return model.ErrUserAlreadyExists
+		}
+		st.users[serviceAccount.ID] = *serviceAccount
+		st.emails[serviceAccount.Email] = serviceAccount.ID
+	} else if _, ok := st.users[k.ServiceAccountID]; !ok {
+		return model.ErrInvalidRequest
+	}
+	if k.AllowedCIDRs == nil {
+		k.AllowedCIDRs = []string{}
+	}
+	st.apiKeys[k.ID] = copyAPIKey(*k)
+	return nil
+}
+
+// GetAPIKey retrieves an API key by ID
+func (st *Store) GetAPIKey(ctx context.Context, keyID string) (*model.APIKey, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	id, err := uuid.Parse(keyID)
+	if err != nil {
+		return nil, model.ErrAPIKeyNotFound
+	}
+	k, ok := st.apiKeys[id]
+	if !ok {
+		return nil, model.ErrAPIKeyNotFound
+	}
+	k = copyAPIKey(k)
+	return &k, nil
+}
+
+// GetAPIKeyByPrefix retrieves an API key by its prefix
+func (st *Store) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*model.APIKey, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	for _, k := range st.apiKeys {
+		if k.Prefix == prefix {
+			k = copyAPIKey(k)
+			return &k, nil
+		}
+	}
+	return nil, model.ErrAPIKeyNotFound
+}
+
+// ListAPIKeys retrieves the API keys of an organization, oldest first
+func (st *Store) ListAPIKeys(ctx context.Context, orgID string) ([]model.APIKey, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	keys := make([]model.APIKey, 0, len(st.apiKeys))
+	for _, k := range st.apiKeys {
+		if k.OrgID.String() != orgID {
+			continue
+		}
+		keys = append(keys, copyAPIKey(k))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID.String() < keys[j].ID.String()
+	})
+	return keys, nil
+}
+
+// UpdateAPIKey stores the changed fields of an API key
+func (st *Store) UpdateAPIKey(ctx context.Context, k *model.APIKey) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	existing, ok := st.apiKeys[k.ID]
+	if !ok {
+		return model.ErrAPIKeyNotFound
+	}
+	existing.Name = k.Name
+	existing.Scopes = k.Scopes
+	existing.AllowedCIDRs = k.AllowedCIDRs
+	existing.ExpiresAt = k.ExpiresAt
+	existing.UpdatedAt = k.UpdatedAt
+	st.apiKeys[k.ID] = copyAPIKey(existing)
+	*k = copyAPIKey(existing)
+	return nil
+}
+
+// DeleteAPIKey removes an API key
+func (st *Store) DeleteAPIKey(ctx context.Context, keyID string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	id, err := uuid.Parse(keyID)
+	if err != nil {
+		return model.ErrAPIKeyNotFound
+	}
+	if _, ok := st.apiKeys[id]; !ok {
+		return model.ErrAPIKeyNotFound
+	}
+	delete(st.apiKeys, id)
+	return nil
+}
+
+// TouchAPIKey records when an API key was last used
+func (st *Store) TouchAPIKey(ctx context.Context, keyID string, at time.Time) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	id, err := uuid.Parse(keyID)
+	if err != nil {
+		return nil
+	}
+	if k, ok := st.apiKeys[id]; ok {
+		k.LastUsedAt = &at
+		st.apiKeys[id] = k
+	}
+	return nil
+}
+
+func copyAPIKey(k model.APIKey) model.APIKey {
+	k.Scopes = append(model.Permissions{}, k.Scopes...)
+	k.AllowedCIDRs = append([]string{}, k.AllowedCIDRs...)
+	k.KeyHash = append([]byte{}, k.KeyHash...)
+	return k
+}
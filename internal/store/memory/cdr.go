@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+type cdrEntry struct {
+	cdr       model.CDR
+	writtenAt *time.Time
+}
+
+// addCDR stores the record of an ended session; the caller must hold st.mu
+func (st *Store) addCDR(cdr *model.CDR) {
+	st.cdrSeq++
+	cdr.ID = st.cdrSeq
+	st.cdrs = append(st.cdrs, &cdrEntry{cdr: *cdr})
+}
+
+// ExportCDRs calls fn for every record of a session that ended in the query's range
+func (st *Store) ExportCDRs(ctx context.Context, query model.CDRQuery, fn func(*model.CDR) error) error {
+	st.mu.RLock()
+	var matches []model.CDR
+	for _, entry := range st.cdrs {
+		endedAt := entry.cdr.EndedAt
+		if !query.From.IsZero() && endedAt.Before(query.From) {
+			continue
+		}
+		if !query.To.IsZero() && !endedAt.Before(query.To) {
+			continue
+		}
+		if session, ok := st.sessions[entry.cdr.SessionID]; !ok || !query.Access.CanSee(&session) {
+			continue
+		}
+		matches = append(matches, entry.cdr)
+	}
+	st.mu.RUnlock()
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if !matches[i].EndedAt.Equal(matches[j].EndedAt) {
+			return matches[i].EndedAt.Before(matches[j].EndedAt)
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	for i := range matches {
+		if err := fn(&matches[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListUnwrittenCDRs returns the oldest records not yet written to a CDR file, by ID
+func (st *Store) ListUnwrittenCDRs(ctx context.Context, limit int) ([]model.CDR, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	cdrs := []model.CDR{}
+	for _, entry := range st.cdrs {
+		if len(cdrs) == limit {
+			break
+		}
+		if entry.writtenAt == nil {
+			cdrs = append(cdrs, entry.cdr)
+		}
+	}
+	return cdrs, nil
+}
+
+// MarkCDRsWritten records that records were written to a CDR file
+func (st *Store) MarkCDRsWritten(ctx context.Context, ids []int64, at time.Time) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	written := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		written[id] = true
+	}
+	for _, entry := range st.cdrs {
+		if written[entry.cdr.ID] && entry.writtenAt == nil {
+			writtenAt := at
+			entry.writtenAt = &writtenAt
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// CreateEvent stores a new session event
+func (st *Store) CreateEvent(ctx context.Context, e *model.SessionEvent) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, ok := st.sessions[e.SessionID]; !ok {
+		return model.ErrSessionNotFound
+	}
+	if err := checkEventTime(e); err != nil {
+		return err
+	}
+	message, err := model.NewEventOutboxMessage(e)
+	if err != nil {
+		return err
+	}
+
+	st.events[e.SessionID] = append(st.events[e.SessionID], *e)
+	st.record(message)
+	return nil
+}
+
+// CreateEvents stores several events, or none if any of them is invalid
+func (st *Store) CreateEvents(ctx context.Context, events []model.SessionEvent) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	messages := make([]model.OutboxMessage, len(events))
+	for i := range events {
+		if _, ok := st.sessions[events[i].SessionID]; !ok {
+			return model.ErrSessionNotFound
+		}
+		if err := checkEventTime(&events[i]); err != nil {
+			return err
+		}
+		message, err := model.NewEventOutboxMessage(&events[i])
+		if err != nil {
+			return err
+		}
+		messages[i] = message
+	}
+	for _, e := range events {
+		st.events[e.SessionID] = append(st.events[e.SessionID], e)
+	}
+	st.record(messages...)
+	return nil
+}
+
+func checkEventTime(e *model.SessionEvent) error {
+	return model.ValidateEventTime(e.EventTime, time.Now())
+}
+
+// GetEvent retrieves an event by ID
+func (st *Store) GetEvent(ctx context.Context, eventID string) (*model.SessionEvent, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	id, err := uuid.Parse(eventID)
+	if err != nil {
+		return nil, model.ErrEventNotFound
+	}
+	for _, events := range st.events {
+		for _, event := range events {
+			if event.ID == id {
+				return &event, nil
+			}
+		}
+	}
+	return nil, model.ErrEventNotFound
+}
+
+// ListEvents retrieves the events of a session in chronological order
+func (st *Store) ListEvents(ctx context.Context, sessionID string) ([]model.SessionEvent, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	session, ok := st.lookupSession(sessionID)
+	if !ok {
+		return []model.SessionEvent{}, nil
+	}
+
+	events := append([]model.SessionEvent{}, st.events[session.ID]...)
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].EventTime.Before(events[j].EventTime)
+	})
+	return events, nil
+}
@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+func newEvent(sessionID uuid.UUID, eventType string, at time.Time) model.SessionEvent {
+	return model.SessionEvent{ID: uuid.New(), SessionID: sessionID, OrgID: model.DefaultOrganizationID, EventType: eventType, EventTime: at, CreatedAt: time.Now()}
+}
+
+func TestCreateEvent(t *testing.T) {
+	ctx := context.Background()
+	st := New()
+	s := newSession(time.Now(), model.SessionStatusOngoing)
+	if err := st.CreateSession(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		event   model.SessionEvent
+		wantErr error
+	}{
+		{name: "valid", event: newEvent(s.ID, "hold", time.Now())},
+		{name: "unknown session", event: newEvent(uuid.New(), "hold", time.Now()), wantErr: model.ErrSessionNotFound},
+		{name: "too old", event: newEvent(s.ID, "hold", time.Now().Add(-model.MaxEventAge-time.Hour)), wantErr: model.ErrEventTimeOutOfRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := tt.event
+			if err := st.CreateEvent(ctx, &event); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateEvent error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			got, err := st.GetEvent(ctx, event.ID.String())
+			if err != nil || got.EventType != event.EventType {
+				t.Fatalf("GetEvent = %+v, %v, want the stored event", got, err)
+			}
+		})
+	}
+}
+
+func TestCreateEventsIsAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	st := New()
+	s := newSession(time.Now(), model.SessionStatusOngoing)
+	if err := st.CreateSession(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	invalid := []model.SessionEvent{newEvent(s.ID, "hold", now), newEvent(uuid.New(), "resume", now)}
+	if err := st.CreateEvents(ctx, invalid); !errors.Is(err, model.ErrSessionNotFound) {
+		t.Fatalf("CreateEvents error = %v, want ErrSessionNotFound", err)
+	}
+	if events, _ := st.ListEvents(ctx, s.ID.String()); len(events) != 0 {
+		t.Fatalf("a failed batch stored %d events", len(events))
+	}
+
+	// Events are listed by event time, not by the order they were stored in
+	valid := []model.SessionEvent{newEvent(s.ID, "resume", now), newEvent(s.ID, "hold", now.Add(-time.Second))}
+	if err := st.CreateEvents(ctx, valid); err != nil {
+		t.Fatal(err)
+	}
+	events, err := st.ListEvents(ctx, s.ID.String())
+	if err != nil || len(events) != 2 || events[0].EventType != "hold" || events[1].EventType != "resume" {
+		t.Fatalf("ListEvents = %+v, %v, want hold then resume", events, err)
+	}
+}
@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// ExportSessions calls fn for every matching session. The matches are copied first so
+// fn runs without holding the lock.
+func (st *Store) ExportSessions(ctx context.Context, filter model.SessionFilter, fn func(*model.Session) error) error {
+	st.mu.RLock()
+	matches := st.matchingSessions(filter)
+	st.mu.RUnlock()
+
+	sortForExport(matches, filter)
+	for i := range matches {
+		if err := fn(&matches[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportEvents calls fn for every event of the matching sessions
+func (st *Store) ExportEvents(ctx context.Context, filter model.SessionFilter, fn func(*model.SessionEvent) error) error {
+	st.mu.RLock()
+	matches := st.matchingSessions(filter)
+	sortForExport(matches, filter)
+	var events []model.SessionEvent
+	for _, session := range matches {
+		sessionEvents := append([]model.SessionEvent{}, st.events[session.ID]...)
+		sort.SliceStable(sessionEvents, func(i, j int) bool {
+			return sessionEvents[i].EventTime.Before(sessionEvents[j].EventTime)
+		})
+		events = append(events, sessionEvents...)
+	}
+	st.mu.RUnlock()
+
+	for i := range events {
+		if err := fn(&events[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortForExport(sessions []model.Session, filter model.SessionFilter) {
+	sortFields := filter.Sort
+	if len(sortFields) == 0 {
+		sortFields = model.DefaultSessionSort
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return model.CompareSessions(&sessions[i], &sessions[j], sortFields) < 0
+	})
+}
+
+// CreateExportJob stores a new export job
+func (st *Store) CreateExportJob(ctx context.Context, j *model.ExportJob) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, exists := st.exportJobs[j.ID]; exists {
+		return model.ErrConflict
+	}
+	st.exportJobs[j.ID] = &exportJob{job: *j}
+	return nil
+}
+
+// GetExportJob retrieves an export job by ID
+func (st *Store) GetExportJob(ctx context.Context, jobID string) (*model.ExportJob, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	id, err := uuid.Parse(jobID)
+	if err != nil {
+		return nil, model.ErrExportJobNotFound
+	}
+	entry, ok := st.exportJobs[id]
+	if !ok {
+		return nil, model.ErrExportJobNotFound
+	}
+	job := entry.job
+	return &job, nil
+}
+
+// ClaimExportJobs leases the oldest pending jobs and running jobs whose lease expired
+func (st *Store) ClaimExportJobs(ctx context.Context, now, leaseUntil time.Time, limit int) ([]model.ExportJob, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var due []*exportJob
+	for _, entry := range st.exportJobs {
+		switch entry.job.Status {
+		case model.ExportJobPending:
+			due = append(due, entry)
+		case model.ExportJobRunning:
+			if !entry.leaseUntil.After(now) {
+				due = append(due, entry)
+			}
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].job.CreatedAt.Before(due[j].job.CreatedAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	jobs := make([]model.ExportJob, 0, len(due))
+	for _, entry := range due {
+		startedAt := now
+		entry.job.Status = model.ExportJobRunning
+		entry.job.Attempts++
+		entry.job.StartedAt = &startedAt
+		entry.leaseUntil = leaseUntil
+		jobs = append(jobs, entry.job)
+	}
+	return jobs, nil
+}
+
+// FinishExportJob stores the outcome of a claimed job
+func (st *Store) FinishExportJob(ctx context.Context, j *model.ExportJob) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	entry, ok := st.exportJobs[j.ID]
+	if !ok {
+		return model.ErrExportJobNotFound
+	}
+	entry.job.Status = j.Status
+	entry.job.RowCount = j.RowCount
+	entry.job.SizeBytes = j.SizeBytes
+	entry.job.Error = j.Error
+	entry.job.FinishedAt = j.FinishedAt
+	entry.job.ExpiresAt = j.ExpiresAt
+	entry.leaseUntil = time.Time{}
+	return nil
+}
+
+// DeleteExpiredExportJobs removes expired jobs and returns them
+func (st *Store) DeleteExpiredExportJobs(ctx context.Context, before time.Time) ([]model.ExportJob, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var deleted []model.ExportJob
+	for id, entry := range st.exportJobs {
+		if entry.job.ExpiresAt != nil && entry.job.ExpiresAt.Before(before) {
+			deleted = append(deleted, entry.job)
+			delete(st.exportJobs, id)
+		}
+	}
+	return deleted, nil
+}
+
+// exportJob is a stored export job together with its lease
+type exportJob struct {
+	job        model.ExportJob
+	leaseUntil time.Time
+}
@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+type idempotencyKey struct {
+	userID string
+	key    string
+}
+
+// ReserveIdempotencyKey claims a key, taking over expired or abandoned records
+func (st *Store) ReserveIdempotencyKey(ctx context.Context, r *model.IdempotencyRecord, staleBefore time.Time) (*model.IdempotencyRecord, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	id := idempotencyKey{r.UserID, r.Key}
+	if existing, ok := st.idempotency[id]; ok {
+		expired := !existing.ExpiresAt.After(r.CreatedAt)
+		abandoned := !existing.Completed() && existing.CreatedAt.Before(staleBefore)
+		if !expired && !abandoned {
+			return &existing, nil
+		}
+	}
+
+	st.idempotency[id] = *r
+	return nil, nil
+}
+
+// RenewIdempotencyKey extends the lock of an unfinished key
+func (st *Store) RenewIdempotencyKey(ctx context.Context, userID, key string, at time.Time) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	id := idempotencyKey{userID, key}
+	if record, ok := st.idempotency[id]; ok && !record.Completed() {
+		record.CreatedAt = at
+		st.idempotency[id] = record
+	}
+	return nil
+}
+
+// CompleteIdempotencyKey stores the response of the request holding the key
+func (st *Store) CompleteIdempotencyKey(ctx context.Context, userID, key string, statusCode int, body []byte) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	id := idempotencyKey{userID, key}
+	if record, ok := st.idempotency[id]; ok {
+		record.StatusCode = statusCode
+		record.ResponseBody = append([]byte{}, body...)
+		st.idempotency[id] = record
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey drops an unfinished key
+func (st *Store) ReleaseIdempotencyKey(ctx context.Context, userID, key string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	id := idempotencyKey{userID, key}
+	if record, ok := st.idempotency[id]; ok && !record.Completed() {
+		delete(st.idempotency, id)
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys removes records that expired before the given time
+func (st *Store) DeleteExpiredIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var deleted int64
+	for id, record := range st.idempotency {
+		if record.ExpiresAt.Before(before) {
+			delete(st.idempotency, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
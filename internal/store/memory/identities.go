@@ -0,0 +1,25 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// UpdateSessionIdentities stores the normalized IDs of sessions; unknown sessions are
+// skipped
+func (st *Store) UpdateSessionIdentities(ctx context.Context, sessions []model.Session) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for _, s := range sessions {
+		stored, ok := st.sessions[s.ID]
+		if !ok {
+			continue
+		}
+		stored.CallerID, stored.CallerIDType, stored.CallerNumberType = s.CallerID, s.CallerIDType, s.CallerNumberType
+		stored.CalleeID, stored.CalleeIDType, stored.CalleeNumberType = s.CalleeID, s.CalleeIDType, s.CalleeNumberType
+		st.sessions[s.ID] = stored
+	}
+	return nil
+}
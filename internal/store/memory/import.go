@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// ImportSessions stores imported sessions, skipping those whose external ID is taken.
+// Nothing is recorded in the outbox or announced, mirroring the Postgres schema.
+func (st *Store) ImportSessions(ctx context.Context, sessions []model.ImportedSession) (int, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	type externalKey struct {
+		org        uuid.UUID
+		source, id string
+	}
+	taken := make(map[externalKey]bool)
+	var stored []*model.ImportedSession
+	for i := range sessions {
+		s := &sessions[i].Session
+		if _, exists := st.sessions[s.ID]; exists {
+			return 0, model.ErrConflict
+		}
+		if s.EndedAt != nil && s.EndedAt.Before(s.StartedAt) {
+			return 0, model.ErrInvalidTimeRange
+		}
+
+		key := externalKey{s.OrgID, *s.ExternalSource, *s.ExternalID}
+		if taken[key] {
+			continue
+		}
+		taken[key] = true
+		if _, exists := st.findByExternalID(key.org, key.source, key.id); exists {
+			continue
+		}
+		stored = append(stored, &sessions[i])
+	}
+
+	for _, imported := range stored {
+		id := imported.Session.ID
+		st.sessions[id] = imported.Session
+		st.events[id] = append(st.events[id], imported.Events...)
+		if imported.Metrics != nil {
+			st.metrics[id] = *imported.Metrics
+		}
+	}
+	return len(stored), nil
+}
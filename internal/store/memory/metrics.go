@@ -0,0 +1,24 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// GetSessionMetrics retrieves the metrics stored when a session ended
+func (st *Store) GetSessionMetrics(ctx context.Context, sessionID string) (*model.SessionMetrics, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return nil, model.ErrNotFound
+	}
+	metrics, ok := st.metrics[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return &metrics, nil
+}
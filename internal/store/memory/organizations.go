@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// ListOrganizations retrieves all organizations by name
+func (st *Store) ListOrganizations(ctx context.Context) ([]model.Organization, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	orgs := make([]model.Organization, 0, len(st.orgs))
+	for _, org := range st.orgs {
+		orgs = append(orgs, copyOrganization(org))
+	}
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].Name < orgs[j].Name })
+	return orgs, nil
+}
+
+// GetOrganization retrieves an organization by ID
+func (st *Store) GetOrganization(ctx context.Context, orgID string) (*model.Organization, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	id, err := uuid.Parse(orgID)
+	if err != nil {
+		return nil, model.ErrOrganizationNotFound
+	}
+	org, ok := st.orgs[id]
+	if !ok {
+		return nil, model.ErrOrganizationNotFound
+	}
+	org = copyOrganization(org)
+	return &org, nil
+}
+
+// CreateOrganization stores a new organization
+func (st *Store) CreateOrganization(ctx context.Context, org *model.Organization) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, exists := st.orgs[org.ID]; exists {
+		return model.ErrConflict
+	}
+	st.orgs[org.ID] = copyOrganization(*org)
+	return nil
+}
+
+// UpdateOrganization stores the name and settings of an organization
+func (st *Store) UpdateOrganization(ctx context.Context, org *model.Organization) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, ok := st.orgs[org.ID]; !ok {
+		return model.ErrOrganizationNotFound
+	}
+	st.orgs[org.ID] = copyOrganization(*org)
+	return nil
+}
+
+func copyOrganization(org model.Organization) model.Organization {
+	org.AllowedEventTypes = append([]string{}, org.AllowedEventTypes...)
+	return org
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// outboxEntry is an outbox message together with its dispatch state
+type outboxEntry struct {
+	message              model.OutboxMessage
+	webhooksDispatchedAt *time.Time
+	publishedAt          *time.Time
+}
+
+// record appends messages to the outbox and announces them as activity, mirroring the
+// outbox writes and notify triggers of the Postgres store; the caller must hold st.mu
+func (st *Store) record(messages ...model.OutboxMessage) {
+	for _, m := range messages {
+		st.outboxSeq++
+		m.ID = st.outboxSeq
+		st.outbox = append(st.outbox, &outboxEntry{message: m})
+		st.emit(m.Type, m.SessionID, m.EventID)
+	}
+}
+
+// ListUnpublishedOutbox returns the oldest messages not yet published, by ID
+func (st *Store) ListUnpublishedOutbox(ctx context.Context, limit int) ([]model.OutboxMessage, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	messages := []model.OutboxMessage{}
+	for _, entry := range st.outbox {
+		if len(messages) == limit {
+			break
+		}
+		if entry.publishedAt == nil {
+			messages = append(messages, entry.message)
+		}
+	}
+	return messages, nil
+}
+
+// MarkOutboxPublished records that messages were accepted by the message bus
+func (st *Store) MarkOutboxPublished(ctx context.Context, ids []int64, at time.Time) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for _, id := range ids {
+		if entry := st.outboxEntry(id); entry != nil && entry.publishedAt == nil {
+			publishedAt := at
+			entry.publishedAt = &publishedAt
+		}
+	}
+	return nil
+}
+
+// PurgeOutbox removes old messages every enabled consumer has processed
+func (st *Store) PurgeOutbox(ctx context.Context, before time.Time, consumers model.OutboxConsumers) (int64, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	referenced := make(map[int64]bool)
+	for _, d := range st.deliveries {
+		referenced[d.MessageID] = true
+	}
+
+	var deleted int64
+	kept := st.outbox[:0]
+	for _, entry := range st.outbox {
+		done := entry.message.CreatedAt.Before(before) && !referenced[entry.message.ID] &&
+			(!consumers.Webhooks || entry.webhooksDispatchedAt != nil) &&
+			(!consumers.Publisher || entry.publishedAt != nil)
+		if done {
+			deleted++
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	st.outbox = kept
+	return deleted, nil
+}
+
+// outboxEntry finds an outbox entry by message ID; the outbox is ordered by ID
+func (st *Store) outboxEntry(id int64) *outboxEntry {
+	i := sort.Search(len(st.outbox), func(i int) bool { return st.outbox[i].message.ID >= id })
+	if i < len(st.outbox) && st.outbox[i].message.ID == id {
+		return st.outbox[i]
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// ListRoles retrieves all roles by name
+func (st *Store) ListRoles(ctx context.Context) ([]model.Role, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	roles := make([]model.Role, 0, len(st.roles))
+	for _, r := range st.roles {
+		roles = append(roles, copyRole(r))
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles, nil
+}
+
+// GetRole retrieves a role by name
+func (st *Store) GetRole(ctx context.Context, name string) (*model.Role, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	r, ok := st.roles[model.UserRole(name)]
+	if !ok {
+		return nil, model.ErrRoleNotFound
+	}
+	r = copyRole(r)
+	return &r, nil
+}
+
+// CreateRole stores a new role
+func (st *Store) CreateRole(ctx context.Context, r *model.Role) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, exists := st.roles[r.Name]; exists {
+		return model.ErrRoleAlreadyExists
+	}
+	st.roles[r.Name] = copyRole(*r)
+	return nil
+}
+
+// UpdateRole stores the description, permissions and session scope of a role
+func (st *Store) UpdateRole(ctx context.Context, r *model.Role) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	existing, ok := st.roles[r.Name]
+	if !ok {
+		return model.ErrRoleNotFound
+	}
+	existing.Description = r.Description
+	existing.Permissions = r.Permissions
+	existing.SessionScope = r.SessionScope
+	existing.UpdatedAt = r.UpdatedAt
+	st.roles[r.Name] = copyRole(existing)
+	*r = copyRole(existing)
+	return nil
+}
+
+// DeleteRole removes a role no user holds
+func (st *Store) DeleteRole(ctx context.Context, name string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	role := model.UserRole(name)
+	if _, ok := st.roles[role]; !ok {
+		return model.ErrRoleNotFound
+	}
+	for _, user := range st.users {
+		if user.Role == role {
+			return model.ErrRoleInUse
+		}
+	}
+	delete(st.roles, role)
+	return nil
+}
+
+func copyRole(r model.Role) model.Role {
+	r.Permissions = append(model.Permissions{}, r.Permissions...)
+	return r
+}
@@ -0,0 +1,298 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// CreateSession stores a new session
+func (st *Store) CreateSession(ctx context.Context, s *model.Session) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, exists := st.sessions[s.ID]; exists {
+		return model.ErrConflict
+	}
+	if s.ExternalID != nil {
+		if _, exists := st.findByExternalID(s.OrgID, *s.ExternalSource, *s.ExternalID); exists {
+			return model.ErrExternalIDConflict
+		}
+	}
+	message, err := model.NewSessionOutboxMessage(model.ActivitySessionStarted, s)
+	if err != nil {
+		return err
+	}
+
+	st.sessions[s.ID] = *s
+	st.record(message)
+	return nil
+}
+
+// GetSessions retrieves the sessions with the given IDs
+func (st *Store) GetSessions(ctx context.Context, sessionIDs []string) ([]model.Session, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	sessions := []model.Session{}
+	for _, sessionID := range sessionIDs {
+		if session, ok := st.lookupSession(sessionID); ok {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+// GetSessionByExternalID retrieves a session of an organization by the ID its source
+// system assigned
+func (st *Store) GetSessionByExternalID(ctx context.Context, orgID, source, externalID string) (*model.Session, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	org, err := uuid.Parse(orgID)
+	if err != nil {
+		return nil, model.ErrSessionNotFound
+	}
+	session, ok := st.findByExternalID(org, source, externalID)
+	if !ok {
+		return nil, model.ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+// GetSession retrieves a session by ID
+func (st *Store) GetSession(ctx context.Context, sessionID string) (*model.Session, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	session, ok := st.lookupSession(sessionID)
+	if !ok {
+		return nil, model.ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+// TransitionSession moves a session to a new state and records the transition event and
+// the session metrics
+func (st *Store) TransitionSession(ctx context.Context, sessionID string, t *model.SessionTransition) (*model.Session, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	session, ok := st.lookupSession(sessionID)
+	if !ok {
+		return nil, model.ErrSessionNotFound
+	}
+	if session.Status != t.From {
+		return nil, model.ErrConcurrentTransition
+	}
+	if t.To.IsTerminal() {
+		if t.Event.EventTime.Before(session.StartedAt) {
+			return nil, model.ErrInvalidTimeRange
+		}
+		endedAt := t.Event.EventTime
+		session.EndedAt = &endedAt
+	}
+	if err := checkEventTime(&t.Event); err != nil {
+		return nil, err
+	}
+
+	session.Status = t.To
+	if t.Disposition != nil {
+		disposition := *t.Disposition
+		session.Disposition = &disposition
+	}
+	session.UpdatedAt = time.Now()
+	sessionMessage, err := model.NewSessionOutboxMessage(model.ActivityTypeForStatus(t.To), &session)
+	if err != nil {
+		return nil, err
+	}
+	eventMessage, err := model.NewEventOutboxMessage(&t.Event)
+	if err != nil {
+		return nil, err
+	}
+
+	st.sessions[session.ID] = session
+	st.events[session.ID] = append(st.events[session.ID], t.Event)
+	if t.Metrics != nil {
+		st.metrics[session.ID] = *t.Metrics
+	}
+	if t.To.IsTerminal() {
+		st.addCDR(model.NewCDR(&session, t.Metrics))
+	}
+	st.record(sessionMessage, eventMessage)
+
+	return &session, nil
+}
+
+// ListSessions retrieves sessions based on filter criteria
+func (st *Store) ListSessions(ctx context.Context, filter model.SessionFilter) (*model.SessionListResponse, error) {
+	st.mu.RLock()
+	matches := st.matchingSessions(filter)
+	st.mu.RUnlock()
+
+	total := int64(len(matches))
+	if filter.UseCursor {
+		return listByCursor(filter, matches, total)
+	}
+
+	sortFields := filter.Sort
+	if len(sortFields) == 0 {
+		sortFields = model.DefaultSessionSort
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return model.CompareSessions(&matches[i], &matches[j], sortFields) < 0
+	})
+
+	response := &model.SessionListResponse{Limit: filter.Limit, Offset: &filter.Offset}
+	if filter.IncludeTotal {
+		response.Total = &total
+	}
+	response.Sessions = page(matches, filter.Offset, filter.Limit)
+
+	return response, nil
+}
+
+// listByCursor pages through the matching sessions on the (started_at, id) keyset
+func listByCursor(filter model.SessionFilter, matches []model.Session, total int64) (*model.SessionListResponse, error) {
+	desc, err := model.CursorSortDirection(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	scanOrder := []model.SortField{{Field: "started_at", Desc: filter.ScanDescending(desc)}, {Field: "id", Desc: filter.ScanDescending(desc)}}
+	sort.Slice(matches, func(i, j int) bool {
+		return model.CompareSessions(&matches[i], &matches[j], scanOrder) < 0
+	})
+
+	// Skip everything up to and including the cursor position
+	start := 0
+	if filter.Cursor != nil {
+		boundary := model.Session{ID: filter.Cursor.ID, StartedAt: filter.Cursor.StartedAt}
+		start = sort.Search(len(matches), func(i int) bool {
+			return model.CompareSessions(&matches[i], &boundary, scanOrder) > 0
+		})
+	}
+
+	response, err := model.NewCursorPage(filter, desc, page(matches, start, filter.Limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if filter.IncludeTotal {
+		response.Total = &total
+	}
+
+	return response, nil
+}
+
+func (st *Store) matchingSessions(filter model.SessionFilter) []model.Session {
+	conditions := filter.AllConditions()
+	matches := []model.Session{}
+	for _, session := range st.sessions {
+		if filter.Access.CanSee(&session) && matchesAll(&session, conditions) {
+			matches = append(matches, session)
+		}
+	}
+	return matches
+}
+
+func matchesAll(s *model.Session, conditions []model.FilterCondition) bool {
+	for _, condition := range conditions {
+		if !condition.Matches(s) {
+			return false
+		}
+	}
+	return true
+}
+
+func (st *Store) lookupSession(sessionID string) (model.Session, bool) {
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return model.Session{}, false
+	}
+	session, ok := st.sessions[id]
+	return session, ok
+}
+
+func (st *Store) findByExternalID(orgID uuid.UUID, source, externalID string) (model.Session, bool) {
+	for _, session := range st.sessions {
+		if session.ExternalID != nil && session.OrgID == orgID && *session.ExternalSource == source && *session.ExternalID == externalID {
+			return session, true
+		}
+	}
+	return model.Session{}, false
+}
+
+// page returns a copy of at most limit sessions starting at offset
+func page(sessions []model.Session, offset, limit int) []model.Session {
+	if offset >= len(sessions) {
+		return []model.Session{}
+	}
+	end := offset + limit
+	if end > len(sessions) {
+		end = len(sessions)
+	}
+	return append([]model.Session{}, sessions[offset:end]...)
+}
+
+// ListStaleSessions finds active sessions that have been idle or running for too long
+func (st *Store) ListStaleSessions(ctx context.Context, q model.StaleSessionQuery) ([]model.Session, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	stale := []model.Session{}
+	for id, session := range st.sessions {
+		if !session.Status.IsActive() {
+			continue
+		}
+
+		lastActivity := session.StartedAt
+		for _, event := range st.events[id] {
+			if event.CreatedAt.After(lastActivity) {
+				lastActivity = event.CreatedAt
+			}
+		}
+
+		inactive := !q.InactiveSince.IsZero() && lastActivity.Before(q.InactiveSince)
+		tooLong := !q.StartedBefore.IsZero() && session.StartedAt.Before(q.StartedBefore)
+		if inactive || tooLong {
+			stale = append(stale, session)
+		}
+	}
+
+	order := []model.SortField{{Field: "started_at"}, {Field: "id"}}
+	sort.Slice(stale, func(i, j int) bool {
+		return model.CompareSessions(&stale[i], &stale[j], order) < 0
+	})
+	return page(stale, 0, q.Limit), nil
+}
+
+// PurgeSessions removes the sessions of an organization that ended before the given
+// time together with their events, metrics and CDRs
+func (st *Store) PurgeSessions(ctx context.Context, orgID string, before time.Time) (int64, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	purged := make(map[uuid.UUID]bool)
+	for id, session := range st.sessions {
+		if session.OrgID.String() == orgID && session.EndedAt != nil && session.EndedAt.Before(before) {
+			purged[id] = true
+			delete(st.sessions, id)
+			delete(st.events, id)
+			delete(st.metrics, id)
+		}
+	}
+	if len(purged) == 0 {
+		return 0, nil
+	}
+	kept := st.cdrs[:0]
+	for _, entry := range st.cdrs {
+		if !purged[entry.cdr.SessionID] {
+			kept = append(kept, entry)
+		}
+	}
+	st.cdrs = kept
+	return int64(len(purged)), nil
+}
@@ -0,0 +1,289 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+var orgAccess = model.SessionAccess{Scope: model.SessionScopeAll, OrgID: model.DefaultOrganizationID, HomeOrgID: model.DefaultOrganizationID}
+
+func newSession(startedAt time.Time, status model.SessionStatus) *model.Session {
+	return &model.Session{
+		ID:        uuid.New(),
+		OrgID:     model.DefaultOrganizationID,
+		StartedAt: startedAt,
+		CallerID:  "+14155550100",
+		CalleeID:  "+14155550199",
+		Status:    status,
+		CreatedAt: startedAt,
+		UpdatedAt: startedAt,
+	}
+}
+
+func TestCreateSession(t *testing.T) {
+	ctx := context.Background()
+	st := New()
+	otherOrg := createOrganization(t, st, "other")
+	source, externalID := "pbx", "call-1"
+
+	withExternalID := func(orgID uuid.UUID) *model.Session {
+		s := newSession(time.Now(), model.SessionStatusInitiated)
+		s.OrgID = orgID
+		s.ExternalSource, s.ExternalID = &source, &externalID
+		return s
+	}
+	first := withExternalID(model.DefaultOrganizationID)
+	if err := st.CreateSession(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		session *model.Session
+		wantErr error
+	}{
+		{name: "duplicate id", session: first, wantErr: model.ErrConflict},
+		{name: "duplicate external id", session: withExternalID(model.DefaultOrganizationID), wantErr: model.ErrExternalIDConflict},
+		{name: "external id of another organization", session: withExternalID(otherOrg)},
+		{name: "without external id", session: newSession(time.Now(), model.SessionStatusInitiated)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := st.CreateSession(ctx, tt.session); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateSession error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	got, err := st.GetSessionByExternalID(ctx, model.DefaultOrganizationID.String(), source, externalID)
+	if err != nil || got.ID != first.ID {
+		t.Fatalf("GetSessionByExternalID = %v, %v, want session %s", got, err, first.ID)
+	}
+	if _, err := st.GetSessionByExternalID(ctx, model.DefaultOrganizationID.String(), source, "call-2"); !errors.Is(err, model.ErrSessionNotFound) {
+		t.Errorf("GetSessionByExternalID of an unknown call error = %v, want ErrSessionNotFound", err)
+	}
+	if _, err := st.GetSession(ctx, "not-a-uuid"); !errors.Is(err, model.ErrSessionNotFound) {
+		t.Errorf("GetSession of an invalid ID error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	ctx := context.Background()
+	st := New()
+	start := time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)
+
+	var ids []uuid.UUID
+	for i, status := range []model.SessionStatus{model.SessionStatusCompleted, model.SessionStatusOngoing, model.SessionStatusCompleted, model.SessionStatusFailed} {
+		s := newSession(start.Add(time.Duration(i)*time.Minute), status)
+		if err := st.CreateSession(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, s.ID)
+	}
+	hidden := newSession(start, model.SessionStatusCompleted)
+	hidden.OrgID = createOrganization(t, st, "other")
+	if err := st.CreateSession(ctx, hidden); err != nil {
+		t.Fatal(err)
+	}
+
+	completed, err := model.ParseSessionFilter("status:eq:completed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		filter model.SessionFilter
+		want   []uuid.UUID
+		total  int64
+	}{
+		{name: "newest first by default", filter: model.SessionFilter{Limit: 10}, want: []uuid.UUID{ids[3], ids[2], ids[1], ids[0]}, total: 4},
+		{name: "sorted ascending", filter: model.SessionFilter{Limit: 10, Sort: []model.SortField{{Field: "started_at"}}}, want: ids, total: 4},
+		{name: "offset and limit", filter: model.SessionFilter{Limit: 2, Offset: 1}, want: []uuid.UUID{ids[2], ids[1]}, total: 4},
+		{name: "offset past the end", filter: model.SessionFilter{Limit: 2, Offset: 10}, want: []uuid.UUID{}, total: 4},
+		{name: "filter expression", filter: model.SessionFilter{Limit: 10, Conditions: completed}, want: []uuid.UUID{ids[2], ids[0]}, total: 2},
+		{name: "status parameter", filter: model.SessionFilter{Limit: 10, Status: model.SessionStatusFailed}, want: []uuid.UUID{ids[3]}, total: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			filter.Access, filter.IncludeTotal = orgAccess, true
+			got, err := st.ListSessions(ctx, filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSessionIDs(t, got.Sessions, tt.want)
+			if got.Total == nil || *got.Total != tt.total {
+				t.Errorf("Total = %v, want %d", got.Total, tt.total)
+			}
+		})
+	}
+}
+
+func TestListSessionsByCursor(t *testing.T) {
+	t.Setenv("CURSOR_SECRET", "cursor-secret")
+	ctx := context.Background()
+	st := New()
+	start := time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)
+
+	// Two sessions share a start time, so the keyset must fall back to the ID
+	var want []uuid.UUID
+	for _, offset := range []time.Duration{0, 0, time.Minute, 2 * time.Minute, 3 * time.Minute} {
+		s := newSession(start.Add(offset), model.SessionStatusCompleted)
+		if err := st.CreateSession(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, s.ID)
+	}
+	if want[0].String() > want[1].String() {
+		want[0], want[1] = want[1], want[0]
+	}
+	for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+		want[i], want[j] = want[j], want[i]
+	}
+
+	list := func(cursor string) *model.SessionListResponse {
+		t.Helper()
+		filter := model.SessionFilter{Limit: 2, UseCursor: true, Access: orgAccess}
+		if cursor != "" {
+			decoded, err := model.DecodeSessionCursor(cursor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filter.Cursor = decoded
+		}
+		page, err := st.ListSessions(ctx, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return page
+	}
+
+	var got []uuid.UUID
+	var pages []*model.SessionListResponse
+	for cursor := ""; ; {
+		page := list(cursor)
+		pages = append(pages, page)
+		for _, s := range page.Sessions {
+			got = append(got, s.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if len(pages) != 3 {
+		t.Fatalf("listed %d pages, want 3", len(pages))
+	}
+	if len(got) != len(want) {
+		t.Fatalf("cursor pages returned %d sessions, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("cursor pages returned %v, want %v", got, want)
+		}
+	}
+
+	previous := list(pages[2].PrevCursor)
+	assertSessionIDs(t, previous.Sessions, want[2:4])
+}
+
+func TestTransitionSession(t *testing.T) {
+	ctx := context.Background()
+	st := New()
+	s := newSession(time.Now().Add(-time.Minute), model.SessionStatusOngoing)
+	if err := st.CreateSession(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+
+	end := func(from model.SessionStatus, at time.Time) error {
+		_, err := st.TransitionSession(ctx, s.ID.String(), &model.SessionTransition{
+			From:  from,
+			To:    model.SessionStatusCompleted,
+			Event: model.SessionEvent{ID: uuid.New(), SessionID: s.ID, EventType: "state_change", EventTime: at, CreatedAt: time.Now()},
+		})
+		return err
+	}
+	if err := end(model.SessionStatusOngoing, s.StartedAt.Add(-time.Second)); !errors.Is(err, model.ErrInvalidTimeRange) {
+		t.Fatalf("ending before the start error = %v, want ErrInvalidTimeRange", err)
+	}
+	if err := end(model.SessionStatusRinging, time.Now()); !errors.Is(err, model.ErrConcurrentTransition) {
+		t.Fatalf("transition from a stale status error = %v, want ErrConcurrentTransition", err)
+	}
+	if err := end(model.SessionStatusOngoing, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := st.GetSession(ctx, s.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != model.SessionStatusCompleted || got.EndedAt == nil {
+		t.Errorf("ended session has status %s and ended_at %v", got.Status, got.EndedAt)
+	}
+	events, err := st.ListEvents(ctx, s.ID.String())
+	if err != nil || len(events) != 1 {
+		t.Errorf("ListEvents = %d events, %v, want the transition event", len(events), err)
+	}
+}
+
+func TestPurgeSessions(t *testing.T) {
+	ctx := context.Background()
+	st := New()
+	now := time.Now()
+
+	ended := func(orgID uuid.UUID, endedAt time.Time) *model.Session {
+		s := newSession(endedAt.Add(-time.Minute), model.SessionStatusCompleted)
+		s.OrgID, s.EndedAt = orgID, &endedAt
+		if err := st.CreateSession(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	otherOrg := createOrganization(t, st, "other")
+	expired := ended(model.DefaultOrganizationID, now.Add(-48*time.Hour))
+	recent := ended(model.DefaultOrganizationID, now.Add(-time.Hour))
+	otherExpired := ended(otherOrg, now.Add(-48*time.Hour))
+	active := newSession(now.Add(-72*time.Hour), model.SessionStatusOngoing)
+	if err := st.CreateSession(ctx, active); err != nil {
+		t.Fatal(err)
+	}
+
+	purged, err := st.PurgeSessions(ctx, model.DefaultOrganizationID.String(), now.Add(-24*time.Hour))
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeSessions = %d, %v, want 1", purged, err)
+	}
+	if _, err := st.GetSession(ctx, expired.ID.String()); !errors.Is(err, model.ErrSessionNotFound) {
+		t.Errorf("expired session was kept: %v", err)
+	}
+	for _, kept := range []*model.Session{recent, otherExpired, active} {
+		if _, err := st.GetSession(ctx, kept.ID.String()); err != nil {
+			t.Errorf("session %s was purged: %v", kept.ID, err)
+		}
+	}
+}
+
+func createOrganization(t *testing.T, st *Store, name string) uuid.UUID {
+	t.Helper()
+	org := &model.Organization{ID: uuid.New(), Name: name, AllowedEventTypes: []string{}, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := st.CreateOrganization(context.Background(), org); err != nil {
+		t.Fatal(err)
+	}
+	return org.ID
+}
+
+func assertSessionIDs(t *testing.T, sessions []model.Session, want []uuid.UUID) {
+	t.Helper()
+	if len(sessions) != len(want) {
+		t.Fatalf("got %d sessions, want %d", len(sessions), len(want))
+	}
+	for i, s := range sessions {
+		if s.ID != want[i] {
+			t.Errorf("session %d is %s, want %s", i, s.ID, want[i])
+		}
+	}
+}
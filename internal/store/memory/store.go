@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// Store is an in-memory implementation of the model stores. It mirrors the constraints
+// of the Postgres schema and is intended for tests and local development.
+type Store struct {
+	mu       sync.RWMutex
+	sessions map[uuid.UUID]model.Session
+	events   map[uuid.UUID][]model.SessionEvent
+	metrics  map[uuid.UUID]model.SessionMetrics
+	users    map[uuid.UUID]model.User
+	emails   map[string]uuid.UUID
+	orgs     map[uuid.UUID]model.Organization
+	roles    map[model.UserRole]model.Role
+
+	refreshTokens       map[uuid.UUID]*model.RefreshToken
+	revokedAccessTokens map[uuid.UUID]time.Time
+
+	apiKeys map[uuid.UUID]model.APIKey
+
+	idempotency map[idempotencyKey]model.IdempotencyRecord
+
+	activitySeq int64
+	listeners   map[chan model.Activity]struct{}
+
+	outbox     []*outboxEntry
+	outboxSeq  int64
+	webhooks   map[uuid.UUID]model.Webhook
+	deliveries map[uuid.UUID]*model.WebhookDelivery
+	attempts   map[uuid.UUID][]model.WebhookAttempt
+
+	exportJobs map[uuid.UUID]*exportJob
+
+	cdrs   []*cdrEntry
+	cdrSeq int64
+}
+
+var (
+	_ model.Store        = (*Store)(nil)
+	_ model.ActivityFeed = (*Store)(nil)
+)
+
+// New creates a Store holding only the default organization and the built-in roles
+func New() *Store {
+	st := &Store{
+		sessions: make(map[uuid.UUID]model.Session),
+		events:   make(map[uuid.UUID][]model.SessionEvent),
+		metrics:  make(map[uuid.UUID]model.SessionMetrics),
+		users:    make(map[uuid.UUID]model.User),
+		emails:   make(map[string]uuid.UUID),
+		orgs:     make(map[uuid.UUID]model.Organization),
+		roles:    make(map[model.UserRole]model.Role),
+
+		refreshTokens:       make(map[uuid.UUID]*model.RefreshToken),
+		revokedAccessTokens: make(map[uuid.UUID]time.Time),
+
+		apiKeys: make(map[uuid.UUID]model.APIKey),
+
+		idempotency: make(map[idempotencyKey]model.IdempotencyRecord),
+		listeners:   make(map[chan model.Activity]struct{}),
+
+		webhooks:   make(map[uuid.UUID]model.Webhook),
+		deliveries: make(map[uuid.UUID]*model.WebhookDelivery),
+		attempts:   make(map[uuid.UUID][]model.WebhookAttempt),
+
+		exportJobs: make(map[uuid.UUID]*exportJob),
+	}
+	now := time.Now()
+	st.orgs[model.DefaultOrganizationID] = model.Organization{
+		ID: model.DefaultOrganizationID, Name: "default", AllowedEventTypes: []string{}, CreatedAt: now, UpdatedAt: now,
+	}
+	for _, r := range model.BuiltinRoles() {
+		r.CreatedAt, r.UpdatedAt = now, now
+		st.roles[r.Name] = r
+	}
+	return st
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"crypto/subtle"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// CreateRefreshToken stores the first token of a new family
+func (st *Store) CreateRefreshToken(ctx context.Context, t *model.RefreshToken) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	token := *t
+	st.refreshTokens[token.ID] = &token
+	return nil
+}
+
+// RotateRefreshToken exchanges a token for its successor
+func (st *Store) RotateRefreshToken(ctx context.Context, tokenHash []byte, next *model.RefreshToken) (*model.RefreshToken, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var used *model.RefreshToken
+	for _, t := range st.refreshTokens {
+		if subtle.ConstantTimeCompare(t.TokenHash, tokenHash) == 1 {
+			used = t
+			break
+		}
+	}
+	if used == nil || used.RevokedAt != nil || !used.ExpiresAt.After(next.CreatedAt) {
+		return nil, model.ErrInvalidRefreshToken
+	}
+	if used.UsedAt != nil {
+		st.revokeRefreshTokens(next.CreatedAt, func(t *model.RefreshToken) bool { return t.FamilyID == used.FamilyID })
+		return nil, model.ErrRefreshTokenReused
+	}
+
+	usedAt := next.CreatedAt
+	used.UsedAt = &usedAt
+	next.UserID, next.FamilyID, next.Scopes = used.UserID, used.FamilyID, used.Scopes
+	token := *next
+	st.refreshTokens[token.ID] = &token
+
+	result := *used
+	return &result, nil
+}
+
+// revokeRefreshTokens revokes the unrevoked tokens matching the condition and denies the
+// access tokens issued with them; the caller must hold the write lock
+func (st *Store) revokeRefreshTokens(at time.Time, match func(*model.RefreshToken) bool) {
+	for _, t := range st.refreshTokens {
+		if t.RevokedAt != nil || !match(t) {
+			continue
+		}
+		revokedAt := at
+		t.RevokedAt = &revokedAt
+		if t.AccessTokenExpiresAt.After(at) {
+			st.revokedAccessTokens[t.AccessTokenID] = t.AccessTokenExpiresAt
+		}
+	}
+}
+
+// RevokeRefreshTokenFamily revokes a token family of a user
+func (st *Store) RevokeRefreshTokenFamily(ctx context.Context, userID, familyID string, at time.Time) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.revokeRefreshTokens(at, func(t *model.RefreshToken) bool {
+		return t.UserID.String() == userID && t.FamilyID.String() == familyID
+	})
+	return nil
+}
+
+// RevokeUserRefreshTokens revokes every token family of a user
+func (st *Store) RevokeUserRefreshTokens(ctx context.Context, userID string, at time.Time) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.revokeRefreshTokens(at, func(t *model.RefreshToken) bool { return t.UserID.String() == userID })
+	return nil
+}
+
+// RevokeRoleRefreshTokens revokes every token family of the users holding a role
+func (st *Store) RevokeRoleRefreshTokens(ctx context.Context, role string, at time.Time) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.revokeRefreshTokens(at, func(t *model.RefreshToken) bool {
+		user, ok := st.users[t.UserID]
+		return ok && string(user.Role) == role
+	})
+	return nil
+}
+
+// IsAccessTokenRevoked reports whether an access token is on the denylist
+func (st *Store) IsAccessTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	id, err := uuid.Parse(tokenID)
+	if err != nil {
+		return false, nil
+	}
+	_, revoked := st.revokedAccessTokens[id]
+	return revoked, nil
+}
+
+// DeleteExpiredTokens removes expired refresh tokens and denylist entries
+func (st *Store) DeleteExpiredTokens(ctx context.Context, before time.Time) (int64, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var deleted int64
+	for id, t := range st.refreshTokens {
+		if t.ExpiresAt.Before(before) {
+			delete(st.refreshTokens, id)
+			deleted++
+		}
+	}
+	for id, expiresAt := range st.revokedAccessTokens {
+		if expiresAt.Before(before) {
+			delete(st.revokedAccessTokens, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
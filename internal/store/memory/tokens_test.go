@@ -0,0 +1,202 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// refreshToken returns a token of a family hashed from the raw token
+func refreshToken(userID, familyID uuid.UUID, raw string, createdAt time.Time) *model.RefreshToken {
+	return &model.RefreshToken{
+		ID:                   uuid.New(),
+		UserID:               userID,
+		FamilyID:             familyID,
+		TokenHash:            model.HashRefreshToken(raw),
+		AccessTokenID:        uuid.New(),
+		AccessTokenExpiresAt: createdAt.Add(15 * time.Minute),
+		CreatedAt:            createdAt,
+		ExpiresAt:            createdAt.Add(24 * time.Hour),
+	}
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	userID := uuid.New()
+
+	tests := []struct {
+		name    string
+		setup   func(st *Store, first *model.RefreshToken)
+		at      time.Time
+		wantErr error
+	}{
+		{name: "unused token", at: now.Add(time.Minute)},
+		{name: "expired token", at: now.Add(25 * time.Hour), wantErr: model.ErrInvalidRefreshToken},
+		{
+			name: "revoked token",
+			setup: func(st *Store, first *model.RefreshToken) {
+				st.RevokeRefreshTokenFamily(ctx, userID.String(), first.FamilyID.String(), now)
+			},
+			at:      now.Add(time.Minute),
+			wantErr: model.ErrInvalidRefreshToken,
+		},
+		{
+			name: "used token",
+			setup: func(st *Store, first *model.RefreshToken) {
+				if _, err := st.RotateRefreshToken(ctx, first.TokenHash, refreshToken(uuid.Nil, uuid.Nil, "rt_second", now)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			at:      now.Add(time.Minute),
+			wantErr: model.ErrRefreshTokenReused,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := New()
+			first := refreshToken(userID, uuid.New(), "rt_first", now)
+			first.Scopes = model.Permissions{model.PermissionSessionsRead}
+			if err := st.CreateRefreshToken(ctx, first); err != nil {
+				t.Fatal(err)
+			}
+			if tt.setup != nil {
+				tt.setup(st, first)
+			}
+
+			next := refreshToken(uuid.Nil, uuid.Nil, "rt_next", tt.at)
+			used, err := st.RotateRefreshToken(ctx, model.HashRefreshToken("rt_first"), next)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RotateRefreshToken error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if used.ID != first.ID || used.UsedAt == nil {
+				t.Errorf("used token %+v, want the first token marked used", used)
+			}
+			if next.UserID != userID || next.FamilyID != first.FamilyID || len(next.Scopes) != 1 {
+				t.Errorf("next token %+v does not continue the family", next)
+			}
+		})
+	}
+
+	if _, err := New().RotateRefreshToken(ctx, model.HashRefreshToken("rt_unknown"), refreshToken(uuid.Nil, uuid.Nil, "rt_next", now)); !errors.Is(err, model.ErrInvalidRefreshToken) {
+		t.Errorf("unknown token error = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	ctx := context.Background()
+	st := New()
+	now := time.Now()
+	userID := uuid.New()
+
+	first := refreshToken(userID, uuid.New(), "rt_first", now)
+	other := refreshToken(userID, uuid.New(), "rt_other", now)
+	for _, token := range []*model.RefreshToken{first, other} {
+		if err := st.CreateRefreshToken(ctx, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	second := refreshToken(uuid.Nil, uuid.Nil, "rt_second", now.Add(time.Minute))
+	if _, err := st.RotateRefreshToken(ctx, first.TokenHash, second); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first token leaked and is presented again
+	if _, err := st.RotateRefreshToken(ctx, first.TokenHash, refreshToken(uuid.Nil, uuid.Nil, "rt_attacker", now.Add(2*time.Minute))); !errors.Is(err, model.ErrRefreshTokenReused) {
+		t.Fatalf("reuse error = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, err := st.RotateRefreshToken(ctx, second.TokenHash, refreshToken(uuid.Nil, uuid.Nil, "rt_third", now.Add(3*time.Minute))); !errors.Is(err, model.ErrInvalidRefreshToken) {
+		t.Errorf("successor of a reused token error = %v, want ErrInvalidRefreshToken", err)
+	}
+
+	for _, tt := range []struct {
+		name        string
+		tokenID     uuid.UUID
+		wantRevoked bool
+	}{
+		{name: "access token of the first token", tokenID: first.AccessTokenID, wantRevoked: true},
+		{name: "access token of its successor", tokenID: second.AccessTokenID, wantRevoked: true},
+		{name: "access token of another login", tokenID: other.AccessTokenID},
+	} {
+		if revoked, _ := st.IsAccessTokenRevoked(ctx, tt.tokenID.String()); revoked != tt.wantRevoked {
+			t.Errorf("%s: revoked = %v, want %v", tt.name, revoked, tt.wantRevoked)
+		}
+	}
+	if _, err := st.RotateRefreshToken(ctx, other.TokenHash, refreshToken(uuid.Nil, uuid.Nil, "rt_other_next", now.Add(time.Minute))); err != nil {
+		t.Errorf("token of another login error = %v", err)
+	}
+
+	if err := st.RevokeUserRefreshTokens(ctx, userID.String(), now.Add(4*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := st.IsAccessTokenRevoked(ctx, other.AccessTokenID.String()); !revoked {
+		t.Error("revoking every token of the user left another login's access token usable")
+	}
+}
+
+func TestRevokeRoleRefreshTokens(t *testing.T) {
+	ctx := context.Background()
+	st := New()
+	now := time.Now()
+
+	agent := &model.User{ID: uuid.New(), OrgID: model.DefaultOrganizationID, Email: "agent@example.com", Role: model.UserRoleUser}
+	admin := &model.User{ID: uuid.New(), OrgID: model.DefaultOrganizationID, Email: "admin@example.com", Role: model.UserRoleAdmin}
+	agentToken := refreshToken(agent.ID, uuid.New(), "rt_agent", now)
+	adminToken := refreshToken(admin.ID, uuid.New(), "rt_admin", now)
+	for i, user := range []*model.User{agent, admin} {
+		if err := st.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.CreateRefreshToken(ctx, []*model.RefreshToken{agentToken, adminToken}[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := st.RevokeRoleRefreshTokens(ctx, string(model.UserRoleUser), now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := st.IsAccessTokenRevoked(ctx, agentToken.AccessTokenID.String()); !revoked {
+		t.Error("access token of a user holding the role is still usable")
+	}
+	if revoked, _ := st.IsAccessTokenRevoked(ctx, adminToken.AccessTokenID.String()); revoked {
+		t.Error("access token of a user holding another role was revoked")
+	}
+}
+
+func TestDeleteExpiredTokens(t *testing.T) {
+	ctx := context.Background()
+	st := New()
+	now := time.Now()
+	userID := uuid.New()
+
+	expired := refreshToken(userID, uuid.New(), "rt_expired", now.Add(-48*time.Hour))
+	current := refreshToken(userID, uuid.New(), "rt_current", now)
+	for _, token := range []*model.RefreshToken{expired, current} {
+		if err := st.CreateRefreshToken(ctx, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Denies the access token of the current token until it expires
+	if err := st.RevokeRefreshTokenFamily(ctx, userID.String(), current.FamilyID.String(), now); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := st.DeleteExpiredTokens(ctx, now)
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteExpiredTokens = %d, %v, want the expired refresh token", deleted, err)
+	}
+	deleted, err = st.DeleteExpiredTokens(ctx, now.Add(time.Hour))
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteExpiredTokens = %d, %v, want the expired denylist entry", deleted, err)
+	}
+	if revoked, _ := st.IsAccessTokenRevoked(ctx, current.AccessTokenID.String()); revoked {
+		t.Error("expired denylist entry was kept")
+	}
+}
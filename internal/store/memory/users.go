@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// CreateUser stores a new user account
+func (st *Store) CreateUser(ctx context.Context, u *model.User) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return st.createUser(u)
+}
+
+// CreateUserWithOrganization stores a new organization and its first user
+func (st *Store) CreateUserWithOrganization(ctx context.Context, u *model.User, org *model.Organization) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, exists := st.orgs[org.ID]; exists {
+		return model.ErrConflict
+	}
+	st.orgs[org.ID] = copyOrganization(*org)
+	if err := st.createUser(u); err != nil {
+		delete(st.orgs, org.ID)
+		return err
+	}
+	return nil
+}
+
+// createUser stores a user; the caller must hold the write lock
+func (st *Store) createUser(u *model.User) error {
+	if _, exists := st.emails[u.Email]; exists {
+		return model.ErrUserAlreadyExists
+	}
+	if _, ok := st.roles[u.Role]; !ok {
+		return model.ErrRoleNotFound
+	}
+	if _, ok := st.orgs[u.OrgID]; !ok {
+		return model.ErrOrganizationNotFound
+	}
+	st.users[u.ID] = *u
+	st.emails[u.Email] = u.ID
+	return nil
+}
+
+// GetUserByID retrieves a user by their ID
+func (st *Store) GetUserByID(ctx context.Context, userID string) (*model.User, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, model.ErrUserNotFound
+	}
+	user, ok := st.users[id]
+	if !ok {
+		return nil, model.ErrUserNotFound
+	}
+	user.Password = ""
+	return &user, nil
+}
+
+// GetUserByEmail retrieves a user and their password hash by email
+func (st *Store) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	id, ok := st.emails[email]
+	if !ok {
+		return nil, model.ErrUserNotFound
+	}
+	user := st.users[id]
+	return &user, nil
+}
+
+// ListUsers retrieves the users of an organization, or all users, oldest first
+func (st *Store) ListUsers(ctx context.Context, orgID string) ([]model.User, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	users := make([]model.User, 0, len(st.users))
+	for _, user := range st.users {
+		if orgID != "" && user.OrgID.String() != orgID {
+			continue
+		}
+		user.Password = ""
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if !users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].CreatedAt.Before(users[j].CreatedAt)
+		}
+		return users[i].ID.String() < users[j].ID.String()
+	})
+	return users, nil
+}
+
+// UpdateUser stores the role, team and organization of a user
+func (st *Store) UpdateUser(ctx context.Context, u *model.User) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	user, ok := st.users[u.ID]
+	if !ok {
+		return model.ErrUserNotFound
+	}
+	if _, ok := st.roles[u.Role]; !ok {
+		return model.ErrRoleNotFound
+	}
+	if _, ok := st.orgs[u.OrgID]; !ok {
+		return model.ErrOrganizationNotFound
+	}
+	user.Role, user.Team, user.OrgID, user.UpdatedAt = u.Role, u.Team, u.OrgID, u.UpdatedAt
+	st.users[u.ID] = user
+	*u = user
+	u.Password = ""
+	return nil
+}
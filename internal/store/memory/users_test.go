@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+func TestCreateUser(t *testing.T) {
+	ctx := context.Background()
+	st := New()
+	user := func(email string, role model.UserRole, orgID uuid.UUID) *model.User {
+		return &model.User{ID: uuid.New(), OrgID: orgID, Email: email, Password: "hash", Role: role, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	}
+	if err := st.CreateUser(ctx, user("alice@example.com", model.UserRoleUser, model.DefaultOrganizationID)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		user    *model.User
+		wantErr error
+	}{
+		{name: "new user", user: user("bob@example.com", model.UserRoleAdmin, model.DefaultOrganizationID)},
+		{name: "email taken", user: user("alice@example.com", model.UserRoleUser, model.DefaultOrganizationID), wantErr: model.ErrUserAlreadyExists},
+		{name: "unknown role", user: user("carol@example.com", "owner", model.DefaultOrganizationID), wantErr: model.ErrRoleNotFound},
+		{name: "unknown organization", user: user("dave@example.com", model.UserRoleUser, uuid.New()), wantErr: model.ErrOrganizationNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := st.CreateUser(ctx, tt.user); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateUser error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateUserWithOrganization(t *testing.T) {
+	ctx := context.Background()
+	st := New()
+	if err := st.CreateUser(ctx, &model.User{ID: uuid.New(), OrgID: model.DefaultOrganizationID, Email: "alice@example.com", Role: model.UserRoleUser}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A taken email stores neither the user nor the organization
+	org := &model.Organization{ID: uuid.New(), Name: "acme"}
+	taken := &model.User{ID: uuid.New(), OrgID: org.ID, Email: "alice@example.com", Role: model.UserRoleOrgAdmin}
+	if err := st.CreateUserWithOrganization(ctx, taken, org); !errors.Is(err, model.ErrUserAlreadyExists) {
+		t.Fatalf("CreateUserWithOrganization error = %v, want ErrUserAlreadyExists", err)
+	}
+	if _, err := st.GetOrganization(ctx, org.ID.String()); !errors.Is(err, model.ErrOrganizationNotFound) {
+		t.Errorf("organization of a rejected user was kept: %v", err)
+	}
+
+	owner := &model.User{ID: uuid.New(), OrgID: org.ID, Email: "owner@acme.example", Role: model.UserRoleOrgAdmin}
+	if err := st.CreateUserWithOrganization(ctx, owner, org); err != nil {
+		t.Fatal(err)
+	}
+	if users, err := st.ListUsers(ctx, org.ID.String()); err != nil || len(users) != 1 {
+		t.Errorf("ListUsers = %+v, %v, want the organization's first user", users, err)
+	}
+}
+
+func TestGetUserHidesPassword(t *testing.T) {
+	ctx := context.Background()
+	st := New()
+	u := &model.User{ID: uuid.New(), OrgID: model.DefaultOrganizationID, Email: "alice@example.com", Password: "hash", Role: model.UserRoleUser}
+	if err := st.CreateUser(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+
+	byID, err := st.GetUserByID(ctx, u.ID.String())
+	if err != nil || byID.Password != "" {
+		t.Fatalf("GetUserByID = %+v, %v, want the user without its password", byID, err)
+	}
+	users, err := st.ListUsers(ctx, "")
+	if err != nil || len(users) != 1 || users[0].Password != "" {
+		t.Fatalf("ListUsers = %+v, %v, want the user without its password", users, err)
+	}
+	// Login needs the hash, so only the lookup by email returns it
+	byEmail, err := st.GetUserByEmail(ctx, u.Email)
+	if err != nil || byEmail.Password != "hash" {
+		t.Fatalf("GetUserByEmail = %+v, %v, want the password hash", byEmail, err)
+	}
+	if _, err := st.GetUserByID(ctx, uuid.NewString()); !errors.Is(err, model.ErrUserNotFound) {
+		t.Errorf("GetUserByID of an unknown user error = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestListUsersByOrganization(t *testing.T) {
+	ctx := context.Background()
+	st := New()
+	otherOrg := createOrganization(t, st, "other")
+	start := time.Now()
+	for i, orgID := range []uuid.UUID{model.DefaultOrganizationID, otherOrg, model.DefaultOrganizationID} {
+		u := &model.User{ID: uuid.New(), OrgID: orgID, Email: uuid.NewString() + "@example.com", Role: model.UserRoleUser, CreatedAt: start.Add(time.Duration(i) * time.Second)}
+		if err := st.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		orgID string
+		want  int
+	}{
+		{orgID: "", want: 3},
+		{orgID: model.DefaultOrganizationID.String(), want: 2},
+		{orgID: otherOrg.String(), want: 1},
+		{orgID: uuid.NewString(), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.orgID, func(t *testing.T) {
+			users, err := st.ListUsers(ctx, tt.orgID)
+			if err != nil || len(users) != tt.want {
+				t.Fatalf("ListUsers(%q) = %d users, %v, want %d", tt.orgID, len(users), err, tt.want)
+			}
+			for i := 1; i < len(users); i++ {
+				if users[i].CreatedAt.Before(users[i-1].CreatedAt) {
+					t.Errorf("ListUsers(%q) is not ordered oldest first", tt.orgID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// CreateWebhook stores a new webhook
+func (st *Store) CreateWebhook(ctx context.Context, w *model.Webhook) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, exists := st.webhooks[w.ID]; exists {
+		return model.ErrConflict
+	}
+	st.webhooks[w.ID] = copyWebhook(*w)
+	return nil
+}
+
+// GetWebhook retrieves a webhook by ID
+func (st *Store) GetWebhook(ctx context.Context, webhookID string) (*model.Webhook, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	w, ok := st.lookupWebhook(webhookID)
+	if !ok {
+		return nil, model.ErrWebhookNotFound
+	}
+	return &w, nil
+}
+
+// ListWebhooks retrieves the webhooks of an organization, oldest first
+func (st *Store) ListWebhooks(ctx context.Context, orgID string) ([]model.Webhook, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	webhooks := make([]model.Webhook, 0, len(st.webhooks))
+	for _, w := range st.webhooks {
+		if w.OrgID.String() != orgID {
+			continue
+		}
+		webhooks = append(webhooks, copyWebhook(w))
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		if !webhooks[i].CreatedAt.Equal(webhooks[j].CreatedAt) {
+			return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+		}
+		return webhooks[i].ID.String() < webhooks[j].ID.String()
+	})
+	return webhooks, nil
+}
+
+// UpdateWebhook stores the changed fields of a webhook
+func (st *Store) UpdateWebhook(ctx context.Context, w *model.Webhook) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	existing, ok := st.webhooks[w.ID]
+	if !ok {
+		return model.ErrWebhookNotFound
+	}
+	w.Secret = existing.Secret
+	w.CreatedAt = existing.CreatedAt
+	st.webhooks[w.ID] = copyWebhook(*w)
+	return nil
+}
+
+// DeleteWebhook removes a webhook together with its deliveries
+func (st *Store) DeleteWebhook(ctx context.Context, webhookID string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	w, ok := st.lookupWebhook(webhookID)
+	if !ok {
+		return model.ErrWebhookNotFound
+	}
+	delete(st.webhooks, w.ID)
+	for id, d := range st.deliveries {
+		if d.WebhookID == w.ID {
+			delete(st.deliveries, id)
+			delete(st.attempts, id)
+		}
+	}
+	return nil
+}
+
+// DispatchOutbox fans undispatched outbox messages out into webhook deliveries
+func (st *Store) DispatchOutbox(ctx context.Context, limit int) (int, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := time.Now()
+	dispatched := 0
+	for _, entry := range st.outbox {
+		if dispatched == limit {
+			break
+		}
+		if entry.webhooksDispatchedAt != nil {
+			continue
+		}
+		for _, w := range st.webhooks {
+			if !w.Matches(&entry.message) {
+				continue
+			}
+			next := now
+			d := &model.WebhookDelivery{
+				ID:            uuid.New(),
+				WebhookID:     w.ID,
+				MessageID:     entry.message.ID,
+				MessageType:   entry.message.Type,
+				SessionID:     entry.message.SessionID,
+				Status:        model.WebhookDeliveryPending,
+				NextAttemptAt: &next,
+				CreatedAt:     now,
+				UpdatedAt:     now,
+			}
+			st.deliveries[d.ID] = d
+		}
+		entry.webhooksDispatchedAt = &now
+		dispatched++
+	}
+	return dispatched, nil
+}
+
+// ClaimWebhookDeliveries leases the pending deliveries that are due
+func (st *Store) ClaimWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]model.WebhookTask, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var due []*model.WebhookDelivery
+	for _, d := range st.deliveries {
+		if d.Status != model.WebhookDeliveryPending || d.NextAttemptAt.After(now) || !st.webhooks[d.WebhookID].Active {
+			continue
+		}
+		due = append(due, d)
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(*due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt)
+		}
+		return due[i].MessageID < due[j].MessageID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	tasks := make([]model.WebhookTask, 0, len(due))
+	for _, d := range due {
+		lease := leaseUntil
+		d.NextAttemptAt = &lease
+		w := st.webhooks[d.WebhookID]
+		tasks = append(tasks, model.WebhookTask{
+			Delivery: *d,
+			URL:      w.URL,
+			Secret:   w.Secret,
+			Message:  st.outboxMessage(d.MessageID),
+		})
+	}
+	return tasks, nil
+}
+
+// RecordWebhookAttempt logs an attempt and updates its delivery
+func (st *Store) RecordWebhookAttempt(ctx context.Context, a *model.WebhookAttempt, update model.WebhookDeliveryUpdate) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	d, ok := st.deliveries[a.DeliveryID]
+	if !ok {
+		return model.ErrWebhookDeliveryNotFound
+	}
+	st.attempts[d.ID] = append(st.attempts[d.ID], *a)
+
+	d.Status = update.Status
+	d.Attempts = a.Attempt
+	d.NextAttemptAt = update.NextAttemptAt
+	d.LastError = a.Error
+	if update.Status == model.WebhookDeliverySucceeded {
+		deliveredAt := a.AttemptedAt
+		d.DeliveredAt = &deliveredAt
+	}
+	d.UpdatedAt = time.Now()
+	return nil
+}
+
+// ListWebhookDeliveries retrieves a page of the deliveries of a webhook, newest first
+func (st *Store) ListWebhookDeliveries(ctx context.Context, webhookID string, filter model.WebhookDeliveryFilter) ([]model.WebhookDelivery, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	w, ok := st.lookupWebhook(webhookID)
+	if !ok {
+		return nil, model.ErrWebhookNotFound
+	}
+
+	deliveries := []model.WebhookDelivery{}
+	for _, d := range st.deliveries {
+		if d.WebhookID == w.ID && (filter.Status == "" || d.Status == filter.Status) {
+			deliveries = append(deliveries, *d)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].MessageID > deliveries[j].MessageID
+	})
+
+	if filter.Offset >= len(deliveries) {
+		return []model.WebhookDelivery{}, nil
+	}
+	deliveries = deliveries[filter.Offset:]
+	if len(deliveries) > filter.Limit {
+		deliveries = deliveries[:filter.Limit]
+	}
+	return deliveries, nil
+}
+
+// GetWebhookDelivery retrieves a delivery of a webhook with its attempt log
+func (st *Store) GetWebhookDelivery(ctx context.Context, webhookID, deliveryID string) (*model.WebhookDeliveryDetails, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	d, ok := st.lookupDelivery(webhookID, deliveryID)
+	if !ok {
+		return nil, model.ErrWebhookDeliveryNotFound
+	}
+	details := &model.WebhookDeliveryDetails{WebhookDelivery: *d, AttemptLog: []model.WebhookAttempt{}}
+	details.AttemptLog = append(details.AttemptLog, st.attempts[d.ID]...)
+	return details, nil
+}
+
+// RetryWebhookDelivery makes a delivery pending again
+func (st *Store) RetryWebhookDelivery(ctx context.Context, webhookID, deliveryID string, at time.Time) (*model.WebhookDelivery, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	d, ok := st.lookupDelivery(webhookID, deliveryID)
+	if !ok {
+		return nil, model.ErrWebhookDeliveryNotFound
+	}
+	d.Status = model.WebhookDeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = &at
+	d.UpdatedAt = time.Now()
+	retried := *d
+	return &retried, nil
+}
+
+// PurgeWebhookDeliveries removes old finished deliveries
+func (st *Store) PurgeWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var deleted int64
+	for id, d := range st.deliveries {
+		if d.Status != model.WebhookDeliveryPending && d.UpdatedAt.Before(before) {
+			delete(st.deliveries, id)
+			delete(st.attempts, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (st *Store) lookupWebhook(webhookID string) (model.Webhook, bool) {
+	id, err := uuid.Parse(webhookID)
+	if err != nil {
+		return model.Webhook{}, false
+	}
+	w, ok := st.webhooks[id]
+	return copyWebhook(w), ok
+}
+
+func (st *Store) lookupDelivery(webhookID, deliveryID string) (*model.WebhookDelivery, bool) {
+	id, err := uuid.Parse(deliveryID)
+	if err != nil {
+		return nil, false
+	}
+	w, err := uuid.Parse(webhookID)
+	if err != nil {
+		return nil, false
+	}
+	d, ok := st.deliveries[id]
+	if !ok || d.WebhookID != w {
+		return nil, false
+	}
+	return d, true
+}
+
+func (st *Store) outboxMessage(id int64) model.OutboxMessage {
+	if entry := st.outboxEntry(id); entry != nil {
+		return entry.message
+	}
+	return model.OutboxMessage{ID: id}
+}
+
+// copyWebhook copies the slices of a webhook so callers cannot modify the stored value
+func copyWebhook(w model.Webhook) model.Webhook {
+	w.Events = append([]model.ActivityType{}, w.Events...)
+	w.EventTypes = append([]string{}, w.EventTypes...)
+	return w
+}
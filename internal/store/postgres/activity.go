@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// activityChannel is the NOTIFY channel written by the notify_session_activity trigger
+const activityChannel = "session_activity"
+
+// ActivityListener receives session activity from every replica through Postgres
+// LISTEN/NOTIFY. It holds its own connection outside the pool, since a listening
+// connection cannot be shared.
+type ActivityListener struct {
+	dsn string
+}
+
+var _ model.ActivityFeed = (*ActivityListener)(nil)
+
+// NewActivityListener creates a listener connecting with the given connection string
+func NewActivityListener(dsn string) *ActivityListener {
+	return &ActivityListener{dsn: dsn}
+}
+
+// ListenActivity calls fn for each notification until ctx is cancelled. Lost
+// connections are re-established automatically; activity committed while
+// disconnected is not delivered.
+func (l *ActivityListener) ListenActivity(ctx context.Context, fn func(model.Activity)) error {
+	listener := pq.NewListener(l.dsn, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Session activity listener: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(activityChannel); err != nil {
+		return err
+	}
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ping.C:
+			go listener.Ping()
+		case n := <-listener.Notify:
+			// A nil notification signals a reconnect
+			if n == nil {
+				continue
+			}
+			var activity model.Activity
+			if err := json.Unmarshal([]byte(n.Extra), &activity); err != nil {
+				log.Printf("Session activity listener: invalid payload %q: %v", n.Extra, err)
+				continue
+			}
+			fn(activity)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// SessionAnalytics aggregates the matching sessions by the bucket of their start time.
+// ROLLUP adds the totals as the row whose bucket is NULL; percentiles cannot be merged
+// from the buckets, so they are computed by the same pass.
+func (st *Store) SessionAnalytics(ctx context.Context, q model.SessionAnalyticsQuery) (*model.SessionAnalytics, error) {
+	var result *model.SessionAnalytics
+	err := st.readScoped(ctx, q.Filter.Access, func(db queryer) error {
+		var err error
+		result, err = sessionAnalytics(ctx, db, q)
+		return err
+	})
+	return result, err
+}
+
+func sessionAnalytics(ctx context.Context, db queryer, q model.SessionAnalyticsQuery) (*model.SessionAnalytics, error) {
+	var qb queryBuilder
+	bucketed := analyticsBucketed(&qb, q)
+	failed := make([]string, 0, len(model.FailedSessionStatuses()))
+	for _, status := range model.FailedSessionStatuses() {
+		failed = append(failed, qb.bind(status))
+	}
+
+	query := `WITH bucketed AS (` + bucketed + `)
+		SELECT bucket, COUNT(*),
+			COUNT(*) FILTER (WHERE status = 'completed'),
+			COUNT(*) FILTER (WHERE status IN (` + strings.Join(failed, ", ") + `)),
+			COUNT(duration), AVG(duration),
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY duration),
+			percentile_cont(0.95) WITHIN GROUP (ORDER BY duration),
+			percentile_cont(0.99) WITHIN GROUP (ORDER BY duration)
+		FROM bucketed
+		GROUP BY ROLLUP (bucket)
+		ORDER BY bucket NULLS FIRST`
+
+	rows, err := db.QueryContext(ctx, query, qb.args...)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	result := &model.SessionAnalytics{Totals: model.AnalyticsBucket{Dispositions: map[string]int64{}}, Buckets: []model.AnalyticsBucket{}}
+	index := make(map[time.Time]int)
+	for rows.Next() {
+		var bucketStart *time.Time
+		b := model.AnalyticsBucket{Dispositions: map[string]int64{}}
+		d := &b.Duration
+		if err := rows.Scan(&bucketStart, &b.Total, &b.Completed, &b.Failed, &d.Count, &d.Avg, &d.P50, &d.P95, &d.P99); err != nil {
+			return nil, err
+		}
+		b.SetSuccessRate()
+
+		if bucketStart == nil {
+			result.Totals = b
+			continue
+		}
+		start := localBucket(*bucketStart, q.Location)
+		b.Start = &start
+		index[start] = len(result.Buckets)
+		result.Buckets = append(result.Buckets, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// Sessions still running have no disposition yet and are left out of the breakdown
+	qb = queryBuilder{}
+	bucketed = analyticsBucketed(&qb, q)
+	query = `WITH bucketed AS (` + bucketed + `)
+		SELECT bucket, disposition, COUNT(*)
+		FROM bucketed
+		WHERE disposition IS NOT NULL
+		GROUP BY GROUPING SETS ((bucket, disposition), (disposition))`
+
+	rows, err = db.QueryContext(ctx, query, qb.args...)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucketStart *time.Time
+		var disposition string
+		var count int64
+		if err := rows.Scan(&bucketStart, &disposition, &count); err != nil {
+			return nil, err
+		}
+		if bucketStart == nil {
+			result.Totals.Dispositions[disposition] = count
+			continue
+		}
+		if i, ok := index[localBucket(*bucketStart, q.Location)]; ok {
+			result.Buckets[i].Dispositions[disposition] = count
+		}
+	}
+	return result, rows.Err()
+}
+
+// analyticsBucketed selects the matching sessions with the local start of their bucket.
+// The bucket is truncated on the wall clock of the requested time zone so days and
+// weeks follow it across DST changes.
+func analyticsBucketed(qb *queryBuilder, q model.SessionAnalyticsQuery) string {
+	query := fmt.Sprintf(`
+		SELECT date_trunc(%s, started_at AT TIME ZONE %s) AS bucket,
+			status, disposition, EXTRACT(EPOCH FROM (ended_at - started_at))::float8 AS duration
+		FROM sessions
+		WHERE %s`,
+		qb.bind(string(q.Interval)), qb.bind(q.Location.String()), qb.whereClause(q.Filter))
+
+	keys := make([]string, 0, len(q.Metadata))
+	for key := range q.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		query += fmt.Sprintf(" AND initial_metadata ->> %s = %s", qb.bind(key), qb.bind(q.Metadata[key]))
+	}
+	return query
+}
+
+// localBucket reads a bucket start returned as a wall clock time in loc
+func localBucket(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+}
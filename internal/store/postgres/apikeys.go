@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+const apiKeyColumns = `id, name, prefix, key_hash, service_account_id, scopes, allowed_cidrs, expires_at, last_used_at, created_by, created_at, updated_at, org_id`
+
+func scanAPIKey(row scanner, k *model.APIKey) error {
+	var scopes []string
+	err := row.Scan(
+		&k.ID, &k.Name, &k.Prefix, &k.KeyHash, &k.ServiceAccountID, pq.Array(&scopes), pq.Array(&k.AllowedCIDRs),
+		&k.ExpiresAt, &k.LastUsedAt, &k.CreatedBy, &k.CreatedAt, &k.UpdatedAt, &k.OrgID,
+	)
+	k.Scopes = toPermissions(scopes)
+	if k.AllowedCIDRs == nil {
+		k.AllowedCIDRs = []string{}
+	}
+	return err
+}
+
+// CreateAPIKey inserts a new API key, and its service account when one is given
+func (st *Store) CreateAPIKey(ctx context.Context, k *model.APIKey, serviceAccount *model.User) error {
+	return st.inTx(ctx, func(tx *sql.Tx) error {
+		if serviceAccount != nil {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO users (id, email, password, role, org_id, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+				serviceAccount.ID, serviceAccount.Email, serviceAccount.Password, serviceAccount.Role,
+				serviceAccount.OrgID, serviceAccount.CreatedAt, serviceAccount.UpdatedAt)
+			if err != nil {
+				return translateError(err, nil)
+			}
+		}
+
+		query := `
+			INSERT INTO api_keys (id, name, prefix, key_hash, service_account_id, scopes, allowed_cidrs, expires_at, created_by, created_at, updated_at, org_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			RETURNING ` + apiKeyColumns
+
+		err := scanAPIKey(tx.QueryRowContext(ctx,
+			query,
+			k.ID, k.Name, k.Prefix, k.KeyHash, k.ServiceAccountID, pq.Array(permissionStrings(k.Scopes)), pq.Array(k.AllowedCIDRs),
+			k.ExpiresAt, k.CreatedBy, k.CreatedAt, k.UpdatedAt, k.OrgID,
+		), k)
+		return translateError(err, nil)
+	})
+}
+
+// GetAPIKey retrieves an API key by ID
+func (st *Store) GetAPIKey(ctx context.Context, keyID string) (*model.APIKey, error) {
+	var k model.APIKey
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1`
+
+	if err := scanAPIKey(st.db.QueryRowContext(ctx, query, keyID), &k); err != nil {
+		return nil, translateError(err, model.ErrAPIKeyNotFound)
+	}
+	return &k, nil
+}
+
+// GetAPIKeyByPrefix retrieves an API key by its prefix
+func (st *Store) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*model.APIKey, error) {
+	var k model.APIKey
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE prefix = $1`
+
+	if err := scanAPIKey(st.db.QueryRowContext(ctx, query, prefix), &k); err != nil {
+		return nil, translateError(err, model.ErrAPIKeyNotFound)
+	}
+	return &k, nil
+}
+
+// ListAPIKeys retrieves the API keys of an organization, oldest first
+func (st *Store) ListAPIKeys(ctx context.Context, orgID string) ([]model.APIKey, error) {
+	rows, err := st.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE org_id = $1 ORDER BY created_at, id`, orgID)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	keys := []model.APIKey{}
+	for rows.Next() {
+		var k model.APIKey
+		if err := scanAPIKey(rows, &k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// UpdateAPIKey stores the changed fields of an API key
+func (st *Store) UpdateAPIKey(ctx context.Context, k *model.APIKey) error {
+	query := `
+		UPDATE api_keys
+		SET name = $1, scopes = $2, allowed_cidrs = $3, expires_at = $4, updated_at = $5
+		WHERE id = $6
+		RETURNING ` + apiKeyColumns
+
+	err := scanAPIKey(st.db.QueryRowContext(ctx,
+		query,
+		k.Name, pq.Array(permissionStrings(k.Scopes)), pq.Array(k.AllowedCIDRs), k.ExpiresAt, k.UpdatedAt, k.ID,
+	), k)
+	return translateError(err, model.ErrAPIKeyNotFound)
+}
+
+// DeleteAPIKey removes an API key
+func (st *Store) DeleteAPIKey(ctx context.Context, keyID string) error {
+	result, err := st.db.ExecContext(ctx, `DELETE FROM api_keys WHERE id = $1`, keyID)
+	if err != nil {
+		return translateError(err, nil)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return model.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// TouchAPIKey records when an API key was last used
+func (st *Store) TouchAPIKey(ctx context.Context, keyID string, at time.Time) error {
+	_, err := st.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = $1 WHERE id = $2`, at, keyID)
+	return translateError(err, nil)
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+const cdrColumns = `id, session_id, caller_id, callee_id, started_at, answered_at, ended_at, duration, billsec, status, disposition, external_source, external_id, initial_metadata, created_at`
+
+func scanCDR(row scanner, c *model.CDR) error {
+	return row.Scan(
+		&c.ID, &c.SessionID, &c.CallerID, &c.CalleeID,
+		&c.StartedAt, &c.AnsweredAt, &c.EndedAt, &c.Duration, &c.Billsec,
+		&c.Status, &c.Disposition, &c.ExternalSource, &c.ExternalID,
+		&c.Metadata, &c.CreatedAt,
+	)
+}
+
+// insertCDR stores the record of a session; q must be the transaction that ends it
+func insertCDR(ctx context.Context, q queryer, c *model.CDR) error {
+	query := `
+		INSERT INTO cdrs (session_id, caller_id, callee_id, started_at, answered_at, ended_at, duration, billsec, status, disposition, external_source, external_id, initial_metadata, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id`
+
+	err := q.QueryRowContext(ctx, query,
+		c.SessionID, c.CallerID, c.CalleeID,
+		c.StartedAt, c.AnsweredAt, c.EndedAt, c.Duration, c.Billsec,
+		c.Status, c.Disposition, c.ExternalSource, c.ExternalID,
+		c.Metadata, c.CreatedAt,
+	).Scan(&c.ID)
+	return translateError(err, nil)
+}
+
+// ExportCDRs streams the records of the sessions that ended in the query's range
+func (st *Store) ExportCDRs(ctx context.Context, query model.CDRQuery, fn func(*model.CDR) error) error {
+	var qb queryBuilder
+	stmt := `SELECT ` + cdrColumns + ` FROM cdrs WHERE TRUE`
+	if !query.Access.Unrestricted() {
+		stmt += ` AND session_id IN (SELECT id FROM sessions WHERE ` + qb.accessClause(query.Access) + `)`
+	}
+	if !query.From.IsZero() {
+		stmt += ` AND ended_at >= ` + qb.bind(query.From)
+	}
+	if !query.To.IsZero() {
+		stmt += ` AND ended_at < ` + qb.bind(query.To)
+	}
+	stmt += ` ORDER BY ended_at, id`
+
+	return st.streamCursor(ctx, query.Access, "cdr_export", stmt, qb.args, func(rows *sql.Rows) error {
+		var cdr model.CDR
+		if err := scanCDR(rows, &cdr); err != nil {
+			return err
+		}
+		return fn(&cdr)
+	})
+}
+
+// ListUnwrittenCDRs returns the oldest records not yet written to a CDR file, by ID
+func (st *Store) ListUnwrittenCDRs(ctx context.Context, limit int) ([]model.CDR, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT `+cdrColumns+` FROM cdrs
+		WHERE written_at IS NULL
+		ORDER BY id
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	cdrs := []model.CDR{}
+	for rows.Next() {
+		var cdr model.CDR
+		if err := scanCDR(rows, &cdr); err != nil {
+			return nil, err
+		}
+		cdrs = append(cdrs, cdr)
+	}
+	return cdrs, rows.Err()
+}
+
+// MarkCDRsWritten records that records were written to a CDR file
+func (st *Store) MarkCDRsWritten(ctx context.Context, ids []int64, at time.Time) error {
+	_, err := st.db.ExecContext(ctx,
+		`UPDATE cdrs SET written_at = $1 WHERE id = ANY($2) AND written_at IS NULL`, at, pq.Array(ids))
+	return translateError(err, nil)
+}
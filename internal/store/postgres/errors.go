@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// constraintErrors maps named table constraints to the domain errors they enforce
+var constraintErrors = map[string]*model.Error{
+	"valid_session_times":            model.ErrInvalidTimeRange,
+	"valid_event_time":               model.ErrEventTimeOutOfRange,
+	"users_email_key":                model.ErrUserAlreadyExists,
+	"users_role_fkey":                model.ErrRoleNotFound,
+	"roles_pkey":                     model.ErrRoleAlreadyExists,
+	"users_org_id_fkey":              model.ErrOrganizationNotFound,
+	"session_events_session_id_fkey": model.ErrSessionNotFound,
+	"idx_sessions_external_id":       model.ErrExternalIDConflict,
+}
+
+// translateError converts driver errors into domain errors, keeping the driver error
+// as the cause. notFound is returned for sql.ErrNoRows when it is non-nil.
+func translateError(err error, notFound *model.Error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) && notFound != nil {
+		return notFound
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	if kind, ok := constraintErrors[pqErr.Constraint]; ok {
+		return model.WrapError(kind, err)
+	}
+
+	switch pqErr.Code.Class() {
+	case "22": // data_exception, e.g. a malformed UUID or timestamp
+		if pqErr.Code.Name() == "invalid_text_representation" {
+			return model.WrapError(model.ErrInvalidID, err)
+		}
+		return model.WrapError(model.ErrInvalidRequest, err)
+	case "23": // integrity_constraint_violation
+		if pqErr.Code.Name() == "unique_violation" {
+			return model.WrapError(model.ErrConflict, err)
+		}
+		return model.WrapError(model.ErrInvalidRequest, err)
+	}
+
+	return err
+}
@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+const eventColumns = `id, session_id, event_type, event_time, metadata, created_at, org_id`
+
+func scanEvent(row scanner, e *model.SessionEvent) error {
+	return row.Scan(&e.ID, &e.SessionID, &e.EventType, &e.EventTime, &e.Metadata, &e.CreatedAt, &e.OrgID)
+}
+
+// CreateEvent inserts a new session event
+func (st *Store) CreateEvent(ctx context.Context, e *model.SessionEvent) error {
+	return st.inOrgTx(ctx, e.OrgID, func(tx *sql.Tx) error {
+		if err := insertEvent(ctx, tx, e); err != nil {
+			return err
+		}
+		return insertEventOutboxMessages(ctx, tx, *e)
+	})
+}
+
+func insertEvent(ctx context.Context, q queryer, e *model.SessionEvent) error {
+	query := `
+		INSERT INTO session_events (id, session_id, event_type, event_time, metadata, created_at, org_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + eventColumns
+
+	err := scanEvent(q.QueryRowContext(ctx,
+		query,
+		e.ID, e.SessionID, e.EventType, e.EventTime, e.Metadata, e.CreatedAt, e.OrgID,
+	), e)
+	return translateError(err, nil)
+}
+
+// eventInsertChunk keeps multi-row inserts well below the 65535 parameter limit
+const eventInsertChunk = 1000
+
+// CreateEvents inserts several events in one transaction using multi-row inserts
+func (st *Store) CreateEvents(ctx context.Context, events []model.SessionEvent) error {
+	orgIDs := make([]uuid.UUID, len(events))
+	for i := range events {
+		orgIDs[i] = events[i].OrgID
+	}
+	return st.inOrgTx(ctx, commonOrganization(orgIDs...), func(tx *sql.Tx) error {
+		for start := 0; start < len(events); start += eventInsertChunk {
+			end := start + eventInsertChunk
+			if end > len(events) {
+				end = len(events)
+			}
+
+			var qb queryBuilder
+			rows := make([]string, 0, end-start)
+			for _, e := range events[start:end] {
+				rows = append(rows, fmt.Sprintf("(%s, %s, %s, %s, %s, %s, %s)",
+					qb.bind(e.ID), qb.bind(e.SessionID), qb.bind(e.EventType),
+					qb.bind(e.EventTime), qb.bind(e.Metadata), qb.bind(e.CreatedAt), qb.bind(e.OrgID)))
+			}
+
+			query := `INSERT INTO session_events (` + eventColumns + `) VALUES ` + strings.Join(rows, ", ")
+			if _, err := tx.ExecContext(ctx, query, qb.args...); err != nil {
+				return translateError(err, nil)
+			}
+			if err := insertEventOutboxMessages(ctx, tx, events[start:end]...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetEvent retrieves an event by ID
+func (st *Store) GetEvent(ctx context.Context, eventID string) (*model.SessionEvent, error) {
+	var event model.SessionEvent
+	query := `SELECT ` + eventColumns + ` FROM session_events WHERE id = $1`
+
+	err := st.scoped(ctx, uuid.Nil, func(q queryer) error {
+		return scanEvent(q.QueryRowContext(ctx, query, eventID), &event)
+	})
+	if err != nil {
+		return nil, translateError(err, model.ErrEventNotFound)
+	}
+
+	return &event, nil
+}
+
+// ListEvents retrieves the events of a session in chronological order
+func (st *Store) ListEvents(ctx context.Context, sessionID string) ([]model.SessionEvent, error) {
+	query := `SELECT ` + eventColumns + ` FROM session_events WHERE session_id = $1 ORDER BY event_time ASC`
+
+	events := []model.SessionEvent{}
+	err := st.scoped(ctx, uuid.Nil, func(q queryer) error {
+		rows, err := q.QueryContext(ctx, query, sessionID)
+		if err != nil {
+			return translateError(err, nil)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var event model.SessionEvent
+			if err := scanEvent(rows, &event); err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,223 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// exportBatchSize is the number of rows fetched from an export cursor at a time
+const exportBatchSize = 1000
+
+// ExportSessions streams the matching sessions through a server-side cursor
+func (st *Store) ExportSessions(ctx context.Context, filter model.SessionFilter, fn func(*model.Session) error) error {
+	var qb queryBuilder
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE ` + qb.whereClause(filter) +
+		` ORDER BY ` + orderClause(exportSort(filter))
+
+	return st.streamCursor(ctx, filter.Access, "session_export", query, qb.args, func(rows *sql.Rows) error {
+		var session model.Session
+		if err := scanSession(rows, &session); err != nil {
+			return err
+		}
+		return fn(&session)
+	})
+}
+
+// ExportEvents streams the events of the matching sessions through a server-side cursor.
+// The sessions are numbered in sort order first so the join needs no session columns.
+func (st *Store) ExportEvents(ctx context.Context, filter model.SessionFilter, fn func(*model.SessionEvent) error) error {
+	var qb queryBuilder
+	query := `
+		SELECT ` + qualify("e", eventColumns) + `
+		FROM (
+			SELECT id, row_number() OVER (ORDER BY ` + orderClause(exportSort(filter)) + `) AS position
+			FROM sessions WHERE ` + qb.whereClause(filter) + `
+		) s
+		JOIN session_events e ON e.session_id = s.id
+		ORDER BY s.position, e.event_time, e.id`
+
+	return st.streamCursor(ctx, filter.Access, "event_export", query, qb.args, func(rows *sql.Rows) error {
+		var event model.SessionEvent
+		if err := scanEvent(rows, &event); err != nil {
+			return err
+		}
+		return fn(&event)
+	})
+}
+
+func exportSort(filter model.SessionFilter) []model.SortField {
+	if len(filter.Sort) == 0 {
+		return model.DefaultSessionSort
+	}
+	return filter.Sort
+}
+
+// qualify prefixes every column of a column list with a table alias
+func qualify(alias, columns string) string {
+	parts := strings.Split(columns, ", ")
+	for i, column := range parts {
+		parts[i] = alias + "." + column
+	}
+	return strings.Join(parts, ", ")
+}
+
+// streamCursor declares a cursor for the query in a read-only transaction limited to
+// the organization of access and fetches it in batches, calling row for every row until
+// the cursor is exhausted
+func (st *Store) streamCursor(ctx context.Context, access model.SessionAccess, name, query string, args []interface{}, row func(*sql.Rows) error) error {
+	tx, err := st.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return translateError(err, nil)
+	}
+	defer tx.Rollback()
+
+	if err := st.setOrganization(ctx, tx, access.OrgID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DECLARE `+name+` NO SCROLL CURSOR FOR `+query, args...); err != nil {
+		return translateError(err, nil)
+	}
+
+	fetch := fmt.Sprintf(`FETCH %d FROM %s`, exportBatchSize, name)
+	for {
+		fetched, err := fetchBatch(ctx, tx, fetch, row)
+		if err != nil {
+			return err
+		}
+		if fetched < exportBatchSize {
+			break
+		}
+	}
+
+	return translateError(tx.Commit(), nil)
+}
+
+func fetchBatch(ctx context.Context, tx *sql.Tx, fetch string, row func(*sql.Rows) error) (int, error) {
+	rows, err := tx.QueryContext(ctx, fetch)
+	if err != nil {
+		return 0, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	fetched := 0
+	for rows.Next() {
+		fetched++
+		if err := row(rows); err != nil {
+			return fetched, err
+		}
+	}
+	return fetched, translateError(rows.Err(), nil)
+}
+
+const exportJobColumns = `id, user_id, all_organizations, format, dataset, query, status, attempts, row_count, size_bytes, error, created_at, started_at, finished_at, expires_at`
+
+func scanExportJob(row scanner, j *model.ExportJob) error {
+	return row.Scan(
+		&j.ID, &j.UserID, &j.AllOrganizations, &j.Format, &j.Dataset, &j.Query,
+		&j.Status, &j.Attempts, &j.RowCount, &j.SizeBytes, &j.Error,
+		&j.CreatedAt, &j.StartedAt, &j.FinishedAt, &j.ExpiresAt,
+	)
+}
+
+// CreateExportJob inserts a new export job
+func (st *Store) CreateExportJob(ctx context.Context, j *model.ExportJob) error {
+	query := `
+		INSERT INTO export_jobs (id, user_id, all_organizations, format, dataset, query, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + exportJobColumns
+
+	err := scanExportJob(st.db.QueryRowContext(ctx, query,
+		j.ID, j.UserID, j.AllOrganizations, j.Format, j.Dataset, j.Query, j.Status, j.CreatedAt,
+	), j)
+	return translateError(err, nil)
+}
+
+// GetExportJob retrieves an export job by ID
+func (st *Store) GetExportJob(ctx context.Context, jobID string) (*model.ExportJob, error) {
+	var job model.ExportJob
+	query := `SELECT ` + exportJobColumns + ` FROM export_jobs WHERE id = $1`
+
+	err := scanExportJob(st.db.QueryRowContext(ctx, query, jobID), &job)
+	if err != nil {
+		return nil, translateError(err, model.ErrExportJobNotFound)
+	}
+
+	return &job, nil
+}
+
+// ClaimExportJobs leases the oldest runnable jobs, skipping those another replica holds
+func (st *Store) ClaimExportJobs(ctx context.Context, now, leaseUntil time.Time, limit int) ([]model.ExportJob, error) {
+	query := `
+		WITH due AS (
+			SELECT id FROM export_jobs
+			WHERE status = 'pending' OR (status = 'running' AND lease_until <= $1)
+			ORDER BY created_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE export_jobs j
+		SET status = 'running', attempts = j.attempts + 1, lease_until = $2, started_at = $1
+		FROM due WHERE j.id = due.id
+		RETURNING ` + qualify("j", exportJobColumns)
+
+	rows, err := st.db.QueryContext(ctx, query, now, leaseUntil, limit)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	var jobs []model.ExportJob
+	for rows.Next() {
+		var job model.ExportJob
+		if err := scanExportJob(rows, &job); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// FinishExportJob stores the outcome of a claimed job and releases its lease
+func (st *Store) FinishExportJob(ctx context.Context, j *model.ExportJob) error {
+	query := `
+		UPDATE export_jobs
+		SET status = $1, row_count = $2, size_bytes = $3, error = $4, finished_at = $5, expires_at = $6, lease_until = NULL
+		WHERE id = $7`
+
+	result, err := st.db.ExecContext(ctx, query,
+		j.Status, j.RowCount, j.SizeBytes, j.Error, j.FinishedAt, j.ExpiresAt, j.ID)
+	if err != nil {
+		return translateError(err, nil)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return model.ErrExportJobNotFound
+	}
+	return nil
+}
+
+// DeleteExpiredExportJobs removes expired jobs and returns them
+func (st *Store) DeleteExpiredExportJobs(ctx context.Context, before time.Time) ([]model.ExportJob, error) {
+	query := `DELETE FROM export_jobs WHERE expires_at < $1 RETURNING ` + exportJobColumns
+
+	rows, err := st.db.QueryContext(ctx, query, before)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	var jobs []model.ExportJob
+	for rows.Next() {
+		var job model.ExportJob
+		if err := scanExportJob(rows, &job); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
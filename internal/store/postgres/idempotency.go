@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// ReserveIdempotencyKey claims a key, taking over expired or abandoned records
+func (st *Store) ReserveIdempotencyKey(ctx context.Context, r *model.IdempotencyRecord, staleBefore time.Time) (*model.IdempotencyRecord, error) {
+	query := `
+		INSERT INTO idempotency_keys (user_id, key, request_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash, status_code = NULL, response_body = NULL,
+			created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+			OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at < $6)
+		RETURNING key`
+
+	var key string
+	err := st.db.QueryRowContext(ctx,
+		query,
+		r.UserID, r.Key, r.RequestHash, r.CreatedAt, r.ExpiresAt, staleBefore,
+	).Scan(&key)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, translateError(err, nil)
+	}
+
+	// The key is held by a live record
+	var existing model.IdempotencyRecord
+	var statusCode sql.NullInt64
+	err = st.db.QueryRowContext(ctx, `
+		SELECT user_id, key, request_hash, status_code, response_body, created_at, expires_at
+		FROM idempotency_keys WHERE user_id = $1 AND key = $2`,
+		r.UserID, r.Key,
+	).Scan(&existing.UserID, &existing.Key, &existing.RequestHash, &statusCode, &existing.ResponseBody, &existing.CreatedAt, &existing.ExpiresAt)
+	if err != nil {
+		// The record vanished between the two statements; let the client retry
+		return nil, translateError(err, model.ErrIdempotencyKeyInUse)
+	}
+	existing.StatusCode = int(statusCode.Int64)
+
+	return &existing, nil
+}
+
+// RenewIdempotencyKey extends the lock of an unfinished key
+func (st *Store) RenewIdempotencyKey(ctx context.Context, userID, key string, at time.Time) error {
+	_, err := st.db.ExecContext(ctx,
+		`UPDATE idempotency_keys SET created_at = $1 WHERE user_id = $2 AND key = $3 AND status_code IS NULL`,
+		at, userID, key)
+	return translateError(err, nil)
+}
+
+// CompleteIdempotencyKey stores the response of the request holding the key
+func (st *Store) CompleteIdempotencyKey(ctx context.Context, userID, key string, statusCode int, body []byte) error {
+	_, err := st.db.ExecContext(ctx,
+		`UPDATE idempotency_keys SET status_code = $1, response_body = $2 WHERE user_id = $3 AND key = $4`,
+		statusCode, body, userID, key)
+	return translateError(err, nil)
+}
+
+// ReleaseIdempotencyKey drops an unfinished key
+func (st *Store) ReleaseIdempotencyKey(ctx context.Context, userID, key string) error {
+	_, err := st.db.ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND status_code IS NULL`,
+		userID, key)
+	return translateError(err, nil)
+}
+
+// DeleteExpiredIdempotencyKeys removes records that expired before the given time
+func (st *Store) DeleteExpiredIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	result, err := st.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, translateError(err, nil)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// UpdateSessionIdentities stores the normalized IDs of sessions with a single statement
+func (st *Store) UpdateSessionIdentities(ctx context.Context, sessions []model.Session) error {
+	if len(sessions) == 0 {
+		return nil
+	}
+	columns := make([][]string, 7)
+	for _, s := range sessions {
+		for i, value := range []string{
+			s.ID.String(),
+			s.CallerID, string(s.CallerIDType), string(s.CallerNumberType),
+			s.CalleeID, string(s.CalleeIDType), string(s.CalleeNumberType),
+		} {
+			columns[i] = append(columns[i], value)
+		}
+	}
+
+	return st.scoped(ctx, uuid.Nil, func(q queryer) error {
+		_, err := q.ExecContext(ctx, `
+			UPDATE sessions s SET
+				caller_id = v.caller_id, caller_id_type = v.caller_id_type, caller_number_type = v.caller_number_type,
+				callee_id = v.callee_id, callee_id_type = v.callee_id_type, callee_number_type = v.callee_number_type
+			FROM unnest($1::uuid[], $2::text[], $3::text[], $4::text[], $5::text[], $6::text[], $7::text[])
+				AS v(id, caller_id, caller_id_type, caller_number_type, callee_id, callee_id_type, callee_number_type)
+			WHERE s.id = v.id`,
+			pq.Array(columns[0]), pq.Array(columns[1]), pq.Array(columns[2]), pq.Array(columns[3]),
+			pq.Array(columns[4]), pq.Array(columns[5]), pq.Array(columns[6]))
+		return translateError(err, nil)
+	})
+}
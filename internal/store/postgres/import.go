@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// importChunk is the number of sessions inserted per statement; their events and
+// metrics stay well below the 65535 parameter limit as well
+const importChunk = 500
+
+// ImportSessions inserts imported sessions with multi-row inserts, letting the unique
+// external ID index skip sessions that were imported before
+func (st *Store) ImportSessions(ctx context.Context, sessions []model.ImportedSession) (int, error) {
+	orgIDs := make([]uuid.UUID, len(sessions))
+	for i := range sessions {
+		orgIDs[i] = sessions[i].Session.OrgID
+	}
+	imported := 0
+	err := st.inOrgTx(ctx, commonOrganization(orgIDs...), func(tx *sql.Tx) error {
+		for start := 0; start < len(sessions); start += importChunk {
+			end := start + importChunk
+			if end > len(sessions) {
+				end = len(sessions)
+			}
+
+			n, err := importSessionChunk(ctx, tx, sessions[start:end])
+			if err != nil {
+				return err
+			}
+			imported += n
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return imported, nil
+}
+
+func importSessionChunk(ctx context.Context, tx *sql.Tx, sessions []model.ImportedSession) (int, error) {
+	var qb queryBuilder
+	rows := make([]string, len(sessions))
+	for i := range sessions {
+		s := &sessions[i].Session
+		rows[i] = fmt.Sprintf("(%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s, true)",
+			qb.bind(s.ID), qb.bind(s.StartedAt), qb.bind(s.EndedAt), qb.bind(s.CallerID),
+			qb.bind(s.CalleeID), qb.bind(s.Status), qb.bind(s.InitialMetadata), qb.bind(s.Disposition),
+			qb.bind(s.ExternalSource), qb.bind(s.ExternalID), qb.bind(s.CreatedAt), qb.bind(s.UpdatedAt),
+			qb.bind(s.CallerIDRaw), qb.bind(s.CallerIDType), qb.bind(s.CallerNumberType),
+			qb.bind(s.CalleeIDRaw), qb.bind(s.CalleeIDType), qb.bind(s.CalleeNumberType),
+			qb.bind(s.CreatedBy), qb.bind(s.Team), qb.bind(s.OrgID))
+	}
+	query := `
+		INSERT INTO sessions (` + sessionColumns + `, imported)
+		VALUES ` + strings.Join(rows, ", ") + `
+		ON CONFLICT (org_id, external_source, external_id) WHERE external_id IS NOT NULL DO NOTHING
+		RETURNING id`
+
+	result, err := tx.QueryContext(ctx, query, qb.args...)
+	if err != nil {
+		return 0, translateError(err, nil)
+	}
+	inserted := make(map[uuid.UUID]bool, len(sessions))
+	for result.Next() {
+		var id uuid.UUID
+		if err := result.Scan(&id); err != nil {
+			result.Close()
+			return 0, err
+		}
+		inserted[id] = true
+	}
+	result.Close()
+	if err := result.Err(); err != nil {
+		return 0, translateError(err, nil)
+	}
+
+	qb = queryBuilder{}
+	var events, metrics []string
+	for i := range sessions {
+		if !inserted[sessions[i].Session.ID] {
+			continue
+		}
+		for _, e := range sessions[i].Events {
+			events = append(events, fmt.Sprintf("(%s, %s, %s, %s, %s, %s, %s, true)",
+				qb.bind(e.ID), qb.bind(e.SessionID), qb.bind(e.EventType),
+				qb.bind(e.EventTime), qb.bind(e.Metadata), qb.bind(e.CreatedAt), qb.bind(e.OrgID)))
+		}
+	}
+	if len(events) > 0 {
+		query := `INSERT INTO session_events (` + eventColumns + `, imported) VALUES ` + strings.Join(events, ", ")
+		if _, err := tx.ExecContext(ctx, query, qb.args...); err != nil {
+			return 0, translateError(err, nil)
+		}
+	}
+
+	qb = queryBuilder{}
+	for i := range sessions {
+		m := sessions[i].Metrics
+		if m == nil || !inserted[sessions[i].Session.ID] {
+			continue
+		}
+		metrics = append(metrics, fmt.Sprintf("(%s, %s, %s, %s, %s, %s, %s, %s, %s, %s)",
+			qb.bind(m.SessionID), qb.bind(m.TimeToAnswer), qb.bind(m.TalkTime),
+			qb.bind(m.HoldTime), qb.bind(m.HoldCount), qb.bind(m.Transfers),
+			qb.bind(m.EventCount), qb.bind(m.AvgEventGap), qb.bind(m.MaxEventGap),
+			qb.bind(m.ComputedAt)))
+	}
+	if len(metrics) > 0 {
+		query := `INSERT INTO session_metrics (` + metricsColumns + `) VALUES ` + strings.Join(metrics, ", ")
+		if _, err := tx.ExecContext(ctx, query, qb.args...); err != nil {
+			return 0, translateError(err, nil)
+		}
+	}
+
+	return len(inserted), nil
+}
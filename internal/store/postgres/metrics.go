@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+const metricsColumns = `session_id, time_to_answer_seconds, talk_time_seconds, hold_time_seconds, hold_count, transfer_count, event_count, avg_event_gap_seconds, max_event_gap_seconds, computed_at`
+
+func scanMetrics(row scanner, m *model.SessionMetrics) error {
+	return row.Scan(
+		&m.SessionID, &m.TimeToAnswer, &m.TalkTime,
+		&m.HoldTime, &m.HoldCount, &m.Transfers,
+		&m.EventCount, &m.AvgEventGap, &m.MaxEventGap,
+		&m.ComputedAt,
+	)
+}
+
+// upsertMetrics stores the metrics of a session, replacing earlier ones
+func upsertMetrics(ctx context.Context, q queryer, m *model.SessionMetrics) error {
+	query := `
+		INSERT INTO session_metrics (` + metricsColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (session_id) DO UPDATE SET
+			time_to_answer_seconds = EXCLUDED.time_to_answer_seconds,
+			talk_time_seconds = EXCLUDED.talk_time_seconds,
+			hold_time_seconds = EXCLUDED.hold_time_seconds,
+			hold_count = EXCLUDED.hold_count,
+			transfer_count = EXCLUDED.transfer_count,
+			event_count = EXCLUDED.event_count,
+			avg_event_gap_seconds = EXCLUDED.avg_event_gap_seconds,
+			max_event_gap_seconds = EXCLUDED.max_event_gap_seconds,
+			computed_at = EXCLUDED.computed_at`
+
+	_, err := q.ExecContext(ctx, query,
+		m.SessionID, m.TimeToAnswer, m.TalkTime,
+		m.HoldTime, m.HoldCount, m.Transfers,
+		m.EventCount, m.AvgEventGap, m.MaxEventGap,
+		m.ComputedAt,
+	)
+	return translateError(err, nil)
+}
+
+// GetSessionMetrics retrieves the metrics stored when a session ended
+func (st *Store) GetSessionMetrics(ctx context.Context, sessionID string) (*model.SessionMetrics, error) {
+	var metrics model.SessionMetrics
+	query := `SELECT ` + metricsColumns + ` FROM session_metrics WHERE session_id = $1`
+
+	err := scanMetrics(st.db.QueryRowContext(ctx, query, sessionID), &metrics)
+	if err != nil {
+		return nil, translateError(err, model.ErrNotFound)
+	}
+
+	return &metrics, nil
+}
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+const organizationColumns = `id, name, retention_days, allowed_event_types, created_at, updated_at`
+
+func scanOrganization(row scanner, o *model.Organization) error {
+	return row.Scan(&o.ID, &o.Name, &o.RetentionDays, pq.Array(&o.AllowedEventTypes), &o.CreatedAt, &o.UpdatedAt)
+}
+
+// ListOrganizations retrieves all organizations by name
+func (st *Store) ListOrganizations(ctx context.Context) ([]model.Organization, error) {
+	rows, err := st.db.QueryContext(ctx, `SELECT `+organizationColumns+` FROM organizations ORDER BY name`)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	orgs := []model.Organization{}
+	for rows.Next() {
+		var o model.Organization
+		if err := scanOrganization(rows, &o); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
+
+// GetOrganization retrieves an organization by ID
+func (st *Store) GetOrganization(ctx context.Context, orgID string) (*model.Organization, error) {
+	var o model.Organization
+	query := `SELECT ` + organizationColumns + ` FROM organizations WHERE id = $1`
+
+	if err := scanOrganization(st.db.QueryRowContext(ctx, query, orgID), &o); err != nil {
+		return nil, translateError(err, model.ErrOrganizationNotFound)
+	}
+	return &o, nil
+}
+
+// CreateOrganization inserts a new organization
+func (st *Store) CreateOrganization(ctx context.Context, o *model.Organization) error {
+	return insertOrganization(ctx, st.db, o)
+}
+
+func insertOrganization(ctx context.Context, q queryer, o *model.Organization) error {
+	query := `
+		INSERT INTO organizations (id, name, retention_days, allowed_event_types, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + organizationColumns
+
+	err := scanOrganization(q.QueryRowContext(ctx,
+		query,
+		o.ID, o.Name, o.RetentionDays, pq.Array(o.AllowedEventTypes), o.CreatedAt, o.UpdatedAt,
+	), o)
+	return translateError(err, nil)
+}
+
+// UpdateOrganization stores the name and settings of an organization
+func (st *Store) UpdateOrganization(ctx context.Context, o *model.Organization) error {
+	query := `
+		UPDATE organizations SET name = $1, retention_days = $2, allowed_event_types = $3, updated_at = $4
+		WHERE id = $5
+		RETURNING ` + organizationColumns
+
+	err := scanOrganization(st.db.QueryRowContext(ctx,
+		query,
+		o.Name, o.RetentionDays, pq.Array(o.AllowedEventTypes), o.UpdatedAt, o.ID,
+	), o)
+	return translateError(err, model.ErrOrganizationNotFound)
+}
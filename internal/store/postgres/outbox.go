@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+const outboxColumns = `id, type, session_id, event_id, event_type, data, created_at, org_id`
+
+func scanOutboxMessage(row scanner, m *model.OutboxMessage) error {
+	var eventType *string
+	err := row.Scan(&m.ID, &m.Type, &m.SessionID, &m.EventID, &eventType, &m.Data, &m.CreatedAt, &m.OrgID)
+	if eventType != nil {
+		m.EventType = *eventType
+	}
+	return err
+}
+
+// insertOutboxMessages appends messages to the outbox; q must be the transaction that
+// makes the change the messages describe
+func insertOutboxMessages(ctx context.Context, q queryer, messages ...model.OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	if err := lockOutboxSessions(ctx, q, messages); err != nil {
+		return err
+	}
+
+	var qb queryBuilder
+	rows := make([]string, 0, len(messages))
+	for _, m := range messages {
+		var eventType *string
+		if m.EventType != "" {
+			eventType = &m.EventType
+		}
+		rows = append(rows, fmt.Sprintf("(%s, %s, %s, %s, %s, %s, %s)",
+			qb.bind(m.Type), qb.bind(m.SessionID), qb.bind(m.EventID),
+			qb.bind(eventType), qb.bind([]byte(m.Data)), qb.bind(m.CreatedAt), qb.bind(m.OrgID)))
+	}
+
+	query := `INSERT INTO outbox_messages (type, session_id, event_id, event_type, data, created_at, org_id) VALUES ` + strings.Join(rows, ", ")
+	_, err := q.ExecContext(ctx, query, qb.args...)
+	return translateError(err, nil)
+}
+
+// lockOutboxSessions locks the sessions the messages belong to until the transaction
+// ends. Writes to a session otherwise only share-lock it, so without this two of them
+// could commit in the opposite order to the one their outbox IDs were assigned in.
+func lockOutboxSessions(ctx context.Context, q queryer, messages []model.OutboxMessage) error {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, m := range messages {
+		id := m.SessionID.String()
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	// Lock in a consistent order so batches spanning sessions cannot deadlock
+	sort.Strings(ids)
+
+	rows, err := q.QueryContext(ctx, `SELECT id FROM sessions WHERE id = ANY($1) ORDER BY id FOR NO KEY UPDATE`, pq.Array(ids))
+	if err != nil {
+		return translateError(err, nil)
+	}
+	rows.Close()
+	return rows.Err()
+}
+
+// insertSessionOutboxMessage records a change to a session in the outbox
+func insertSessionOutboxMessage(ctx context.Context, q queryer, activityType model.ActivityType, s *model.Session) error {
+	m, err := model.NewSessionOutboxMessage(activityType, s)
+	if err != nil {
+		return err
+	}
+	return insertOutboxMessages(ctx, q, m)
+}
+
+// insertEventOutboxMessages records logged events in the outbox
+func insertEventOutboxMessages(ctx context.Context, q queryer, events ...model.SessionEvent) error {
+	messages := make([]model.OutboxMessage, len(events))
+	for i := range events {
+		m, err := model.NewEventOutboxMessage(&events[i])
+		if err != nil {
+			return err
+		}
+		messages[i] = m
+	}
+	return insertOutboxMessages(ctx, q, messages...)
+}
+
+// ListUnpublishedOutbox returns the oldest messages not yet published, by ID
+func (st *Store) ListUnpublishedOutbox(ctx context.Context, limit int) ([]model.OutboxMessage, error) {
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT `+outboxColumns+` FROM outbox_messages
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	messages := []model.OutboxMessage{}
+	for rows.Next() {
+		var m model.OutboxMessage
+		if err := scanOutboxMessage(rows, &m); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// MarkOutboxPublished records that messages were accepted by the message bus
+func (st *Store) MarkOutboxPublished(ctx context.Context, ids []int64, at time.Time) error {
+	_, err := st.db.ExecContext(ctx,
+		`UPDATE outbox_messages SET published_at = $1 WHERE id = ANY($2) AND published_at IS NULL`, at, pq.Array(ids))
+	return translateError(err, nil)
+}
+
+// PurgeOutbox removes old messages every enabled consumer has processed
+func (st *Store) PurgeOutbox(ctx context.Context, before time.Time, consumers model.OutboxConsumers) (int64, error) {
+	query := `
+		DELETE FROM outbox_messages m
+		WHERE m.created_at < $1
+			AND NOT EXISTS (SELECT 1 FROM webhook_deliveries d WHERE d.message_id = m.id)`
+	if consumers.Webhooks {
+		query += ` AND m.webhooks_dispatched_at IS NOT NULL`
+	}
+	if consumers.Publisher {
+		query += ` AND m.published_at IS NOT NULL`
+	}
+
+	result, err := st.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, translateError(err, nil)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// queryBuilder accumulates positional arguments for a parameterized query
+type queryBuilder struct {
+	args []interface{}
+}
+
+func (b *queryBuilder) bind(value interface{}) string {
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+// whereClause renders the filter conditions and access as a parameterized SQL
+// predicate. Only columns from the declared session schema are ever spliced into the
+// query.
+func (b *queryBuilder) whereClause(filter model.SessionFilter) string {
+	clauses := []string{b.accessClause(filter.Access)}
+
+	for _, condition := range filter.AllConditions() {
+		field, _ := model.LookupSessionField(condition.Field)
+		column := field.Column
+
+		switch condition.Operator {
+		case model.OpIsNull:
+			if condition.Values[0].(bool) {
+				clauses = append(clauses, column+" IS NULL")
+			} else {
+				clauses = append(clauses, column+" IS NOT NULL")
+			}
+		case model.OpIn, model.OpNotIn:
+			placeholders := make([]string, len(condition.Values))
+			for i, value := range condition.Values {
+				placeholders[i] = b.bind(value)
+			}
+			keyword := "IN"
+			if condition.Operator == model.OpNotIn {
+				keyword = "NOT IN"
+			}
+			clauses = append(clauses, fmt.Sprintf("%s %s (%s)", column, keyword, strings.Join(placeholders, ", ")))
+		case model.OpContains:
+			pattern := "%" + escapeLike(condition.Values[0].(string)) + "%"
+			clauses = append(clauses, fmt.Sprintf("%s ILIKE %s", column, b.bind(pattern)))
+		default:
+			clauses = append(clauses, fmt.Sprintf("%s %s %s", column, sqlOperators[condition.Operator], b.bind(condition.Values[0])))
+		}
+	}
+
+	return strings.Join(clauses, " AND ")
+}
+
+// accessClause renders the sessions visible under access as a parameterized SQL
+// predicate
+func (b *queryBuilder) accessClause(access model.SessionAccess) string {
+	var scope string
+	switch {
+	case access.Scope == model.SessionScopeAll:
+		scope = "1=1"
+	case access.Scope == model.SessionScopeTeam && access.Team != "":
+		scope = fmt.Sprintf("(created_by = %s OR team = %s)", b.bind(access.UserID), b.bind(access.Team))
+	default:
+		scope = "created_by = " + b.bind(access.UserID)
+	}
+	if access.OrgID == uuid.Nil {
+		return scope
+	}
+	return fmt.Sprintf("org_id = %s AND %s", b.bind(access.OrgID), scope)
+}
+
+var sqlOperators = map[model.FilterOperator]string{
+	model.OpEq:  "=",
+	model.OpNe:  "<>",
+	model.OpGt:  ">",
+	model.OpGte: ">=",
+	model.OpLt:  "<",
+	model.OpLte: "<=",
+}
+
+// orderClause renders the sort fields, always ending with id so pages are deterministic
+func orderClause(sort []model.SortField) string {
+	keys := make([]string, 0, len(sort)+1)
+	hasID := false
+
+	for _, s := range sort {
+		direction := "ASC"
+		if s.Desc {
+			direction = "DESC"
+		}
+		if s.Field == "id" {
+			hasID = true
+		}
+		field, _ := model.LookupSessionField(s.Field)
+		keys = append(keys, field.Column+" "+direction)
+	}
+	if !hasID {
+		keys = append(keys, "id ASC")
+	}
+
+	return strings.Join(keys, ", ")
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+const roleColumns = `name, description, permissions, session_scope, builtin, created_at, updated_at`
+
+func scanRole(row scanner, r *model.Role) error {
+	var permissions []string
+	err := row.Scan(&r.Name, &r.Description, pq.Array(&permissions), &r.SessionScope, &r.Builtin, &r.CreatedAt, &r.UpdatedAt)
+	r.Permissions = toPermissions(permissions)
+	return err
+}
+
+func toPermissions(values []string) model.Permissions {
+	permissions := make(model.Permissions, len(values))
+	for i, v := range values {
+		permissions[i] = model.Permission(v)
+	}
+	return permissions
+}
+
+func permissionStrings(permissions model.Permissions) []string {
+	out := make([]string, len(permissions))
+	for i, p := range permissions {
+		out[i] = string(p)
+	}
+	return out
+}
+
+// ListRoles retrieves all roles by name
+func (st *Store) ListRoles(ctx context.Context) ([]model.Role, error) {
+	rows, err := st.db.QueryContext(ctx, `SELECT `+roleColumns+` FROM roles ORDER BY name`)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	roles := []model.Role{}
+	for rows.Next() {
+		var r model.Role
+		if err := scanRole(rows, &r); err != nil {
+			return nil, err
+		}
+		roles = append(roles, r)
+	}
+	return roles, rows.Err()
+}
+
+// GetRole retrieves a role by name
+func (st *Store) GetRole(ctx context.Context, name string) (*model.Role, error) {
+	var r model.Role
+	query := `SELECT ` + roleColumns + ` FROM roles WHERE name = $1`
+
+	if err := scanRole(st.db.QueryRowContext(ctx, query, name), &r); err != nil {
+		return nil, translateError(err, model.ErrRoleNotFound)
+	}
+	return &r, nil
+}
+
+// CreateRole inserts a new role
+func (st *Store) CreateRole(ctx context.Context, r *model.Role) error {
+	query := `
+		INSERT INTO roles (name, description, permissions, session_scope, builtin, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + roleColumns
+
+	err := scanRole(st.db.QueryRowContext(ctx,
+		query,
+		r.Name, r.Description, pq.Array(permissionStrings(r.Permissions)), r.SessionScope, r.Builtin, r.CreatedAt, r.UpdatedAt,
+	), r)
+	return translateError(err, nil)
+}
+
+// UpdateRole stores the description, permissions and session scope of a role
+func (st *Store) UpdateRole(ctx context.Context, r *model.Role) error {
+	query := `
+		UPDATE roles SET description = $1, permissions = $2, session_scope = $3, updated_at = $4
+		WHERE name = $5
+		RETURNING ` + roleColumns
+
+	err := scanRole(st.db.QueryRowContext(ctx,
+		query,
+		r.Description, pq.Array(permissionStrings(r.Permissions)), r.SessionScope, r.UpdatedAt, r.Name,
+	), r)
+	return translateError(err, model.ErrRoleNotFound)
+}
+
+// DeleteRole removes a role no user holds. The outer query sees the roles as they were
+// before the delete, which tells a role in use from a missing one.
+func (st *Store) DeleteRole(ctx context.Context, name string) error {
+	var deleted, existed bool
+	err := st.db.QueryRowContext(ctx, `
+		WITH deleted AS (
+			DELETE FROM roles
+			WHERE name = $1 AND NOT EXISTS (SELECT 1 FROM users WHERE role = $1)
+			RETURNING name
+		)
+		SELECT EXISTS(SELECT 1 FROM deleted), EXISTS(SELECT 1 FROM roles WHERE name = $1)`,
+		name).Scan(&deleted, &existed)
+	switch {
+	case err != nil:
+		return translateError(err, nil)
+	case deleted:
+		return nil
+	case existed:
+		return model.ErrRoleInUse
+	}
+	return model.ErrRoleNotFound
+}
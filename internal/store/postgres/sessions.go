@@ -0,0 +1,330 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+const sessionColumns = `id, started_at, ended_at, caller_id, callee_id, status, initial_metadata, disposition, external_source, external_id, created_at, updated_at, caller_id_raw, caller_id_type, caller_number_type, callee_id_raw, callee_id_type, callee_number_type, created_by, team, org_id`
+
+func scanSession(row scanner, s *model.Session) error {
+	return row.Scan(
+		&s.ID, &s.StartedAt, &s.EndedAt,
+		&s.CallerID, &s.CalleeID, &s.Status,
+		&s.InitialMetadata, &s.Disposition,
+		&s.ExternalSource, &s.ExternalID,
+		&s.CreatedAt, &s.UpdatedAt,
+		&s.CallerIDRaw, &s.CallerIDType, &s.CallerNumberType,
+		&s.CalleeIDRaw, &s.CalleeIDType, &s.CalleeNumberType,
+		&s.CreatedBy, &s.Team, &s.OrgID,
+	)
+}
+
+// CreateSession inserts a new session
+func (st *Store) CreateSession(ctx context.Context, s *model.Session) error {
+	query := `
+		INSERT INTO sessions (id, started_at, caller_id, callee_id, status, initial_metadata, external_source, external_id, created_at, updated_at,
+			caller_id_raw, caller_id_type, caller_number_type, callee_id_raw, callee_id_type, callee_number_type, created_by, team, org_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+		RETURNING ` + sessionColumns
+
+	return st.inOrgTx(ctx, s.OrgID, func(tx *sql.Tx) error {
+		err := scanSession(tx.QueryRowContext(ctx,
+			query,
+			s.ID, s.StartedAt, s.CallerID, s.CalleeID, s.Status, s.InitialMetadata, s.ExternalSource, s.ExternalID, s.CreatedAt, s.UpdatedAt,
+			s.CallerIDRaw, s.CallerIDType, s.CallerNumberType, s.CalleeIDRaw, s.CalleeIDType, s.CalleeNumberType, s.CreatedBy, s.Team, s.OrgID,
+		), s)
+		if err != nil {
+			return translateError(err, nil)
+		}
+
+		return insertSessionOutboxMessage(ctx, tx, model.ActivitySessionStarted, s)
+	})
+}
+
+// GetSession retrieves a session by ID
+func (st *Store) GetSession(ctx context.Context, sessionID string) (*model.Session, error) {
+	var session model.Session
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = $1`
+
+	err := st.scoped(ctx, uuid.Nil, func(q queryer) error {
+		return scanSession(q.QueryRowContext(ctx, query, sessionID), &session)
+	})
+	if err != nil {
+		return nil, translateError(err, model.ErrSessionNotFound)
+	}
+
+	return &session, nil
+}
+
+// GetSessions retrieves the sessions with the given IDs
+func (st *Store) GetSessions(ctx context.Context, sessionIDs []string) ([]model.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = ANY($1::uuid[])`
+	var sessions []model.Session
+	err := st.scoped(ctx, uuid.Nil, func(q queryer) error {
+		var err error
+		sessions, err = querySessions(ctx, q, query, []interface{}{pq.Array(sessionIDs)})
+		return err
+	})
+	return sessions, err
+}
+
+// GetSessionByExternalID retrieves a session of an organization by the ID its source
+// system assigned
+func (st *Store) GetSessionByExternalID(ctx context.Context, orgID, source, externalID string) (*model.Session, error) {
+	org, err := uuid.Parse(orgID)
+	if err != nil {
+		return nil, model.ErrSessionNotFound
+	}
+	var session model.Session
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE org_id = $1 AND external_source = $2 AND external_id = $3`
+
+	err = st.scoped(ctx, org, func(q queryer) error {
+		return scanSession(q.QueryRowContext(ctx, query, org, source, externalID), &session)
+	})
+	if err != nil {
+		return nil, translateError(err, model.ErrSessionNotFound)
+	}
+
+	return &session, nil
+}
+
+// TransitionSession moves a session to a new state and records the transition event and
+// the session metrics in the same transaction
+func (st *Store) TransitionSession(ctx context.Context, sessionID string, t *model.SessionTransition) (*model.Session, error) {
+	var endedAt *time.Time
+	if t.To.IsTerminal() {
+		endedAt = &t.Event.EventTime
+	}
+
+	var session model.Session
+	err := st.inOrgTx(ctx, t.Event.OrgID, func(tx *sql.Tx) error {
+		query := `
+			UPDATE sessions
+			SET status = $1, disposition = COALESCE($2, disposition), ended_at = COALESCE($3, ended_at), updated_at = CURRENT_TIMESTAMP
+			WHERE id = $4 AND status = $5
+			RETURNING ` + sessionColumns
+
+		err := scanSession(tx.QueryRowContext(ctx,
+			query,
+			t.To, t.Disposition, endedAt, sessionID, t.From,
+		), &session)
+		if err != nil {
+			return translateError(err, model.ErrConcurrentTransition)
+		}
+
+		if err := insertEvent(ctx, tx, &t.Event); err != nil {
+			return err
+		}
+		if t.Metrics != nil {
+			if err := upsertMetrics(ctx, tx, t.Metrics); err != nil {
+				return err
+			}
+		}
+		if t.To.IsTerminal() {
+			if err := insertCDR(ctx, tx, model.NewCDR(&session, t.Metrics)); err != nil {
+				return err
+			}
+		}
+
+		if err := insertSessionOutboxMessage(ctx, tx, model.ActivityTypeForStatus(t.To), &session); err != nil {
+			return err
+		}
+		return insertEventOutboxMessages(ctx, tx, t.Event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// ListSessions retrieves sessions based on filter criteria
+func (st *Store) ListSessions(ctx context.Context, filter model.SessionFilter) (*model.SessionListResponse, error) {
+	var response *model.SessionListResponse
+	err := st.readScoped(ctx, filter.Access, func(db queryer) error {
+		var err error
+		if filter.UseCursor {
+			response, err = listSessionsByCursor(ctx, db, filter)
+		} else {
+			response, err = listSessionsByOffset(ctx, db, filter)
+		}
+		return err
+	})
+	return response, err
+}
+
+// listSessionsByOffset pages through sessions by offset in the filter's sort order
+func listSessionsByOffset(ctx context.Context, db queryer, filter model.SessionFilter) (*model.SessionListResponse, error) {
+	var response model.SessionListResponse
+	response.Limit = filter.Limit
+	response.Offset = &filter.Offset
+
+	// Build query
+	var qb queryBuilder
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE ` + qb.whereClause(filter)
+
+	// Get total count
+	if filter.IncludeTotal {
+		total, err := countSessions(ctx, db, query, qb.args)
+		if err != nil {
+			return nil, err
+		}
+		response.Total = &total
+	}
+
+	// Add sorting and pagination
+	sort := filter.Sort
+	if len(sort) == 0 {
+		sort = model.DefaultSessionSort
+	}
+	query += " ORDER BY " + orderClause(sort)
+	query += fmt.Sprintf(" LIMIT %s OFFSET %s", qb.bind(filter.Limit), qb.bind(filter.Offset))
+
+	sessions, err := querySessions(ctx, db, query, qb.args)
+	if err != nil {
+		return nil, err
+	}
+	response.Sessions = sessions
+
+	return &response, nil
+}
+
+// listSessionsByCursor pages through sessions on the (started_at, id) keyset, which stays
+// stable while new sessions are inserted
+func listSessionsByCursor(ctx context.Context, db queryer, filter model.SessionFilter) (*model.SessionListResponse, error) {
+	desc, err := model.CursorSortDirection(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build query
+	var qb queryBuilder
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE ` + qb.whereClause(filter)
+
+	// The total ignores the cursor position so it describes the whole result set
+	var total *int64
+	if filter.IncludeTotal {
+		count, err := countSessions(ctx, db, query, qb.args)
+		if err != nil {
+			return nil, err
+		}
+		total = &count
+	}
+
+	scanDesc := filter.ScanDescending(desc)
+	if filter.Cursor != nil {
+		comparison := ">"
+		if scanDesc {
+			comparison = "<"
+		}
+		query += fmt.Sprintf(" AND (started_at, id) %s (%s, %s)",
+			comparison, qb.bind(filter.Cursor.StartedAt), qb.bind(filter.Cursor.ID))
+	}
+	direction := "ASC"
+	if scanDesc {
+		direction = "DESC"
+	}
+	query += fmt.Sprintf(" ORDER BY started_at %s, id %s LIMIT %s", direction, direction, qb.bind(filter.Limit+1))
+
+	sessions, err := querySessions(ctx, db, query, qb.args)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := model.NewCursorPage(filter, desc, sessions)
+	if err != nil {
+		return nil, err
+	}
+	response.Total = total
+
+	return response, nil
+}
+
+func countSessions(ctx context.Context, db queryer, query string, args []interface{}) (int64, error) {
+	var total int64
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s) as count_query", query)
+	err := db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
+	return total, translateError(err, nil)
+}
+
+func querySessions(ctx context.Context, db queryer, query string, args []interface{}) ([]model.Session, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	sessions := []model.Session{}
+	for rows.Next() {
+		var session model.Session
+		if err := scanSession(rows, &session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// ListStaleSessions finds active sessions that have been idle or running for too long.
+// Activity is measured by when events were received, falling back to the start time.
+func (st *Store) ListStaleSessions(ctx context.Context, q model.StaleSessionQuery) ([]model.Session, error) {
+	var qb queryBuilder
+	var criteria []string
+	if !q.InactiveSince.IsZero() {
+		criteria = append(criteria, fmt.Sprintf(
+			"COALESCE((SELECT MAX(e.created_at) FROM session_events e WHERE e.session_id = sessions.id), started_at) < %s",
+			qb.bind(q.InactiveSince)))
+	}
+	if !q.StartedBefore.IsZero() {
+		criteria = append(criteria, "started_at < "+qb.bind(q.StartedBefore))
+	}
+	if len(criteria) == 0 {
+		return []model.Session{}, nil
+	}
+
+	statuses := make([]string, 0, len(model.ActiveSessionStatuses()))
+	for _, status := range model.ActiveSessionStatuses() {
+		statuses = append(statuses, qb.bind(status))
+	}
+
+	query := `SELECT ` + sessionColumns + ` FROM sessions` +
+		` WHERE status IN (` + strings.Join(statuses, ", ") + `)` +
+		` AND (` + strings.Join(criteria, " OR ") + `)` +
+		` ORDER BY started_at ASC, id ASC LIMIT ` + qb.bind(q.Limit)
+
+	var sessions []model.Session
+	err := st.scoped(ctx, uuid.Nil, func(q queryer) error {
+		var err error
+		sessions, err = querySessions(ctx, q, query, qb.args)
+		return err
+	})
+	return sessions, err
+}
+
+// PurgeSessions deletes the sessions of an organization that ended before the given
+// time; their events, metrics and CDRs are removed by the cascading foreign keys
+func (st *Store) PurgeSessions(ctx context.Context, orgID string, before time.Time) (int64, error) {
+	org, err := uuid.Parse(orgID)
+	if err != nil {
+		return 0, model.ErrOrganizationNotFound
+	}
+	var purged int64
+	err = st.scoped(ctx, org, func(q queryer) error {
+		result, err := q.ExecContext(ctx, `DELETE FROM sessions WHERE org_id = $1 AND ended_at < $2`, org, before)
+		if err != nil {
+			return translateError(err, nil)
+		}
+		purged, err = result.RowsAffected()
+		return err
+	})
+	return purged, err
+}
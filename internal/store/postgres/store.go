@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// Store implements the model stores on top of PostgreSQL
+type Store struct {
+	db               *sql.DB
+	rowLevelSecurity bool
+}
+
+var _ model.Store = (*Store)(nil)
+
+// Option configures optional Store behaviour
+type Option func(*Store)
+
+// WithRowLevelSecurity runs every statement on the sessions and session_events tables
+// in a transaction that sets app.org_id to the organization it acts for, so the
+// row-level security policies of the tables hide, and refuse to write, the rows of other
+// organizations even if a filter were wrong. Statements acting for no organization in
+// particular, such as background work and lookups by ID the service checks afterwards,
+// switch to the workerRole instead. The policies and workerRole are set up by
+// `migrate rls`, and the server must then connect as a role that does not own the
+// tables and is a member of workerRole.
+func WithRowLevelSecurity() Option {
+	return func(st *Store) {
+		st.rowLevelSecurity = true
+	}
+}
+
+// workerRole is the database role the row-level security policies let see the sessions
+// of every organization. The policies only apply to it while it is the current role, so
+// membership alone grants nothing.
+const workerRole = "session_workers"
+
+// New creates a Store backed by the given connection pool
+func New(db *sql.DB, opts ...Option) *Store {
+	st := &Store{db: db}
+	for _, opt := range opts {
+		opt(st)
+	}
+	return st
+}
+
+// CheckRowLevelSecurity reports whether the database is set up for WithRowLevelSecurity:
+// the policies are enabled by `migrate rls`, and the server connects as a member of the
+// workerRole that does not own the tables, which the policies would not bind
+func (st *Store) CheckRowLevelSecurity(ctx context.Context) error {
+	var roleExists, member, enabled, owner bool
+	err := st.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = $1),
+			EXISTS (SELECT 1 FROM pg_roles WHERE rolname = $1 AND pg_has_role(current_user, oid, 'MEMBER')),
+			COALESCE(bool_and(c.relrowsecurity), false) AND count(*) = 2,
+			COALESCE(bool_or(pg_has_role(current_user, c.relowner, 'MEMBER')), false)
+		FROM pg_class c
+		WHERE c.oid IN ('sessions'::regclass, 'session_events'::regclass)`,
+		workerRole).Scan(&roleExists, &member, &enabled, &owner)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !roleExists || !enabled:
+		return errors.New("row-level security is not set up: run `migrate rls` as the owner of the tables")
+	case !member:
+		return errors.New("the database user is not a member of " + workerRole)
+	case owner:
+		return errors.New("the database user owns the sessions tables, which row-level security does not bind")
+	}
+	return nil
+}
+
+// scanner is satisfied by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// inTx runs fn in a transaction, committing if it succeeds and rolling back otherwise
+func (st *Store) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err, nil)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return translateError(tx.Commit(), nil)
+}
+
+// scoped runs fn against the pool, or, with row-level security enabled, in a
+// transaction limited to an organization, or to every organization when orgID is
+// uuid.Nil. The settings are local to the transaction, so they never leak to other
+// users of a pooled connection.
+func (st *Store) scoped(ctx context.Context, orgID uuid.UUID, fn func(q queryer) error) error {
+	if !st.rowLevelSecurity {
+		return fn(st.db)
+	}
+	return st.inOrgTx(ctx, orgID, func(tx *sql.Tx) error {
+		return fn(tx)
+	})
+}
+
+// readScoped runs fn like scoped, in a read-only transaction limited to the
+// organization of access
+func (st *Store) readScoped(ctx context.Context, access model.SessionAccess, fn func(q queryer) error) error {
+	if !st.rowLevelSecurity {
+		return fn(st.db)
+	}
+
+	tx, err := st.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return translateError(err, nil)
+	}
+	defer tx.Rollback()
+
+	if err := st.setOrganization(ctx, tx, access.OrgID); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return translateError(tx.Commit(), nil)
+}
+
+// inOrgTx runs fn in a transaction like inTx, limited to an organization like scoped
+func (st *Store) inOrgTx(ctx context.Context, orgID uuid.UUID, fn func(tx *sql.Tx) error) error {
+	return st.inTx(ctx, func(tx *sql.Tx) error {
+		if err := st.setOrganization(ctx, tx, orgID); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// setOrganization limits the rest of a transaction to the rows of an organization when
+// row-level security is enabled, or lets it see every organization as the workerRole
+// when orgID is uuid.Nil
+func (st *Store) setOrganization(ctx context.Context, tx *sql.Tx, orgID uuid.UUID) error {
+	if !st.rowLevelSecurity {
+		return nil
+	}
+	if orgID == uuid.Nil {
+		_, err := tx.ExecContext(ctx, `SET LOCAL ROLE `+workerRole)
+		return translateError(err, nil)
+	}
+	_, err := tx.ExecContext(ctx, `SELECT set_config('app.org_id', $1, true)`, orgID.String())
+	return translateError(err, nil)
+}
+
+// commonOrganization returns the organization all of orgIDs belong to, or uuid.Nil when
+// they span several
+func commonOrganization(orgIDs ...uuid.UUID) uuid.UUID {
+	if len(orgIDs) == 0 {
+		return uuid.Nil
+	}
+	for _, id := range orgIDs[1:] {
+		if id != orgIDs[0] {
+			return uuid.Nil
+		}
+	}
+	return orgIDs[0]
+}
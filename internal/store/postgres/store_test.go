@@ -0,0 +1,252 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/vasu74/Call_Session_Management/internal/migrate"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// openTestDB connects to the database named by TEST_DATABASE_URL and migrates it,
+// skipping the test when the variable is not set
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrate.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func createOrganization(t *testing.T, st *Store) uuid.UUID {
+	t.Helper()
+	now := time.Now()
+	org := &model.Organization{ID: uuid.New(), Name: "test-" + uuid.NewString(), AllowedEventTypes: []string{}, CreatedAt: now, UpdatedAt: now}
+	if err := st.CreateOrganization(context.Background(), org); err != nil {
+		t.Fatal(err)
+	}
+	return org.ID
+}
+
+func createSession(t *testing.T, st *Store, orgID uuid.UUID) *model.Session {
+	t.Helper()
+	now := time.Now()
+	s := &model.Session{
+		ID:               uuid.New(),
+		OrgID:            orgID,
+		StartedAt:        now,
+		CallerID:         "+14155550100",
+		CallerIDType:     model.IdentityUnknown,
+		CallerNumberType: model.NumberTypeUnknown,
+		CalleeID:         "+14155550199",
+		CalleeIDType:     model.IdentityUnknown,
+		CalleeNumberType: model.NumberTypeUnknown,
+		Status:           model.SessionStatusInitiated,
+		InitialMetadata:  model.SessionMetadata{},
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if err := st.CreateSession(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestTransitionSession(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	st := New(db)
+	s := createSession(t, st, createOrganization(t, st))
+
+	transition, err := model.NewSessionTransition(s, model.SessionStatusRinging, time.Now(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := st.TransitionSession(ctx, s.ID.String(), transition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != model.SessionStatusRinging {
+		t.Errorf("status = %s, want %s", got.Status, model.SessionStatusRinging)
+	}
+
+	var events, messages int
+	if err := db.QueryRowContext(ctx, `SELECT count(*) FROM session_events WHERE session_id = $1`, s.ID).Scan(&events); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRowContext(ctx, `SELECT count(*) FROM outbox_messages WHERE session_id = $1`, s.ID).Scan(&messages); err != nil {
+		t.Fatal(err)
+	}
+	// The start of the session, its transition and the transition event
+	if events != 1 || messages != 3 {
+		t.Errorf("got %d events and %d outbox messages, want 1 and 3", events, messages)
+	}
+
+	// A transition from the state the session has left loses the race
+	stale, err := model.NewSessionTransition(s, model.SessionStatusAnswered, time.Now(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.TransitionSession(ctx, s.ID.String(), stale); !errors.Is(err, model.ErrConcurrentTransition) {
+		t.Fatalf("stale transition error = %v, want %v", err, model.ErrConcurrentTransition)
+	}
+}
+
+func TestLockOutboxSessions(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	st := New(db)
+	orgID := createOrganization(t, st)
+	first, second := createSession(t, st, orgID), createSession(t, st, orgID)
+
+	messages := func(sessions ...*model.Session) []model.OutboxMessage {
+		var m []model.OutboxMessage
+		for _, s := range sessions {
+			m = append(m, model.OutboxMessage{SessionID: s.ID, OrgID: s.OrgID})
+		}
+		return m
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if err := lockOutboxSessions(ctx, tx, messages(first, second)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A batch naming the sessions in the opposite order waits for the first to commit
+	// instead of deadlocking with it
+	done := make(chan error, 1)
+	go func() {
+		done <- st.inTx(ctx, func(other *sql.Tx) error {
+			return lockOutboxSessions(ctx, other, messages(second, first, second))
+		})
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("second batch did not wait for the locks, error = %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("second batch still waiting after the first committed")
+	}
+}
+
+// rlsTestRole is the role TestRowLevelSecurity acts as: a member of workerRole that does
+// not own the tables, like the server is required to connect as
+const rlsTestRole = "csm_rls_test"
+
+func TestRowLevelSecurity(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	migrator, err := migrate.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.EnableRowLevelSecurity(ctx); err != nil {
+		t.Skipf("cannot set up row-level security: %v", err)
+	}
+	_, err = db.ExecContext(ctx, `
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = '`+rlsTestRole+`') THEN
+				CREATE ROLE `+rlsTestRole+` NOLOGIN;
+			END IF;
+		END
+		$$;
+		GRANT `+workerRole+` TO `+rlsTestRole)
+	if err != nil {
+		t.Skipf("cannot create %s: %v", rlsTestRole, err)
+	}
+
+	// A pool of a single connection keeps the role for every statement the store runs
+	roleDB, err := sql.Open("postgres", os.Getenv("TEST_DATABASE_URL"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { roleDB.Close() })
+	roleDB.SetMaxOpenConns(1)
+	if _, err := roleDB.ExecContext(ctx, `SET ROLE `+rlsTestRole); err != nil {
+		t.Skipf("cannot act as %s: %v", rlsTestRole, err)
+	}
+
+	st := New(roleDB, WithRowLevelSecurity())
+	if err := st.CheckRowLevelSecurity(ctx); err != nil {
+		t.Fatalf("CheckRowLevelSecurity = %v", err)
+	}
+	orgA, orgB := createOrganization(t, st), createOrganization(t, st)
+	a, b := createSession(t, st, orgA), createSession(t, st, orgB)
+
+	var n int
+	countSessions := func(q queryer) error {
+		return q.QueryRowContext(ctx, `SELECT count(*) FROM sessions WHERE id = ANY($1::uuid[])`,
+			pq.Array([]string{a.ID.String(), b.ID.String()})).Scan(&n)
+	}
+
+	t.Run("organization", func(t *testing.T) {
+		err := st.readScoped(ctx, model.SessionAccess{Scope: model.SessionScopeAll, OrgID: orgA}, countSessions)
+		if err != nil || n != 1 {
+			t.Fatalf("saw %d sessions, %v; want only the one of the organization", n, err)
+		}
+	})
+
+	t.Run("workers", func(t *testing.T) {
+		err := st.readScoped(ctx, model.FullSessionAccess, countSessions)
+		if err != nil || n != 2 {
+			t.Fatalf("saw %d sessions, %v; want both", n, err)
+		}
+		got, err := st.GetSession(ctx, b.ID.String())
+		if err != nil || got.OrgID != orgB {
+			t.Fatalf("GetSession = %+v, %v; want the session of the other organization", got, err)
+		}
+	})
+
+	t.Run("unset", func(t *testing.T) {
+		if err := countSessions(roleDB); err != nil || n != 0 {
+			t.Fatalf("saw %d sessions without an organization, %v; want none", n, err)
+		}
+	})
+
+	t.Run("write to another organization", func(t *testing.T) {
+		err := st.scoped(ctx, orgA, func(q queryer) error {
+			_, err := q.ExecContext(ctx, `UPDATE sessions SET org_id = $1 WHERE id = $2`, orgB, a.ID)
+			return err
+		})
+		if err == nil {
+			t.Fatal("moving a session to another organization succeeded")
+		}
+	})
+}
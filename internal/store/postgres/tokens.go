@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+const refreshTokenColumns = `id, user_id, family_id, token_hash, access_token_id, access_token_expires_at, created_at, expires_at, used_at, revoked_at, scopes`
+
+func scanRefreshToken(row scanner, t *model.RefreshToken) error {
+	var scopes []string
+	err := row.Scan(
+		&t.ID, &t.UserID, &t.FamilyID, &t.TokenHash,
+		&t.AccessTokenID, &t.AccessTokenExpiresAt,
+		&t.CreatedAt, &t.ExpiresAt, &t.UsedAt, &t.RevokedAt, pq.Array(&scopes),
+	)
+	if scopes != nil {
+		t.Scopes = toPermissions(scopes)
+	}
+	return err
+}
+
+// scopesArray stores nil scopes as NULL, meaning all permissions of the role
+func scopesArray(scopes model.Permissions) interface{} {
+	if scopes == nil {
+		return nil
+	}
+	return pq.Array(permissionStrings(scopes))
+}
+
+func insertRefreshToken(ctx context.Context, q queryer, t *model.RefreshToken) error {
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, access_token_id, access_token_expires_at, created_at, expires_at, scopes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		t.ID, t.UserID, t.FamilyID, t.TokenHash, t.AccessTokenID, t.AccessTokenExpiresAt, t.CreatedAt, t.ExpiresAt, scopesArray(t.Scopes))
+	return translateError(err, nil)
+}
+
+// revokeRefreshTokens revokes the unrevoked refresh tokens matching the condition and
+// adds the access tokens issued with them that are still valid to the denylist. $1 is
+// the revocation time; the condition's arguments start at $2.
+func revokeRefreshTokens(ctx context.Context, q queryer, condition string, at time.Time, args ...interface{}) error {
+	_, err := q.ExecContext(ctx, `
+		WITH revoked AS (
+			UPDATE refresh_tokens SET revoked_at = $1
+			WHERE revoked_at IS NULL AND `+condition+`
+			RETURNING access_token_id, access_token_expires_at
+		)
+		INSERT INTO revoked_access_tokens (token_id, expires_at)
+		SELECT access_token_id, access_token_expires_at FROM revoked
+		WHERE access_token_expires_at > $1
+		ON CONFLICT (token_id) DO NOTHING`,
+		append([]interface{}{at}, args...)...)
+	return translateError(err, nil)
+}
+
+// CreateRefreshToken stores the first token of a new family
+func (st *Store) CreateRefreshToken(ctx context.Context, t *model.RefreshToken) error {
+	return insertRefreshToken(ctx, st.db, t)
+}
+
+// RotateRefreshToken exchanges a token for its successor. The token row is locked, so
+// of two concurrent exchanges of the same token one succeeds and the other is taken as
+// reuse.
+func (st *Store) RotateRefreshToken(ctx context.Context, tokenHash []byte, next *model.RefreshToken) (*model.RefreshToken, error) {
+	var used model.RefreshToken
+	reused := false
+	err := st.inTx(ctx, func(tx *sql.Tx) error {
+		err := scanRefreshToken(tx.QueryRowContext(ctx,
+			`SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE`, tokenHash), &used)
+		if err != nil {
+			return translateError(err, model.ErrInvalidRefreshToken)
+		}
+		if used.RevokedAt != nil || !used.ExpiresAt.After(next.CreatedAt) {
+			return model.ErrInvalidRefreshToken
+		}
+		if used.UsedAt != nil {
+			// Commit the revocation before reporting the reuse
+			reused = true
+			return revokeRefreshTokens(ctx, tx, `family_id = $2`, next.CreatedAt, used.FamilyID)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE refresh_tokens SET used_at = $1 WHERE id = $2`, next.CreatedAt, used.ID); err != nil {
+			return translateError(err, nil)
+		}
+		next.UserID, next.FamilyID, next.Scopes = used.UserID, used.FamilyID, used.Scopes
+		return insertRefreshToken(ctx, tx, next)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, model.ErrRefreshTokenReused
+	}
+	return &used, nil
+}
+
+// RevokeRefreshTokenFamily revokes a token family of a user
+func (st *Store) RevokeRefreshTokenFamily(ctx context.Context, userID, familyID string, at time.Time) error {
+	return revokeRefreshTokens(ctx, st.db, `user_id = $2 AND family_id = $3`, at, userID, familyID)
+}
+
+// RevokeUserRefreshTokens revokes every token family of a user
+func (st *Store) RevokeUserRefreshTokens(ctx context.Context, userID string, at time.Time) error {
+	return revokeRefreshTokens(ctx, st.db, `user_id = $2`, at, userID)
+}
+
+// RevokeRoleRefreshTokens revokes every token family of the users holding a role
+func (st *Store) RevokeRoleRefreshTokens(ctx context.Context, role string, at time.Time) error {
+	return revokeRefreshTokens(ctx, st.db, `user_id IN (SELECT id FROM users WHERE role = $2)`, at, role)
+}
+
+// IsAccessTokenRevoked reports whether an access token is on the denylist
+func (st *Store) IsAccessTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	var revoked bool
+	err := st.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM revoked_access_tokens WHERE token_id = $1)`, tokenID).Scan(&revoked)
+	if err != nil {
+		return false, translateError(err, nil)
+	}
+	return revoked, nil
+}
+
+// DeleteExpiredTokens removes expired refresh tokens and denylist entries
+func (st *Store) DeleteExpiredTokens(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	err := st.inTx(ctx, func(tx *sql.Tx) error {
+		for _, query := range []string{
+			`DELETE FROM refresh_tokens WHERE expires_at < $1`,
+			`DELETE FROM revoked_access_tokens WHERE expires_at < $1`,
+		} {
+			result, err := tx.ExecContext(ctx, query, before)
+			if err != nil {
+				return translateError(err, nil)
+			}
+			n, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			deleted += n
+		}
+		return nil
+	})
+	return deleted, err
+}
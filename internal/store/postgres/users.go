@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+// CreateUser inserts a new user account
+func (st *Store) CreateUser(ctx context.Context, u *model.User) error {
+	return insertUser(ctx, st.db, u)
+}
+
+// CreateUserWithOrganization inserts a new organization and its first user
+func (st *Store) CreateUserWithOrganization(ctx context.Context, u *model.User, org *model.Organization) error {
+	return st.inTx(ctx, func(tx *sql.Tx) error {
+		if err := insertOrganization(ctx, tx, org); err != nil {
+			return err
+		}
+		return insertUser(ctx, tx, u)
+	})
+}
+
+func insertUser(ctx context.Context, q queryer, u *model.User) error {
+	// Check if user already exists
+	var exists bool
+	err := q.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)", u.Email).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return model.ErrUserAlreadyExists
+	}
+
+	query := `
+		INSERT INTO users (id, email, password, role, team, org_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, email, role, team, org_id, created_at, updated_at`
+
+	err = q.QueryRowContext(ctx,
+		query,
+		u.ID, u.Email, u.Password, u.Role, u.Team, u.OrgID, u.CreatedAt, u.UpdatedAt,
+	).Scan(&u.ID, &u.Email, &u.Role, &u.Team, &u.OrgID, &u.CreatedAt, &u.UpdatedAt)
+	return translateError(err, nil)
+}
+
+// GetUserByID retrieves a user by their ID
+func (st *Store) GetUserByID(ctx context.Context, userID string) (*model.User, error) {
+	var user model.User
+	query := `SELECT id, email, role, team, org_id, created_at, updated_at FROM users WHERE id = $1`
+	err := st.db.QueryRowContext(ctx, query, userID).Scan(
+		&user.ID, &user.Email, &user.Role, &user.Team, &user.OrgID, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, translateError(err, model.ErrUserNotFound)
+	}
+	return &user, nil
+}
+
+// GetUserByEmail retrieves a user and their password hash by email
+func (st *Store) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	var user model.User
+	query := `SELECT id, email, password, role, team, org_id, created_at, updated_at FROM users WHERE email = $1`
+	err := st.db.QueryRowContext(ctx, query, email).Scan(
+		&user.ID, &user.Email, &user.Password, &user.Role, &user.Team, &user.OrgID, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, translateError(err, model.ErrUserNotFound)
+	}
+	return &user, nil
+}
+
+// ListUsers retrieves the users of an organization, or all users, oldest first
+func (st *Store) ListUsers(ctx context.Context, orgID string) ([]model.User, error) {
+	var qb queryBuilder
+	query := `SELECT id, email, role, team, org_id, created_at, updated_at FROM users`
+	if orgID != "" {
+		query += ` WHERE org_id = ` + qb.bind(orgID)
+	}
+	query += ` ORDER BY created_at, id`
+
+	rows, err := st.db.QueryContext(ctx, query, qb.args...)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	users := []model.User{}
+	for rows.Next() {
+		var user model.User
+		if err := rows.Scan(&user.ID, &user.Email, &user.Role, &user.Team, &user.OrgID, &user.CreatedAt, &user.UpdatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// UpdateUser stores the role, team and organization of a user
+func (st *Store) UpdateUser(ctx context.Context, u *model.User) error {
+	query := `
+		UPDATE users SET role = $1, team = $2, org_id = $3, updated_at = $4
+		WHERE id = $5
+		RETURNING id, email, role, team, org_id, created_at, updated_at`
+	err := st.db.QueryRowContext(ctx, query, u.Role, u.Team, u.OrgID, u.UpdatedAt, u.ID).Scan(
+		&u.ID, &u.Email, &u.Role, &u.Team, &u.OrgID, &u.CreatedAt, &u.UpdatedAt,
+	)
+	return translateError(err, model.ErrUserNotFound)
+}
@@ -0,0 +1,354 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+)
+
+const webhookColumns = `id, url, description, secret, events, event_types, active, created_at, updated_at, org_id`
+
+func scanWebhook(row scanner, w *model.Webhook) error {
+	var events []string
+	err := row.Scan(&w.ID, &w.URL, &w.Description, &w.Secret, pq.Array(&events), pq.Array(&w.EventTypes), &w.Active, &w.CreatedAt, &w.UpdatedAt, &w.OrgID)
+	w.Events = make([]model.ActivityType, len(events))
+	for i, e := range events {
+		w.Events[i] = model.ActivityType(e)
+	}
+	if w.EventTypes == nil {
+		w.EventTypes = []string{}
+	}
+	return err
+}
+
+func activityTypeStrings(types []model.ActivityType) []string {
+	out := make([]string, len(types))
+	for i, t := range types {
+		out[i] = string(t)
+	}
+	return out
+}
+
+// deliveryColumns selects a delivery joined with its outbox message as d and m
+const deliveryColumns = `d.id, d.webhook_id, d.message_id, m.type, m.session_id, d.status, d.attempts, d.next_attempt_at, d.last_error, d.delivered_at, d.created_at, d.updated_at`
+
+func scanDelivery(row scanner, d *model.WebhookDelivery, extra ...interface{}) error {
+	dest := []interface{}{
+		&d.ID, &d.WebhookID, &d.MessageID, &d.MessageType, &d.SessionID, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastError, &d.DeliveredAt, &d.CreatedAt, &d.UpdatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// CreateWebhook inserts a new webhook
+func (st *Store) CreateWebhook(ctx context.Context, w *model.Webhook) error {
+	query := `
+		INSERT INTO webhooks (id, url, description, secret, events, event_types, active, created_at, updated_at, org_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING ` + webhookColumns
+
+	err := scanWebhook(st.db.QueryRowContext(ctx,
+		query,
+		w.ID, w.URL, w.Description, w.Secret, pq.Array(activityTypeStrings(w.Events)), pq.Array(w.EventTypes), w.Active, w.CreatedAt, w.UpdatedAt, w.OrgID,
+	), w)
+	return translateError(err, nil)
+}
+
+// GetWebhook retrieves a webhook by ID
+func (st *Store) GetWebhook(ctx context.Context, webhookID string) (*model.Webhook, error) {
+	var w model.Webhook
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1`
+
+	if err := scanWebhook(st.db.QueryRowContext(ctx, query, webhookID), &w); err != nil {
+		return nil, translateError(err, model.ErrWebhookNotFound)
+	}
+	return &w, nil
+}
+
+// ListWebhooks retrieves the webhooks of an organization, oldest first
+func (st *Store) ListWebhooks(ctx context.Context, orgID string) ([]model.Webhook, error) {
+	rows, err := st.db.QueryContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE org_id = $1 ORDER BY created_at, id`, orgID)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	webhooks := []model.Webhook{}
+	for rows.Next() {
+		var w model.Webhook
+		if err := scanWebhook(rows, &w); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+// UpdateWebhook stores the changed fields of a webhook
+func (st *Store) UpdateWebhook(ctx context.Context, w *model.Webhook) error {
+	query := `
+		UPDATE webhooks
+		SET url = $1, description = $2, events = $3, event_types = $4, active = $5, updated_at = $6
+		WHERE id = $7
+		RETURNING ` + webhookColumns
+
+	err := scanWebhook(st.db.QueryRowContext(ctx,
+		query,
+		w.URL, w.Description, pq.Array(activityTypeStrings(w.Events)), pq.Array(w.EventTypes), w.Active, w.UpdatedAt, w.ID,
+	), w)
+	return translateError(err, model.ErrWebhookNotFound)
+}
+
+// DeleteWebhook removes a webhook; its deliveries are removed by the foreign key
+func (st *Store) DeleteWebhook(ctx context.Context, webhookID string) error {
+	result, err := st.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1`, webhookID)
+	if err != nil {
+		return translateError(err, nil)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return model.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// DispatchOutbox fans undispatched outbox messages out into webhook deliveries
+func (st *Store) DispatchOutbox(ctx context.Context, limit int) (int, error) {
+	var dispatched int
+	err := st.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT `+outboxColumns+` FROM outbox_messages
+			WHERE webhooks_dispatched_at IS NULL
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED`, limit)
+		if err != nil {
+			return translateError(err, nil)
+		}
+		var messages []model.OutboxMessage
+		for rows.Next() {
+			var m model.OutboxMessage
+			if err := scanOutboxMessage(rows, &m); err != nil {
+				rows.Close()
+				return err
+			}
+			messages = append(messages, m)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil || len(messages) == 0 {
+			return err
+		}
+
+		rows, err = tx.QueryContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE active`)
+		if err != nil {
+			return translateError(err, nil)
+		}
+		var webhooks []model.Webhook
+		for rows.Next() {
+			var w model.Webhook
+			if err := scanWebhook(rows, &w); err != nil {
+				rows.Close()
+				return err
+			}
+			webhooks = append(webhooks, w)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		ids := make([]int64, len(messages))
+		for i := range messages {
+			ids[i] = messages[i].ID
+			for j := range webhooks {
+				if !webhooks[j].Matches(&messages[i]) {
+					continue
+				}
+				_, err := tx.ExecContext(ctx, `
+					INSERT INTO webhook_deliveries (id, webhook_id, message_id, status, next_attempt_at, created_at, updated_at)
+					VALUES ($1, $2, $3, $4, $5, $5, $5)
+					ON CONFLICT (webhook_id, message_id) DO NOTHING`,
+					uuid.New(), webhooks[j].ID, messages[i].ID, model.WebhookDeliveryPending, now)
+				if err != nil {
+					return translateError(err, nil)
+				}
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE outbox_messages SET webhooks_dispatched_at = $1 WHERE id = ANY($2)`, now, pq.Array(ids))
+		if err != nil {
+			return translateError(err, nil)
+		}
+		dispatched = len(messages)
+		return nil
+	})
+	return dispatched, err
+}
+
+// ClaimWebhookDeliveries leases the pending deliveries that are due
+func (st *Store) ClaimWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]model.WebhookTask, error) {
+	query := `
+		WITH due AS (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhooks w ON w.id = d.webhook_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= $1 AND w.active
+			ORDER BY d.next_attempt_at
+			LIMIT $3
+			FOR UPDATE OF d SKIP LOCKED
+		), claimed AS (
+			UPDATE webhook_deliveries d SET next_attempt_at = $2
+			FROM due WHERE d.id = due.id
+			RETURNING d.*
+		)
+		SELECT ` + deliveryColumns + `, w.url, w.secret, m.event_id, m.event_type, m.data, m.created_at
+		FROM claimed d
+		JOIN webhooks w ON w.id = d.webhook_id
+		JOIN outbox_messages m ON m.id = d.message_id
+		ORDER BY d.next_attempt_at, d.created_at`
+
+	rows, err := st.db.QueryContext(ctx, query, now, leaseUntil, limit)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	var tasks []model.WebhookTask
+	for rows.Next() {
+		var t model.WebhookTask
+		var eventType *string
+		err := scanDelivery(rows, &t.Delivery,
+			&t.URL, &t.Secret, &t.Message.EventID, &eventType, &t.Message.Data, &t.Message.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		t.Message.ID = t.Delivery.MessageID
+		t.Message.Type = t.Delivery.MessageType
+		t.Message.SessionID = t.Delivery.SessionID
+		if eventType != nil {
+			t.Message.EventType = *eventType
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// RecordWebhookAttempt logs an attempt and updates its delivery in one transaction
+func (st *Store) RecordWebhookAttempt(ctx context.Context, a *model.WebhookAttempt, update model.WebhookDeliveryUpdate) error {
+	return st.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO webhook_attempts (id, delivery_id, attempt, attempted_at, duration_ms, status_code, error)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			a.ID, a.DeliveryID, a.Attempt, a.AttemptedAt, a.DurationMs, a.StatusCode, a.Error)
+		if err != nil {
+			return translateError(err, nil)
+		}
+
+		var deliveredAt *time.Time
+		if update.Status == model.WebhookDeliverySucceeded {
+			deliveredAt = &a.AttemptedAt
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET status = $1, attempts = $2, next_attempt_at = $3, last_error = $4, delivered_at = $5, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $6`,
+			update.Status, a.Attempt, update.NextAttemptAt, a.Error, deliveredAt, a.DeliveryID)
+		return translateError(err, nil)
+	})
+}
+
+// ListWebhookDeliveries retrieves a page of the deliveries of a webhook, newest first
+func (st *Store) ListWebhookDeliveries(ctx context.Context, webhookID string, filter model.WebhookDeliveryFilter) ([]model.WebhookDelivery, error) {
+	if _, err := st.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	var qb queryBuilder
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries d JOIN outbox_messages m ON m.id = d.message_id
+		WHERE d.webhook_id = ` + qb.bind(webhookID)
+	if filter.Status != "" {
+		query += ` AND d.status = ` + qb.bind(filter.Status)
+	}
+	query += ` ORDER BY d.created_at DESC, d.id DESC LIMIT ` + qb.bind(filter.Limit) + ` OFFSET ` + qb.bind(filter.Offset)
+
+	rows, err := st.db.QueryContext(ctx, query, qb.args...)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	deliveries := []model.WebhookDelivery{}
+	for rows.Next() {
+		var d model.WebhookDelivery
+		if err := scanDelivery(rows, &d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetWebhookDelivery retrieves a delivery of a webhook with its attempt log
+func (st *Store) GetWebhookDelivery(ctx context.Context, webhookID, deliveryID string) (*model.WebhookDeliveryDetails, error) {
+	var details model.WebhookDeliveryDetails
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries d JOIN outbox_messages m ON m.id = d.message_id
+		WHERE d.webhook_id = $1 AND d.id = $2`
+
+	if err := scanDelivery(st.db.QueryRowContext(ctx, query, webhookID, deliveryID), &details.WebhookDelivery); err != nil {
+		return nil, translateError(err, model.ErrWebhookDeliveryNotFound)
+	}
+
+	rows, err := st.db.QueryContext(ctx, `
+		SELECT id, delivery_id, attempt, attempted_at, duration_ms, status_code, error
+		FROM webhook_attempts WHERE delivery_id = $1 ORDER BY attempt, attempted_at`, deliveryID)
+	if err != nil {
+		return nil, translateError(err, nil)
+	}
+	defer rows.Close()
+
+	details.AttemptLog = []model.WebhookAttempt{}
+	for rows.Next() {
+		var a model.WebhookAttempt
+		if err := rows.Scan(&a.ID, &a.DeliveryID, &a.Attempt, &a.AttemptedAt, &a.DurationMs, &a.StatusCode, &a.Error); err != nil {
+			return nil, err
+		}
+		details.AttemptLog = append(details.AttemptLog, a)
+	}
+	return &details, rows.Err()
+}
+
+// RetryWebhookDelivery makes a delivery pending again
+func (st *Store) RetryWebhookDelivery(ctx context.Context, webhookID, deliveryID string, at time.Time) (*model.WebhookDelivery, error) {
+	var d model.WebhookDelivery
+	query := `
+		WITH retried AS (
+			UPDATE webhook_deliveries
+			SET status = 'pending', attempts = 0, next_attempt_at = $1, updated_at = CURRENT_TIMESTAMP
+			WHERE webhook_id = $2 AND id = $3
+			RETURNING *
+		)
+		SELECT ` + deliveryColumns + `
+		FROM retried d JOIN outbox_messages m ON m.id = d.message_id`
+
+	if err := scanDelivery(st.db.QueryRowContext(ctx, query, at, webhookID, deliveryID), &d); err != nil {
+		return nil, translateError(err, model.ErrWebhookDeliveryNotFound)
+	}
+	return &d, nil
+}
+
+// PurgeWebhookDeliveries removes old finished deliveries
+func (st *Store) PurgeWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	result, err := st.db.ExecContext(ctx,
+		`DELETE FROM webhook_deliveries WHERE status <> 'pending' AND updated_at < $1`, before)
+	if err != nil {
+		return 0, translateError(err, nil)
+	}
+	return result.RowsAffected()
+}
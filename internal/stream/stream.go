@@ -0,0 +1,275 @@
+package stream
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+)
+
+// Notification is a session activity together with the session and event it concerns,
+// as delivered to stream subscribers. Status is the state of the session after the
+// activity; Session is loaded afterwards and may have moved on.
+type Notification struct {
+	ID      int64               `json:"id"`
+	Type    model.ActivityType  `json:"type"`
+	Status  model.SessionStatus `json:"status"`
+	Session *model.Session      `json:"session"`
+	Event   *model.SessionEvent `json:"event,omitempty"`
+}
+
+// Filter selects the notifications a subscriber receives; empty fields match everything.
+// Access always applies, so subscribers only hear of the sessions they can see.
+type Filter struct {
+	Access    model.SessionAccess
+	SessionID string
+	Statuses  []model.SessionStatus
+	CallerID  string
+	CalleeID  string
+}
+
+// Matches reports whether the notification passes the filter. Statuses are matched
+// against the state of the session after the activity, not its current state.
+func (f Filter) Matches(n Notification) bool {
+	s := n.Session
+	if !f.Access.CanSee(s) {
+		return false
+	}
+	if f.SessionID != "" && s.ID.String() != f.SessionID {
+		return false
+	}
+	if f.CallerID != "" && s.CallerID != f.CallerID {
+		return false
+	}
+	if f.CalleeID != "" && s.CalleeID != f.CalleeID {
+		return false
+	}
+	if len(f.Statuses) == 0 {
+		return true
+	}
+	for _, status := range f.Statuses {
+		if n.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Config controls the replay buffer and keep-alive behaviour of a Hub
+type Config struct {
+	// ReplayBuffer is the number of recent notifications kept for resuming streams
+	ReplayBuffer int
+	// Heartbeat is how often idle streams are sent a keep-alive
+	Heartbeat time.Duration
+	// SubscriberBuffer is how many notifications a subscriber may fall behind before
+	// it is disconnected
+	SubscriberBuffer int
+}
+
+// Hub fans session activity out to stream subscribers and keeps the most recent
+// notifications so reconnecting clients can resume where they left off
+type Hub struct {
+	svc *service.Service
+	cfg Config
+
+	mu     sync.Mutex
+	replay []Notification // ring buffer of the most recent notifications
+	start  int
+	subs   map[*Subscription]struct{}
+	closed bool
+	done   chan struct{}
+}
+
+// NewHub creates a hub loading sessions and events through svc
+func NewHub(svc *service.Service, cfg Config) *Hub {
+	if cfg.ReplayBuffer <= 0 {
+		cfg.ReplayBuffer = 1000
+	}
+	if cfg.Heartbeat <= 0 {
+		cfg.Heartbeat = 15 * time.Second
+	}
+	if cfg.SubscriberBuffer <= 0 {
+		cfg.SubscriberBuffer = 256
+	}
+	return &Hub{
+		svc:    svc,
+		cfg:    cfg,
+		replay: make([]Notification, 0, cfg.ReplayBuffer),
+		subs:   make(map[*Subscription]struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Heartbeat returns how often idle streams should be sent a keep-alive
+func (h *Hub) Heartbeat() time.Duration {
+	return h.cfg.Heartbeat
+}
+
+// Run publishes the activity delivered by feed until ctx is cancelled
+func (h *Hub) Run(ctx context.Context, feed model.ActivityFeed) error {
+	return feed.ListenActivity(ctx, func(activity model.Activity) {
+		n, err := h.load(ctx, activity)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Stream hub failed to load activity %d: %v", activity.ID, err)
+			}
+			return
+		}
+		h.publish(n)
+	})
+}
+
+func (h *Hub) load(ctx context.Context, activity model.Activity) (Notification, error) {
+	n := Notification{ID: activity.ID, Type: activity.Type, Status: activity.Status}
+
+	session, err := h.svc.GetSession(ctx, model.FullSessionAccess, activity.SessionID.String())
+	if err != nil {
+		return n, err
+	}
+	n.Session = session
+	// Activity announced before the status was part of the payload
+	if n.Status == "" {
+		n.Status = session.Status
+	}
+
+	if activity.EventID != nil {
+		event, err := h.svc.GetEvent(ctx, activity.EventID.String())
+		if err != nil {
+			return n, err
+		}
+		n.Event = event
+	}
+	return n, nil
+}
+
+func (h *Hub) publish(n Notification) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.replay) < cap(h.replay) {
+		h.replay = append(h.replay, n)
+	} else {
+		h.replay[h.start] = n
+		h.start = (h.start + 1) % len(h.replay)
+	}
+
+	for sub := range h.subs {
+		if !sub.filter.Matches(n) {
+			continue
+		}
+		select {
+		case sub.ch <- n:
+		default:
+			// Disconnect subscribers that cannot keep up rather than stall the others;
+			// they can reconnect and resume from the replay buffer
+			h.remove(sub)
+		}
+	}
+}
+
+// Subscribe registers a subscriber for notifications matching filter. If lastEventID
+// is set, the buffered notifications after it are returned for replay.
+func (h *Hub) Subscribe(filter Filter, lastEventID string) (*Subscription, []Notification, error) {
+	var lastID int64
+	if lastEventID != "" {
+		var err error
+		if lastID, err = strconv.ParseInt(lastEventID, 10, 64); err != nil {
+			return nil, nil, model.ErrInvalidRequest.WithMessage("Last-Event-ID must be the ID of a stream event")
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &Subscription{hub: h, filter: filter, ch: make(chan Notification, h.cfg.SubscriberBuffer)}
+	if h.closed {
+		close(sub.ch)
+		return sub, nil, nil
+	}
+	h.subs[sub] = struct{}{}
+
+	if lastEventID == "" {
+		return sub, nil, nil
+	}
+	return sub, h.replaySince(lastID, filter), nil
+}
+
+// replaySince returns the buffered notifications after lastID. Activity IDs come from a
+// sequence taken before commit, so they are not strictly ordered; the buffer position of
+// lastID is used when it is still buffered.
+func (h *Hub) replaySince(lastID int64, filter Filter) []Notification {
+	buffered := make([]Notification, 0, len(h.replay))
+	buffered = append(buffered, h.replay[h.start:]...)
+	buffered = append(buffered, h.replay[:h.start]...)
+
+	from := -1
+	for i, n := range buffered {
+		if n.ID == lastID {
+			from = i + 1
+			break
+		}
+	}
+
+	var replay []Notification
+	for i, n := range buffered {
+		if from >= 0 && i < from || from < 0 && n.ID <= lastID {
+			continue
+		}
+		if filter.Matches(n) {
+			replay = append(replay, n)
+		}
+	}
+	return replay
+}
+
+// Close disconnects all subscribers; it is called when the server shuts down
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	h.closed = true
+	close(h.done)
+	for sub := range h.subs {
+		h.remove(sub)
+	}
+}
+
+// Done is closed when the hub shuts down
+func (h *Hub) Done() <-chan struct{} {
+	return h.done
+}
+
+// remove unregisters a subscriber and closes its channel; the caller must hold h.mu
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Subscription receives the notifications matching its filter
+type Subscription struct {
+	hub    *Hub
+	filter Filter
+	ch     chan Notification
+}
+
+// Notifications returns the channel notifications are delivered on. It is closed when
+// the subscriber falls too far behind or the hub shuts down.
+func (s *Subscription) Notifications() <-chan Notification {
+	return s.ch
+}
+
+// Close unregisters the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
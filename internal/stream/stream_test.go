@@ -0,0 +1,159 @@
+package stream
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+	"github.com/vasu74/Call_Session_Management/internal/store/memory"
+)
+
+var orgAccess = model.SessionAccess{Scope: model.SessionScopeAll, OrgID: model.DefaultOrganizationID, HomeOrgID: model.DefaultOrganizationID}
+
+func notification(id int64, session *model.Session) Notification {
+	return Notification{ID: id, Type: model.ActivitySessionStarted, Status: session.Status, Session: session}
+}
+
+func newSession(status model.SessionStatus) *model.Session {
+	return &model.Session{ID: uuid.New(), OrgID: model.DefaultOrganizationID, CallerID: "+14155550100", CalleeID: "+14155550199", Status: status}
+}
+
+func TestFilterMatches(t *testing.T) {
+	owner := uuid.New()
+	session := newSession(model.SessionStatusRinging)
+	session.CreatedBy = &owner
+	otherOrg := *session
+	otherOrg.OrgID = uuid.New()
+
+	tests := []struct {
+		name    string
+		filter  Filter
+		session *model.Session
+		want    bool
+	}{
+		{name: "everything", filter: Filter{Access: orgAccess}, session: session, want: true},
+		{name: "other organization", filter: Filter{Access: orgAccess}, session: &otherOrg},
+		{name: "own session", filter: Filter{Access: model.SessionAccess{Scope: model.SessionScopeOwn, OrgID: model.DefaultOrganizationID, UserID: owner}}, session: session, want: true},
+		{name: "someone else's session", filter: Filter{Access: model.SessionAccess{Scope: model.SessionScopeOwn, OrgID: model.DefaultOrganizationID, UserID: uuid.New()}}, session: session},
+		{name: "session ID", filter: Filter{Access: orgAccess, SessionID: session.ID.String()}, session: session, want: true},
+		{name: "other session ID", filter: Filter{Access: orgAccess, SessionID: uuid.NewString()}, session: session},
+		{name: "status", filter: Filter{Access: orgAccess, Statuses: []model.SessionStatus{model.SessionStatusAnswered, model.SessionStatusRinging}}, session: session, want: true},
+		{name: "other status", filter: Filter{Access: orgAccess, Statuses: []model.SessionStatus{model.SessionStatusAnswered}}, session: session},
+		{name: "caller", filter: Filter{Access: orgAccess, CallerID: "+14155550100"}, session: session, want: true},
+		{name: "other callee", filter: Filter{Access: orgAccess, CalleeID: "+14155550100"}, session: session},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(notification(1, tt.session)); got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHubFiltersOnActivityStatus(t *testing.T) {
+	st := memory.New()
+	hub := NewHub(service.New(st), Config{})
+	session := newSession(model.SessionStatusAnswered)
+	if err := st.CreateSession(context.Background(), session); err != nil {
+		t.Fatal(err)
+	}
+
+	// The session has been answered by the time the ringing activity is loaded
+	n, err := hub.load(context.Background(), model.Activity{ID: 1, Type: model.ActivitySessionUpdated, SessionID: session.ID, Status: model.SessionStatusRinging})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Status != model.SessionStatusRinging || n.Session.Status != model.SessionStatusAnswered {
+		t.Fatalf("loaded status %s with session status %s", n.Status, n.Session.Status)
+	}
+	if !(Filter{Access: orgAccess, Statuses: []model.SessionStatus{model.SessionStatusRinging}}).Matches(n) {
+		t.Error("ringing subscriber missed the ringing activity")
+	}
+	if (Filter{Access: orgAccess, Statuses: []model.SessionStatus{model.SessionStatusAnswered}}).Matches(n) {
+		t.Error("answered subscriber received the ringing activity")
+	}
+}
+
+func TestHubDeliversAndReplays(t *testing.T) {
+	hub := NewHub(nil, Config{ReplayBuffer: 3})
+	answered := newSession(model.SessionStatusAnswered)
+	ringing := newSession(model.SessionStatusRinging)
+
+	sub, replay, err := hub.Subscribe(Filter{Access: orgAccess, Statuses: []model.SessionStatus{model.SessionStatusAnswered}}, "")
+	if err != nil || replay != nil {
+		t.Fatalf("Subscribe = %v, %v, want no replay", replay, err)
+	}
+	for id, session := range []*model.Session{answered, ringing, answered, answered, ringing} {
+		hub.publish(notification(int64(id+1), session))
+	}
+	for _, want := range []int64{1, 3, 4} {
+		if n := <-sub.Notifications(); n.ID != want {
+			t.Fatalf("received notification %d, want %d", n.ID, want)
+		}
+	}
+
+	tests := []struct {
+		lastEventID string
+		want        []int64
+	}{
+		// Notification 2 has left the three notification buffer
+		{lastEventID: "2", want: []int64{3, 4}},
+		{lastEventID: "3", want: []int64{4}},
+		{lastEventID: "5", want: nil},
+		{lastEventID: "1", want: []int64{3, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lastEventID, func(t *testing.T) {
+			resumed, replay, err := hub.Subscribe(Filter{Access: orgAccess, Statuses: []model.SessionStatus{model.SessionStatusAnswered}}, tt.lastEventID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resumed.Close()
+			if len(replay) != len(tt.want) {
+				t.Fatalf("replayed %d notifications, want %v", len(replay), tt.want)
+			}
+			for i, n := range replay {
+				if n.ID != tt.want[i] {
+					t.Errorf("replayed notification %d, want %d", n.ID, tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, _, err := hub.Subscribe(Filter{Access: orgAccess}, "latest"); err == nil {
+		t.Error("Subscribe accepted a Last-Event-ID that is not a number")
+	}
+}
+
+func TestHubDisconnectsSlowSubscribers(t *testing.T) {
+	hub := NewHub(nil, Config{SubscriberBuffer: 1})
+	slow, _, _ := hub.Subscribe(Filter{Access: orgAccess}, "")
+	fast, _, _ := hub.Subscribe(Filter{Access: orgAccess}, "")
+
+	hub.publish(notification(1, newSession(model.SessionStatusRinging)))
+	<-fast.Notifications()
+	hub.publish(notification(2, newSession(model.SessionStatusRinging)))
+
+	if n, ok := <-slow.Notifications(); !ok || n.ID != 1 {
+		t.Fatalf("slow subscriber received %d, %v, want the buffered notification", n.ID, ok)
+	}
+	if _, ok := <-slow.Notifications(); ok {
+		t.Error("slow subscriber was not disconnected")
+	}
+	if n, ok := <-fast.Notifications(); !ok || n.ID != 2 {
+		t.Errorf("fast subscriber received %d, %v, want notification 2", n.ID, ok)
+	}
+
+	hub.Close()
+	if _, ok := <-fast.Notifications(); ok {
+		t.Error("subscriber was not disconnected when the hub closed")
+	}
+	select {
+	case <-hub.Done():
+	default:
+		t.Error("Done is not closed after Close")
+	}
+}
@@ -0,0 +1,352 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+)
+
+// LockKey identifies the advisory lock that elects the replica delivering webhooks
+const LockKey int64 = 0x63736d5f776873 // "csm_whs"
+
+// Delivery request headers
+const (
+	IDHeader        = "X-Webhook-ID"
+	EventHeader     = "X-Webhook-Event"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+// maxResponseBody bounds how much of a receiver's response is read before the
+// connection is given up rather than reused; the body itself is not kept
+const maxResponseBody = 64 << 10
+
+// Leader decides whether this replica should deliver webhooks
+type Leader interface {
+	Acquire(ctx context.Context) (bool, error)
+	Release()
+}
+
+// Config controls how often the outbox is polled and how deliveries are retried
+type Config struct {
+	Interval time.Duration
+	// Timeout bounds a single delivery attempt
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts after which a delivery is dead-lettered
+	MaxAttempts int
+	// BackoffBase is the delay after the first failed attempt; it doubles with every
+	// further attempt up to BackoffMax
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// Retention is how long finished deliveries and their attempt logs are kept
+	Retention   time.Duration
+	BatchSize   int
+	Concurrency int
+	// AllowPrivateNetworks lets deliveries connect to loopback, private and link-local
+	// addresses, which are refused by default so that webhooks cannot reach internal
+	// services
+	AllowPrivateNetworks bool
+}
+
+// Enabled reports whether webhooks should be delivered at all
+func (c Config) Enabled() bool {
+	return c.Interval > 0
+}
+
+// Backoff returns the delay before the attempt following the given failed attempt
+func (c Config) Backoff(attempt int) time.Duration {
+	delay := c.BackoffBase
+	for i := 1; i < attempt && delay < c.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > c.BackoffMax {
+		delay = c.BackoffMax
+	}
+	return delay
+}
+
+// Sign computes the signature header value for a delivery: the hex encoded
+// HMAC-SHA256 of the timestamp, a dot and the body, keyed with the webhook's secret
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher turns outbox messages into webhook deliveries and attempts them,
+// retrying failures with exponential backoff until they succeed or are dead-lettered
+type Dispatcher struct {
+	svc    *service.Service
+	leader Leader
+	cfg    Config
+	logger *log.Logger
+	client *http.Client
+
+	lastPurge time.Time
+}
+
+// New creates a dispatcher; leader may be nil when only a single replica runs
+func New(svc *service.Service, leader Leader, cfg Config, logger *log.Logger) *Dispatcher {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 10
+	}
+	if cfg.BackoffBase <= 0 {
+		cfg.BackoffBase = 30 * time.Second
+	}
+	if cfg.BackoffMax <= 0 {
+		cfg.BackoffMax = 6 * time.Hour
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 8
+	}
+
+	// Addresses are checked as connections are made, after the host has been resolved,
+	// so a name that resolves differently at delivery time cannot bypass the check.
+	// Proxies are not used, since the addresses they connect to cannot be checked.
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = refuseNonPublicAddress
+	}
+	client := &http.Client{
+		Timeout: cfg.Timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+		// A redirect is reported as a failed attempt rather than followed
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return &Dispatcher{svc: svc, leader: leader, cfg: cfg, logger: logger, client: client}
+}
+
+// nonPublicPrefixes are the ranges IsGlobalUnicast and IsPrivate leave out that are not
+// reachable on the public internet either
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// isPublicAddress reports whether deliveries may connect to addr: loopback, private,
+// link-local, multicast, unspecified and shared address space are refused
+func isPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// refuseNonPublicAddress is the dialer's Control function, which runs for every address
+// a connection is attempted to
+func refuseNonPublicAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !isPublicAddress(addr) {
+		return fmt.Errorf("refusing to connect to non-public address %s", addr)
+	}
+	return nil
+}
+
+// Run delivers webhooks every interval until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	if d.leader != nil {
+		defer d.leader.Release()
+	}
+
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.tick(ctx)
+		}
+	}
+}
+
+func (d *Dispatcher) tick(ctx context.Context) {
+	if d.leader != nil {
+		leading, err := d.leader.Acquire(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				d.logger.Printf("Webhook leader election failed: %v", err)
+			}
+			return
+		}
+		if !leading {
+			return
+		}
+	}
+
+	for ctx.Err() == nil {
+		dispatched, err := d.svc.DispatchOutbox(ctx, d.cfg.BatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				d.logger.Printf("Webhook outbox dispatch failed: %v", err)
+			}
+			return
+		}
+		if dispatched < d.cfg.BatchSize {
+			break
+		}
+	}
+
+	for ctx.Err() == nil {
+		tasks, err := d.svc.ClaimWebhookDeliveries(ctx, d.cfg.BatchSize, d.lease())
+		if err != nil {
+			if ctx.Err() == nil {
+				d.logger.Printf("Webhook delivery claim failed: %v", err)
+			}
+			return
+		}
+		d.deliverAll(ctx, tasks)
+		if len(tasks) < d.cfg.BatchSize {
+			break
+		}
+	}
+
+	if d.cfg.Retention > 0 && time.Since(d.lastPurge) >= time.Hour {
+		d.lastPurge = time.Now()
+		if _, err := d.svc.PurgeWebhookDeliveries(ctx, d.cfg.Retention); err != nil && ctx.Err() == nil {
+			d.logger.Printf("Webhook delivery purge failed: %v", err)
+		}
+	}
+}
+
+// lease is how long a claimed delivery is reserved; it outlasts an attempt, so only
+// deliveries abandoned by a crashed replica become due again
+func (d *Dispatcher) lease() time.Duration {
+	return d.cfg.Timeout + 30*time.Second
+}
+
+func (d *Dispatcher) deliverAll(ctx context.Context, tasks []model.WebhookTask) {
+	sem := make(chan struct{}, d.cfg.Concurrency)
+	var wg sync.WaitGroup
+	for _, task := range tasks {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(task model.WebhookTask) {
+			defer func() { <-sem; wg.Done() }()
+			d.deliver(ctx, task)
+		}(task)
+	}
+	wg.Wait()
+}
+
+// deliver attempts a delivery and records the outcome. Attempts cut short by shutdown
+// are not recorded; the delivery becomes due again when its lease expires.
+func (d *Dispatcher) deliver(ctx context.Context, task model.WebhookTask) {
+	attempt := &model.WebhookAttempt{
+		ID:          uuid.New(),
+		DeliveryID:  task.Delivery.ID,
+		Attempt:     task.Delivery.Attempts + 1,
+		AttemptedAt: time.Now(),
+	}
+
+	statusCode, err := d.post(ctx, task)
+	if ctx.Err() != nil {
+		return
+	}
+	attempt.DurationMs = time.Since(attempt.AttemptedAt).Milliseconds()
+	if statusCode != 0 {
+		attempt.StatusCode = &statusCode
+	}
+	if err == nil && !attempt.Succeeded() {
+		err = fmt.Errorf("receiver responded with status %d", statusCode)
+	}
+	if err != nil {
+		message := err.Error()
+		attempt.Error = &message
+	}
+
+	update := model.WebhookDeliveryUpdate{Status: model.WebhookDeliverySucceeded}
+	switch {
+	case attempt.Succeeded():
+	case attempt.Attempt >= d.cfg.MaxAttempts:
+		update.Status = model.WebhookDeliveryDead
+		d.logger.Printf("Webhook delivery %s dead-lettered after %d attempts: %v", task.Delivery.ID, attempt.Attempt, err)
+	default:
+		next := time.Now().Add(d.cfg.Backoff(attempt.Attempt))
+		update.Status = model.WebhookDeliveryPending
+		update.NextAttemptAt = &next
+	}
+
+	if err := d.svc.RecordWebhookAttempt(context.WithoutCancel(ctx), attempt, update); err != nil {
+		d.logger.Printf("Webhook delivery %s: failed to record attempt: %v", task.Delivery.ID, err)
+	}
+}
+
+// post sends the message to the webhook and returns the response status. The response
+// body is discarded, so that receivers cannot be used to read internal pages.
+func (d *Dispatcher) post(ctx context.Context, task model.WebhookTask) (int, error) {
+	body, err := json.Marshal(task.Message)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, task.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Call-Session-Management-Webhooks/1.0")
+	req.Header.Set(IDHeader, task.Delivery.ID.String())
+	req.Header.Set(EventHeader, string(task.Message.Type))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(task.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+	return resp.StatusCode, nil
+}
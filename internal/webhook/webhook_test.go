@@ -0,0 +1,206 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vasu74/Call_Session_Management/internal/model"
+	"github.com/vasu74/Call_Session_Management/internal/service"
+	"github.com/vasu74/Call_Session_Management/internal/store/memory"
+)
+
+var orgAccess = model.SessionAccess{Scope: model.SessionScopeAll, OrgID: model.DefaultOrganizationID, HomeOrgID: model.DefaultOrganizationID}
+
+func TestSign(t *testing.T) {
+	got := Sign("whsec_test", 1700000000, []byte(`{"id":1}`))
+	if want := "sha256=2f441ba4b3b2d50d28a9ab9d9fd8880376ecd1eb5d0435401553f5d8d0a5dcf8"; got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+	if Sign("whsec_test", 1700000001, []byte(`{"id":1}`)) == got {
+		t.Error("signature does not cover the timestamp")
+	}
+}
+
+func TestConfigBackoff(t *testing.T) {
+	cfg := Config{BackoffBase: 30 * time.Second, BackoffMax: 5 * time.Minute}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 30 * time.Second},
+		{attempt: 2, want: time.Minute},
+		{attempt: 4, want: 4 * time.Minute},
+		{attempt: 5, want: 5 * time.Minute},
+		{attempt: 50, want: 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.attempt), func(t *testing.T) {
+			if got := cfg.Backoff(tt.attempt); got != tt.want {
+				t.Errorf("Backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+// receiver records the webhook requests it answers with status
+type receiver struct {
+	status int
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	r.mu.Unlock()
+	w.WriteHeader(r.status)
+}
+
+func TestDispatcherDeliversAndRetries(t *testing.T) {
+	ctx := context.Background()
+	svc := service.New(memory.New())
+
+	ok := &receiver{status: http.StatusNoContent}
+	failing := &receiver{status: http.StatusInternalServerError}
+	webhooks := make(map[*receiver]*model.Webhook)
+	for _, r := range []*receiver{ok, failing} {
+		server := httptest.NewServer(r)
+		defer server.Close()
+		webhook, err := svc.CreateWebhook(ctx, orgAccess, model.CreateWebhookRequest{URL: server.URL, Events: []model.ActivityType{model.ActivitySessionStarted}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		webhooks[r] = webhook
+	}
+	session, _, err := svc.StartSession(ctx, orgAccess, model.StartSessionRequest{CallerID: "+14155550100", CalleeID: "+14155550199"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The receivers listen on the loopback interface
+	d := New(svc, nil, Config{Interval: time.Minute, MaxAttempts: 2, BackoffBase: time.Millisecond, BackoffMax: time.Millisecond, AllowPrivateNetworks: true}, log.New(io.Discard, "", 0))
+	d.tick(ctx)
+
+	if len(ok.requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(ok.requests))
+	}
+	req, body := ok.requests[0], ok.bodies[0]
+	timestamp, err := strconv.ParseInt(req.Header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Header.Get(SignatureHeader) != Sign(webhooks[ok].Secret, timestamp, body) {
+		t.Errorf("signature %s does not match the body", req.Header.Get(SignatureHeader))
+	}
+	if req.Header.Get(EventHeader) != string(model.ActivitySessionStarted) || req.Header.Get(IDHeader) == "" {
+		t.Errorf("headers %v", req.Header)
+	}
+
+	tests := []struct {
+		name     string
+		receiver *receiver
+		// ticks run before the delivery is checked, each after the backoff has passed
+		ticks    int
+		status   model.WebhookDeliveryStatus
+		attempts int
+	}{
+		{name: "accepted", receiver: ok, status: model.WebhookDeliverySucceeded, attempts: 1},
+		{name: "retried", receiver: failing, status: model.WebhookDeliveryPending, attempts: 1},
+		{name: "dead-lettered", receiver: failing, ticks: 1, status: model.WebhookDeliveryDead, attempts: 2},
+		{name: "accepted is not redelivered", receiver: ok, status: model.WebhookDeliverySucceeded, attempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < tt.ticks; i++ {
+				time.Sleep(5 * time.Millisecond)
+				d.tick(ctx)
+			}
+			deliveries, err := svc.ListWebhookDeliveries(ctx, orgAccess, webhooks[tt.receiver].ID.String(), model.WebhookDeliveryFilter{Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(deliveries) != 1 {
+				t.Fatalf("%d deliveries, want 1", len(deliveries))
+			}
+			delivery := deliveries[0]
+			if delivery.SessionID != session.ID || delivery.Status != tt.status || delivery.Attempts != tt.attempts {
+				t.Errorf("delivery %s after %d attempts, want %s after %d", delivery.Status, delivery.Attempts, tt.status, tt.attempts)
+			}
+			if len(tt.receiver.requests) != tt.attempts {
+				t.Errorf("receiver got %d requests, want %d", len(tt.receiver.requests), tt.attempts)
+			}
+		})
+	}
+}
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.216.34", want: true},
+		{addr: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{addr: "127.0.0.1"},
+		{addr: "::1"},
+		{addr: "10.1.2.3"},
+		{addr: "172.16.0.1"},
+		{addr: "192.168.1.1"},
+		{addr: "169.254.169.254"},
+		{addr: "fe80::1"},
+		{addr: "fd00::1"},
+		{addr: "100.64.0.1"},
+		{addr: "0.0.0.0"},
+		{addr: "224.0.0.1"},
+		{addr: "::ffff:127.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := isPublicAddress(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("isPublicAddress = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatcherRefusesPrivateAddresses(t *testing.T) {
+	ctx := context.Background()
+	svc := service.New(memory.New())
+
+	r := &receiver{status: http.StatusNoContent}
+	server := httptest.NewServer(r)
+	defer server.Close()
+	// A name resolving to the loopback interface is refused when the delivery connects
+	webhook, err := svc.CreateWebhook(ctx, orgAccess, model.CreateWebhookRequest{URL: strings.Replace(server.URL, "127.0.0.1", "localhost", 1), Events: []model.ActivityType{model.ActivitySessionStarted}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := svc.StartSession(ctx, orgAccess, model.StartSessionRequest{CallerID: "+14155550100", CalleeID: "+14155550199"}); err != nil {
+		t.Fatal(err)
+	}
+
+	New(svc, nil, Config{Interval: time.Minute}, log.New(io.Discard, "", 0)).tick(ctx)
+
+	if len(r.requests) != 0 {
+		t.Fatalf("receiver on the loopback interface got %d requests", len(r.requests))
+	}
+	deliveries, err := svc.ListWebhookDeliveries(ctx, orgAccess, webhook.ID.String(), model.WebhookDeliveryFilter{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].LastError == nil || !strings.Contains(*deliveries[0].LastError, "non-public address") {
+		t.Fatalf("deliveries %+v, want an attempt refused for its address", deliveries)
+	}
+}